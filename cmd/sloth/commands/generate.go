@@ -19,47 +19,218 @@ import (
 	"github.com/slok/sloth/internal/alert"
 	"github.com/slok/sloth/internal/app/generate"
 	"github.com/slok/sloth/internal/chronosphere"
+	"github.com/slok/sloth/internal/datadog"
+	"github.com/slok/sloth/internal/elastic"
+	"github.com/slok/sloth/internal/honeycomb"
 	"github.com/slok/sloth/internal/info"
 	"github.com/slok/sloth/internal/k8sprometheus"
+	"github.com/slok/sloth/internal/lightstep"
 	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/newrelic"
 	"github.com/slok/sloth/internal/openslo"
 	"github.com/slok/sloth/internal/prometheus"
+	"github.com/slok/sloth/internal/signalfx"
+	"github.com/slok/sloth/internal/sysdig"
 	kubernetesv1 "github.com/slok/sloth/pkg/kubernetes/api/sloth/v1"
 	prometheusv1 "github.com/slok/sloth/pkg/prometheus/api/v1"
 )
 
 type generateCommand struct {
-	slosInput             string
-	slosOut               string
-	slosExcludeRegex      string
-	slosIncludeRegex      string
-	slosOutputFormat      string
-	disableRecordings     bool
-	disableAlerts         bool
-	disableOptimizedRules bool
-	extraLabels           map[string]string
-	sliPluginsPaths       []string
-	sloPeriodWindowsPath  string
-	sloPeriod             string
+	slosInput              string
+	slosOut                string
+	slosExcludeRegex       string
+	slosIncludeRegex       string
+	slosOutputFormat       string
+	slosOutputEncoding     string
+	slosOutputGzip         bool
+	slosOutputGzipLevel    int
+	slosOutputBuffered     bool
+	slosOutputBufferSize   int
+	disableRecordings      bool
+	disableMetaRecordings  bool
+	indexGroup             bool
+	dedupRecRules          bool
+	singleLineExprs        bool
+	otlpMappingPath        string
+	passthroughRulesPath   string
+	strict                 bool
+	failOnNoAlerts         bool
+	emptySLOs              string
+	rwKeepRegex            bool
+	recRulesPrefix         string
+	sloComments            bool
+	groupsOrder            string
+	sloSelector            string
+	shadowSLOIDs           []string
+	shadowSLOSelector      string
+	shadowLabels           map[string]string
+	severityMapping        map[string]string
+	defaultSeverity        string
+	severityLabel          string
+	disableAlerts          bool
+	disableOptimizedRules  bool
+	extraLabels            map[string]string
+	alertAnnotationTpls    map[string]string
+	sliPluginsPaths        []string
+	sloPeriodWindowsPath   string
+	sloPeriod              string
+	mimirTenant            string
+	mimirSourceTenants     []string
+	vmTenant               string
+	vmEvalOffset           time.Duration
+	cortexTenant           string
+	cortexNamespace        string
+	thanosPRStrategy       string
+	k8sName                string
+	k8sNamespace           string
+	k8sLabels              map[string]string
+	groupLabels            map[string]string
+	targetPromVersion      string
+	omitUnsupportedFields  bool
+	coralogixApp           string
+	coralogixSubsystem     string
+	disableRulesValidation bool
+	disableDisclaimer      bool
+	disableDisclaimerVer   bool
+	disclaimerTimestamp    bool
+	headerTemplate         string
+	chronoTeamLabel        string
+	chronoNotifPolLabel    string
+	chronoBucketLabel      string
+	chronoUnderscoreNames  bool
+	chronoRateWindowIntvl  bool
+	chronoCollectionDesc   string
+	chronoRuleSlugTpl      string
+	chronoStrictLabels     bool
+	chronoGroupBy          string
+	chronoGroupByLabel     string
+	chronoAPIVersion       string
+	chronoFormat           string
+	chronoMonitorsOut      string
+	chronoSLIRecsInterval  time.Duration
+	chronoMetaRecsInterval time.Duration
+	chronoMonitorsInterval time.Duration
+	datadogGoodQueryLabel  string
+	datadogTotalQueryLabel string
+	newRelicValidQueryLbl  string
+	newRelicBadQueryLbl    string
+	sysdigMetricQueryLabel string
+	lightstepQueryLabel    string
+	lightstepOpLabel       string
+	honeycombSLIExprLabel  string
+	signalFxErrorStreamLbl string
+	signalFxTotalStreamLbl string
+	elasticIndexLabel      string
+	elasticGoodQueryLbl    string
+	elasticTotalQueryLbl   string
+	elasticTimestampField  string
+	rulesPrefix            string
+	queryOffset            time.Duration
+	minRulesInterval       time.Duration
+	maxRulesInterval       time.Duration
+	singleGroup            bool
+	singleGroupName        string
 }
 
 // NewGenerateCommand returns the generate command.
 func NewGenerateCommand(app *kingpin.Application) Command {
-	c := &generateCommand{extraLabels: map[string]string{}}
+	c := &generateCommand{extraLabels: map[string]string{}, alertAnnotationTpls: map[string]string{}, k8sLabels: map[string]string{}, groupLabels: map[string]string{}, shadowLabels: map[string]string{}, severityMapping: map[string]string{}}
 	cmd := app.Command("generate", "Generates Prometheus SLOs.")
 	cmd.Flag("input", "SLO spec input file path or directory (if directory is used, slos will be discovered recursively and out must be a directory).").Short('i').StringVar(&c.slosInput)
 	cmd.Flag("out", "Generated rules output file path or directory. If `-` it will use stdout (if input is a directory this must be a directory).").Default("-").Short('o').StringVar(&c.slosOut)
-	cmd.Flag("out-flavor", "generated rules output format (prometheus, chronosphere)").Default("prometheus").Short('f').StringVar(&c.slosOutputFormat)
+	cmd.Flag("out-flavor", "generated rules output format (prometheus, mimir, victoriametrics, cortex, thanos, kubernetes, coralogix, chronosphere, openslo, datadog, newrelic, sysdig, lightstep, honeycomb, signalfx, elastic)").Default("prometheus").Short('f').StringVar(&c.slosOutputFormat)
+	cmd.Flag("out-encoding", "Generated rules output encoding, jsonl has a rule per line (used with prometheus, mimir, victoriametrics, cortex and thanos out flavors, json and jsonl don't have disclaimer).").Default("yaml").EnumVar(&c.slosOutputEncoding, "yaml", "json", "jsonl")
+	cmd.Flag("out-gzip", "Compresses the generated rules output with gzip (used with prometheus, mimir, victoriametrics, cortex and thanos out flavors).").BoolVar(&c.slosOutputGzip)
+	cmd.Flag("out-gzip-level", "The gzip compression level from -2 (huffman only) to 9 (best compression), if not set the gzip default (used with out-gzip).").IntVar(&c.slosOutputGzipLevel)
+	cmd.Flag("out-buffered", "Buffers the writes of the generated rules output, useful with slow outputs (used with prometheus, mimir, victoriametrics, cortex and thanos out flavors).").BoolVar(&c.slosOutputBuffered)
+	cmd.Flag("out-buffer-size", "The size in bytes of the output writes buffer, if not set 64KiB (used with out-buffered).").IntVar(&c.slosOutputBufferSize)
 	cmd.Flag("fs-exclude", "Filter regex to ignore matched discovered SLO file paths (used with directory based input/output).").Short('e').StringVar(&c.slosExcludeRegex)
 	cmd.Flag("fs-include", "Filter regex to include matched discovered SLO file paths, everything else will be ignored. Exclude has preference (used with directory based input/output).").Short('n').StringVar(&c.slosIncludeRegex)
 
 	cmd.Flag("extra-labels", "Extra labels that will be added to all the generated Prometheus rules ('key=value' form, can be repeated).").Short('l').StringMapVar(&c.extraLabels)
+	cmd.Flag("group-labels", "Labels of all the generated rule groups, added by Prometheus to all the group series and alerts ('key=value' form, can be repeated) (used with prometheus based out flavors).").StringMapVar(&c.groupLabels)
+	cmd.Flag("target-prometheus-version", "The Prometheus version that will load the rules (e.g: 2.41), the rule fields not supported by it will fail (used with prometheus based out flavors).").StringVar(&c.targetPromVersion)
+	cmd.Flag("omit-unsupported-fields", "Removes the rule fields not supported by the target Prometheus version instead of failing (used with prometheus based out flavors).").BoolVar(&c.omitUnsupportedFields)
+	cmd.Flag("alert-annotation-template", "Go template of an annotation that will be added to the SLO alert rules, it receives the SLO, e.g: 'runbook=https://runbooks.io/{{ .Service }}/{{ .ID }}' (used with prometheus, mimir, victoriametrics, cortex, thanos and kubernetes out flavors, can be repeated).").StringMapVar(&c.alertAnnotationTpls)
 	cmd.Flag("disable-recordings", "Disables recording rules generation.").BoolVar(&c.disableRecordings)
+	cmd.Flag("disable-metadata-recordings", "Skips the SLO metadata recording rule groups, keeping the SLI recordings and the alerts (used with prometheus and mimir out flavors).").BoolVar(&c.disableMetaRecordings)
+	cmd.Flag("index-group", "Adds a rule group with an info recording rule per SLO (sloth_slo_index_info), to list all the SLOs with a single query (used with prometheus and mimir out flavors).").BoolVar(&c.indexGroup)
+	cmd.Flag("dedup-recording-rules", "Removes the recording rules that are identical (same record, expression and labels) to a previous one of another SLO (used with prometheus and mimir out flavors).").BoolVar(&c.dedupRecRules)
+	cmd.Flag("single-line-exprs", "Writes the rule expressions in a single line, quoting the multi-line and long ones, for stable and greppable diffs (used with prometheus based out flavors and YAML format).").BoolVar(&c.singleLineExprs)
+	cmd.Flag("otlp-mapping-path", "The path to a YAML file with the OpenTelemetry (OTLP) metric and label names ('metrics' and 'labels' maps) of the classic ones, the SLI recording rules expressions will be rewritten with them (used with prometheus based out flavors).").StringVar(&c.otlpMappingPath)
+	cmd.Flag("passthrough-rules-path", "The path to a Prometheus rules file with hand-written rule groups that will be stored as they are after the SLOs rule groups (used with prometheus based out flavors).").StringVar(&c.passthroughRulesPath)
+	cmd.Flag("strict", "Fails on the rules with empty label or annotation values, or with unresolved template values (<no value>), instead of storing them (used with prometheus based and chronosphere out flavors).").BoolVar(&c.strict)
+	cmd.Flag("fail-on-no-alerts", "Fails when there are recording rules but none of the SLOs has alert rules, usually a misconfiguration of the SLO alerts (used with prometheus based out flavors).").BoolVar(&c.failOnNoAlerts)
+	cmd.Flag("empty-slos", "How the SLOs that don't produce any rule are handled, ignored, logging a warning for each of them or failing with all of them (used with prometheus based out flavors).").Default("ignore").EnumVar(&c.emptySLOs, "ignore", "warn", "error")
+	cmd.Flag("remote-write-keep-regex", "Adds a header comment with the regex matching the recording rules metric names, to keep them on the remote write relabel configs (used with prometheus based out flavors).").BoolVar(&c.rwKeepRegex)
+	cmd.Flag("recording-rules-prefix", "Prefix added to the recording rules metric names and their references on the rules expressions, e.g: team_a_ (used with prometheus based out flavors).").StringVar(&c.recRulesPrefix)
+	cmd.Flag("slo-comments", "Writes a comment before the first rule group of each SLO with its service, name, objective and time window, it can't be used with a single group (used with prometheus, mimir, victoriametrics, cortex and thanos out flavors).").BoolVar(&c.sloComments)
+	cmd.Flag("groups-order", "The order of the rule groups, by service for human review, lexical by name for minimal diffs or the SLOs input order (used with prometheus based out flavors).").Default("input").EnumVar(&c.groupsOrder, "grouped-by-service", "lexical", "input")
+	cmd.Flag("slo-selector", "Label selector that the SLO labels must match to generate their rules, e.g: env!=staging,tier in (1,2) (used with prometheus, mimir, victoriametrics, cortex, thanos and kubernetes out flavors).").StringVar(&c.sloSelector)
+	cmd.Flag("shadow-slo-id", "The ID of an SLO whose alert rules will have the shadow labels, so they can be routed to a non paging receiver (can be repeated) (used with prometheus based out flavors).").StringsVar(&c.shadowSLOIDs)
+	cmd.Flag("shadow-slo-selector", "Label selector of the SLOs whose alert rules will have the shadow labels, e.g: rollout=canary (used with prometheus based out flavors).").StringVar(&c.shadowSLOSelector)
+	cmd.Flag("shadow-labels", "Labels added to the alert rules of the shadow SLOs, if not set shadow=true ('key=value' form, can be repeated) (used with prometheus based out flavors).").StringMapVar(&c.shadowLabels)
+	cmd.Flag("severity-mapping", "Maps an alert rules severity label synonym to the canonical severity, e.g: critical=page ('synonym=severity' form, can be repeated) (used with prometheus based out flavors).").StringMapVar(&c.severityMapping)
+	cmd.Flag("default-severity", "Severity set on the alert rules without severity label (used with prometheus based out flavors).").StringVar(&c.defaultSeverity)
+	cmd.Flag("severity-label", "The alert rules severity label normalized with the severity mapping and default, if not set 'severity' (used with prometheus based out flavors).").StringVar(&c.severityLabel)
 	cmd.Flag("disable-alerts", "Disables alert rules generation.").BoolVar(&c.disableAlerts)
 	cmd.Flag("sli-plugins-path", "The path to SLI plugins (can be repeated), if not set it disable plugins support.").Short('p').StringsVar(&c.sliPluginsPaths)
 	cmd.Flag("slo-period-windows-path", "The directory path to custom SLO period windows catalog (replaces default ones).").StringVar(&c.sloPeriodWindowsPath)
 	cmd.Flag("default-slo-period", "The default SLO period windows to be used for the SLOs.").Default("30d").StringVar(&c.sloPeriod)
 	cmd.Flag("disable-optimized-rules", "If enabled it will disable optimized generated rules.").BoolVar(&c.disableOptimizedRules)
+	cmd.Flag("rules-prefix", "The prefix of the generated rule group names and Chronosphere slugs, useful to avoid clashes between multiple Sloth instances (if not set, sloth-slo).").StringVar(&c.rulesPrefix)
+	cmd.Flag("single-group", "Merges the rules of all the SLOs in a single rule group (used with prometheus, mimir, victoriametrics, cortex, thanos and kubernetes out flavors).").BoolVar(&c.singleGroup)
+	cmd.Flag("single-group-name", "The name of the single rule group (if not set, <rules-prefix>-slos) (used with single-group).").StringVar(&c.singleGroupName)
+	cmd.Flag("query-offset", "The offset the generated rule groups will use to query the data, useful with late arriving metrics, requires Prometheus 2.53 or newer (used with prometheus, mimir, victoriametrics, cortex and thanos out flavors).").DurationVar(&c.queryOffset)
+	cmd.Flag("min-rules-interval", "The floor of the rules evaluation intervals, the lower intervals will be clamped to it (used with prometheus based and chronosphere out flavors).").DurationVar(&c.minRulesInterval)
+	cmd.Flag("max-rules-interval", "The ceiling of the rules evaluation intervals, the higher intervals will be clamped to it (used with prometheus based and chronosphere out flavors).").DurationVar(&c.maxRulesInterval)
+	cmd.Flag("disable-rules-validation", "Disables the validation of the generated rules before writing them (used with prometheus, mimir, victoriametrics, cortex, thanos and kubernetes out flavors).").BoolVar(&c.disableRulesValidation)
+	cmd.Flag("disable-disclaimer", "Disables the top disclaimer of the generated rules (used with prometheus, mimir and chronosphere out flavors).").BoolVar(&c.disableDisclaimer)
+	cmd.Flag("disable-disclaimer-version", "Removes the Sloth version from the top disclaimer to have reproducible outputs between versions (used with prometheus, mimir and chronosphere out flavors).").BoolVar(&c.disableDisclaimerVer)
+	cmd.Flag("disclaimer-timestamp", "Adds the generation timestamp to the top disclaimer, the outputs will not be reproducible (used with prometheus and mimir out flavors).").BoolVar(&c.disclaimerTimestamp)
+	cmd.Flag("header-template", "Go template of the header that replaces the top disclaimer of the generated rules, it receives the Version, Timestamp and SLOs count, every line must be a YAML comment (used with prometheus, mimir, victoriametrics, cortex and thanos out flavors).").StringVar(&c.headerTemplate)
+	cmd.Flag("chronosphere-team-label", "The SLO label that has the Chronosphere team slug of the collection (used with chronosphere out flavor).").StringVar(&c.chronoTeamLabel)
+	cmd.Flag("chronosphere-notification-policy-label", "The SLO label that has the Chronosphere notification policy slug of the collection (if not set, sloth_chronosphere_notification_policy) (used with chronosphere out flavor).").StringVar(&c.chronoNotifPolLabel)
+	cmd.Flag("chronosphere-bucket-label", "The SLO label that has the Chronosphere bucket slug of the recording rules, if not set or missing the collection slug will be used (used with chronosphere out flavor).").StringVar(&c.chronoBucketLabel)
+	cmd.Flag("chronosphere-underscore-metric-names", "Replaces the colons of the recording rules metric names with underscores, the rule expressions are not changed (used with chronosphere out flavor).").BoolVar(&c.chronoUnderscoreNames)
+	cmd.Flag("chronosphere-rate-window-intervals", "Evaluates the SLI recording rules based on their rate window (10 times per window, between 30s and 5m), instead of the SLO interval (used with chronosphere out flavor).").BoolVar(&c.chronoRateWindowIntvl)
+	cmd.Flag("chronosphere-collection-description", "The Go template of the Chronosphere collections description, it receives the SLO, e.g: 'SLOs of {{ .Service }}' (used with chronosphere out flavor).").StringVar(&c.chronoCollectionDesc)
+	cmd.Flag("chronosphere-recording-rule-slug", "The Go template of the Chronosphere recording rules slug, it receives the Prefix, Service, SLOID, Record and Kind (sli or metadata), e.g: 'team-a-{{ .SLOID }}-{{ .Record }}' (used with chronosphere out flavor).").StringVar(&c.chronoRuleSlugTpl)
+	cmd.Flag("chronosphere-strict-labels", "Fails on recording rule labels with empty values instead of dropping them (used with chronosphere out flavor).").BoolVar(&c.chronoStrictLabels)
+	cmd.Flag("chronosphere-collection-group-by", "How the SLOs are grouped in Chronosphere collections, by service, team (requires chronosphere-team-label) or label (requires chronosphere-collection-group-by-label) (used with chronosphere out flavor).").Default("service").EnumVar(&c.chronoGroupBy, "service", "team", "label")
+	cmd.Flag("chronosphere-collection-group-by-label", "The SLO label used to group the SLOs in Chronosphere collections (used with chronosphere-collection-group-by label).").StringVar(&c.chronoGroupByLabel)
+	cmd.Flag("chronosphere-api-version", "The Chronosphere config API version of the generated objects (if not set, v1/config) (used with chronosphere out flavor).").StringVar(&c.chronoAPIVersion)
+	cmd.Flag("chronosphere-format", "The format of the Chronosphere objects, chronoctl YAML documents or Terraform Chronosphere provider HCL resources (used with chronosphere out flavor).").Default("yaml").EnumVar(&c.chronoFormat, "yaml", "hcl")
+	cmd.Flag("chronosphere-monitors-out", "Output file for the Chronosphere monitors, so they are stored separately from the collections and recording rules, by default the monitors are written on the out (used with chronosphere out flavor).").StringVar(&c.chronoMonitorsOut)
+	cmd.Flag("chronosphere-sli-recordings-interval", "The evaluation interval of the SLI recording rules of the SLOs without a custom interval (used with chronosphere out flavor).").DurationVar(&c.chronoSLIRecsInterval)
+	cmd.Flag("chronosphere-meta-recordings-interval", "The evaluation interval of the metadata recording rules of the SLOs without a custom interval (used with chronosphere out flavor).").DurationVar(&c.chronoMetaRecsInterval)
+	cmd.Flag("chronosphere-monitors-interval", "The evaluation interval of the monitors of the SLOs without a custom interval (used with chronosphere out flavor).").DurationVar(&c.chronoMonitorsInterval)
+	cmd.Flag("datadog-good-events-query-label", "The SLO label that has the Datadog metric query of the good events (if not set, datadog_good_events_query) (used with datadog out flavor).").StringVar(&c.datadogGoodQueryLabel)
+	cmd.Flag("datadog-total-events-query-label", "The SLO label that has the Datadog metric query of the total events (if not set, datadog_total_events_query) (used with datadog out flavor).").StringVar(&c.datadogTotalQueryLabel)
+	cmd.Flag("newrelic-valid-events-query-label", "The SLO label that has the New Relic NRQL query of the valid events, e.g: FROM Transaction WHERE appName = 'svc1' (if not set, newrelic_valid_events_query) (used with newrelic out flavor).").StringVar(&c.newRelicValidQueryLbl)
+	cmd.Flag("newrelic-bad-events-query-label", "The SLO label that has the New Relic NRQL query of the bad events (if not set, newrelic_bad_events_query) (used with newrelic out flavor).").StringVar(&c.newRelicBadQueryLbl)
+	cmd.Flag("sysdig-metric-query-label", "The SLO label that has the Sysdig PromQL query of the SLI error ratio (if not set, sysdig_metric_query) (used with sysdig out flavor).").StringVar(&c.sysdigMetricQueryLabel)
+	cmd.Flag("lightstep-stream-query-label", "The SLO label that has the Lightstep stream query of the SLO spans (if not set, lightstep_stream_query) (used with lightstep out flavor).").StringVar(&c.lightstepQueryLabel)
+	cmd.Flag("lightstep-operation-label", "The SLO label that has the Lightstep operation used to create the stream query with the SLO service, if the SLO doesn't have a stream query (if not set, lightstep_operation) (used with lightstep out flavor).").StringVar(&c.lightstepOpLabel)
+	cmd.Flag("honeycomb-sli-expression-label", "The SLO label that has the Honeycomb derived column expression of the SLI good events (if not set, honeycomb_sli_expression) (used with honeycomb out flavor).").StringVar(&c.honeycombSLIExprLabel)
+	cmd.Flag("signalfx-error-stream-label", "The SLO label that has the SignalFlow stream of the error events, e.g: data('http_requests', filter=filter('code', '5*')).sum() (if not set, signalfx_error_stream) (used with signalfx out flavor).").StringVar(&c.signalFxErrorStreamLbl)
+	cmd.Flag("signalfx-total-stream-label", "The SLO label that has the SignalFlow stream of all the events (if not set, signalfx_total_stream) (used with signalfx out flavor).").StringVar(&c.signalFxTotalStreamLbl)
+	cmd.Flag("elastic-index-label", "The SLO label that has the Elastic index pattern of the SLI events, e.g: logs-* (if not set, elastic_index) (used with elastic out flavor).").StringVar(&c.elasticIndexLabel)
+	cmd.Flag("elastic-good-query-label", "The SLO label that has the Elastic KQL query of the good events, e.g: http.response.status_code < 500 (if not set, elastic_good_query) (used with elastic out flavor).").StringVar(&c.elasticGoodQueryLbl)
+	cmd.Flag("elastic-total-query-label", "The SLO label that has the Elastic KQL query of all the events, if the SLO doesn't have it all the index events are used (if not set, elastic_total_query) (used with elastic out flavor).").StringVar(&c.elasticTotalQueryLbl)
+	cmd.Flag("elastic-timestamp-field", "The timestamp field of the Elastic SLI events (if not set, @timestamp) (used with elastic out flavor).").StringVar(&c.elasticTimestampField)
+	cmd.Flag("mimir-tenant", "The Mimir tenant that will be set on the generated rules header (used with mimir out flavor).").StringVar(&c.mimirTenant)
+	cmd.Flag("mimir-source-tenant", "The Mimir source tenants of the federated rule groups (used with mimir out flavor, can be repeated).").StringsVar(&c.mimirSourceTenants)
+	cmd.Flag("cortex-tenant", "The Cortex tenant that will be set on the generated rules header (used with cortex out flavor).").StringVar(&c.cortexTenant)
+	cmd.Flag("cortex-namespace", "The Cortex ruler namespace of the generated rule groups (used with cortex out flavor).").StringVar(&c.cortexNamespace)
+	cmd.Flag("thanos-partial-response-strategy", "The Thanos partial response strategy of the rule groups, warn or abort (used with thanos out flavor).").Default("abort").EnumVar(&c.thanosPRStrategy, "warn", "abort")
+	cmd.Flag("kubernetes-name", "The name of the generated PrometheusRule object (used with kubernetes out flavor).").StringVar(&c.k8sName)
+	cmd.Flag("kubernetes-namespace", "The namespace of the generated PrometheusRule object (used with kubernetes out flavor).").StringVar(&c.k8sNamespace)
+	cmd.Flag("coralogix-application", "The Coralogix application of the generated rule groups (if not set, sloth) (used with coralogix out flavor).").StringVar(&c.coralogixApp)
+	cmd.Flag("coralogix-subsystem", "The Coralogix subsystem of the generated rule groups (if not set, the SLO service) (used with coralogix out flavor).").StringVar(&c.coralogixSubsystem)
+	cmd.Flag("kubernetes-labels", "Labels of the generated PrometheusRule object ('key=value' form, can be repeated) (used with kubernetes out flavor).").StringMapVar(&c.k8sLabels)
+	cmd.Flag("victoriametrics-tenant", "The VictoriaMetrics tenant of the rule groups in accountID[:projectID] form (used with victoriametrics out flavor).").StringVar(&c.vmTenant)
+	cmd.Flag("victoriametrics-eval-offset", "The VictoriaMetrics evaluation offset of the rule groups (used with victoriametrics out flavor).").DurationVar(&c.vmEvalOffset)
 
 	return c
 }
@@ -133,6 +304,30 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 		return fmt.Errorf("invalid default slo period: %w", err)
 	}
 
+	var otlpMapping prometheus.OTLPMapping
+	if g.otlpMappingPath != "" {
+		data, err := os.ReadFile(g.otlpMappingPath)
+		if err != nil {
+			return fmt.Errorf("could not read OTLP mapping: %w", err)
+		}
+		otlpMapping, err = prometheus.ParseOTLPMapping(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	var passthroughGroups []prometheus.RuleGroup
+	if g.passthroughRulesPath != "" {
+		data, err := os.ReadFile(g.passthroughRulesPath)
+		if err != nil {
+			return fmt.Errorf("could not read passthrough rules: %w", err)
+		}
+		passthroughGroups, err = prometheus.ParsePassthroughRuleGroups(data)
+		if err != nil {
+			return fmt.Errorf("could not parse passthrough rules: %w", err)
+		}
+	}
+
 	// Create Spec loaders.
 	promYAMLLoader := prometheus.NewYAMLSpecLoader(pluginRepo, sloPeriod)
 	kubeYAMLLoader := k8sprometheus.NewYAMLSpecLoader(pluginRepo, sloPeriod)
@@ -141,6 +336,15 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 	// Get SLO targets.
 	genTargets := []generateTarget{}
 
+	// The out files are replaced once all the targets have been generated, so on errors the
+	// existing files are unchanged.
+	outFiles := []*prometheus.AtomicFileWriter{}
+	defer func() {
+		for _, f := range outFiles {
+			_ = f.Abort()
+		}
+	}()
+
 	// FIle based input/outputs.
 	if !inputInfo.IsDir() {
 		// Get SLO spec data.
@@ -161,11 +365,11 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 		// Prepare store output.
 		var out = config.Stdout
 		if g.slosOut != "-" {
-			outFile, err := os.Create(g.slosOut)
+			outFile, err := prometheus.NewAtomicFileWriter(g.slosOut)
 			if err != nil {
 				return fmt.Errorf("could not create out file: %w", err)
 			}
-			defer f.Close()
+			outFiles = append(outFiles, outFile)
 			out = outFile
 		}
 		for _, s := range splittedSLOsData {
@@ -224,11 +428,11 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 			}
 
 			// Create the target file.
-			outFile, err := os.Create(outputPath)
+			outFile, err := prometheus.NewAtomicFileWriter(outputPath)
 			if err != nil {
 				return fmt.Errorf("could not create out file: %w", err)
 			}
-			defer outFile.Close()
+			outFiles = append(outFiles, outFile)
 
 			// Split YAMLs in case we have multiple yaml files in a single file.
 			splittedSLOsData := splitYAML(slxData)
@@ -248,6 +452,161 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 		disableAlerts:         g.disableAlerts,
 		disableOptimizedRules: g.disableOptimizedRules,
 		extraLabels:           g.extraLabels,
+		alertAnnotationTpls:   g.alertAnnotationTpls,
+		prometheusStorageConfig: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+			Logger:                        logger,
+			Format:                        prometheus.OutputFormat(g.slosOutputEncoding),
+			MimirTenant:                   g.mimirTenant,
+			MimirSourceTenants:            g.mimirSourceTenants,
+			VictoriaMetricsTenant:         g.vmTenant,
+			VictoriaMetricsEvalOffset:     g.vmEvalOffset,
+			CortexTenant:                  g.cortexTenant,
+			CortexNamespace:               g.cortexNamespace,
+			ThanosPartialResponseStrategy: g.thanosPRStrategy,
+			CoralogixApplication:          g.coralogixApp,
+			CoralogixSubsystem:            g.coralogixSubsystem,
+			GroupLabels:                   g.groupLabels,
+			TargetPrometheusVersion:       g.targetPromVersion,
+			OmitUnsupportedFields:         g.omitUnsupportedFields,
+			GroupPrefix:                   g.rulesPrefix,
+			QueryOffset:                   g.queryOffset,
+			MinInterval:                   g.minRulesInterval,
+			MaxInterval:                   g.maxRulesInterval,
+			SingleGroup:                   g.singleGroup,
+			SingleGroupName:               g.singleGroupName,
+			Gzip:                          g.slosOutputGzip,
+			GzipLevel:                     g.slosOutputGzipLevel,
+			BufferedWrites:                g.slosOutputBuffered,
+			WriteBufferSize:               g.slosOutputBufferSize,
+			DisableValidation:             g.disableRulesValidation,
+			DisableMetadataRecordings:     g.disableMetaRecordings,
+			IndexGroup:                    g.indexGroup,
+			DeduplicateRecordingRules:     g.dedupRecRules,
+			SingleLineExpressions:         g.singleLineExprs,
+			OTLPMapping:                   otlpMapping,
+			PassthroughRuleGroups:         passthroughGroups,
+			Strict:                        g.strict,
+			FailOnNoAlertRules:            g.failOnNoAlerts,
+			EmptySLOs:                     prometheus.EmptySLOsHandling(g.emptySLOs),
+			RemoteWriteKeepRegex:          g.rwKeepRegex,
+			RecordingRulesPrefix:          g.recRulesPrefix,
+			SLOComments:                   g.sloComments,
+			GroupsOrder:                   prometheus.GroupsOrder(g.groupsOrder),
+			SLOSelector:                   g.sloSelector,
+			ShadowSLOIDs:                  g.shadowSLOIDs,
+			ShadowSLOSelector:             g.shadowSLOSelector,
+			ShadowLabels:                  g.shadowLabels,
+			SeverityMapping:               g.severityMapping,
+			DefaultSeverity:               g.defaultSeverity,
+			SeverityLabel:                 g.severityLabel,
+			DisableDisclaimer:             g.disableDisclaimer,
+			DisableDisclaimerVersion:      g.disableDisclaimerVer,
+			DisclaimerTimestamp:           g.disclaimerTimestamp,
+			HeaderTemplate:                g.headerTemplate,
+		},
+		opensloStorageConfig: openslo.IOWriterYAMLRepoConfig{
+			Logger:                   logger,
+			DisableDisclaimer:        g.disableDisclaimer,
+			DisableDisclaimerVersion: g.disableDisclaimerVer,
+		},
+		chronosphereStorageConfig: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+			Logger:                        logger,
+			MinInterval:                   g.minRulesInterval,
+			MaxInterval:                   g.maxRulesInterval,
+			DisableDisclaimer:             g.disableDisclaimer,
+			DisableDisclaimerVersion:      g.disableDisclaimerVer,
+			TeamSlugLabel:                 g.chronoTeamLabel,
+			NotificationPolicySlugLabel:   g.chronoNotifPolLabel,
+			BucketSlugLabel:               g.chronoBucketLabel,
+			CollectionDescriptionTemplate: g.chronoCollectionDesc,
+			RecordingRuleSlugTemplate:     g.chronoRuleSlugTpl,
+			SlugPrefix:                    g.rulesPrefix,
+			StrictLabels:                  g.chronoStrictLabels,
+			Strict:                        g.strict,
+			CollectionGrouping:            chronosphere.CollectionGrouping(g.chronoGroupBy),
+			CollectionGroupingLabel:       g.chronoGroupByLabel,
+			APIVersion:                    g.chronoAPIVersion,
+			Format:                        chronosphere.OutputFormat(g.chronoFormat),
+			KindIntervals: chronosphere.KindIntervals{
+				SLIRecordings:      g.chronoSLIRecsInterval,
+				MetadataRecordings: g.chronoMetaRecsInterval,
+				Monitors:           g.chronoMonitorsInterval,
+			},
+		},
+		datadogStorageConfig: datadog.IOWriterJSONRepoConfig{
+			Logger:                logger,
+			GoodEventsQueryLabel:  g.datadogGoodQueryLabel,
+			TotalEventsQueryLabel: g.datadogTotalQueryLabel,
+		},
+		newRelicStorageConfig: newrelic.IOWriterJSONRepoConfig{
+			Logger:                logger,
+			ValidEventsQueryLabel: g.newRelicValidQueryLbl,
+			BadEventsQueryLabel:   g.newRelicBadQueryLbl,
+		},
+		sysdigStorageConfig: sysdig.IOWriterJSONRepoConfig{
+			Logger:           logger,
+			MetricQueryLabel: g.sysdigMetricQueryLabel,
+		},
+		lightstepStorageConfig: lightstep.IOWriterJSONRepoConfig{
+			Logger:           logger,
+			StreamQueryLabel: g.lightstepQueryLabel,
+			OperationLabel:   g.lightstepOpLabel,
+		},
+		honeycombStorageConfig: honeycomb.IOWriterJSONRepoConfig{
+			Logger:             logger,
+			SLIExpressionLabel: g.honeycombSLIExprLabel,
+		},
+		signalFxStorageConfig: signalfx.IOWriterJSONRepoConfig{
+			Logger:           logger,
+			ErrorStreamLabel: g.signalFxErrorStreamLbl,
+			TotalStreamLabel: g.signalFxTotalStreamLbl,
+		},
+		elasticStorageConfig: elastic.IOWriterJSONRepoConfig{
+			Logger:          logger,
+			IndexLabel:      g.elasticIndexLabel,
+			GoodQueryLabel:  g.elasticGoodQueryLbl,
+			TotalQueryLabel: g.elasticTotalQueryLbl,
+			TimestampField:  g.elasticTimestampField,
+		},
+	}
+	if g.chronoUnderscoreNames {
+		gen.chronosphereStorageConfig.MetricNameTransform = chronosphere.UnderscoreMetricName
+	}
+	if g.chronoRateWindowIntvl {
+		gen.chronosphereStorageConfig.RateWindowInterval = chronosphere.RateWindowInterval
+	}
+	if g.chronoMonitorsOut != "" {
+		monitorsFile, err := prometheus.NewAtomicFileWriter(g.chronoMonitorsOut)
+		if err != nil {
+			return fmt.Errorf("could not create Chronosphere monitors out file: %w", err)
+		}
+		outFiles = append(outFiles, monitorsFile)
+		gen.chronosphereStorageConfig.MonitorsWriter = monitorsFile
+	}
+
+	switch g.slosOutputFormat {
+	case "mimir":
+		gen.prometheusStorageConfig.Flavor = prometheus.MimirFlavor
+	case "victoriametrics":
+		gen.prometheusStorageConfig.Flavor = prometheus.VictoriaMetricsFlavor
+	case "cortex":
+		gen.prometheusStorageConfig.Flavor = prometheus.CortexFlavor
+	case "thanos":
+		gen.prometheusStorageConfig.Flavor = prometheus.ThanosFlavor
+	case "kubernetes":
+		if g.k8sName == "" {
+			return fmt.Errorf("kubernetes name is required with kubernetes out flavor")
+		}
+		if g.slosOutputGzip || g.slosOutputEncoding != "yaml" {
+			return fmt.Errorf("kubernetes out flavor only supports uncompressed yaml encoding")
+		}
+		gen.kubernetesMeta = &k8sprometheus.K8sMeta{
+			Name:      g.k8sName,
+			Namespace: g.k8sNamespace,
+			Labels:    g.k8sLabels,
+		}
+	case "coralogix":
+		gen.prometheusStorageConfig.Flavor = prometheus.CoralogixFlavor
 	}
 
 	for _, genTarget := range genTargets {
@@ -262,7 +621,7 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 			}
 
 			switch g.slosOutputFormat {
-			case "prometheus":
+			case "prometheus", "mimir", "victoriametrics", "cortex", "thanos", "kubernetes", "coralogix":
 				err = gen.GeneratePrometheus(ctx, *slos, genTarget.Out)
 				if err != nil {
 					return fmt.Errorf("could not generate Prometheus format rules: %w", err)
@@ -272,6 +631,46 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 				if err != nil {
 					return fmt.Errorf("could not generate Chronosphere format rules: %w", err)
 				}
+			case "openslo":
+				err = gen.GenerateOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate OpenSLO format SLOs: %w", err)
+				}
+			case "datadog":
+				err = gen.GenerateDatadogFromPrometheus(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Datadog format SLOs: %w", err)
+				}
+			case "newrelic":
+				err = gen.GenerateNewRelicFromPrometheus(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate New Relic format SLOs: %w", err)
+				}
+			case "sysdig":
+				err = gen.GenerateSysdigFromPrometheus(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Sysdig format SLOs: %w", err)
+				}
+			case "lightstep":
+				err = gen.GenerateLightstepFromPrometheus(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Lightstep format SLOs: %w", err)
+				}
+			case "honeycomb":
+				err = gen.GenerateHoneycombFromPrometheus(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Honeycomb format SLOs: %w", err)
+				}
+			case "signalfx":
+				err = gen.GenerateSignalFxFromPrometheus(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate SignalFx format detectors: %w", err)
+				}
+			case "elastic":
+				err = gen.GenerateElasticFromPrometheus(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Elastic format SLOs: %w", err)
+				}
 			}
 
 		case kubeYAMLLoader.IsSpecType(ctx, dataB):
@@ -292,7 +691,7 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 			}
 
 			switch g.slosOutputFormat {
-			case "prometheus":
+			case "prometheus", "mimir", "victoriametrics", "cortex", "thanos", "kubernetes", "coralogix":
 				err = gen.GeneratePrometheusFromOpenSLO(ctx, *slos, genTarget.Out)
 				if err != nil {
 					return fmt.Errorf("could not generate Prometheus format rules: %w", err)
@@ -302,12 +701,59 @@ func (g generateCommand) Run(ctx context.Context, config RootConfig) error {
 				if err != nil {
 					return fmt.Errorf("could not generate Chronosphere format rules: %w", err)
 				}
+			case "openslo":
+				err = gen.GenerateOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate OpenSLO format SLOs: %w", err)
+				}
+			case "datadog":
+				err = gen.GenerateDatadogFromOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Datadog format SLOs: %w", err)
+				}
+			case "newrelic":
+				err = gen.GenerateNewRelicFromOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate New Relic format SLOs: %w", err)
+				}
+			case "sysdig":
+				err = gen.GenerateSysdigFromOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Sysdig format SLOs: %w", err)
+				}
+			case "lightstep":
+				err = gen.GenerateLightstepFromOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Lightstep format SLOs: %w", err)
+				}
+			case "honeycomb":
+				err = gen.GenerateHoneycombFromOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Honeycomb format SLOs: %w", err)
+				}
+			case "signalfx":
+				err = gen.GenerateSignalFxFromOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate SignalFx format detectors: %w", err)
+				}
+			case "elastic":
+				err = gen.GenerateElasticFromOpenSLO(ctx, *slos, genTarget.Out)
+				if err != nil {
+					return fmt.Errorf("could not generate Elastic format SLOs: %w", err)
+				}
 			}
 		default:
 			return fmt.Errorf("invalid spec, could not load with any of the supported spec types")
 		}
 	}
 
+	for _, f := range outFiles {
+		err := f.Commit()
+		if err != nil {
+			return fmt.Errorf("could not write out file: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -323,6 +769,40 @@ type generator struct {
 	disableAlerts         bool
 	disableOptimizedRules bool
 	extraLabels           map[string]string
+	alertAnnotationTpls   map[string]string
+	// prometheusStorageConfig is the base configuration of the Prometheus storage,
+	// the writer will be set for each of the targets.
+	prometheusStorageConfig prometheus.IOWriterGroupedRulesYAMLRepoConfig
+	// kubernetesMeta is the Prometheus operator object that will wrap the Prometheus
+	// rule groups, only set with the kubernetes out flavor.
+	kubernetesMeta *k8sprometheus.K8sMeta
+	// chronosphereStorageConfig is the base configuration of the Chronosphere storage,
+	// the writer will be set for each of the targets.
+	chronosphereStorageConfig chronosphere.IOWriterGroupedRulesYAMLRepoConfig
+	// opensloStorageConfig is the base configuration of the OpenSLO storage,
+	// the writer will be set for each of the targets.
+	opensloStorageConfig openslo.IOWriterYAMLRepoConfig
+	// datadogStorageConfig is the base configuration of the Datadog storage,
+	// the writer will be set for each of the targets.
+	datadogStorageConfig datadog.IOWriterJSONRepoConfig
+	// newRelicStorageConfig is the base configuration of the New Relic storage,
+	// the writer will be set for each of the targets.
+	newRelicStorageConfig newrelic.IOWriterJSONRepoConfig
+	// sysdigStorageConfig is the base configuration of the Sysdig storage,
+	// the writer will be set for each of the targets.
+	sysdigStorageConfig sysdig.IOWriterJSONRepoConfig
+	// lightstepStorageConfig is the base configuration of the Lightstep storage,
+	// the writer will be set for each of the targets.
+	lightstepStorageConfig lightstep.IOWriterJSONRepoConfig
+	// honeycombStorageConfig is the base configuration of the Honeycomb storage,
+	// the writer will be set for each of the targets.
+	honeycombStorageConfig honeycomb.IOWriterJSONRepoConfig
+	// signalFxStorageConfig is the base configuration of the SignalFx storage,
+	// the writer will be set for each of the targets.
+	signalFxStorageConfig signalfx.IOWriterJSONRepoConfig
+	// elasticStorageConfig is the base configuration of the Elastic storage,
+	// the writer will be set for each of the targets.
+	elasticStorageConfig elastic.IOWriterJSONRepoConfig
 }
 
 // GeneratePrometheus generates the SLOs based on a raw regular Prometheus spec format input and outs a Prometheus raw yaml.
@@ -339,10 +819,31 @@ func (g generator) GeneratePrometheus(ctx context.Context, slos prometheus.SLOGr
 		return err
 	}
 
-	repo := prometheus.NewIOWriterGroupedRulesYAMLRepo(out, g.logger)
-	storageSLOs := make([]prometheus.StorageSLO, 0, len(result.PrometheusSLOs))
+	return g.storePrometheus(ctx, result, out)
+}
+
+func (g generator) GenerateChronosphereFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating chronosphere from Prometheus spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenPrometheus,
+		Spec:    prometheusv1.Version,
+	}
+
+	result, err := g.generateRules(ctx, info, slos)
+	if err != nil {
+		return err
+	}
+
+	repoConfig := g.chronosphereStorageConfig
+	repoConfig.Writer = out
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Chronosphere storage: %w", err)
+	}
+	storageSLOs := make([]chronosphere.StorageSLO, 0, len(result.PrometheusSLOs))
 	for _, s := range result.PrometheusSLOs {
-		storageSLOs = append(storageSLOs, prometheus.StorageSLO{
+		storageSLOs = append(storageSLOs, chronosphere.StorageSLO{
 			SLO:   s.SLO,
 			Rules: s.SLORules,
 		})
@@ -356,12 +857,13 @@ func (g generator) GeneratePrometheus(ctx context.Context, slos prometheus.SLOGr
 	return nil
 }
 
-func (g generator) GenerateChronosphereFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
-	g.logger.Infof("Generating chronosphere from Prometheus spec")
+// generateOpenSLO generates the SLOs based on a OpenSLO spec format input and outs a Prometheus raw yaml.
+func (g generator) GenerateChronosphereFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating from OpenSLO spec")
 	info := info.Info{
 		Version: info.Version,
-		Mode:    info.ModeCLIGenPrometheus,
-		Spec:    prometheusv1.Version,
+		Mode:    info.ModeCLIGenOpenSLO,
+		Spec:    openslov1alpha.APIVersion,
 	}
 
 	result, err := g.generateRules(ctx, info, slos)
@@ -369,7 +871,12 @@ func (g generator) GenerateChronosphereFromPrometheus(ctx context.Context, slos
 		return err
 	}
 
-	repo := chronosphere.NewIOWriterGroupedRulesYAMLRepo(out, g.logger)
+	repoConfig := g.chronosphereStorageConfig
+	repoConfig.Writer = out
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Chronosphere storage: %w", err)
+	}
 	storageSLOs := make([]chronosphere.StorageSLO, 0, len(result.PrometheusSLOs))
 	for _, s := range result.PrometheusSLOs {
 		storageSLOs = append(storageSLOs, chronosphere.StorageSLO{
@@ -386,26 +893,342 @@ func (g generator) GenerateChronosphereFromPrometheus(ctx context.Context, slos
 	return nil
 }
 
-// generateOpenSLO generates the SLOs based on a OpenSLO spec format input and outs a Prometheus raw yaml.
-func (g generator) GenerateChronosphereFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
-	g.logger.Infof("Generating from OpenSLO spec")
+// GenerateOpenSLO outs the SLOs as OpenSLO v1 SLOs, the SLOs are validated but there is
+// no need to generate their rules.
+func (g generator) GenerateOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating OpenSLO")
+
+	err := slos.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid SLO group: %w", err)
+	}
+
+	repoConfig := g.opensloStorageConfig
+	repoConfig.Writer = out
+	repo, err := openslo.NewIOWriterYAMLRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create OpenSLO storage: %w", err)
+	}
+	storageSLOs := make([]openslo.StorageSLO, 0, len(slos.SLOs))
+	for _, s := range slos.SLOs {
+		storageSLOs = append(storageSLOs, openslo.StorageSLO{SLO: s})
+	}
+
+	err = repo.StoreSLOs(ctx, storageSLOs)
+	if err != nil {
+		return fmt.Errorf("could not store SLOS: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateDatadogFromPrometheus generates the SLOs based on a raw regular Prometheus spec format input and outs Datadog SLOs and monitors.
+func (g generator) GenerateDatadogFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Datadog from Prometheus spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenPrometheus,
+		Spec:    prometheusv1.Version,
+	}
+
+	return g.generateDatadog(ctx, info, slos, out)
+}
+
+// GenerateDatadogFromOpenSLO generates the SLOs based on a OpenSLO spec format input and outs Datadog SLOs and monitors.
+func (g generator) GenerateDatadogFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Datadog from OpenSLO spec")
 	info := info.Info{
 		Version: info.Version,
 		Mode:    info.ModeCLIGenOpenSLO,
 		Spec:    openslov1alpha.APIVersion,
 	}
 
+	return g.generateDatadog(ctx, info, slos, out)
+}
+
+// generateDatadog outs the SLOs as Datadog SLOs and monitors, the Prometheus rules are not used
+// but the SLO alerts are required for the monitors.
+func (g generator) generateDatadog(ctx context.Context, info info.Info, slos prometheus.SLOGroup, out io.Writer) error {
 	result, err := g.generateRules(ctx, info, slos)
 	if err != nil {
 		return err
 	}
 
-	repo := chronosphere.NewIOWriterGroupedRulesYAMLRepo(out, g.logger)
-	storageSLOs := make([]chronosphere.StorageSLO, 0, len(result.PrometheusSLOs))
+	repoConfig := g.datadogStorageConfig
+	repoConfig.Writer = out
+	repo, err := datadog.NewIOWriterJSONRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Datadog storage: %w", err)
+	}
+	storageSLOs := make([]datadog.StorageSLO, 0, len(result.PrometheusSLOs))
 	for _, s := range result.PrometheusSLOs {
-		storageSLOs = append(storageSLOs, chronosphere.StorageSLO{
-			SLO:   s.SLO,
-			Rules: s.SLORules,
+		storageSLOs = append(storageSLOs, datadog.StorageSLO{
+			SLO:    s.SLO,
+			Alerts: s.Alerts,
+		})
+	}
+
+	err = repo.StoreSLOs(ctx, storageSLOs)
+	if err != nil {
+		return fmt.Errorf("could not store SLOS: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateNewRelicFromPrometheus generates the SLOs based on a raw regular Prometheus spec format input and outs New Relic service levels and alert conditions.
+func (g generator) GenerateNewRelicFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating New Relic from Prometheus spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenPrometheus,
+		Spec:    prometheusv1.Version,
+	}
+
+	return g.generateNewRelic(ctx, info, slos, out)
+}
+
+// GenerateNewRelicFromOpenSLO generates the SLOs based on a OpenSLO spec format input and outs New Relic service levels and alert conditions.
+func (g generator) GenerateNewRelicFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating New Relic from OpenSLO spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenOpenSLO,
+		Spec:    openslov1alpha.APIVersion,
+	}
+
+	return g.generateNewRelic(ctx, info, slos, out)
+}
+
+// generateNewRelic outs the SLOs as New Relic service levels and alert conditions, like Datadog,
+// only the SLO alerts of the generated rules are used.
+func (g generator) generateNewRelic(ctx context.Context, info info.Info, slos prometheus.SLOGroup, out io.Writer) error {
+	result, err := g.generateRules(ctx, info, slos)
+	if err != nil {
+		return err
+	}
+
+	repoConfig := g.newRelicStorageConfig
+	repoConfig.Writer = out
+	repo, err := newrelic.NewIOWriterJSONRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create New Relic storage: %w", err)
+	}
+	storageSLOs := make([]newrelic.StorageSLO, 0, len(result.PrometheusSLOs))
+	for _, s := range result.PrometheusSLOs {
+		storageSLOs = append(storageSLOs, newrelic.StorageSLO{
+			SLO:    s.SLO,
+			Alerts: s.Alerts,
+		})
+	}
+
+	err = repo.StoreSLOs(ctx, storageSLOs)
+	if err != nil {
+		return fmt.Errorf("could not store SLOS: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateSysdigFromPrometheus generates the SLOs based on a raw regular Prometheus spec format input and outs Sysdig SLOs.
+func (g generator) GenerateSysdigFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Sysdig from Prometheus spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenPrometheus,
+		Spec:    prometheusv1.Version,
+	}
+
+	return g.generateSysdig(ctx, info, slos, out)
+}
+
+// GenerateSysdigFromOpenSLO generates the SLOs based on a OpenSLO spec format input and outs Sysdig SLOs.
+func (g generator) GenerateSysdigFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Sysdig from OpenSLO spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenOpenSLO,
+		Spec:    openslov1alpha.APIVersion,
+	}
+
+	return g.generateSysdig(ctx, info, slos, out)
+}
+
+// generateSysdig outs the SLOs as Sysdig SLOs, the rules are generated to validate the SLOs
+// but not used.
+func (g generator) generateSysdig(ctx context.Context, info info.Info, slos prometheus.SLOGroup, out io.Writer) error {
+	result, err := g.generateRules(ctx, info, slos)
+	if err != nil {
+		return err
+	}
+
+	repoConfig := g.sysdigStorageConfig
+	repoConfig.Writer = out
+	repo, err := sysdig.NewIOWriterJSONRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Sysdig storage: %w", err)
+	}
+	storageSLOs := make([]sysdig.StorageSLO, 0, len(result.PrometheusSLOs))
+	for _, s := range result.PrometheusSLOs {
+		storageSLOs = append(storageSLOs, sysdig.StorageSLO{SLO: s.SLO})
+	}
+
+	err = repo.StoreSLOs(ctx, storageSLOs)
+	if err != nil {
+		return fmt.Errorf("could not store SLOS: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateLightstepFromPrometheus generates the SLOs based on a raw regular Prometheus spec format input and outs Lightstep streams and SLOs.
+func (g generator) GenerateLightstepFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Lightstep from Prometheus spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenPrometheus,
+		Spec:    prometheusv1.Version,
+	}
+
+	return g.generateLightstep(ctx, info, slos, out)
+}
+
+// GenerateLightstepFromOpenSLO generates the SLOs based on a OpenSLO spec format input and outs Lightstep streams and SLOs.
+func (g generator) GenerateLightstepFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Lightstep from OpenSLO spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenOpenSLO,
+		Spec:    openslov1alpha.APIVersion,
+	}
+
+	return g.generateLightstep(ctx, info, slos, out)
+}
+
+// generateLightstep outs the SLOs as Lightstep streams and SLOs, like Sysdig, the rules are
+// generated to validate the SLOs but not used.
+func (g generator) generateLightstep(ctx context.Context, info info.Info, slos prometheus.SLOGroup, out io.Writer) error {
+	result, err := g.generateRules(ctx, info, slos)
+	if err != nil {
+		return err
+	}
+
+	repoConfig := g.lightstepStorageConfig
+	repoConfig.Writer = out
+	repo, err := lightstep.NewIOWriterJSONRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Lightstep storage: %w", err)
+	}
+	storageSLOs := make([]lightstep.StorageSLO, 0, len(result.PrometheusSLOs))
+	for _, s := range result.PrometheusSLOs {
+		storageSLOs = append(storageSLOs, lightstep.StorageSLO{SLO: s.SLO})
+	}
+
+	err = repo.StoreSLOs(ctx, storageSLOs)
+	if err != nil {
+		return fmt.Errorf("could not store SLOS: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateHoneycombFromPrometheus generates the SLOs based on a raw regular Prometheus spec format input and outs Honeycomb derived columns and SLOs.
+func (g generator) GenerateHoneycombFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Honeycomb from Prometheus spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenPrometheus,
+		Spec:    prometheusv1.Version,
+	}
+
+	return g.generateHoneycomb(ctx, info, slos, out)
+}
+
+// GenerateHoneycombFromOpenSLO generates the SLOs based on a OpenSLO spec format input and outs Honeycomb derived columns and SLOs.
+func (g generator) GenerateHoneycombFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Honeycomb from OpenSLO spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenOpenSLO,
+		Spec:    openslov1alpha.APIVersion,
+	}
+
+	return g.generateHoneycomb(ctx, info, slos, out)
+}
+
+// generateHoneycomb outs the SLOs as Honeycomb derived columns, SLOs and burn alerts, like
+// Lightstep, the rules are generated to validate the SLOs but only the SLO quick alerts are used.
+func (g generator) generateHoneycomb(ctx context.Context, info info.Info, slos prometheus.SLOGroup, out io.Writer) error {
+	result, err := g.generateRules(ctx, info, slos)
+	if err != nil {
+		return err
+	}
+
+	repoConfig := g.honeycombStorageConfig
+	repoConfig.Writer = out
+	repo, err := honeycomb.NewIOWriterJSONRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Honeycomb storage: %w", err)
+	}
+	storageSLOs := make([]honeycomb.StorageSLO, 0, len(result.PrometheusSLOs))
+	for _, s := range result.PrometheusSLOs {
+		storageSLOs = append(storageSLOs, honeycomb.StorageSLO{
+			SLO:    s.SLO,
+			Alerts: s.Alerts,
+		})
+	}
+
+	err = repo.StoreSLOs(ctx, storageSLOs)
+	if err != nil {
+		return fmt.Errorf("could not store SLOS: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateSignalFxFromPrometheus generates the SLOs based on a raw regular Prometheus spec format input and outs SignalFx detectors.
+func (g generator) GenerateSignalFxFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating SignalFx from Prometheus spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenPrometheus,
+		Spec:    prometheusv1.Version,
+	}
+
+	return g.generateSignalFx(ctx, info, slos, out)
+}
+
+// GenerateSignalFxFromOpenSLO generates the SLOs based on a OpenSLO spec format input and outs SignalFx detectors.
+func (g generator) GenerateSignalFxFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating SignalFx from OpenSLO spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenOpenSLO,
+		Spec:    openslov1alpha.APIVersion,
+	}
+
+	return g.generateSignalFx(ctx, info, slos, out)
+}
+
+// generateSignalFx outs the SLOs as SignalFx detectors, like New Relic, only the SLO alerts
+// of the generated rules are used.
+func (g generator) generateSignalFx(ctx context.Context, info info.Info, slos prometheus.SLOGroup, out io.Writer) error {
+	result, err := g.generateRules(ctx, info, slos)
+	if err != nil {
+		return err
+	}
+
+	repoConfig := g.signalFxStorageConfig
+	repoConfig.Writer = out
+	repo, err := signalfx.NewIOWriterJSONRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create SignalFx storage: %w", err)
+	}
+	storageSLOs := make([]signalfx.StorageSLO, 0, len(result.PrometheusSLOs))
+	for _, s := range result.PrometheusSLOs {
+		storageSLOs = append(storageSLOs, signalfx.StorageSLO{
+			SLO:    s.SLO,
+			Alerts: s.Alerts,
 		})
 	}
 
@@ -417,6 +1240,57 @@ func (g generator) GenerateChronosphereFromOpenSLO(ctx context.Context, slos pro
 	return nil
 }
 
+// GenerateElasticFromPrometheus generates the SLOs based on a raw regular Prometheus spec format input and outs Kibana SLO API payloads.
+func (g generator) GenerateElasticFromPrometheus(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Elastic from Prometheus spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenPrometheus,
+		Spec:    prometheusv1.Version,
+	}
+
+	return g.generateElastic(ctx, info, slos, out)
+}
+
+// GenerateElasticFromOpenSLO generates the SLOs based on a OpenSLO spec format input and outs Kibana SLO API payloads.
+func (g generator) GenerateElasticFromOpenSLO(ctx context.Context, slos prometheus.SLOGroup, out io.Writer) error {
+	g.logger.Infof("Generating Elastic from OpenSLO spec")
+	info := info.Info{
+		Version: info.Version,
+		Mode:    info.ModeCLIGenOpenSLO,
+		Spec:    openslov1alpha.APIVersion,
+	}
+
+	return g.generateElastic(ctx, info, slos, out)
+}
+
+// generateElastic outs the SLOs as Kibana SLO API payloads, like Honeycomb, the rules are
+// generated to validate the SLOs but not used.
+func (g generator) generateElastic(ctx context.Context, info info.Info, slos prometheus.SLOGroup, out io.Writer) error {
+	result, err := g.generateRules(ctx, info, slos)
+	if err != nil {
+		return err
+	}
+
+	repoConfig := g.elasticStorageConfig
+	repoConfig.Writer = out
+	repo, err := elastic.NewIOWriterJSONRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Elastic storage: %w", err)
+	}
+	storageSLOs := make([]elastic.StorageSLO, 0, len(result.PrometheusSLOs))
+	for _, s := range result.PrometheusSLOs {
+		storageSLOs = append(storageSLOs, elastic.StorageSLO{SLO: s.SLO})
+	}
+
+	err = repo.StoreSLOs(ctx, storageSLOs)
+	if err != nil {
+		return fmt.Errorf("could not store SLOS: %w", err)
+	}
+
+	return nil
+}
+
 // generateKubernetes generates the SLOs based on a Kuberentes spec format input and outs a Kubernetes prometheus operator CRD yaml.
 func (g generator) GenerateKubernetes(ctx context.Context, sloGroup k8sprometheus.SLOGroup, out io.Writer) error {
 	g.logger.Infof("Generating from Kubernetes Prometheus spec")
@@ -462,15 +1336,42 @@ func (g generator) GeneratePrometheusFromOpenSLO(ctx context.Context, slos prome
 		return err
 	}
 
-	repo := prometheus.NewIOWriterGroupedRulesYAMLRepo(out, g.logger)
+	return g.storePrometheus(ctx, result, out)
+}
+
+// storePrometheus stores the generated SLO rules with the Prometheus storage, with the kubernetes
+// out flavor the built rule groups are wrapped in a Prometheus operator object instead.
+func (g generator) storePrometheus(ctx context.Context, result *generate.Response, out io.Writer) error {
+	repoConfig := g.prometheusStorageConfig
+	repoConfig.Writer = out
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(repoConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Prometheus storage: %w", err)
+	}
 	storageSLOs := make([]prometheus.StorageSLO, 0, len(result.PrometheusSLOs))
 	for _, s := range result.PrometheusSLOs {
 		storageSLOs = append(storageSLOs, prometheus.StorageSLO{
-			SLO:   s.SLO,
-			Rules: s.SLORules,
+			SLO:                      s.SLO,
+			Rules:                    s.SLORules,
+			AlertAnnotationTemplates: g.alertAnnotationTpls,
 		})
 	}
 
+	if g.kubernetesMeta != nil {
+		groups, err := repo.BuildRuleGroups(ctx, storageSLOs)
+		if err != nil {
+			return fmt.Errorf("could not build rule groups: %w", err)
+		}
+
+		k8sRepo := k8sprometheus.NewIOWriterPrometheusOperatorYAMLRepo(out, g.logger)
+		err = k8sRepo.StoreRuleGroups(ctx, *g.kubernetesMeta, groups)
+		if err != nil {
+			return fmt.Errorf("could not store SLOS: %w", err)
+		}
+
+		return nil
+	}
+
 	err = repo.StoreSLOs(ctx, storageSLOs)
 	if err != nil {
 		return fmt.Errorf("could not store SLOS: %w", err)
@@ -0,0 +1,265 @@
+package chronosphere
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Terraform Chronosphere provider resource types.
+const (
+	hclCollectionResource    = "chronosphere_collection"
+	hclRecordingRuleResource = "chronosphere_recording_rule"
+	hclMonitorResource       = "chronosphere_monitor"
+)
+
+// writeChronosphereHCL writes the Chronosphere objects to the writer as Terraform Chronosphere
+// provider HCL resources, the monitors reference the collection resources of the same output.
+func writeChronosphereHCL(w io.Writer, objs *chronosphereObjects) error {
+	blocks := []hclBlock{}
+
+	usedNames := map[string]bool{}
+	collectionNames := map[string]string{}
+	for _, c := range objs.collections {
+		name := uniqueHCLIdentifier(usedNames, c.Slug)
+		collectionNames[c.Slug] = name
+
+		attrs := []hclAttr{
+			{name: "slug", value: hclString(c.Slug)},
+			{name: "name", value: hclString(c.Name)},
+			{name: "description", value: hclString(c.Description)},
+		}
+		if c.Team_slug != "" {
+			attrs = append(attrs, hclAttr{name: "team_id", value: hclString(c.Team_slug)})
+		}
+		if c.Notification_policy_slug != "" {
+			attrs = append(attrs, hclAttr{name: "notification_policy_id", value: hclString(c.Notification_policy_slug)})
+		}
+		blocks = append(blocks, hclBlock{labels: []string{"resource", hclCollectionResource, name}, attrs: attrs})
+	}
+
+	usedNames = map[string]bool{}
+	for _, r := range objs.rules {
+		blocks = append(blocks, hclBlock{
+			labels: []string{"resource", hclRecordingRuleResource, uniqueHCLIdentifier(usedNames, r.Slug)},
+			attrs: []hclAttr{
+				{name: "slug", value: hclString(r.Slug)},
+				{name: "name", value: hclString(r.Name)},
+				{name: "bucket_id", value: hclString(r.Bucket_slug)},
+				{name: "interval", value: hclString(fmt.Sprintf("%ds", r.Interval_secs))},
+				{name: "metric_name", value: hclString(r.Metric_name)},
+				{name: "expr", value: hclString(r.Expr)},
+			},
+			blocks: []hclBlock{
+				{labels: []string{"label_policy"}, attrs: []hclAttr{{name: "add", mapValue: r.Label_policy.Add, isMap: true}}},
+			},
+		})
+	}
+
+	usedNames = map[string]bool{}
+	for _, m := range objs.monitors {
+		collection := hclString(m.Collection)
+		if name, ok := collectionNames[m.Collection]; ok {
+			collection = fmt.Sprintf("%s.%s.id", hclCollectionResource, name)
+		}
+
+		attrs := []hclAttr{
+			{name: "slug", value: hclString(m.Slug)},
+			{name: "name", value: hclString(m.Name)},
+			{name: "collection_id", value: collection},
+			{name: "interval", value: hclString(fmt.Sprintf("%ds", m.Interval_secs))},
+		}
+		if m.Notification_policy_slug != "" {
+			attrs = append(attrs, hclAttr{name: "notification_policy_id", value: hclString(m.Notification_policy_slug)})
+		}
+		attrs = append(attrs, hclAttr{name: "labels", mapValue: m.Labels, isMap: true})
+		if len(m.Annotations) > 0 {
+			attrs = append(attrs, hclAttr{name: "annotations", mapValue: m.Annotations, isMap: true})
+		}
+
+		// Same nesting as the YAML series conditions (e.g: `defaults.critical.conditions`).
+		conditions := hclBlock{labels: []string{"series_conditions"}}
+		groups := make([]string, 0, len(m.Series_conditions))
+		for group := range m.Series_conditions {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+		for _, group := range groups {
+			groupBlock := hclBlock{labels: []string{group}}
+			severities := make([]string, 0, len(m.Series_conditions[group]))
+			for severity := range m.Series_conditions[group] {
+				severities = append(severities, severity)
+			}
+			sort.Strings(severities)
+			for _, severity := range severities {
+				severityBlock := hclBlock{labels: []string{severity}}
+				for _, c := range m.Series_conditions[group][severity]["conditions"] {
+					severityBlock.blocks = append(severityBlock.blocks, hclBlock{
+						labels: []string{"conditions"},
+						attrs: []hclAttr{
+							{name: "value", value: strconv.FormatFloat(c.Value, 'f', -1, 64)},
+							{name: "op", value: hclString(c.Op)},
+							{name: "sustain", value: hclString(fmt.Sprintf("%ds", c.Sustain_secs))},
+							{name: "resolve_sustain", value: hclString(fmt.Sprintf("%ds", c.Resolve_sustain_secs))},
+						},
+					})
+				}
+				groupBlock.blocks = append(groupBlock.blocks, severityBlock)
+			}
+			conditions.blocks = append(conditions.blocks, groupBlock)
+		}
+
+		blocks = append(blocks, hclBlock{
+			labels: []string{"resource", hclMonitorResource, uniqueHCLIdentifier(usedNames, m.Slug)},
+			attrs:  attrs,
+			blocks: []hclBlock{
+				{labels: []string{"query"}, attrs: []hclAttr{{name: "prometheus_expr", value: hclString(m.Query)}}},
+				conditions,
+			},
+		})
+	}
+
+	var b strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		block.render(&b, "")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return fmt.Errorf("could not write HCL resources: %w", err)
+	}
+
+	return nil
+}
+
+// hclBlock is an HCL block, the first label is the block type (e.g: `resource`).
+type hclBlock struct {
+	labels []string
+	attrs  []hclAttr
+	blocks []hclBlock
+}
+
+// hclAttr is an HCL block attribute, the value is already an HCL expression unless it's a map.
+type hclAttr struct {
+	name     string
+	value    string
+	mapValue map[string]string
+	isMap    bool
+}
+
+// render writes the block with the attributes aligned, like `terraform fmt` does.
+func (h hclBlock) render(b *strings.Builder, indent string) {
+	b.WriteString(indent + h.labels[0])
+	for _, l := range h.labels[1:] {
+		b.WriteString(" " + hclString(l))
+	}
+	b.WriteString(" {\n")
+
+	inner := indent + "  "
+	width := 0
+	for _, a := range h.attrs {
+		if len(a.name) > width {
+			width = len(a.name)
+		}
+	}
+	for _, a := range h.attrs {
+		fmt.Fprintf(b, "%s%-*s = ", inner, width, a.name)
+		if a.isMap {
+			b.WriteString(hclMap(a.mapValue, inner))
+		} else {
+			b.WriteString(a.value)
+		}
+		b.WriteString("\n")
+	}
+
+	for _, block := range h.blocks {
+		block.render(b, inner)
+	}
+
+	b.WriteString(indent + "}\n")
+}
+
+// hclMap returns the HCL object expression of the map with sorted and quoted keys.
+func hclMap(m map[string]string, indent string) string {
+	if len(m) == 0 {
+		return "{}"
+	}
+
+	keys := make([]string, 0, len(m))
+	width := 0
+	for k := range m {
+		keys = append(keys, k)
+		if l := len(hclString(k)); l > width {
+			width = l
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s  %-*s = %s\n", indent, width, hclString(k), hclString(m[k]))
+	}
+	b.WriteString(indent + "}")
+
+	return b.String()
+}
+
+// hclString returns the HCL quoted string, escaping the template sequences (`${` and `%{`) so
+// the expressions and annotations are used as they are.
+func hclString(s string) string {
+	var b strings.Builder
+	b.WriteString(`"`)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(`"`)
+
+	res := b.String()
+	res = strings.ReplaceAll(res, "${", "$${")
+	res = strings.ReplaceAll(res, "%{", "%%{")
+
+	return res
+}
+
+var invalidHCLIdentifierCharsRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// uniqueHCLIdentifier returns the Terraform resource name of the slug, the invalid characters are
+// replaced by `_` and the names that don't start with a letter are prefixed with `_`. The names
+// are unique for the used ones, adding a numeric suffix if required.
+func uniqueHCLIdentifier(used map[string]bool, slug string) string {
+	name := invalidHCLIdentifierCharsRegexp.ReplaceAllString(slug, "_")
+	if name == "" || !unicode.IsLetter(rune(name[0])) && name[0] != '_' {
+		name = "_" + name
+	}
+
+	unique := name
+	for n := 2; used[unique]; n++ {
+		unique = fmt.Sprintf("%s_%d", name, n)
+	}
+	used[unique] = true
+
+	return unique
+}
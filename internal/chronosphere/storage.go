@@ -1,17 +1,25 @@
 package chronosphere
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-
+	"regexp"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
 
-	"github.com/slok/sloth/internal/prometheus"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
 	"gopkg.in/yaml.v2"
 
+	"github.com/slok/sloth/internal/alert"
 	"github.com/slok/sloth/internal/info"
 	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
 )
 
 var (
@@ -20,171 +28,1208 @@ var (
 	ErrNoSLORules = fmt.Errorf("0 SLO Prometheus rules generated")
 )
 
-func NewIOWriterGroupedRulesYAMLRepo(writer io.Writer, logger log.Logger) IOWriterGroupedRulesYAMLRepo {
-	return IOWriterGroupedRulesYAMLRepo{
-		writer: writer,
-		logger: logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "yaml"}),
+const (
+	defaultInterval = 60 * time.Second
+
+	defaultAPIVersion        = "v1/config"
+	defaultCollectionKind    = "Collection"
+	defaultRecordingRuleKind = "RecordingRule"
+	defaultMonitorKind       = "Monitor"
+
+	defaultCollectionDescription = "SLOs generated by Sloth"
+	defaultRecordingRuleSlug     = "{{ .Prefix }}-sli-recordings-{{ .SLOID }}-{{ .Record }}"
+	defaultSlugPrefix            = "sloth-slo"
+
+	// defaultNotificationPolicySlugLabel is the well-known SLO label that has the Chronosphere
+	// notification policy slug of the SLO collection.
+	defaultNotificationPolicySlugLabel = "sloth_chronosphere_notification_policy"
+
+	// slothSeverityLabelName is the label that Sloth sets on the alerts with the alert severity.
+	slothSeverityLabelName = "sloth_severity"
+
+	// ctxCheckSLOs is the number of SLOs processed between context cancellation checks.
+	ctxCheckSLOs = 100
+
+	minRateWindowInterval = 30 * time.Second
+	maxRateWindowInterval = 5 * time.Minute
+)
+
+// Flavor is the output flavor of the Chronosphere objects, it's registered so the Prometheus
+// repositories configured with it store the objects with the default configuration.
+const Flavor prometheus.OutputFlavor = "chronosphere"
+
+func init() {
+	err := prometheus.RegisterRuleSerializer(Flavor, prometheus.RuleSerializerFunc(func(slos []prometheus.StorageSLO) (int, []byte, error) {
+		repo, err := NewIOWriterGroupedRulesYAMLRepo(IOWriterGroupedRulesYAMLRepoConfig{Writer: io.Discard})
+		if err != nil {
+			return 0, nil, fmt.Errorf("could not create Chronosphere storage: %w", err)
+		}
+		return repo.Serialize(slos)
+	}))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// OutputFormat is the serialization format of the Chronosphere objects.
+type OutputFormat string
+
+const (
+	// YAMLFormat will output the Chronosphere objects as `chronoctl` YAML documents.
+	YAMLFormat OutputFormat = "yaml"
+	// HCLFormat will output the Chronosphere objects as Terraform Chronosphere provider HCL
+	// resources (`chronosphere_collection`, `chronosphere_recording_rule` and `chronosphere_monitor`).
+	HCLFormat OutputFormat = "hcl"
+)
+
+// CollectionGrouping is the way the SLOs are grouped in Chronosphere collections.
+type CollectionGrouping string
+
+const (
+	// ServiceCollectionGrouping will create a collection for each SLO service.
+	ServiceCollectionGrouping CollectionGrouping = "service"
+	// TeamCollectionGrouping will create a collection for each SLO team (the team slug label).
+	TeamCollectionGrouping CollectionGrouping = "team"
+	// LabelCollectionGrouping will create a collection for each value of an SLO label.
+	LabelCollectionGrouping CollectionGrouping = "label"
+)
+
+type IOWriterGroupedRulesYAMLRepoConfig struct {
+	Writer io.Writer
+	// MonitorsWriter will be used to write the monitors, so these can be stored separately from the
+	// collections and recording rules written on the Writer. The HCL monitors will reference the
+	// collections by slug. By default the monitors are written on the Writer.
+	MonitorsWriter io.Writer
+	Logger         log.Logger
+	// Format is the serialization format of the Chronosphere objects, by default YAML.
+	Format OutputFormat
+	// DefaultInterval is the evaluation interval used on the rules of the SLOs that
+	// don't have a custom one.
+	DefaultInterval time.Duration
+	// RateWindowInterval returns the evaluation interval of the SLI recording rules based on their
+	// rate window (e.g: `slo:sli_error:ratio_rate5m`) instead of using the SLO interval, the rules
+	// without a rate window use the SLO interval (e.g: RateWindowInterval). By default disabled.
+	RateWindowInterval func(window time.Duration) time.Duration
+	// MinInterval is the floor of the rules evaluation intervals (SLO, default and rate window
+	// ones), the intervals below it will be clamped to it. By default disabled.
+	MinInterval time.Duration
+	// MaxInterval is the ceiling of the rules evaluation intervals, the intervals above it will
+	// be clamped to it. By default disabled.
+	MaxInterval time.Duration
+	// KindIntervals are the evaluation intervals of the rules by kind (SLI recordings, metadata
+	// recordings and monitors), these override the default interval for their kind, the SLOs with
+	// a custom interval use it on all the kinds.
+	KindIntervals KindIntervals
+	// Sync will sync the writer after writing the rules if it supports it (has a `Sync() error`
+	// method, like files), so the written rules are durable.
+	Sync bool
+	// StrictLabels will fail on recording rule labels with empty values, instead of dropping
+	// them (Chronosphere ignores them).
+	StrictLabels bool
+	// Strict will fail on the rules with empty label or annotation values, or with unresolved
+	// template values (`<no value>`), for both the recording rules and the monitors.
+	Strict bool
+	// ExtraLabels are the labels that will be added to the recording rules label policy and
+	// the monitors of all the SLOs, along with the SLO extra labels that take precedence over these.
+	ExtraLabels map[string]string
+	// ExtraLabelsOverride will make the extra labels override the rules labels on conflicts, by
+	// default the rules labels (computed by Sloth) win.
+	ExtraLabelsOverride bool
+	// DisableDisclaimer will disable the top disclaimer of the generated rules.
+	DisableDisclaimer bool
+	// DisclaimerVersion is the version that will be set on the disclaimer, by default
+	// the Sloth version.
+	DisclaimerVersion string
+	// DisableDisclaimerVersion will remove the version from the disclaimer, this is useful
+	// to have reproducible outputs between Sloth versions.
+	DisableDisclaimerVersion bool
+	// TeamSlugLabel is the SLO label that has the Chronosphere team slug of the SLO
+	// collection, if empty the collections will not have a team.
+	TeamSlugLabel string
+	// NotificationPolicySlugLabel is the SLO label that has the Chronosphere notification policy
+	// slug of the SLO collection, by default `sloth_chronosphere_notification_policy`. The collections
+	// of SLOs without the label will not have a notification policy.
+	NotificationPolicySlugLabel string
+	// MetricNameTransform transforms the recording rule names into the Chronosphere recording
+	// rules metric names (e.g: UnderscoreMetricName), the rule expressions are not changed. By
+	// default the recording rule name.
+	MetricNameTransform func(name string) string
+	// BucketSlugLabel is the SLO label that has the Chronosphere bucket slug of the SLO recording
+	// rules, if empty or the SLO doesn't have it, the recording rules will use the SLO collection
+	// slug as the bucket slug.
+	BucketSlugLabel string
+	// SlugPrefix is the prefix of the collections, recording rules and monitors slugs (e.g
+	// `<prefix>-alerts-<slo-id>-<alert>`), by default `sloth-slo`.
+	SlugPrefix string
+	// CollectionDescriptionTemplate is the Go template used to render the description of the
+	// collections, it receives the SLO (e.g: `SLOs of {{ .Service }} service`). When multiple SLOs
+	// share the same collection, the first SLO will be used. By default a static description.
+	CollectionDescriptionTemplate string
+	// RecordingRuleSlugTemplate is the Go template used to render the slug (and name) of the
+	// recording rules, it receives the RecordingRuleSlugData (e.g: `team-a-{{ .SLOID }}-{{ .Record }}`).
+	// The rendered slugs are sanitized like the default ones and must be valid Chronosphere slugs.
+	// By default `<prefix>-sli-recordings-<slo-id>-<record>`.
+	RecordingRuleSlugTemplate string
+	// CollectionGrouping is how the SLOs will be grouped in collections, by default
+	// one collection per service.
+	CollectionGrouping CollectionGrouping
+	// CollectionGroupingLabel is the SLO label used to group the SLOs in collections (used
+	// with label collection grouping).
+	CollectionGroupingLabel string
+	// APIVersion is the Chronosphere config API version of the objects, by default `v1/config`.
+	APIVersion string
+	// CollectionKind is the kind of the collection objects, by default `Collection`.
+	CollectionKind string
+	// RecordingRuleKind is the kind of the recording rule objects, by default `RecordingRule`.
+	RecordingRuleKind string
+	// MonitorKind is the kind of the monitor objects, by default `Monitor`.
+	MonitorKind string
+}
+
+func (c *IOWriterGroupedRulesYAMLRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.DefaultInterval == 0 {
+		c.DefaultInterval = defaultInterval
+	}
+	_, err := intervalSecs(c.DefaultInterval)
+	if err != nil {
+		return fmt.Errorf("invalid default interval: %w", err)
+	}
+
+	err = c.KindIntervals.validate()
+	if err != nil {
+		return fmt.Errorf("invalid kind intervals: %w", err)
+	}
+
+	if c.MinInterval < 0 || c.MaxInterval < 0 {
+		return fmt.Errorf("min and max intervals can't be negative")
+	}
+	if (c.MinInterval != 0 && c.MinInterval < time.Second) || (c.MaxInterval != 0 && c.MaxInterval < time.Second) {
+		return fmt.Errorf("min and max intervals must be at least 1s")
+	}
+	if c.MinInterval != 0 && c.MaxInterval != 0 && c.MinInterval > c.MaxInterval {
+		return fmt.Errorf("min interval can't be greater than the max interval")
+	}
+
+	if c.SlugPrefix == "" {
+		c.SlugPrefix = defaultSlugPrefix
+	}
+	if !validSlugRegexp.MatchString(c.SlugPrefix) {
+		return fmt.Errorf("invalid %q slug prefix", c.SlugPrefix)
+	}
+
+	if c.APIVersion == "" {
+		c.APIVersion = defaultAPIVersion
+	}
+	if !validAPIVersionRegexp.MatchString(c.APIVersion) {
+		return fmt.Errorf("invalid %q api version", c.APIVersion)
+	}
+	if c.CollectionKind == "" {
+		c.CollectionKind = defaultCollectionKind
+	}
+	if c.RecordingRuleKind == "" {
+		c.RecordingRuleKind = defaultRecordingRuleKind
+	}
+	if c.MonitorKind == "" {
+		c.MonitorKind = defaultMonitorKind
+	}
+	for _, kind := range []string{c.CollectionKind, c.RecordingRuleKind, c.MonitorKind} {
+		if !validKindRegexp.MatchString(kind) {
+			return fmt.Errorf("invalid %q kind", kind)
+		}
+	}
+
+	if c.CollectionGrouping == "" {
+		c.CollectionGrouping = ServiceCollectionGrouping
+	}
+	switch c.CollectionGrouping {
+	case ServiceCollectionGrouping:
+	case TeamCollectionGrouping:
+		if c.TeamSlugLabel == "" {
+			return fmt.Errorf("team slug label is required to group the collections by team")
+		}
+		c.CollectionGroupingLabel = c.TeamSlugLabel
+	case LabelCollectionGrouping:
+		if c.CollectionGroupingLabel == "" {
+			return fmt.Errorf("collection grouping label is required to group the collections by label")
+		}
+	default:
+		return fmt.Errorf("unknown %q collection grouping", c.CollectionGrouping)
+	}
+
+	if c.CollectionDescriptionTemplate == "" {
+		c.CollectionDescriptionTemplate = defaultCollectionDescription
+	}
+
+	if c.RecordingRuleSlugTemplate == "" {
+		c.RecordingRuleSlugTemplate = defaultRecordingRuleSlug
+	}
+
+	if c.DisableDisclaimerVersion {
+		c.DisclaimerVersion = ""
+	} else if c.DisclaimerVersion == "" {
+		c.DisclaimerVersion = info.Version
+	}
+
+	if c.NotificationPolicySlugLabel == "" {
+		c.NotificationPolicySlugLabel = defaultNotificationPolicySlugLabel
+	}
+
+	if c.MetricNameTransform == nil {
+		c.MetricNameTransform = func(name string) string { return name }
+	}
+
+	if c.Format == "" {
+		c.Format = YAMLFormat
+	}
+	if c.Format != YAMLFormat && c.Format != HCLFormat {
+		return fmt.Errorf("unknown %q output format", c.Format)
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": c.Format})
+
+	return nil
+}
+
+func NewIOWriterGroupedRulesYAMLRepo(config IOWriterGroupedRulesYAMLRepoConfig) (*IOWriterGroupedRulesYAMLRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	descTpl, err := template.New("collectionDescription").Option("missingkey=error").Parse(config.CollectionDescriptionTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: invalid collection description template: %w", err)
+	}
+
+	ruleSlugTpl, err := template.New("recordingRuleSlug").Option("missingkey=error").Parse(config.RecordingRuleSlugTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: invalid recording rule slug template: %w", err)
+	}
+
+	api := chronosphereAPI{
+		version:           config.APIVersion,
+		collectionKind:    config.CollectionKind,
+		recordingRuleKind: config.RecordingRuleKind,
+		monitorKind:       config.MonitorKind,
 	}
+
+	return &IOWriterGroupedRulesYAMLRepo{
+		writer:            config.Writer,
+		monitorsWriter:    config.MonitorsWriter,
+		format:            config.Format,
+		rateWindowIntvl:   config.RateWindowInterval,
+		intervals:         intervalRange{min: config.MinInterval, max: config.MaxInterval},
+		kindIntervals:     config.KindIntervals.withDefault(config.DefaultInterval),
+		sync:              config.Sync,
+		strictLabels:      config.StrictLabels,
+		strict:            config.Strict,
+		extraLabels:       config.ExtraLabels,
+		extraLabelsOver:   config.ExtraLabelsOverride,
+		disclaimerVersion: config.DisclaimerVersion,
+		disableDisclaimer: config.DisableDisclaimer,
+		teamSlugLabel:     config.TeamSlugLabel,
+		notifPolicyLabel:  config.NotificationPolicySlugLabel,
+		bucketSlugLabel:   config.BucketSlugLabel,
+		metricName:        config.MetricNameTransform,
+		slugPrefix:        config.SlugPrefix,
+		descTpl:           descTpl,
+		ruleSlugTpl:       ruleSlugTpl,
+		groupByLabel:      config.CollectionGroupingLabel,
+		api:               api,
+		logger:            config.Logger,
+	}, nil
 }
 
 // IOWriterGroupedRulesYAMLRepo knows to store all the SLO rules (recordings and alerts)
 // grouped in an IOWriter in YAML format, that is compatible with Prometheus.
 type IOWriterGroupedRulesYAMLRepo struct {
-	writer io.Writer
-	logger log.Logger
+	writer            io.Writer
+	monitorsWriter    io.Writer
+	format            OutputFormat
+	rateWindowIntvl   func(window time.Duration) time.Duration
+	intervals         intervalRange
+	kindIntervals     KindIntervals
+	sync              bool
+	strictLabels      bool
+	strict            bool
+	extraLabels       map[string]string
+	extraLabelsOver   bool
+	disableDisclaimer bool
+	disclaimerVersion string
+	teamSlugLabel     string
+	notifPolicyLabel  string
+	bucketSlugLabel   string
+	metricName        func(name string) string
+	slugPrefix        string
+	descTpl           *template.Template
+	ruleSlugTpl       *template.Template
+	groupByLabel      string
+	api               chronosphereAPI
+	logger            log.Logger
+}
+
+// chronosphereAPI is the Chronosphere config API version and kinds of the objects.
+type chronosphereAPI struct {
+	version           string
+	collectionKind    string
+	recordingRuleKind string
+	monitorKind       string
 }
 
 type StorageSLO struct {
 	SLO   prometheus.SLO
 	Rules prometheus.SLORules
+	// Interval is the evaluation interval of the SLO rules, if not set
+	// the repository default interval will be used.
+	Interval time.Duration
+	// ExtraLabels are the labels that will be added to all the SLO recording rules and
+	// monitors, on conflicts these take precedence over the repository extra labels.
+	ExtraLabels map[string]string
+}
+
+// StoreResult is the result of storing the SLO rules.
+type StoreResult struct {
+	Collections    int
+	RecordingRules int
+	Monitors       int
+	BytesWritten   int
 }
 
 // StoreSLOs will store the recording and alert prometheus rules, if grouped is false it will
 // split and store as 2 different groups the alerts and the recordings, if true
 // it will be save as a single group.
 func (i IOWriterGroupedRulesYAMLRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	_, err := i.StoreSLOsResult(ctx, slos)
+	return err
+}
+
+// StoreSLOsResult is like StoreSLOs but returns the result of the stored rules.
+func (i IOWriterGroupedRulesYAMLRepo) StoreSLOsResult(ctx context.Context, slos []StorageSLO) (*StoreResult, error) {
 	if len(slos) == 0 {
-		return fmt.Errorf("slo rules required")
+		return nil, fmt.Errorf("slo rules required")
 	}
 
-	// If we don't have anything to store, error so we can increase the reliability
-	// because maybe this was due to an unintended error (typos, misconfig, too many disable...).
-	rules := 0
-	err := error(nil)
-	var rulesYaml []byte
-
 	logger := i.logger.WithCtxValues(ctx)
 
-	// Convert to YAML (Prometheus rule format).
-	rules, rulesYaml, err = rawChronosphereYAML(slos, logger)
+	res, objs, err := i.buildChronosphereObjects(ctx, slos, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	writers := []io.Writer{i.writer}
+	if i.monitorsWriter == nil {
+		res.BytesWritten, err = i.write(i.writer, objs)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		writers = append(writers, i.monitorsWriter)
+		res.BytesWritten, err = i.write(i.writer, &chronosphereObjects{collections: objs.collections, rules: objs.rules})
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := i.write(i.monitorsWriter, &chronosphereObjects{monitors: objs.monitors})
+		if err != nil {
+			return nil, fmt.Errorf("could not write monitors: %w", err)
+		}
+		res.BytesWritten += n
+	}
+
+	if i.sync {
+		for _, w := range writers {
+			if s, ok := w.(syncer); ok {
+				err := s.Sync()
+				if err != nil {
+					return nil, fmt.Errorf("could not sync rules: %w", err)
+				}
+			}
+		}
+	}
+
+	logger.WithValues(log.Kv{
+		"collections":     res.Collections,
+		"recording-rules": res.RecordingRules,
+		"monitors":        res.Monitors,
+		"services":        sloServices(slos),
+	}).Infof("Chronosphere collections and rules written")
+
+	return res, nil
+}
+
+// ValidateSLOs will generate the SLO rules in the same way StoreSLOs does, but without
+// writing them, returning the result (without written bytes) or the error that StoreSLOs
+// would return (including ErrNoSLORules).
+func (i IOWriterGroupedRulesYAMLRepo) ValidateSLOs(ctx context.Context, slos []StorageSLO) (*StoreResult, error) {
+	if len(slos) == 0 {
+		return nil, fmt.Errorf("slo rules required")
+	}
 
+	res, _, err := i.buildChronosphereObjects(ctx, slos, i.logger.WithCtxValues(ctx))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// DiffSLOs will generate the SLO rules in the same way StoreSLOs does, but instead of writing
+// them, it will compare them with the rules of the file on the path. A missing file is handled
+// as an empty one.
+func (i IOWriterGroupedRulesYAMLRepo) DiffSLOs(ctx context.Context, path string, slos []StorageSLO) (*prometheus.DiffResult, error) {
+	if len(slos) == 0 {
+		return nil, fmt.Errorf("slo rules required")
+	}
+
+	_, objs, err := i.buildChronosphereObjects(ctx, slos, i.logger.WithCtxValues(ctx))
+	if err != nil {
+		return nil, err
 	}
 
-	rulesYaml = writeTopDisclaimer(rulesYaml)
-	_, err = i.writer.Write(rulesYaml)
+	var b bytes.Buffer
+	_, err = i.write(&b, objs)
 	if err != nil {
-		return fmt.Errorf("could not write top disclaimer: %w", err)
+		return nil, err
 	}
 
-	logger.WithValues(log.Kv{"groups": rules}).Infof("Prometheus rules written")
+	return prometheus.DiffFile(path, b.Bytes())
+}
 
-	return nil
+// Serialize satisfies prometheus.RuleSerializer interface, so the Chronosphere objects can be
+// stored by the Prometheus repositories, it returns the number of collections and the objects
+// that StoreSLOs would write.
+func (i IOWriterGroupedRulesYAMLRepo) Serialize(slos []prometheus.StorageSLO) (int, []byte, error) {
+	if len(slos) == 0 {
+		return 0, nil, fmt.Errorf("slo rules required")
+	}
+
+	chronoSLOs := make([]StorageSLO, 0, len(slos))
+	for _, slo := range slos {
+		chronoSLOs = append(chronoSLOs, StorageSLO{
+			SLO:         slo.SLO,
+			Rules:       slo.Rules,
+			Interval:    slo.Interval,
+			ExtraLabels: slo.ExtraLabels,
+		})
+	}
+
+	res, objs, err := i.buildChronosphereObjects(context.Background(), chronoSLOs, i.logger)
+	if errors.Is(err, ErrNoSLORules) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var b bytes.Buffer
+	_, err = i.write(&b, objs)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return res.Collections, b.Bytes(), nil
 }
-func rawChronosphereYAML(slos []StorageSLO, logger log.Logger) (int, []byte, error) {
+
+// write writes the disclaimer and the Chronosphere objects to the writer and returns
+// the written bytes.
+func (i IOWriterGroupedRulesYAMLRepo) write(w io.Writer, objs *chronosphereObjects) (int, error) {
+	cw := &countWriter{w: w}
+	if !i.disableDisclaimer {
+		_, err := io.WriteString(cw, disclaimer(i.disclaimerVersion))
+		if err != nil {
+			return 0, fmt.Errorf("could not write top disclaimer: %w", err)
+		}
+	}
+
+	var err error
+	switch i.format {
+	case HCLFormat:
+		err = writeChronosphereHCL(cw, objs)
+	default:
+		err = writeChronosphereYAML(cw, i.api, objs)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return cw.n, nil
+}
+
+// chronosphereObjects are the Chronosphere objects that will be written, sorted.
+type chronosphereObjects struct {
+	collections []chronosphereCollection
+	rules       []chronosphereRecordingRule
+	monitors    []chronosphereMonitor
+}
+
+func (i IOWriterGroupedRulesYAMLRepo) buildChronosphereObjects(ctx context.Context, slos []StorageSLO, logger log.Logger) (*StoreResult, *chronosphereObjects, error) {
 	collections := make(map[string]chronosphereCollection)
 	rules := []chronosphereRecordingRule{}
 	monitors := []chronosphereMonitor{}
 
-	for _, slo := range slos {
-		collection := createChronosphereCollection(slo)
-		rules = append(rules, createChronosphereRecordingRules(slo, collection.Slug)...)
-		monitors = append(monitors, createChronosphereMonitors(slo, collection.Slug, logger)...)
+	for idx, slo := range slos {
+		// Don't check the context on every SLO to keep the overhead negligible.
+		if idx%ctxCheckSLOs == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		sliIntervalSecs, err := sloIntervalSecs(slo, "SLI recordings", i.kindIntervals.SLIRecordings, i.intervals, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		metaIntervalSecs, err := sloIntervalSecs(slo, "metadata recordings", i.kindIntervals.MetadataRecordings, i.intervals, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		monitorsIntervalSecs, err := sloIntervalSecs(slo, "monitors", i.kindIntervals.Monitors, i.intervals, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		slo.Rules = setExtraLabels(slo.Rules, mergeLabels(i.extraLabels, slo.ExtraLabels), i.extraLabelsOver)
+
+		if i.strict {
+			err := checkStrictRules(slo.Rules)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid %q SLO rules: %w", slo.SLO.ID, err)
+			}
+		}
+
+		collection, err := createChronosphereCollection(slo, i.slugPrefix, i.groupByLabel, i.descTpl)
+		if err != nil {
+			return nil, nil, err
+		}
+		collection.Team_slug = slo.SLO.Labels[i.teamSlugLabel]
+		collection.Notification_policy_slug = slo.SLO.Labels[i.notifPolicyLabel]
+
+		// Multiple SLOs can share the same collection, if they are not the first
+		// ones, merge the collection settings.
+		if c, ok := collections[collection.Slug]; ok {
+			collection, err = mergeChronosphereCollections(c, collection, logger)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid %q SLO collection: %w", slo.SLO.ID, err)
+			}
+		}
+
+		// Recording rules belong to buckets, by default the bucket with the collection slug.
+		bucketSlug := slo.SLO.Labels[i.bucketSlugLabel]
+		if i.bucketSlugLabel == "" || bucketSlug == "" {
+			bucketSlug = collection.Slug
+		}
+
+		sloRules, err := createChronosphereRecordingRules(slo, i.slugPrefix, i.ruleSlugTpl, bucketSlug, sliIntervalSecs, metaIntervalSecs, i.rateWindowIntvl, i.intervals, i.strictLabels, i.metricName, logger)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %q SLO recording rules: %w", slo.SLO.ID, err)
+		}
+		rules = append(rules, sloRules...)
+		monitors = append(monitors, createChronosphereMonitors(slo, i.slugPrefix, collection.Slug, monitorsIntervalSecs, logger)...)
 		collections[collection.Slug] = collection
 	}
 
-	if len(collections) == 0 {
-		return 0, nil, ErrNoSLORules
+	// If we don't have anything to store, error so we can increase the reliability
+	// because maybe this was due to an unintended error (typos, misconfig, too many disable...).
+	if len(rules) == 0 && len(monitors) == 0 {
+		return nil, nil, ErrNoSLORules
 	}
 
-	outputYaml := make([]byte, 0)
+	// Slugs must be unique, different rules could end with the same slug after sanitizing.
+	usedRuleSlugs := map[string]bool{}
+	for i := range rules {
+		slug := uniqueSlug(usedRuleSlugs, rules[i].Slug)
+		if slug != rules[i].Slug {
+			logger.Warningf("recording rule slug %q already used, using %q", rules[i].Slug, slug)
+		}
+		rules[i].Slug = slug
+		rules[i].Name = slug
+	}
+	usedMonitorSlugs := map[string]bool{}
+	for i := range monitors {
+		slug := uniqueSlug(usedMonitorSlugs, monitors[i].Slug)
+		if slug != monitors[i].Slug {
+			logger.Warningf("monitor slug %q already used, using %q", monitors[i].Slug, slug)
+		}
+		monitors[i].Slug = slug
+	}
 
-	for _, collection := range collections {
-		chronosphereCollectionYAML := NewChronosphereCollectionYAML()
+	// Sort everything by slug so the output is deterministic between executions.
+	collectionSlugs := make([]string, 0, len(collections))
+	for slug := range collections {
+		collectionSlugs = append(collectionSlugs, slug)
+	}
+	sort.Strings(collectionSlugs)
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Slug < rules[j].Slug })
+	sort.SliceStable(monitors, func(i, j int) bool { return monitors[i].Slug < monitors[j].Slug })
+
+	objs := &chronosphereObjects{
+		rules:    rules,
+		monitors: monitors,
+	}
+	for _, slug := range collectionSlugs {
+		objs.collections = append(objs.collections, collections[slug])
+	}
+
+	res := &StoreResult{
+		Collections:    len(collections),
+		RecordingRules: len(rules),
+		Monitors:       len(monitors),
+	}
+
+	return res, objs, nil
+}
+
+// writeChronosphereYAML streams the Chronosphere objects to the writer as YAML documents,
+// one at a time, each of the documents ends with a `---` separator.
+func writeChronosphereYAML(w io.Writer, api chronosphereAPI, objs *chronosphereObjects) error {
+	docs := &yamlDocumentsWriter{w: w}
+	for _, collection := range objs.collections {
+		chronosphereCollectionYAML := NewChronosphereCollectionYAML(api.version, api.collectionKind)
 		chronosphereCollectionYAML.Spec = collection
-		collectionYaml, err := yaml.Marshal(chronosphereCollectionYAML)
+		err := docs.encode(chronosphereCollectionYAML)
 		if err != nil {
-			return 0, nil, fmt.Errorf("could not format collections: %w", err)
+			return fmt.Errorf("could not format collections: %w", err)
 		}
-		outputYaml = append(outputYaml, collectionYaml...)
-		outputYaml = append(outputYaml, []byte("---\n")...)
 	}
 
-	for _, rule := range rules {
-		chronosphereRuleYAML := NewChronosphereRecordingRuleYAML()
+	for _, rule := range objs.rules {
+		chronosphereRuleYAML := NewChronosphereRecordingRuleYAML(api.version, api.recordingRuleKind)
 		chronosphereRuleYAML.Spec = rule
-		ruleYaml, err := yaml.Marshal(chronosphereRuleYAML)
+		err := docs.encode(chronosphereRuleYAML)
 		if err != nil {
-			return 0, nil, fmt.Errorf("could not format recording rule: %w", err)
+			return fmt.Errorf("could not format recording rule: %w", err)
 		}
-		outputYaml = append(outputYaml, ruleYaml...)
-		outputYaml = append(outputYaml, []byte("---\n")...)
 	}
 
-	for _, monitor := range monitors {
-		chronosphereMonitorYAML := NewChronosphereMonitorYAML()
+	for _, monitor := range objs.monitors {
+		chronosphereMonitorYAML := NewChronosphereMonitorYAML(api.version, api.monitorKind)
 		chronosphereMonitorYAML.Spec = monitor
-		monitorYaml, err := yaml.Marshal(chronosphereMonitorYAML)
+		err := docs.encode(chronosphereMonitorYAML)
+		if err != nil {
+			return fmt.Errorf("could not format monitor: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// yamlDocumentsWriter encodes YAML documents only writing the documents separator between
+// them, so strict YAML multi-document readers don't get empty leading or trailing documents.
+type yamlDocumentsWriter struct {
+	w    io.Writer
+	docs int
+}
+
+func (y *yamlDocumentsWriter) encode(v interface{}) error {
+	if y.docs > 0 {
+		_, err := io.WriteString(y.w, "---\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	enc := yaml.NewEncoder(y.w)
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
+
+	y.docs++
+	return nil
+}
+
+// syncer is implemented by the writers that can commit the written data to a durable
+// storage (e.g: os.File).
+type syncer interface {
+	Sync() error
+}
+
+// countWriter counts the bytes written on the wrapped writer.
+type countWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// sloIntervalSecs returns the evaluation interval in seconds for the SLO rules of a kind, the SLO
+// interval takes precedence over the kind one.
+func sloIntervalSecs(slo StorageSLO, kind string, kindInterval time.Duration, intervals intervalRange, logger log.Logger) (int, error) {
+	interval := slo.Interval
+	if interval == 0 {
+		interval = kindInterval
+	}
+
+	secs, err := intervalSecs(interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %q SLO interval: %w", slo.SLO.ID, err)
+	}
+
+	return intervals.clampSecs(fmt.Sprintf("%q SLO %s", slo.SLO.ID, kind), secs, logger), nil
+}
+
+// ParseIntervalSecs parses an interval duration (e.g: `30s`, `5m`) into the Chronosphere
+// `interval_secs`, the intervals that are not whole seconds (e.g: `500ms`) are rejected
+// instead of truncated.
+func ParseIntervalSecs(interval string) (int, error) {
+	d, err := prommodel.ParseDuration(interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %q interval: %w", interval, err)
+	}
+
+	return intervalSecs(time.Duration(d))
+}
+
+// intervalSecs returns the interval in seconds, failing on the sub-second intervals and the ones
+// that are not whole seconds.
+func intervalSecs(interval time.Duration) (int, error) {
+	if interval < time.Second {
+		return 0, fmt.Errorf("interval %s must be at least 1s", interval)
+	}
+	if interval%time.Second != 0 {
+		return 0, fmt.Errorf("interval %s must be whole seconds", interval)
+	}
+
+	return int(interval / time.Second), nil
+}
+
+// KindIntervals are the evaluation intervals by rule kind, zero values use the default interval.
+type KindIntervals struct {
+	SLIRecordings      time.Duration
+	MetadataRecordings time.Duration
+	Monitors           time.Duration
+}
+
+func (k KindIntervals) validate() error {
+	for _, interval := range []time.Duration{k.SLIRecordings, k.MetadataRecordings, k.Monitors} {
+		if interval == 0 {
+			continue
+		}
+		_, err := intervalSecs(interval)
 		if err != nil {
-			return 0, nil, fmt.Errorf("could not format monitor: %w", err)
+			return err
 		}
-		outputYaml = append(outputYaml, monitorYaml...)
-		outputYaml = append(outputYaml, []byte("---\n")...)
 	}
 
-	return len(collections), outputYaml, nil
+	return nil
+}
+
+// withDefault returns the kind intervals using the default interval on the ones not set.
+func (k KindIntervals) withDefault(interval time.Duration) KindIntervals {
+	if k.SLIRecordings == 0 {
+		k.SLIRecordings = interval
+	}
+	if k.MetadataRecordings == 0 {
+		k.MetadataRecordings = interval
+	}
+	if k.Monitors == 0 {
+		k.Monitors = interval
+	}
+
+	return k
+}
+
+// intervalRange is the allowed range of the evaluation intervals, zero values disable the limits.
+type intervalRange struct {
+	min time.Duration
+	max time.Duration
+}
+
+// clampSecs returns the interval in seconds clamped to the range, logging the clamped ones.
+func (r intervalRange) clampSecs(name string, secs int, logger log.Logger) int {
+	minSecs, maxSecs := int(r.min.Seconds()), int(r.max.Seconds())
+
+	clamped := secs
+	switch {
+	case minSecs != 0 && secs < minSecs:
+		clamped = minSecs
+	case maxSecs != 0 && secs > maxSecs:
+		clamped = maxSecs
+	default:
+		return secs
+	}
+
+	logger.Warningf("%s interval %ds is out of the allowed range, using %ds", name, secs, clamped)
+
+	return clamped
+}
+
+// sliRateWindowRegexp matches the rate window of the SLI error recording rules (e.g: `slo:sli_error:ratio_rate30m`).
+var sliRateWindowRegexp = regexp.MustCompile(`^slo:sli_error:ratio_rate(\w+)$`)
+
+// RateWindowInterval is a rate window interval mapping that evaluates the rules 10 times per
+// rate window, between 30s and 5m (e.g: 5m window every 30s, 30m window every 3m and 1h or
+// longer windows every 5m).
+func RateWindowInterval(window time.Duration) time.Duration {
+	interval := (window / 10).Round(time.Second)
+	switch {
+	case interval < minRateWindowInterval:
+		return minRateWindowInterval
+	case interval > maxRateWindowInterval:
+		return maxRateWindowInterval
+	}
+
+	return interval
+}
+
+// ruleIntervalSecs returns the evaluation interval in seconds of the SLI recording rule based on
+// its rate window, if the rule doesn't have a rate window, the SLO interval will be used.
+func ruleIntervalSecs(record string, sloIntervalSecs int, rateWindowInterval func(time.Duration) time.Duration) (int, error) {
+	if rateWindowInterval == nil {
+		return sloIntervalSecs, nil
+	}
+
+	match := sliRateWindowRegexp.FindStringSubmatch(record)
+	if match == nil {
+		return sloIntervalSecs, nil
+	}
+
+	window, err := prommodel.ParseDuration(match[1])
+	if err != nil {
+		return sloIntervalSecs, nil
+	}
+
+	secs, err := intervalSecs(rateWindowInterval(time.Duration(window)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s rate window interval: %w", time.Duration(window), err)
+	}
+
+	return secs, nil
 }
 
-func createChronosphereCollection(slo StorageSLO) chronosphereCollection {
+// createChronosphereCollection returns the collection of the SLO, the collection will be based on
+// the SLO service or the SLO label value if a grouping label is used.
+func createChronosphereCollection(slo StorageSLO, prefix, groupByLabel string, descTpl *template.Template) (chronosphereCollection, error) {
+	key := slo.SLO.Service
+	if groupByLabel != "" {
+		key = slo.SLO.Labels[groupByLabel]
+		if key == "" {
+			return chronosphereCollection{}, fmt.Errorf("%q SLO doesn't have the %q collection grouping label", slo.SLO.ID, groupByLabel)
+		}
+	}
+
+	var desc bytes.Buffer
+	err := descTpl.Execute(&desc, slo.SLO)
+	if err != nil {
+		return chronosphereCollection{}, fmt.Errorf("could not render %q SLO collection description: %w", slo.SLO.ID, err)
+	}
+
 	return chronosphereCollection{
-		Slug:        fmt.Sprintf("sloth-slo-%s", slo.SLO.Service),
-		Name:        fmt.Sprintf("sloth-slo-%s", slo.SLO.Service),
-		Description: "SLOs generated by Sloth",
+		Slug:        sanitizeSlug(fmt.Sprintf("%s-%s", prefix, key)),
+		Name:        fmt.Sprintf("%s-%s", prefix, key),
+		Description: desc.String(),
+	}, nil
+}
+
+var (
+	invalidSlugCharsRegexp = regexp.MustCompile(`[^a-z0-9_-]`)
+	validSlugRegexp        = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	validAPIVersionRegexp  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/-]*$`)
+	validKindRegexp        = regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`)
+)
+
+// RecordingRuleKind is the kind of the SLO recording rule.
+type RecordingRuleKind string
+
+const (
+	// SLIRecordingRuleKind are the SLI error ratio recording rules.
+	SLIRecordingRuleKind RecordingRuleKind = "sli"
+	// MetadataRecordingRuleKind are the SLO metadata recording rules (objective, error budget...).
+	MetadataRecordingRuleKind RecordingRuleKind = "metadata"
+)
+
+// RecordingRuleSlugData is the data received by the recording rule slug template.
+type RecordingRuleSlugData struct {
+	Prefix  string
+	Service string
+	SLOID   string
+	Record  string
+	Kind    RecordingRuleKind
+}
+
+// recordingRuleSlug renders the slug of a recording rule, the rendered slug is sanitized
+// and validated.
+func recordingRuleSlug(tpl *template.Template, data RecordingRuleSlugData) (string, error) {
+	var b bytes.Buffer
+	err := tpl.Execute(&b, data)
+	if err != nil {
+		return "", fmt.Errorf("could not render slug: %w", err)
+	}
+
+	slug := sanitizeSlug(strings.TrimSpace(b.String()))
+	if !validSlugRegexp.MatchString(slug) {
+		return "", fmt.Errorf("invalid %q slug: must start with an alphanumeric character and only have alphanumeric, '-' and '_' characters", slug)
+	}
+
+	return slug, nil
+}
+
+// sanitizeSlug returns a valid Chronosphere slug, these are case insensitive and only
+// support alphanumeric, `-` and `_` characters, the invalid ones will be replaced by `_`.
+func sanitizeSlug(slug string) string {
+	return invalidSlugCharsRegexp.ReplaceAllString(strings.ToLower(slug), "_")
+}
+
+// UnderscoreMetricName is a metric name transform that replaces the colons of the recording
+// rule names with underscores (e.g: `slo:sli_error:ratio_rate5m` to `slo_sli_error_ratio_rate5m`).
+func UnderscoreMetricName(name string) string {
+	return strings.ReplaceAll(name, ":", "_")
+}
+
+// uniqueSlug returns the slug if it's not used yet, otherwise it will add a numeric suffix
+// until it is. The returned slug will be marked as used.
+func uniqueSlug(used map[string]bool, slug string) string {
+	unique := slug
+	for n := 2; used[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d", slug, n)
+	}
+	used[unique] = true
+
+	return unique
+}
+
+// mergeChronosphereCollections merges the settings of the same collection set by different
+// SLOs, these can be missing on some of the SLOs but the teams can't be different. On different
+// notification policies the first one will be kept.
+func mergeChronosphereCollections(c1, c2 chronosphereCollection, logger log.Logger) (chronosphereCollection, error) {
+	switch {
+	case c1.Team_slug == "":
+		c1.Team_slug = c2.Team_slug
+	case c2.Team_slug != "" && c1.Team_slug != c2.Team_slug:
+		return c1, fmt.Errorf("collection %q has conflicting team slugs: %q and %q", c1.Slug, c1.Team_slug, c2.Team_slug)
+	}
+
+	switch {
+	case c1.Notification_policy_slug == "":
+		c1.Notification_policy_slug = c2.Notification_policy_slug
+	case c2.Notification_policy_slug != "" && c1.Notification_policy_slug != c2.Notification_policy_slug:
+		// Keep the first one, the routing can be fixed on the SLOs without breaking the generation.
+		logger.Warningf("collection %q has conflicting notification policy slugs: %q and %q, using %q", c1.Slug, c1.Notification_policy_slug, c2.Notification_policy_slug, c1.Notification_policy_slug)
 	}
+
+	return c1, nil
 }
 
-func createChronosphereRecordingRules(slo StorageSLO, collectionSlug string) []chronosphereRecordingRule {
+func createChronosphereRecordingRules(slo StorageSLO, prefix string, slugTpl *template.Template, bucketSlug string, sliIntervalSecs, metaIntervalSecs int, rateWindowInterval func(time.Duration) time.Duration, intervals intervalRange, strictLabels bool, metricName func(string) string, logger log.Logger) ([]chronosphereRecordingRule, error) {
 	rules := []chronosphereRecordingRule{}
 	for _, rule := range slo.Rules.SLIErrorRecRules {
-		ruleId := fmt.Sprintf("sloth-slo-sli-recordings-%s-%s", slo.SLO.ID, strings.Replace(rule.Record, ":", "_", -1))
+		labels, err := labelPolicyLabels(rule.Labels, strictLabels, logger)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q rule labels: %w", rule.Record, err)
+		}
+
+		ruleIntervalSecs, err := ruleIntervalSecs(rule.Record, sliIntervalSecs, rateWindowInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q rule interval: %w", rule.Record, err)
+		}
+		ruleIntervalSecs = intervals.clampSecs(fmt.Sprintf("%q rule", rule.Record), ruleIntervalSecs, logger)
+
+		ruleId, err := recordingRuleSlug(slugTpl, RecordingRuleSlugData{
+			Prefix:  prefix,
+			Service: slo.SLO.Service,
+			SLOID:   slo.SLO.ID,
+			Record:  rule.Record,
+			Kind:    SLIRecordingRuleKind,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q rule slug: %w", rule.Record, err)
+		}
 		chronoRule := chronosphereRecordingRule{
 			Slug:          ruleId,
 			Name:          ruleId,
-			Collection:    collectionSlug,
-			Interval_secs: 60,
-			Metric_name:   rule.Record,
+			Bucket_slug:   bucketSlug,
+			Interval_secs: ruleIntervalSecs,
+			Metric_name:   metricName(rule.Record),
 			Expr:          rule.Expr,
 			Label_policy: chronosphereLabelPolicy{
-				Add: rule.Labels,
+				Add: labels,
 			},
 		}
 		rules = append(rules, chronoRule)
 	}
 
 	for _, rule := range slo.Rules.MetadataRecRules {
-		ruleId := fmt.Sprintf("sloth-slo-sli-recordings-%s-%s", slo.SLO.ID, strings.Replace(rule.Record, ":", "_", -1))
+		labels, err := labelPolicyLabels(rule.Labels, strictLabels, logger)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q rule labels: %w", rule.Record, err)
+		}
+
+		ruleId, err := recordingRuleSlug(slugTpl, RecordingRuleSlugData{
+			Prefix:  prefix,
+			Service: slo.SLO.Service,
+			SLOID:   slo.SLO.ID,
+			Record:  rule.Record,
+			Kind:    MetadataRecordingRuleKind,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q rule slug: %w", rule.Record, err)
+		}
 		chronoRule := chronosphereRecordingRule{
 			Slug:          ruleId,
 			Name:          ruleId,
-			Collection:    collectionSlug,
-			Interval_secs: 60,
-			Metric_name:   rule.Record,
+			Bucket_slug:   bucketSlug,
+			Interval_secs: metaIntervalSecs,
+			Metric_name:   metricName(rule.Record),
 			Expr:          rule.Expr,
 			Label_policy: chronosphereLabelPolicy{
-				Add: rule.Labels,
+				Add: labels,
 			},
 		}
 		rules = append(rules, chronoRule)
 	}
 
-	return rules
+	return rules, nil
+}
+
+var (
+	invalidLabelNameCharsRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	validLabelNameRegexp        = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// setExtraLabels returns the SLO rules with the extra labels, on conflicts the rules labels
+// win unless override is used. The original rules are not modified.
+func setExtraLabels(rules prometheus.SLORules, extraLabels map[string]string, override bool) prometheus.SLORules {
+	if len(extraLabels) == 0 {
+		return rules
+	}
+
+	set := func(rules []rulefmt.Rule) []rulefmt.Rule {
+		res := make([]rulefmt.Rule, 0, len(rules))
+		for _, r := range rules {
+			if override {
+				r.Labels = mergeLabels(r.Labels, extraLabels)
+			} else {
+				r.Labels = mergeLabels(extraLabels, r.Labels)
+			}
+			res = append(res, r)
+		}
+		return res
+	}
+
+	return prometheus.SLORules{
+		SLIErrorRecRules: set(rules.SLIErrorRecRules),
+		MetadataRecRules: set(rules.MetadataRecRules),
+		AlertRules:       set(rules.AlertRules),
+	}
 }
 
-func createChronosphereMonitors(slo StorageSLO, collectionSlug string, logger log.Logger) []chronosphereMonitor {
+// sloServices returns the sorted distinct services of the SLOs.
+func sloServices(slos []StorageSLO) []string {
+	set := map[string]bool{}
+	services := []string{}
+	for _, slo := range slos {
+		if set[slo.SLO.Service] {
+			continue
+		}
+		set[slo.SLO.Service] = true
+		services = append(services, slo.SLO.Service)
+	}
+	sort.Strings(services)
+
+	return services
+}
+
+// checkStrictRules checks the SLO rules labels and annotations values, the same way as the Prometheus rules.
+func checkStrictRules(rules prometheus.SLORules) error {
+	for _, rs := range [][]rulefmt.Rule{rules.SLIErrorRecRules, rules.MetadataRecRules, rules.AlertRules} {
+		for _, r := range rs {
+			err := prometheus.CheckStrictRuleValues(r.Labels, r.Annotations)
+			if err != nil {
+				name := r.Record
+				if name == "" {
+					name = r.Alert
+				}
+				return fmt.Errorf("invalid %q rule: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func mergeLabels(ms ...map[string]string) map[string]string {
+	res := map[string]string{}
+	for _, m := range ms {
+		for k, v := range m {
+			res[k] = v
+		}
+	}
+
+	return res
+}
+
+// labelPolicyLabels returns the labels that can be added by a Chronosphere label policy. The
+// invalid characters of the label names will be replaced by `_` (e.g: `k8s.cluster` to `k8s_cluster`)
+// and the labels with empty values will be dropped, or fail if strict.
+func labelPolicyLabels(labels map[string]string, strict bool, logger log.Logger) (map[string]string, error) {
+	res := make(map[string]string, len(labels))
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // Deterministic errors and logs.
+
+	for _, k := range keys {
+		v := labels[k]
+		if v == "" {
+			if strict {
+				return nil, fmt.Errorf("label %q has an empty value", k)
+			}
+			logger.Warningf("label %q has an empty value, dropping", k)
+			continue
+		}
+
+		name := invalidLabelNameCharsRegexp.ReplaceAllString(k, "_")
+		if !validLabelNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("label %q can't be used as a Chronosphere label name", k)
+		}
+		if _, ok := res[name]; ok {
+			return nil, fmt.Errorf("label %q collides with another label as %q", k, name)
+		}
+		res[name] = v
+	}
+
+	return res, nil
+}
+
+func createChronosphereMonitors(slo StorageSLO, prefix, collectionSlug string, intervalSecs int, logger log.Logger) []chronosphereMonitor {
 	monitors := []chronosphereMonitor{}
 	for _, rule := range slo.Rules.AlertRules {
-		severity, ok := rule.Labels["severity"]
+		severity, ok := monitorSeverity(rule)
 		if !ok {
 			logger.Warningf("alert rule %q doesn't have a severity label, skipping", rule.Alert)
 			continue
 		}
+
+		// Alerts that need to be active for some time before firing, will need to meet
+		// the condition for the same time on Chronosphere.
+		sustainSecs := defaultSustainSecs
+		if rule.For > 0 {
+			sustainSecs = int(time.Duration(rule.For).Seconds())
+		}
+
 		conditions := map[string]map[string][]chronosphereMonitorConditions{
 			severity: {
 				"conditions": {
 					chronosphereMonitorConditions{
 						Value:                0,
-						Sustain_secs:         60,
+						Sustain_secs:         sustainSecs,
 						Resolve_sustain_secs: 60,
 						Op:                   chronosphereOperation(EXISTS).String(),
 					},
@@ -192,16 +1237,21 @@ func createChronosphereMonitors(slo StorageSLO, collectionSlug string, logger lo
 			},
 		}
 
-		ruleId := fmt.Sprintf("sloth-slo-alerts-%s-%s", slo.SLO.ID, strings.Replace(rule.Alert, ":", "_", -1))
+		ruleId := sanitizeSlug(fmt.Sprintf("%s-alerts-%s-%s", prefix, slo.SLO.ID, rule.Alert))
+
+		name := rule.Annotations["summary"]
+		if name == "" {
+			name = rule.Alert
+		}
 
 		monitor := chronosphereMonitor{
 			Slug:                     ruleId,
-			Name:                     rule.Annotations["summary"],
+			Name:                     name,
 			Query:                    rule.Expr,
 			Collection:               collectionSlug,
-			Interval_secs:            60,
+			Interval_secs:            intervalSecs,
 			Labels:                   rule.Labels,
-			Annotations:              rule.Annotations,
+			Annotations:              monitorAnnotations(rule.Annotations),
 			Notification_policy_slug: rule.Labels["routing_key"], // TODO set routing
 			Series_conditions:        map[string]map[string]map[string][]chronosphereMonitorConditions{"defaults": conditions},
 		}
@@ -211,14 +1261,59 @@ func createChronosphereMonitors(slo StorageSLO, collectionSlug string, logger lo
 	return monitors
 }
 
-var disclaimer = fmt.Sprintf(`
-# Code generated by Sloth (%s): https://github.com/slok/sloth.
+const defaultSustainSecs = 60
+
+// monitorAnnotations returns the Chronosphere monitor annotations of the alert rule annotations
+// (e.g: summary, runbook...), the annotations without value are dropped and nil is returned if
+// there aren't annotations, so the monitor doesn't have them.
+func monitorAnnotations(annotations map[string]string) map[string]string {
+	res := map[string]string{}
+	for k, v := range annotations {
+		if v == "" {
+			continue
+		}
+		res[k] = v
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// monitorSeverity returns the Chronosphere severity for the alert rule. A `severity` label
+// with a valid Chronosphere severity has preference, if not we will map the Sloth alert
+// severity (page alerts are critical and ticket alerts are warnings).
+func monitorSeverity(rule rulefmt.Rule) (string, bool) {
+	switch severity := rule.Labels["severity"]; severity {
+	case Warn.String(), Critical.String():
+		return severity, true
+	}
+
+	switch rule.Labels[slothSeverityLabelName] {
+	case alert.PageAlertSeverity.String():
+		return Critical.String(), true
+	case alert.TicketAlertSeverity.String():
+		return Warn.String(), true
+	}
+
+	return "", false
+}
+
+const disclaimerFmt = `
+# Code generated by Sloth%s: https://github.com/slok/sloth.
 # DO NOT EDIT.
 
-`, info.Version)
+`
+
+// disclaimer returns the disclaimer, if the version is empty it will be omitted.
+func disclaimer(version string) string {
+	if version != "" {
+		version = fmt.Sprintf(" (%s)", version)
+	}
 
-func writeTopDisclaimer(bs []byte) []byte {
-	return append([]byte(disclaimer), bs...)
+	return fmt.Sprintf(disclaimerFmt, version)
 }
 
 type chronosphereCollectionYAML struct {
@@ -227,10 +1322,10 @@ type chronosphereCollectionYAML struct {
 	Spec        chronosphereCollection `yaml:"spec"`
 }
 
-func NewChronosphereCollectionYAML() chronosphereCollectionYAML {
+func NewChronosphereCollectionYAML(apiVersion, kind string) chronosphereCollectionYAML {
 	return chronosphereCollectionYAML{
-		Api_version: "v1/config",
-		Kind:        "Collection",
+		Api_version: apiVersion,
+		Kind:        kind,
 	}
 }
 
@@ -252,10 +1347,10 @@ type chronosphereRecordingRuleYAML struct {
 	Spec        chronosphereRecordingRule `yaml:"spec"`
 }
 
-func NewChronosphereRecordingRuleYAML() chronosphereRecordingRuleYAML {
+func NewChronosphereRecordingRuleYAML(apiVersion, kind string) chronosphereRecordingRuleYAML {
 	return chronosphereRecordingRuleYAML{
-		Api_version: "v1/config",
-		Kind:        "RecordingRule",
+		Api_version: apiVersion,
+		Kind:        kind,
 	}
 }
 
@@ -265,17 +1360,19 @@ type ChronosphereMonitorYAML struct {
 	Spec        chronosphereMonitor `yaml:"spec"`
 }
 
-func NewChronosphereMonitorYAML() ChronosphereMonitorYAML {
+func NewChronosphereMonitorYAML(apiVersion, kind string) ChronosphereMonitorYAML {
 	return ChronosphereMonitorYAML{
-		Api_version: "v1/config",
-		Kind:        "Monitor",
+		Api_version: apiVersion,
+		Kind:        kind,
 	}
 }
 
+// chronosphereRecordingRule is the Chronosphere recording rule, these belong to a bucket
+// (not to a collection like the monitors), that can be independent of the SLO collection.
 type chronosphereRecordingRule struct {
 	Slug          string                  `yaml:"slug"`
 	Name          string                  `yaml:"name"`
-	Collection    string                  `yaml:"bucket_slug"`
+	Bucket_slug   string                  `yaml:"bucket_slug"`
 	Interval_secs int                     `yaml:"interval_secs"`
 	Metric_name   string                  `yaml:"metric_name"`
 	Expr          string                  `yaml:"prometheus_expr"`
@@ -324,7 +1421,7 @@ type chronosphereMonitor struct {
 	Collection               string                                                           `yaml:"collection_slug"`
 	Interval_secs            int                                                              `yaml:"interval_secs"`
 	Labels                   map[string]string                                                `yaml:"labels"`
-	Annotations              map[string]string                                                `yaml:"annotations"`
+	Annotations              map[string]string                                                `yaml:"annotations,omitempty"`
 	Notification_policy_slug string                                                           `yaml:"notification_policy_slug"`
 	Series_conditions        map[string]map[string]map[string][]chronosphereMonitorConditions `yaml:"series_conditions"`
 }
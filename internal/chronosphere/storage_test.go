@@ -0,0 +1,2745 @@
+package chronosphere_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	hclv2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/slok/sloth/internal/chronosphere"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestIOWriterGroupedRulesYAMLRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config  chronosphere.IOWriterGroupedRulesYAMLRepoConfig
+		slos    []chronosphere.StorageSLO
+		expYAML string
+		expErr  bool
+	}{
+		"Having 0 SLO rules should fail.": {
+			slos:   []chronosphere.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having a single SLI recording rule should render correctly with the default interval.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+								Labels: map[string]string{"test-label": "one"},
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add:
+      test_label: one
+`,
+		},
+
+		"Having an SLO with a custom interval should use it on the rules.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1", Service: "svc1"},
+					Interval: 30 * time.Second,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 30
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having an SLO without interval should use the custom default interval.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DefaultInterval: 10 * time.Minute,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1", Service: "svc1"},
+					Interval: 0,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 600
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having a rate window interval should use it on the SLI recording rules with a rate window and the SLO interval on the rest.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				RateWindowInterval: chronosphere.RateWindowInterval,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1", Service: "svc1"},
+					Interval: 2 * time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr1"},
+							{Record: "slo:sli_error:ratio_rate30m", Expr: "test-expr2"},
+							{Record: "slo:sli_error:ratio_rate3d", Expr: "test-expr3"},
+							{Record: "test:record", Expr: "test-expr4"},
+						},
+						MetadataRecRules: []rulefmt.Rule{
+							{Record: "slo:objective:ratio", Expr: "test-expr5"},
+						},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-slo_objective_ratio
+  name: sloth-slo-sli-recordings-test1-slo_objective_ratio
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 120
+  metric_name: slo:objective:ratio
+  prometheus_expr: test-expr5
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-slo_sli_error_ratio_rate30m
+  name: sloth-slo-sli-recordings-test1-slo_sli_error_ratio_rate30m
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 180
+  metric_name: slo:sli_error:ratio_rate30m
+  prometheus_expr: test-expr2
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-slo_sli_error_ratio_rate3d
+  name: sloth-slo-sli-recordings-test1-slo_sli_error_ratio_rate3d
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 300
+  metric_name: slo:sli_error:ratio_rate3d
+  prometheus_expr: test-expr3
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-slo_sli_error_ratio_rate5m
+  name: sloth-slo-sli-recordings-test1-slo_sli_error_ratio_rate5m
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 30
+  metric_name: slo:sli_error:ratio_rate5m
+  prometheus_expr: test-expr1
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 120
+  metric_name: test:record
+  prometheus_expr: test-expr4
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having a rate window interval lower than 1s should fail.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				RateWindowInterval: func(window time.Duration) time.Duration { return window / 1000 },
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO interval that is not whole seconds should fail instead of truncating it.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1", Service: "svc1"},
+					Interval: 1500 * time.Millisecond,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with recording and alert rules should render both kinds of rules on the same collection.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+						AlertRules: []rulefmt.Rule{
+							{
+								Alert:       "testAlertPage",
+								Expr:        "test-expr-page",
+								Labels:      map[string]string{"sloth_severity": "page", "severity": "pageteam"},
+								Annotations: map[string]string{"summary": "test page"},
+							},
+							{
+								Alert:       "testAlertTicket",
+								Expr:        "test-expr-ticket",
+								For:         prommodel.Duration(5 * time.Minute),
+								Labels:      map[string]string{"sloth_severity": "ticket"},
+								Annotations: map[string]string{"summary": "test ticket"},
+							},
+							{
+								Alert:  "testAlertCustom",
+								Expr:   "test-expr-custom",
+								Labels: map[string]string{"sloth_severity": "page", "severity": "warn"},
+							},
+							{
+								Alert: "testAlertMissing",
+								Expr:  "test-expr-missing",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: Monitor
+spec:
+  slug: sloth-slo-alerts-test1-testalertcustom
+  name: testAlertCustom
+  prometheus_query: test-expr-custom
+  collection_slug: sloth-slo-svc1
+  interval_secs: 60
+  labels:
+    severity: warn
+    sloth_severity: page
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      warn:
+        conditions:
+        - sustain_secs: 60
+          resolve_sustain_secs: 60
+          op: EXISTS
+---
+api_version: v1/config
+kind: Monitor
+spec:
+  slug: sloth-slo-alerts-test1-testalertpage
+  name: test page
+  prometheus_query: test-expr-page
+  collection_slug: sloth-slo-svc1
+  interval_secs: 60
+  labels:
+    severity: pageteam
+    sloth_severity: page
+  annotations:
+    summary: test page
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      critical:
+        conditions:
+        - sustain_secs: 60
+          resolve_sustain_secs: 60
+          op: EXISTS
+---
+api_version: v1/config
+kind: Monitor
+spec:
+  slug: sloth-slo-alerts-test1-testalertticket
+  name: test ticket
+  prometheus_query: test-expr-ticket
+  collection_slug: sloth-slo-svc1
+  interval_secs: 60
+  labels:
+    sloth_severity: ticket
+  annotations:
+    summary: test ticket
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      warn:
+        conditions:
+        - sustain_secs: 300
+          resolve_sustain_secs: 60
+          op: EXISTS
+`,
+		},
+
+		"Having alert rules with annotations should propagate them to the monitors dropping the empty ones.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimer: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						AlertRules: []rulefmt.Rule{
+							{
+								Alert:  "testAlertPage",
+								Expr:   "test-expr-page",
+								Labels: map[string]string{"sloth_severity": "page"},
+								Annotations: map[string]string{
+									"summary": "test page",
+									"runbook": "https://runbooks.io/svc1/test1",
+									"title":   "",
+								},
+							},
+							{
+								Alert:       "testAlertTicket",
+								Expr:        "test-expr-ticket",
+								Labels:      map[string]string{"sloth_severity": "ticket"},
+								Annotations: map[string]string{"title": ""},
+							},
+						},
+					},
+				},
+			},
+			expYAML: `api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: Monitor
+spec:
+  slug: sloth-slo-alerts-test1-testalertpage
+  name: test page
+  prometheus_query: test-expr-page
+  collection_slug: sloth-slo-svc1
+  interval_secs: 60
+  labels:
+    sloth_severity: page
+  annotations:
+    runbook: https://runbooks.io/svc1/test1
+    summary: test page
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      critical:
+        conditions:
+        - sustain_secs: 60
+          resolve_sustain_secs: 60
+          op: EXISTS
+---
+api_version: v1/config
+kind: Monitor
+spec:
+  slug: sloth-slo-alerts-test1-testalertticket
+  name: testAlertTicket
+  prometheus_query: test-expr-ticket
+  collection_slug: sloth-slo-svc1
+  interval_secs: 60
+  labels:
+    sloth_severity: ticket
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      warn:
+        conditions:
+        - sustain_secs: 60
+          resolve_sustain_secs: 60
+          op: EXISTS
+`,
+		},
+
+		"Having the disclaimer disabled should render the rules without it.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimer: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having the disclaimer version disabled should render the disclaimer without version.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimerVersion: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth: https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having SLOs with team and notification policy labels should set them on the collection.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				TeamSlugLabel:               "team",
+				NotificationPolicySlugLabel: "notification_policy",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2", Service: "svc1", Labels: map[string]string{"team": "team-a", "notification_policy": "policy-a"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test3", Service: "svc1", Labels: map[string]string{"team": "team-a"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+  team_slug: team-a
+  notification_policy_slug: policy-a
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test2-test_record
+  name: sloth-slo-sli-recordings-test2-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test3-test_record
+  name: sloth-slo-sli-recordings-test3-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having SLOs with a bucket slug label should set the bucket on the recording rules independently of the collection.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				BucketSlugLabel: "bucket",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1", Labels: map[string]string{"bucket": "bucket-a"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules: []rulefmt.Rule{
+							{
+								Alert:  "testAlertPage",
+								Expr:   "test-expr-page",
+								Labels: map[string]string{"sloth_severity": "page"},
+							},
+						},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: bucket-a
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test2-test_record
+  name: sloth-slo-sli-recordings-test2-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: Monitor
+spec:
+  slug: sloth-slo-alerts-test1-testalertpage
+  name: testAlertPage
+  prometheus_query: test-expr-page
+  collection_slug: sloth-slo-svc1
+  interval_secs: 60
+  labels:
+    sloth_severity: page
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      critical:
+        conditions:
+        - sustain_secs: 60
+          resolve_sustain_secs: 60
+          op: EXISTS
+`,
+		},
+
+		"Having a metric name transform should transform the recording rules metric names without changing the expressions.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				MetricNameTransform: chronosphere.UnderscoreMetricName,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate5m", Expr: "slo:sli_error:ratio_rate1m"}},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-slo_sli_error_ratio_rate5m
+  name: sloth-slo-sli-recordings-test1-slo_sli_error_ratio_rate5m
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: slo_sli_error_ratio_rate5m
+  prometheus_expr: slo:sli_error:ratio_rate1m
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having extra labels should add them to all the rules, with the SLO ones and the rule labels taking precedence.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				ExtraLabels: map[string]string{"team": "team-a", "tier": "2", "environment": "prod"},
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO:         prometheus.SLO{ID: "test1", Service: "svc1"},
+					ExtraLabels: map[string]string{"tier": "1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1", Labels: map[string]string{"sloth_id": "test1"}}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr3", Labels: map[string]string{"team": "team-b", "sloth_severity": "page"}}},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record1
+  name: sloth-slo-sli-recordings-test1-test_record1
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record1
+  prometheus_expr: test-expr1
+  label_policy:
+    add:
+      environment: prod
+      sloth_id: test1
+      team: team-a
+      tier: "1"
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record2
+  name: sloth-slo-sli-recordings-test1-test_record2
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record2
+  prometheus_expr: test-expr2
+  label_policy:
+    add:
+      environment: prod
+      team: team-a
+      tier: "1"
+---
+api_version: v1/config
+kind: Monitor
+spec:
+  slug: sloth-slo-alerts-test1-testalert
+  name: testAlert
+  prometheus_query: test-expr3
+  collection_slug: sloth-slo-svc1
+  interval_secs: 60
+  labels:
+    environment: prod
+    sloth_severity: page
+    team: team-b
+    tier: "1"
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      critical:
+        conditions:
+        - sustain_secs: 60
+          resolve_sustain_secs: 60
+          op: EXISTS
+`,
+		},
+
+		"Having extra labels with override should replace the rule labels.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				ExtraLabels:         map[string]string{"team": "team-a"},
+				ExtraLabelsOverride: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1", Labels: map[string]string{"team": "team-b"}}},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record1
+  name: sloth-slo-sli-recordings-test1-test_record1
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record1
+  prometheus_expr: test-expr1
+  label_policy:
+    add:
+      team: team-a
+`,
+		},
+
+		"Having SLOs of multiple services grouped by team should share the team collections.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				TeamSlugLabel:      "team",
+				CollectionGrouping: chronosphere.TeamCollectionGrouping,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1", Labels: map[string]string{"team": "team-a"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "svc2-slo1", Service: "svc2", Labels: map[string]string{"team": "team-a"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "svc3-slo1", Service: "svc3", Labels: map[string]string{"team": "team-b"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-team-a
+  name: sloth-slo-team-a
+  description: SLOs generated by Sloth
+  team_slug: team-a
+---
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-team-b
+  name: sloth-slo-team-b
+  description: SLOs generated by Sloth
+  team_slug: team-b
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-svc1-slo1-test_record
+  name: sloth-slo-sli-recordings-svc1-slo1-test_record
+  bucket_slug: sloth-slo-team-a
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-svc2-slo1-test_record
+  name: sloth-slo-sli-recordings-svc2-slo1-test_record
+  bucket_slug: sloth-slo-team-a
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-svc3-slo1-test_record
+  name: sloth-slo-sli-recordings-svc3-slo1-test_record
+  bucket_slug: sloth-slo-team-b
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having SLOs grouped by a custom label should create a collection for each label value.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				CollectionGrouping:      chronosphere.LabelCollectionGrouping,
+				CollectionGroupingLabel: "domain",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1", Labels: map[string]string{"domain": "Payments"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "svc2-slo1", Service: "svc2", Labels: map[string]string{"domain": "Payments"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-payments
+  name: sloth-slo-Payments
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-svc1-slo1-test_record
+  name: sloth-slo-sli-recordings-svc1-slo1-test_record
+  bucket_slug: sloth-slo-payments
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-svc2-slo1-test_record
+  name: sloth-slo-sli-recordings-svc2-slo1-test_record
+  bucket_slug: sloth-slo-payments
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having SLOs grouped by team without the team label should fail.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				TeamSlugLabel:      "team",
+				CollectionGrouping: chronosphere.TeamCollectionGrouping,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs of the same service with different teams should fail.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				TeamSlugLabel: "team",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1", Labels: map[string]string{"team": "team-a"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2", Service: "svc1", Labels: map[string]string{"team": "team-b"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs with the well-known notification policy label should set it on the collection.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimer: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1", Labels: map[string]string{"sloth_chronosphere_notification_policy": "policy-a"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+  notification_policy_slug: policy-a
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having a custom collection description template should render it with the SLO data.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimer:             true,
+				CollectionDescriptionTemplate: "SLOs of {{ .Service }} ({{ .Labels.owner }})",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1", Labels: map[string]string{"owner": "team-a"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs of svc1 (team-a)
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having a collection description template with a missing SLO label should fail.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				CollectionDescriptionTemplate: "SLOs of {{ .Labels.owner }}",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1", Labels: map[string]string{}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having recording rules that collide on the same slug should disambiguate them.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimer: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{Record: "test:Record", Expr: "test-expr1"},
+							{Record: "test_record", Expr: "test-expr2"},
+						},
+					},
+				},
+			},
+			expYAML: `api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:Record
+  prometheus_expr: test-expr1
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record-2
+  name: sloth-slo-sli-recordings-test1-test_record-2
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test_record
+  prometheus_expr: test-expr2
+  label_policy:
+    add: {}
+`,
+		},
+
+		"Having a custom slug prefix should use it on all the slugs.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimer: true,
+				SlugPrefix:        "team-a-slo",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules: []rulefmt.Rule{
+							{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}},
+						},
+					},
+				},
+			},
+			expYAML: `api_version: v1/config
+kind: Collection
+spec:
+  slug: team-a-slo-svc1
+  name: team-a-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: team-a-slo-sli-recordings-test1-test_record
+  name: team-a-slo-sli-recordings-test1-test_record
+  bucket_slug: team-a-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v1/config
+kind: Monitor
+spec:
+  slug: team-a-slo-alerts-test1-testalert
+  name: testAlert
+  prometheus_query: test-expr
+  collection_slug: team-a-slo-svc1
+  interval_secs: 60
+  labels:
+    sloth_severity: page
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      critical:
+        conditions:
+        - sustain_secs: 60
+          resolve_sustain_secs: 60
+          op: EXISTS
+`,
+		},
+
+		"Having recording rule labels with invalid names and empty values should sanitize and drop them.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+								Labels: map[string]string{"k8s.namespace": "default", "test_empty": ""},
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+api_version: v1/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v1/config
+kind: RecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add:
+      k8s_namespace: default
+`,
+		},
+
+		"Having recording rule labels with empty values in strict mode should fail.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				StrictLabels: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						MetadataRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+								Labels: map[string]string{"test_empty": ""},
+							},
+						},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having rule labels with empty values in strict mode should fail.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				Strict: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+								Labels: map[string]string{"team": ""},
+							},
+						},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having alert annotations with unresolved template values in strict mode should fail.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				Strict: true,
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						AlertRules: []rulefmt.Rule{
+							{
+								Alert:       "testAlert",
+								Expr:        "test-expr",
+								Labels:      map[string]string{"severity": "page"},
+								Annotations: map[string]string{"runbook": "https://runbooks/<no value>"},
+							},
+						},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having recording rule labels that collide once sanitized should fail.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+								Labels: map[string]string{"k8s.namespace": "a", "k8s_namespace": "b"},
+							},
+						},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having recording rule labels that can't be sanitized should fail.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+								Labels: map[string]string{"8s": "a"},
+							},
+						},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having a custom api version and kinds should use them on all the objects.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimer: true,
+				APIVersion:        "v2/config",
+				RecordingRuleKind: "CustomRecordingRule",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules: []rulefmt.Rule{
+							{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}},
+						},
+					},
+				},
+			},
+			expYAML: `api_version: v2/config
+kind: Collection
+spec:
+  slug: sloth-slo-svc1
+  name: sloth-slo-svc1
+  description: SLOs generated by Sloth
+---
+api_version: v2/config
+kind: CustomRecordingRule
+spec:
+  slug: sloth-slo-sli-recordings-test1-test_record
+  name: sloth-slo-sli-recordings-test1-test_record
+  bucket_slug: sloth-slo-svc1
+  interval_secs: 60
+  metric_name: test:record
+  prometheus_expr: test-expr
+  label_policy:
+    add: {}
+---
+api_version: v2/config
+kind: Monitor
+spec:
+  slug: sloth-slo-alerts-test1-testalert
+  name: testAlert
+  prometheus_query: test-expr
+  collection_slug: sloth-slo-svc1
+  interval_secs: 60
+  labels:
+    sloth_severity: page
+  notification_policy_slug: ""
+  series_conditions:
+    defaults:
+      critical:
+        conditions:
+        - sustain_secs: 60
+          resolve_sustain_secs: 60
+          op: EXISTS
+`,
+		},
+
+		"Having an SLO with an invalid interval should fail.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1", Service: "svc1"},
+					Interval: 500 * time.Millisecond,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotYAML bytes.Buffer
+			test.config.Writer = &gotYAML
+			test.config.Logger = log.Noop
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expYAML, gotYAML.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreDeterministic(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []chronosphere.StorageSLO{}
+	for _, svc := range []string{"svc-c", "svc-a", "svc-d", "svc-b"} {
+		slos = append(slos, chronosphere.StorageSLO{
+			SLO: prometheus.SLO{ID: svc + "-slo", Service: svc},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "test:record-b", Expr: "test-expr"},
+					{Record: "test:record-a", Expr: "test-expr"},
+				},
+			},
+		})
+	}
+
+	store := func() string {
+		var gotYAML bytes.Buffer
+		repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+			Writer: &gotYAML,
+			Logger: log.Noop,
+		})
+		require.NoError(err)
+		err = repo.StoreSLOs(context.TODO(), slos)
+		require.NoError(err)
+		return gotYAML.String()
+	}
+
+	// Maps iteration order is random, execute multiple times to be sure.
+	exp := store()
+	for i := 0; i < 20; i++ {
+		assert.Equal(exp, store())
+	}
+
+	// Check the order of the collections and the rules.
+	gotSlugs := regexp.MustCompile(`(?m)^  slug: (.*)$`).FindAllStringSubmatch(exp, -1)
+	expSlugs := []string{
+		"sloth-slo-svc-a",
+		"sloth-slo-svc-b",
+		"sloth-slo-svc-c",
+		"sloth-slo-svc-d",
+		"sloth-slo-sli-recordings-svc-a-slo-test_record-a",
+		"sloth-slo-sli-recordings-svc-a-slo-test_record-b",
+		"sloth-slo-sli-recordings-svc-b-slo-test_record-a",
+		"sloth-slo-sli-recordings-svc-b-slo-test_record-b",
+		"sloth-slo-sli-recordings-svc-c-slo-test_record-a",
+		"sloth-slo-sli-recordings-svc-c-slo-test_record-b",
+		"sloth-slo-sli-recordings-svc-d-slo-test_record-a",
+		"sloth-slo-sli-recordings-svc-d-slo-test_record-b",
+	}
+	require.Len(gotSlugs, len(expSlugs))
+	for i, s := range expSlugs {
+		assert.Equal(s, gotSlugs[i][1])
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreClampedIntervals(t *testing.T) {
+	tests := map[string]struct {
+		interval        time.Duration
+		rateWindow      bool
+		expIntervalSecs []string
+	}{
+		"Having an interval below the floor should be clamped to the floor.": {
+			interval:        10 * time.Second,
+			expIntervalSecs: []string{"45", "45", "45"},
+		},
+
+		"Having an interval in the range should not be changed.": {
+			interval:        90 * time.Second,
+			expIntervalSecs: []string{"90", "90", "90"},
+		},
+
+		"Having an interval above the ceiling should be clamped to the ceiling.": {
+			interval:        10 * time.Minute,
+			expIntervalSecs: []string{"120", "120", "120"},
+		},
+
+		"Having rate window intervals out of the range should be clamped.": {
+			interval:        90 * time.Second,
+			rateWindow:      true,
+			expIntervalSecs: []string{"45", "120", "90"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			config := chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				MinInterval: 45 * time.Second,
+				MaxInterval: 2 * time.Minute,
+			}
+			if test.rateWindow {
+				config.RateWindowInterval = chronosphere.RateWindowInterval
+			}
+
+			var gotYAML bytes.Buffer
+			config.Writer = &gotYAML
+			config.Logger = log.Noop
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), []chronosphere.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1", Service: "svc1"},
+					Interval: test.interval,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr"},
+							{Record: "slo:sli_error:ratio_rate6h", Expr: "test-expr"},
+						},
+						AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"severity": "critical"}}},
+					},
+				},
+			})
+			require.NoError(err)
+
+			// The recording rules (sorted by slug) go before the monitors.
+			got := []string{}
+			for _, m := range regexp.MustCompile(`(?m)^  interval_secs: (.*)$`).FindAllStringSubmatch(gotYAML.String(), -1) {
+				got = append(got, m[1])
+			}
+			assert.Equal(test.expIntervalSecs, got)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreKindIntervals(t *testing.T) {
+	tests := map[string]struct {
+		config          chronosphere.IOWriterGroupedRulesYAMLRepoConfig
+		interval        time.Duration
+		expIntervalSecs []string
+	}{
+		"Without kind intervals all the rules should use the default interval.": {
+			config:          chronosphere.IOWriterGroupedRulesYAMLRepoConfig{DefaultInterval: 2 * time.Minute},
+			expIntervalSecs: []string{"120", "120", "120"},
+		},
+
+		"Having kind intervals should use them on their rule kind.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				DefaultInterval: 2 * time.Minute,
+				KindIntervals: chronosphere.KindIntervals{
+					SLIRecordings:      30 * time.Second,
+					MetadataRecordings: 5 * time.Minute,
+				},
+			},
+			expIntervalSecs: []string{"300", "30", "120"},
+		},
+
+		"Having kind intervals with SLO intervals should use the SLO ones.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				KindIntervals: chronosphere.KindIntervals{
+					SLIRecordings:      30 * time.Second,
+					MetadataRecordings: 5 * time.Minute,
+					Monitors:           time.Minute,
+				},
+			},
+			interval:        90 * time.Second,
+			expIntervalSecs: []string{"90", "90", "90"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var gotYAML bytes.Buffer
+			test.config.Writer = &gotYAML
+			test.config.Logger = log.Noop
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), []chronosphere.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1", Service: "svc1"},
+					Interval: test.interval,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr"}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "slo:objective:ratio", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"severity": "critical"}}},
+					},
+				},
+			})
+			require.NoError(err)
+
+			// The recording rules (sorted by slug, metadata first) go before the monitors.
+			got := []string{}
+			for _, m := range regexp.MustCompile(`(?m)^  interval_secs: (.*)$`).FindAllStringSubmatch(gotYAML.String(), -1) {
+				got = append(got, m[1])
+			}
+			assert.Equal(test.expIntervalSecs, got)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreRecordingRuleSlugTemplate(t *testing.T) {
+	slos := []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr"}},
+				MetadataRecRules: []rulefmt.Rule{{Record: "slo:objective:ratio", Expr: "vector(0.99)"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		template string
+		expSlugs []string
+		expErr   bool
+	}{
+		"Without template the default slugs should be used.": {
+			expSlugs: []string{
+				"sloth-slo-sli-recordings-svc1-slo1-slo_objective_ratio",
+				"sloth-slo-sli-recordings-svc1-slo1-slo_sli_error_ratio_rate5m",
+			},
+		},
+
+		"A custom template should render the slugs with the rule data.": {
+			template: `team-a-{{ .Service }}-{{ .Kind }}-{{ .Record | printf "%.15s" }}`,
+			expSlugs: []string{
+				"team-a-svc1-metadata-slo_objective_r",
+				"team-a-svc1-sli-slo_sli_error_r",
+			},
+		},
+
+		"A template that renders an invalid slug should fail.": {
+			template: `-{{ .SLOID }}-{{ .Record }}`,
+			expErr:   true,
+		},
+
+		"A template that renders an empty slug should fail.": {
+			template: `{{ if eq .Kind "sli" }}{{ .Record }}{{ end }}`,
+			expErr:   true,
+		},
+
+		"A template with missing data should fail.": {
+			template: `{{ .Team }}-{{ .Record }}`,
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var gotYAML bytes.Buffer
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:                    &gotYAML,
+				Logger:                    log.Noop,
+				RecordingRuleSlugTemplate: test.template,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			// Skip the collection slug.
+			got := []string{}
+			for _, m := range regexp.MustCompile(`(?m)^  slug: (.*)$`).FindAllStringSubmatch(gotYAML.String(), -1)[1:] {
+				got = append(got, m[1])
+			}
+			assert.Equal(test.expSlugs, got)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidRecordingRuleSlugTemplate(t *testing.T) {
+	_, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:                    &bytes.Buffer{},
+		RecordingRuleSlugTemplate: "{{ .Record",
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidIntervalsRange(t *testing.T) {
+	tests := map[string]chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		"Negative min interval should fail.":             {MinInterval: -1 * time.Second},
+		"Max interval below 1s should fail.":             {MaxInterval: 500 * time.Millisecond},
+		"Min interval greater than the max should fail.": {MinInterval: 2 * time.Minute, MaxInterval: time.Minute},
+		"Kind interval below 1s should fail.":            {KindIntervals: chronosphere.KindIntervals{Monitors: 500 * time.Millisecond}},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config.Writer = &bytes.Buffer{}
+			_, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreResult(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "test:record1", Expr: "test-expr"},
+					{Record: "test:record2", Expr: "test-expr"},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "test:record3", Expr: "test-expr"},
+				},
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlert1", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}},
+				},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlert2", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}},
+					{Alert: "testAlert3", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "ticket"}},
+				},
+			},
+		},
+	}
+
+	var gotYAML bytes.Buffer
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &gotYAML,
+		Logger: log.Noop,
+	})
+	require.NoError(err)
+
+	gotRes, err := repo.StoreSLOsResult(context.TODO(), slos)
+	require.NoError(err)
+
+	expRes := &chronosphere.StoreResult{
+		Collections:    2,
+		RecordingRules: 3,
+		Monitors:       3,
+		BytesWritten:   gotYAML.Len(),
+	}
+	assert.Equal(expRes, gotRes)
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, fmt.Errorf("should not write") }
+
+func TestIOWriterGroupedRulesYAMLRepoValidate(t *testing.T) {
+	tests := map[string]struct {
+		config chronosphere.IOWriterGroupedRulesYAMLRepoConfig
+		slos   []chronosphere.StorageSLO
+		expRes *chronosphere.StoreResult
+		expErr error
+	}{
+		"Having 0 SLO rules should fail.": {
+			slos:   []chronosphere.StorageSLO{},
+			expErr: fmt.Errorf("slo rules required"),
+		},
+
+		"Having 0 SLO rules generated should fail.": {
+			slos:   []chronosphere.StorageSLO{{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}}},
+			expErr: chronosphere.ErrNoSLORules,
+		},
+
+		"Having valid SLO rules should return the result without writing.": {
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}}},
+					},
+				},
+			},
+			expRes: &chronosphere.StoreResult{Collections: 1, RecordingRules: 1, Monitors: 1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			test.config.Writer = failWriter{}
+			test.config.Logger = log.Noop
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+
+			gotRes, err := repo.ValidateSLOs(context.TODO(), test.slos)
+
+			if test.expErr != nil {
+				assert.Equal(test.expErr, err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expRes, gotRes)
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidSlugPrefix(t *testing.T) {
+	_, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:     &bytes.Buffer{},
+		SlugPrefix: "Team:A",
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidCollectionGrouping(t *testing.T) {
+	tests := map[string]chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		"Unknown grouping should fail.":                 {CollectionGrouping: "something"},
+		"Team grouping without team label should fail.": {CollectionGrouping: chronosphere.TeamCollectionGrouping},
+		"Label grouping without label should fail.":     {CollectionGrouping: chronosphere.LabelCollectionGrouping},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config.Writer = &bytes.Buffer{}
+			_, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidAPI(t *testing.T) {
+	tests := map[string]chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		"Invalid api version should fail.": {APIVersion: " "},
+		"Invalid kind should fail.":        {MonitorKind: "monitor kind"},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config.Writer = &bytes.Buffer{}
+			_, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func BenchmarkIOWriterGroupedRulesYAMLRepoStore(b *testing.B) {
+	slos := []chronosphere.StorageSLO{}
+	for i := 0; i < 500; i++ {
+		slos = append(slos, chronosphere.StorageSLO{
+			SLO: prometheus.SLO{ID: fmt.Sprintf("slo-%d", i), Service: fmt.Sprintf("svc-%d", i%10)},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "slo:sli_error:ratio_rate5m", Expr: "sum(rate(errors[5m])) / sum(rate(total[5m]))", Labels: map[string]string{"sloth_id": "test"}},
+					{Record: "slo:sli_error:ratio_rate1h", Expr: "sum(rate(errors[1h])) / sum(rate(total[1h]))", Labels: map[string]string{"sloth_id": "test"}},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "slo:objective:ratio", Expr: "vector(0.99)", Labels: map[string]string{"sloth_id": "test"}},
+				},
+				AlertRules: []rulefmt.Rule{
+					{Alert: "SLOErrorBudgetBurn", Expr: "slo:sli_error:ratio_rate5m > 0.1", Labels: map[string]string{"sloth_severity": "page"}},
+				},
+			},
+		})
+	}
+
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: io.Discard,
+		Logger: log.Noop,
+	})
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := repo.StoreSLOs(context.TODO(), slos)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type fakeSyncWriter struct {
+	bytes.Buffer
+	syncs   int
+	syncErr error
+}
+
+func (f *fakeSyncWriter) Sync() error {
+	f.syncs++
+	return f.syncErr
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSync(t *testing.T) {
+	slos := []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		sync     bool
+		syncErr  error
+		expSyncs int
+		expErr   bool
+	}{
+		"Without sync enabled, the writer should not be synced.": {
+			sync:     false,
+			expSyncs: 0,
+		},
+
+		"With sync enabled, the writer should be synced once after writing.": {
+			sync:     true,
+			expSyncs: 1,
+		},
+
+		"With sync enabled, a sync error should fail.": {
+			sync:     true,
+			syncErr:  fmt.Errorf("something"),
+			expSyncs: 1,
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			w := &fakeSyncWriter{syncErr: test.syncErr}
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer: w,
+				Logger: log.Noop,
+				Sync:   test.sync,
+			})
+			require.NoError(err)
+
+			res, err := repo.StoreSLOsResult(context.TODO(), slos)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(w.Len(), res.BytesWritten)
+			}
+			assert.Equal(test.expSyncs, w.syncs)
+		})
+	}
+
+	// Writers that can't be synced should not be affected.
+	var b bytes.Buffer
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &b,
+		Logger: log.Noop,
+		Sync:   true,
+	})
+	require.NoError(t, err)
+	res, err := repo.StoreSLOsResult(context.TODO(), slos)
+	require.NoError(t, err)
+	assert.Equal(t, b.Len(), res.BytesWritten)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoDiff(t *testing.T) {
+	slos := []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	// Get the rules that would be stored.
+	var b bytes.Buffer
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{Writer: &b, Logger: log.Noop})
+	require.NoError(t, err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(t, err)
+	expRules := b.String()
+
+	tests := map[string]struct {
+		existing   *string
+		expChanged bool
+		expDiff    *regexp.Regexp
+	}{
+		"A missing file should be handled as all the rules being added.": {
+			existing:   nil,
+			expChanged: true,
+			expDiff:    regexp.MustCompile(`(?m)^--- /dev/null\n[\s\S]*^\+  prometheus_expr: test-expr$`),
+		},
+
+		"A file with the same rules should not have changes.": {
+			existing:   &expRules,
+			expChanged: false,
+			expDiff:    regexp.MustCompile(`^$`),
+		},
+
+		"A file with different rules should return the diff.": {
+			existing: func() *string {
+				s := strings.Replace(expRules, "test-expr", "test-old-expr", 1)
+				return &s
+			}(),
+			expChanged: true,
+			expDiff:    regexp.MustCompile(`(?m)^-  prometheus_expr: test-old-expr\n\+  prometheus_expr: test-expr$`),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			path := filepath.Join(t.TempDir(), "rules.yaml")
+			if test.existing != nil {
+				err := os.WriteFile(path, []byte(*test.existing), 0644)
+				require.NoError(err)
+			}
+
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer: failWriter{},
+				Logger: log.Noop,
+			})
+			require.NoError(err)
+
+			res, err := repo.DiffSLOs(context.TODO(), path, slos)
+			require.NoError(err)
+
+			assert.Equal(test.expChanged, res.Changed)
+			assert.Regexp(test.expDiff, res.Diff)
+			assert.Equal(expRules, string(res.Rules))
+		})
+	}
+}
+
+type warningsLogger struct {
+	log.Logger
+	warnings *[]string
+}
+
+func (w warningsLogger) Warningf(format string, args ...interface{}) {
+	*w.warnings = append(*w.warnings, fmt.Sprintf(format, args...))
+}
+
+func (w warningsLogger) WithValues(map[string]interface{}) log.Logger { return w }
+func (w warningsLogger) WithCtxValues(context.Context) log.Logger     { return w }
+
+type infoLog struct {
+	msg    string
+	values log.Kv
+}
+
+// infoLogger captures the info logs with their values.
+type infoLogger struct {
+	log.Logger
+	values log.Kv
+	logs   *[]infoLog
+}
+
+func (l infoLogger) Infof(format string, args ...interface{}) {
+	*l.logs = append(*l.logs, infoLog{msg: fmt.Sprintf(format, args...), values: l.values})
+}
+
+func (l infoLogger) WithValues(values map[string]interface{}) log.Logger {
+	kv := log.Kv{}
+	for k, v := range l.values {
+		kv[k] = v
+	}
+	for k, v := range values {
+		kv[k] = v
+	}
+	l.values = kv
+	return l
+}
+
+func (l infoLogger) WithCtxValues(context.Context) log.Logger { return l }
+
+func TestIOWriterGroupedRulesYAMLRepoStoreLog(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	logs := []infoLog{}
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &bytes.Buffer{},
+		Logger: infoLogger{Logger: log.Noop, logs: &logs},
+	})
+	require.NoError(err)
+
+	err = repo.StoreSLOs(context.TODO(), []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"severity": "critical"}}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				MetadataRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	})
+	require.NoError(err)
+
+	require.Len(logs, 1)
+	assert.Equal("Chronosphere collections and rules written", logs[0].msg)
+	assert.Equal(2, logs[0].values["collections"])
+	assert.Equal(2, logs[0].values["recording-rules"])
+	assert.Equal(1, logs[0].values["monitors"])
+	assert.Equal([]string{"svc1", "svc2"}, logs[0].values["services"])
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreNotificationPolicyConflict(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var gotYAML bytes.Buffer
+	warnings := []string{}
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:                      &gotYAML,
+		Logger:                      warningsLogger{Logger: log.Noop, warnings: &warnings},
+		NotificationPolicySlugLabel: "notification_policy",
+		DisableDisclaimer:           true,
+	})
+	require.NoError(err)
+
+	err = repo.StoreSLOs(context.TODO(), []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1", Labels: map[string]string{"notification_policy": "policy-a"}},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2", Service: "svc1", Labels: map[string]string{"notification_policy": "policy-b"}},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	})
+	require.NoError(err)
+
+	// The first SLO notification policy should be kept.
+	assert.Contains(gotYAML.String(), "  notification_policy_slug: policy-a\n")
+	assert.NotContains(gotYAML.String(), "policy-b")
+	assert.Equal([]string{`collection "sloth-slo-svc1" has conflicting notification policy slugs: "policy-a" and "policy-b", using "policy-a"`}, warnings)
+}
+
+// cancelOnCheckCtx is a context that is cancelled after the first time its error is checked.
+type cancelOnCheckCtx struct {
+	context.Context
+	cancel func()
+}
+
+func (c cancelOnCheckCtx) Err() error {
+	err := c.Context.Err()
+	c.cancel()
+	return err
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreCancelled(t *testing.T) {
+	slos := []chronosphere.StorageSLO{}
+	for i := 0; i < 250; i++ {
+		slos = append(slos, chronosphere.StorageSLO{
+			SLO: prometheus.SLO{ID: fmt.Sprintf("slo-%d", i), Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr"}},
+			},
+		})
+	}
+
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: failWriter{},
+		Logger: log.Noop,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = repo.StoreSLOs(cancelOnCheckCtx{Context: ctx, cancel: cancel}, slos)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreYAMLDocuments(t *testing.T) {
+	slos := []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		config  chronosphere.IOWriterGroupedRulesYAMLRepoConfig
+		expDocs []string
+	}{
+		"Having the disclaimer should not generate empty documents.": {
+			expDocs: []string{"Collection", "RecordingRule", "Monitor"},
+		},
+
+		"Having the disclaimer disabled should not generate empty documents.": {
+			config:  chronosphere.IOWriterGroupedRulesYAMLRepoConfig{DisableDisclaimer: true},
+			expDocs: []string{"Collection", "RecordingRule", "Monitor"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var out bytes.Buffer
+			test.config.Writer = &out
+			test.config.Logger = log.Noop
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			// Decode all the documents, empty documents would be decoded as nulls.
+			gotDocs := []string{}
+			dec := yaml.NewDecoder(&out)
+			for {
+				var doc *struct {
+					Kind string `yaml:"kind"`
+				}
+				err := dec.Decode(&doc)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(err)
+				if assert.NotNil(doc, "empty YAML document") {
+					gotDocs = append(gotDocs, doc.Kind)
+				}
+			}
+			assert.Equal(test.expDocs, gotDocs)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreMonitorsWriter(t *testing.T) {
+	slos := []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlertPage", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}},
+					{Alert: "testAlertTicket", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "ticket"}},
+				},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	decodeKinds := func(t *testing.T, r io.Reader) []string {
+		kinds := []string{}
+		dec := yaml.NewDecoder(r)
+		for {
+			var doc struct {
+				Kind string `yaml:"kind"`
+			}
+			err := dec.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			kinds = append(kinds, doc.Kind)
+		}
+		return kinds
+	}
+
+	tests := map[string]struct {
+		monitorsWriter bool
+		expDocs        []string
+		expMonitorDocs []string
+	}{
+		"Without monitors writer all the objects should be written on the writer.": {
+			expDocs: []string{"Collection", "Collection", "RecordingRule", "RecordingRule", "Monitor", "Monitor"},
+		},
+
+		"With monitors writer the monitors should be written only on the monitors writer.": {
+			monitorsWriter: true,
+			expDocs:        []string{"Collection", "Collection", "RecordingRule", "RecordingRule"},
+			expMonitorDocs: []string{"Monitor", "Monitor"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var out, monitorsOut bytes.Buffer
+			config := chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer: &out,
+				Logger: log.Noop,
+			}
+			if test.monitorsWriter {
+				config.MonitorsWriter = &monitorsOut
+			}
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(config)
+			require.NoError(err)
+			res, err := repo.StoreSLOsResult(context.TODO(), slos)
+			require.NoError(err)
+
+			assert.Equal(out.Len()+monitorsOut.Len(), res.BytesWritten)
+			assert.Equal(test.expDocs, decodeKinds(t, &out))
+			if test.monitorsWriter {
+				assert.Equal(test.expMonitorDocs, decodeKinds(t, &monitorsOut))
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreMonitorsWriterHCL(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var out, monitorsOut bytes.Buffer
+	repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:            &out,
+		MonitorsWriter:    &monitorsOut,
+		Logger:            log.Noop,
+		Format:            chronosphere.HCLFormat,
+		DisableDisclaimer: true,
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), []chronosphere.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}}},
+			},
+		},
+	})
+	require.NoError(err)
+
+	assert.Contains(out.String(), `resource "chronosphere_collection"`)
+	assert.Contains(out.String(), `resource "chronosphere_recording_rule"`)
+	assert.NotContains(out.String(), `resource "chronosphere_monitor"`)
+	assert.NotContains(monitorsOut.String(), `resource "chronosphere_collection"`)
+	assert.NotContains(monitorsOut.String(), `resource "chronosphere_recording_rule"`)
+	assert.Contains(monitorsOut.String(), `resource "chronosphere_monitor"`)
+	checkHCLSyntax(t, out.String())
+	checkHCLSyntax(t, monitorsOut.String())
+	// The collections are in another output, so they are referenced by slug.
+	assert.Regexp(`collection_id += "sloth-slo-svc1"`, monitorsOut.String())
+}
+
+func TestIOWriterGroupedRulesYAMLRepoSerializer(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slo := prometheus.StorageSLO{
+		SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+		Rules: prometheus.SLORules{
+			SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}}},
+		},
+	}
+
+	var expYAML bytes.Buffer
+	chronoRepo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &expYAML,
+		Logger: log.Noop,
+	})
+	require.NoError(err)
+	err = chronoRepo.StoreSLOs(context.TODO(), []chronosphere.StorageSLO{{SLO: slo.SLO, Rules: slo.Rules}})
+	require.NoError(err)
+
+	// Store the Chronosphere objects through the Prometheus repository.
+	var gotYAML bytes.Buffer
+	promRepo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:     &gotYAML,
+		Serializer: chronoRepo,
+	})
+	require.NoError(err)
+	res, err := promRepo.StoreSLOsResult(context.TODO(), []prometheus.StorageSLO{slo})
+	require.NoError(err)
+
+	assert.Equal(expYAML.String(), gotYAML.String())
+	assert.Equal(1, res.Groups)
+
+	// Without Chronosphere objects it should be handled as no rules.
+	_, err = promRepo.StoreSLOsResult(context.TODO(), []prometheus.StorageSLO{{SLO: slo.SLO}})
+	assert.ErrorIs(err, prometheus.ErrNoSLORules)
+
+	// The Chronosphere flavor is registered with the default configuration.
+	var flavorYAML bytes.Buffer
+	flavorRepo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &flavorYAML,
+		Flavor: chronosphere.Flavor,
+	})
+	require.NoError(err)
+	err = flavorRepo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{slo})
+	require.NoError(err)
+	assert.Equal(expYAML.String(), flavorYAML.String())
+}
+
+// checkHCLSyntax parses the generated HCL and checks the resources blocks.
+func checkHCLSyntax(t *testing.T, hcl string) {
+	t.Helper()
+
+	file, diags := hclsyntax.ParseConfig([]byte(hcl), "test.tf", hclv2.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	resourceTypes := map[string]bool{"chronosphere_collection": true, "chronosphere_recording_rule": true, "chronosphere_monitor": true}
+	nameRegexp := regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+		assert.Equal(t, "resource", b.Type)
+		require.Len(t, b.Labels, 2)
+		assert.True(t, resourceTypes[b.Labels[0]], b.Labels[0])
+		assert.Regexp(t, nameRegexp, b.Labels[1])
+	}
+}
+
+func TestParseIntervalSecs(t *testing.T) {
+	tests := map[string]struct {
+		interval string
+		expSecs  int
+		expErr   bool
+	}{
+		"Seconds should be parsed.": {
+			interval: "30s",
+			expSecs:  30,
+		},
+
+		"Minutes should be parsed.": {
+			interval: "5m",
+			expSecs:  300,
+		},
+
+		"Whole seconds in milliseconds should be parsed.": {
+			interval: "2000ms",
+			expSecs:  2,
+		},
+
+		"Sub-second intervals should fail.": {
+			interval: "500ms",
+			expErr:   true,
+		},
+
+		"Intervals that are not whole seconds should fail.": {
+			interval: "1500ms",
+			expErr:   true,
+		},
+
+		"Invalid durations should fail.": {
+			interval: "5 minutes",
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotSecs, err := chronosphere.ParseIntervalSecs(test.interval)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expSecs, gotSecs)
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreHCL(t *testing.T) {
+	tests := map[string]struct {
+		config chronosphere.IOWriterGroupedRulesYAMLRepoConfig
+		slos   []chronosphere.StorageSLO
+		expHCL string
+	}{
+		"Having SLOs should render the Chronosphere Terraform resources.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{TeamSlugLabel: "team"},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:      "svc1-slo1",
+						Service: "svc1",
+						Labels: map[string]string{
+							"team":                                   "team-a",
+							"sloth_chronosphere_notification_policy": "policy-a",
+						},
+					},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "slo:sli_error:ratio_rate5m",
+								Expr:   `sum(rate(http_requests_total{code=~"5.."}[5m])) / sum(rate(http_requests_total[5m]))`,
+								Labels: map[string]string{"sloth_id": "svc1-slo1"},
+							},
+						},
+						MetadataRecRules: []rulefmt.Rule{
+							{Record: "slo:objective:ratio", Expr: "vector(0.999)"},
+						},
+						AlertRules: []rulefmt.Rule{
+							{
+								Alert:  "Slo1Page",
+								Expr:   "slo:sli_error:ratio_rate5m > 0.0144",
+								Labels: map[string]string{"sloth_severity": "page", "sloth_id": "svc1-slo1"},
+								Annotations: map[string]string{
+									"summary": "High error rate on ${svc}",
+									"title":   "{{$labels.sloth_id}} burning",
+								},
+							},
+						},
+					},
+				},
+			},
+			expHCL: `resource "chronosphere_collection" "sloth_slo_svc1" {
+  slug                   = "sloth-slo-svc1"
+  name                   = "sloth-slo-svc1"
+  description            = "SLOs generated by Sloth"
+  team_id                = "team-a"
+  notification_policy_id = "policy-a"
+}
+
+resource "chronosphere_recording_rule" "sloth_slo_sli_recordings_svc1_slo1_slo_objective_ratio" {
+  slug        = "sloth-slo-sli-recordings-svc1-slo1-slo_objective_ratio"
+  name        = "sloth-slo-sli-recordings-svc1-slo1-slo_objective_ratio"
+  bucket_id   = "sloth-slo-svc1"
+  interval    = "60s"
+  metric_name = "slo:objective:ratio"
+  expr        = "vector(0.999)"
+  label_policy {
+    add = {}
+  }
+}
+
+resource "chronosphere_recording_rule" "sloth_slo_sli_recordings_svc1_slo1_slo_sli_error_ratio_rate5m" {
+  slug        = "sloth-slo-sli-recordings-svc1-slo1-slo_sli_error_ratio_rate5m"
+  name        = "sloth-slo-sli-recordings-svc1-slo1-slo_sli_error_ratio_rate5m"
+  bucket_id   = "sloth-slo-svc1"
+  interval    = "60s"
+  metric_name = "slo:sli_error:ratio_rate5m"
+  expr        = "sum(rate(http_requests_total{code=~\"5..\"}[5m])) / sum(rate(http_requests_total[5m]))"
+  label_policy {
+    add = {
+      "sloth_id" = "svc1-slo1"
+    }
+  }
+}
+
+resource "chronosphere_monitor" "sloth_slo_alerts_svc1_slo1_slo1page" {
+  slug          = "sloth-slo-alerts-svc1-slo1-slo1page"
+  name          = "High error rate on $${svc}"
+  collection_id = chronosphere_collection.sloth_slo_svc1.id
+  interval      = "60s"
+  labels        = {
+    "sloth_id"       = "svc1-slo1"
+    "sloth_severity" = "page"
+  }
+  annotations   = {
+    "summary" = "High error rate on $${svc}"
+    "title"   = "{{$labels.sloth_id}} burning"
+  }
+  query {
+    prometheus_expr = "slo:sli_error:ratio_rate5m > 0.0144"
+  }
+  series_conditions {
+    defaults {
+      critical {
+        conditions {
+          value           = 0
+          op              = "EXISTS"
+          sustain         = "60s"
+          resolve_sustain = "60s"
+        }
+      }
+    }
+  }
+}
+`,
+		},
+
+		"Having slugs that aren't valid HCL identifiers should use valid resource names.": {
+			config: chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+				RecordingRuleSlugTemplate: "{{ .Record }}",
+			},
+			slos: []chronosphere.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{Record: "5xx:ratio", Expr: "test-expr"},
+						},
+					},
+				},
+			},
+			expHCL: `resource "chronosphere_collection" "sloth_slo_svc1" {
+  slug        = "sloth-slo-svc1"
+  name        = "sloth-slo-svc1"
+  description = "SLOs generated by Sloth"
+}
+
+resource "chronosphere_recording_rule" "_5xx_ratio" {
+  slug        = "5xx_ratio"
+  name        = "5xx_ratio"
+  bucket_id   = "sloth-slo-svc1"
+  interval    = "60s"
+  metric_name = "5xx:ratio"
+  expr        = "test-expr"
+  label_policy {
+    add = {}
+  }
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var gotHCL bytes.Buffer
+			test.config.Writer = &gotHCL
+			test.config.Logger = log.Noop
+			test.config.Format = chronosphere.HCLFormat
+			test.config.DisableDisclaimer = true
+			repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+			require.NoError(err)
+
+			assert.Equal(test.expHCL, gotHCL.String())
+			checkHCLSyntax(t, gotHCL.String())
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidFormat(t *testing.T) {
+	_, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &bytes.Buffer{},
+		Format: "json",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,286 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/slok/sloth/internal/alert"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLOs will be used when there are no SLOs to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLOs = fmt.Errorf("0 Datadog SLOs generated")
+)
+
+const (
+	defaultGoodEventsQueryLabel  = "datadog_good_events_query"
+	defaultTotalEventsQueryLabel = "datadog_total_events_query"
+
+	sloTypeMetric      = "metric"
+	monitorTypeQuery   = "query alert"
+	slothIDTag         = "sloth_id"
+	slothServiceTag    = "sloth_service"
+	slothSeverityTag   = "sloth_severity"
+	defaultMonitorText = "%s SLO error budget is burning too fast."
+)
+
+// timeframes are the SLO time windows Datadog supports.
+var timeframes = map[time.Duration]string{
+	7 * 24 * time.Hour:  "7d",
+	30 * 24 * time.Hour: "30d",
+	90 * 24 * time.Hour: "90d",
+}
+
+type IOWriterJSONRepoConfig struct {
+	Writer io.Writer
+	Logger log.Logger
+	// GoodEventsQueryLabel is the SLO label that has the Datadog metric query of the good
+	// events (SLO numerator), by default `datadog_good_events_query`.
+	GoodEventsQueryLabel string
+	// TotalEventsQueryLabel is the SLO label that has the Datadog metric query of the total
+	// events (SLO denominator), by default `datadog_total_events_query`.
+	TotalEventsQueryLabel string
+}
+
+func (c *IOWriterJSONRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.GoodEventsQueryLabel == "" {
+		c.GoodEventsQueryLabel = defaultGoodEventsQueryLabel
+	}
+
+	if c.TotalEventsQueryLabel == "" {
+		c.TotalEventsQueryLabel = defaultTotalEventsQueryLabel
+	}
+
+	if c.GoodEventsQueryLabel == c.TotalEventsQueryLabel {
+		return fmt.Errorf("good and total events query labels can't be the same")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "json", "flavor": "datadog"})
+
+	return nil
+}
+
+// IOWriterJSONRepo knows to store the SLOs in an IOWriter as a JSON document with the Datadog
+// API SLO and monitor definitions. Datadog doesn't use PromQL, so the Datadog metric queries
+// are taken from the SLO labels instead of the SLI and the generated Prometheus rules.
+type IOWriterJSONRepo struct {
+	writer              io.Writer
+	goodEventsQueryLbl  string
+	totalEventsQueryLbl string
+	logger              log.Logger
+}
+
+func NewIOWriterJSONRepo(config IOWriterJSONRepoConfig) (*IOWriterJSONRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &IOWriterJSONRepo{
+		writer:              config.Writer,
+		goodEventsQueryLbl:  config.GoodEventsQueryLabel,
+		totalEventsQueryLbl: config.TotalEventsQueryLabel,
+		logger:              config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO prometheus.SLO
+	// Alerts are the SLO alerts that will be used to create the Datadog monitors.
+	Alerts alert.MWMBAlertGroup
+}
+
+// StoreSLOs will store the SLOs as Datadog SLOs and monitors API definitions.
+func (i IOWriterJSONRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	if len(slos) == 0 {
+		return ErrNoSLOs
+	}
+
+	doc := datadogJSON{
+		SLOs:     []sloJSON{},
+		Monitors: []monitorJSON{},
+	}
+	for _, slo := range slos {
+		s, err := i.mapModelToSLO(slo.SLO)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to Datadog: %w", slo.SLO.ID, err)
+		}
+		doc.SLOs = append(doc.SLOs, *s)
+		doc.Monitors = append(doc.Monitors, mapModelToMonitors(slo, s.Query, s.Tags)...)
+	}
+
+	// Don't escape the HTML characters, the queries have comparison operators.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("could not format SLOs: %w", err)
+	}
+
+	_, err = i.writer.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write SLOs: %w", err)
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{"slos": len(doc.SLOs), "monitors": len(doc.Monitors)}).Infof("Datadog SLOs written")
+
+	return nil
+}
+
+func (i IOWriterJSONRepo) mapModelToSLO(slo prometheus.SLO) (*sloJSON, error) {
+	good := slo.Labels[i.goodEventsQueryLbl]
+	if good == "" {
+		return nil, fmt.Errorf("missing Datadog good events query, %q label is required", i.goodEventsQueryLbl)
+	}
+
+	total := slo.Labels[i.totalEventsQueryLbl]
+	if total == "" {
+		return nil, fmt.Errorf("missing Datadog total events query, %q label is required", i.totalEventsQueryLbl)
+	}
+
+	timeframe, ok := timeframes[slo.TimeWindow]
+	if !ok {
+		return nil, fmt.Errorf("unsupported %s time window, Datadog only supports 7d, 30d and 90d", slo.TimeWindow)
+	}
+
+	// The queries are not tags.
+	tags := []string{
+		fmt.Sprintf("%s:%s", slothIDTag, slo.ID),
+		fmt.Sprintf("%s:%s", slothServiceTag, slo.Service),
+	}
+	for k, v := range slo.Labels {
+		if k == i.goodEventsQueryLbl || k == i.totalEventsQueryLbl {
+			continue
+		}
+		tags = append(tags, fmt.Sprintf("%s:%s", k, v))
+	}
+	sort.Strings(tags[2:])
+
+	return &sloJSON{
+		Name:        slo.Name,
+		Description: slo.Description,
+		Type:        sloTypeMetric,
+		Query:       sloQueryJSON{Numerator: good, Denominator: total},
+		Thresholds: []sloThresholdJSON{
+			{Timeframe: timeframe, Target: slo.Objective},
+		},
+		Tags: tags,
+	}, nil
+}
+
+// mapModelToMonitors maps the page and ticket alerts of the SLO to Datadog metric monitors
+// on the SLO error ratio, using the long window and burn rate of the quick alerts.
+func mapModelToMonitors(slo StorageSLO, query sloQueryJSON, tags []string) []monitorJSON {
+	monitors := []monitorJSON{}
+	alerts := []struct {
+		meta  prometheus.AlertMeta
+		alert alert.MWMBAlert
+	}{
+		{meta: slo.SLO.PageAlertMeta, alert: slo.Alerts.PageQuick},
+		{meta: slo.SLO.TicketAlertMeta, alert: slo.Alerts.TicketQuick},
+	}
+	for _, a := range alerts {
+		if a.meta.Disable {
+			continue
+		}
+
+		// Round to remove float multiplication artifacts.
+		threshold := math.Round(a.alert.BurnRateFactor*a.alert.ErrorBudget/100*1e10) / 1e10
+		message := a.meta.Annotations["summary"]
+		if message == "" {
+			message = fmt.Sprintf(defaultMonitorText, slo.SLO.Name)
+		}
+
+		monitorTags := append([]string{}, tags...)
+		monitorTags = append(monitorTags, fmt.Sprintf("%s:%s", slothSeverityTag, a.alert.Severity))
+
+		monitors = append(monitors, monitorJSON{
+			Name: a.meta.Name,
+			Type: monitorTypeQuery,
+			Query: fmt.Sprintf("sum(last_%s):1 - (%s) / (%s) > %v",
+				durationToDatadog(a.alert.LongWindow), query.Numerator, query.Denominator, threshold),
+			Message: message,
+			Tags:    monitorTags,
+			Options: monitorOptionsJSON{
+				Thresholds: monitorThresholdsJSON{Critical: threshold},
+			},
+		})
+	}
+
+	return monitors
+}
+
+// durationToDatadog returns the duration with the Datadog monitor time window format
+// (e.g: `1h`) using the biggest unit that represents the duration exactly.
+func durationToDatadog(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+type datadogJSON struct {
+	SLOs     []sloJSON     `json:"slos"`
+	Monitors []monitorJSON `json:"monitors"`
+}
+
+// sloJSON is the Datadog API SLO definition.
+type sloJSON struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type"`
+	Query       sloQueryJSON       `json:"query"`
+	Thresholds  []sloThresholdJSON `json:"thresholds"`
+	Tags        []string           `json:"tags"`
+}
+
+type sloQueryJSON struct {
+	Numerator   string `json:"numerator"`
+	Denominator string `json:"denominator"`
+}
+
+type sloThresholdJSON struct {
+	Timeframe string  `json:"timeframe"`
+	Target    float64 `json:"target"`
+}
+
+// monitorJSON is the Datadog API monitor definition.
+type monitorJSON struct {
+	Name    string             `json:"name"`
+	Type    string             `json:"type"`
+	Query   string             `json:"query"`
+	Message string             `json:"message"`
+	Tags    []string           `json:"tags"`
+	Options monitorOptionsJSON `json:"options"`
+}
+
+type monitorOptionsJSON struct {
+	Thresholds monitorThresholdsJSON `json:"thresholds"`
+}
+
+type monitorThresholdsJSON struct {
+	Critical float64 `json:"critical"`
+}
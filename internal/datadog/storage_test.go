@@ -0,0 +1,284 @@
+package datadog_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sloth/internal/alert"
+	"github.com/slok/sloth/internal/datadog"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func getAlertGroup() alert.MWMBAlertGroup {
+	return alert.MWMBAlertGroup{
+		PageQuick: alert.MWMBAlert{
+			ShortWindow:    5 * time.Minute,
+			LongWindow:     time.Hour,
+			BurnRateFactor: 14.4,
+			ErrorBudget:    0.1,
+			Severity:       alert.PageAlertSeverity,
+		},
+		TicketQuick: alert.MWMBAlert{
+			ShortWindow:    2 * time.Hour,
+			LongWindow:     24 * time.Hour,
+			BurnRateFactor: 3,
+			ErrorBudget:    0.1,
+			Severity:       alert.TicketAlertSeverity,
+		},
+	}
+}
+
+func TestIOWriterJSONRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config  datadog.IOWriterJSONRepoConfig
+		slos    []datadog.StorageSLO
+		expJSON string
+		expErr  bool
+	}{
+		"Having 0 SLOs should fail.": {
+			slos:   []datadog.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having an SLO without the Datadog good events query should fail.": {
+			slos: []datadog.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"datadog_total_events_query": "sum:requests.total{*}.as_count()"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO without the Datadog total events query should fail.": {
+			slos: []datadog.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"datadog_good_events_query": "sum:requests.ok{*}.as_count()"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with a time window not supported by Datadog should fail.": {
+			slos: []datadog.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Labels: map[string]string{
+						"datadog_good_events_query":  "sum:requests.ok{*}.as_count()",
+						"datadog_total_events_query": "sum:requests.total{*}.as_count()",
+					},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs should render the Datadog SLOs and their monitors.": {
+			slos: []datadog.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:          "svc1-slo1",
+						Name:        "slo1",
+						Description: "Test SLO 1.",
+						Service:     "svc1",
+						TimeWindow:  30 * 24 * time.Hour,
+						Objective:   99.9,
+						Labels: map[string]string{
+							"owner":                      "team-a",
+							"datadog_good_events_query":  "sum:requests.ok{service:svc1}.as_count()",
+							"datadog_total_events_query": "sum:requests.total{service:svc1}.as_count()",
+						},
+						PageAlertMeta: prometheus.AlertMeta{
+							Name:        "Svc1SLO1HighErrorRate",
+							Annotations: map[string]string{"summary": "Svc1 is burning the error budget."},
+						},
+						TicketAlertMeta: prometheus.AlertMeta{
+							Name: "Svc1SLO1ErrorBudgetBurn",
+						},
+					},
+					Alerts: getAlertGroup(),
+				},
+				{
+					SLO: prometheus.SLO{
+						ID:         "svc1-slo2",
+						Name:       "slo2",
+						Service:    "svc1",
+						TimeWindow: 7 * 24 * time.Hour,
+						Objective:  99,
+						Labels: map[string]string{
+							"datadog_good_events_query":  "sum:jobs.ok{*}.as_count()",
+							"datadog_total_events_query": "sum:jobs.total{*}.as_count()",
+						},
+						PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+						TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+					},
+				},
+			},
+			expJSON: `{
+  "slos": [
+    {
+      "name": "slo1",
+      "description": "Test SLO 1.",
+      "type": "metric",
+      "query": {
+        "numerator": "sum:requests.ok{service:svc1}.as_count()",
+        "denominator": "sum:requests.total{service:svc1}.as_count()"
+      },
+      "thresholds": [
+        {
+          "timeframe": "30d",
+          "target": 99.9
+        }
+      ],
+      "tags": [
+        "sloth_id:svc1-slo1",
+        "sloth_service:svc1",
+        "owner:team-a"
+      ]
+    },
+    {
+      "name": "slo2",
+      "type": "metric",
+      "query": {
+        "numerator": "sum:jobs.ok{*}.as_count()",
+        "denominator": "sum:jobs.total{*}.as_count()"
+      },
+      "thresholds": [
+        {
+          "timeframe": "7d",
+          "target": 99
+        }
+      ],
+      "tags": [
+        "sloth_id:svc1-slo2",
+        "sloth_service:svc1"
+      ]
+    }
+  ],
+  "monitors": [
+    {
+      "name": "Svc1SLO1HighErrorRate",
+      "type": "query alert",
+      "query": "sum(last_1h):1 - (sum:requests.ok{service:svc1}.as_count()) / (sum:requests.total{service:svc1}.as_count()) > 0.0144",
+      "message": "Svc1 is burning the error budget.",
+      "tags": [
+        "sloth_id:svc1-slo1",
+        "sloth_service:svc1",
+        "owner:team-a",
+        "sloth_severity:page"
+      ],
+      "options": {
+        "thresholds": {
+          "critical": 0.0144
+        }
+      }
+    },
+    {
+      "name": "Svc1SLO1ErrorBudgetBurn",
+      "type": "query alert",
+      "query": "sum(last_1d):1 - (sum:requests.ok{service:svc1}.as_count()) / (sum:requests.total{service:svc1}.as_count()) > 0.003",
+      "message": "slo1 SLO error budget is burning too fast.",
+      "tags": [
+        "sloth_id:svc1-slo1",
+        "sloth_service:svc1",
+        "owner:team-a",
+        "sloth_severity:ticket"
+      ],
+      "options": {
+        "thresholds": {
+          "critical": 0.003
+        }
+      }
+    }
+  ]
+}
+`,
+		},
+
+		"Having custom query labels should use them to get the Datadog queries.": {
+			config: datadog.IOWriterJSONRepoConfig{
+				GoodEventsQueryLabel:  "dd_good",
+				TotalEventsQueryLabel: "dd_total",
+			},
+			slos: []datadog.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:         "svc1-slo1",
+						Name:       "slo1",
+						Service:    "svc1",
+						TimeWindow: 90 * 24 * time.Hour,
+						Objective:  95,
+						Labels: map[string]string{
+							"dd_good":  "sum:jobs.ok{*}.as_count()",
+							"dd_total": "sum:jobs.total{*}.as_count()",
+						},
+						PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+						TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+					},
+				},
+			},
+			expJSON: `{
+  "slos": [
+    {
+      "name": "slo1",
+      "type": "metric",
+      "query": {
+        "numerator": "sum:jobs.ok{*}.as_count()",
+        "denominator": "sum:jobs.total{*}.as_count()"
+      },
+      "thresholds": [
+        {
+          "timeframe": "90d",
+          "target": 95
+        }
+      ],
+      "tags": [
+        "sloth_id:svc1-slo1",
+        "sloth_service:svc1"
+      ]
+    }
+  ],
+  "monitors": []
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotJSON bytes.Buffer
+			test.config.Writer = &gotJSON
+			test.config.Logger = log.Noop
+			repo, err := datadog.NewIOWriterJSONRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expJSON, gotJSON.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterJSONRepoInvalidConfig(t *testing.T) {
+	_, err := datadog.NewIOWriterJSONRepo(datadog.IOWriterJSONRepoConfig{
+		Writer:                &bytes.Buffer{},
+		GoodEventsQueryLabel:  "dd_query",
+		TotalEventsQueryLabel: "dd_query",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLOs will be used when there are no SLOs to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLOs = fmt.Errorf("0 Elastic SLOs generated")
+)
+
+const (
+	defaultIndexLabel      = "elastic_index"
+	defaultGoodQueryLabel  = "elastic_good_query"
+	defaultTotalQueryLabel = "elastic_total_query"
+	defaultTimestampField  = "@timestamp"
+
+	indicatorTypeKQLCustom     = "sli.kql.custom"
+	timeWindowTypeRolling      = "rolling"
+	budgetingMethodOccurrences = "occurrences"
+
+	day = 24 * time.Hour
+)
+
+type IOWriterJSONRepoConfig struct {
+	Writer io.Writer
+	Logger log.Logger
+	// IndexLabel is the SLO label that has the index pattern of the SLI events (e.g: `logs-*`),
+	// by default `elastic_index`.
+	IndexLabel string
+	// GoodQueryLabel is the SLO label that has the KQL query of the good events (e.g:
+	// `http.response.status_code < 500`), by default `elastic_good_query`.
+	GoodQueryLabel string
+	// TotalQueryLabel is the SLO label that has the KQL query of all the events, by default
+	// `elastic_total_query`, if the SLO doesn't have it all the index events are used.
+	TotalQueryLabel string
+	// TimestampField is the timestamp field of the SLI events, by default `@timestamp`.
+	TimestampField string
+}
+
+func (c *IOWriterJSONRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.IndexLabel == "" {
+		c.IndexLabel = defaultIndexLabel
+	}
+
+	if c.GoodQueryLabel == "" {
+		c.GoodQueryLabel = defaultGoodQueryLabel
+	}
+
+	if c.TotalQueryLabel == "" {
+		c.TotalQueryLabel = defaultTotalQueryLabel
+	}
+
+	if c.GoodQueryLabel == c.TotalQueryLabel {
+		return fmt.Errorf("good and total query labels can't be the same")
+	}
+
+	if c.TimestampField == "" {
+		c.TimestampField = defaultTimestampField
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "json", "flavor": "elastic"})
+
+	return nil
+}
+
+// IOWriterJSONRepo knows to store the SLOs in an IOWriter as a JSON document with the Kibana SLO
+// API create payloads. Elastic SLIs are KQL queries instead of PromQL, so the indicator index and
+// queries are taken from the SLO labels.
+type IOWriterJSONRepo struct {
+	writer         io.Writer
+	indexLbl       string
+	goodQueryLbl   string
+	totalQueryLbl  string
+	timestampField string
+	logger         log.Logger
+}
+
+func NewIOWriterJSONRepo(config IOWriterJSONRepoConfig) (*IOWriterJSONRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &IOWriterJSONRepo{
+		writer:         config.Writer,
+		indexLbl:       config.IndexLabel,
+		goodQueryLbl:   config.GoodQueryLabel,
+		totalQueryLbl:  config.TotalQueryLabel,
+		timestampField: config.TimestampField,
+		logger:         config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO prometheus.SLO
+}
+
+// StoreSLOs will store the SLOs as Kibana SLO API create payloads, with a custom KQL indicator,
+// rolling time window and occurrences budgeting method.
+func (i IOWriterJSONRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	if len(slos) == 0 {
+		return ErrNoSLOs
+	}
+
+	doc := elasticJSON{SLOs: []sloJSON{}}
+	for _, slo := range slos {
+		s, err := i.mapModelToSLO(slo.SLO)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to Elastic: %w", slo.SLO.ID, err)
+		}
+		doc.SLOs = append(doc.SLOs, *s)
+	}
+
+	// Don't escape the HTML characters, the queries could have them.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("could not format SLOs: %w", err)
+	}
+
+	_, err = i.writer.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write SLOs: %w", err)
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{"slos": len(doc.SLOs)}).Infof("Elastic SLOs written")
+
+	return nil
+}
+
+func (i IOWriterJSONRepo) mapModelToSLO(slo prometheus.SLO) (*sloJSON, error) {
+	index := strings.TrimSpace(slo.Labels[i.indexLbl])
+	if index == "" {
+		return nil, fmt.Errorf("missing Elastic SLI index, %q label is required", i.indexLbl)
+	}
+
+	good := strings.TrimSpace(slo.Labels[i.goodQueryLbl])
+	if good == "" {
+		return nil, fmt.Errorf("missing Elastic SLI good events query, %q label is required", i.goodQueryLbl)
+	}
+
+	if slo.TimeWindow < day || slo.TimeWindow%day != 0 {
+		return nil, fmt.Errorf("unsupported %s time window, Elastic rolling windows only support days", slo.TimeWindow)
+	}
+
+	return &sloJSON{
+		Name:        slo.Name,
+		Description: slo.Description,
+		Indicator: indicatorJSON{
+			Type: indicatorTypeKQLCustom,
+			Params: indicatorParamsJSON{
+				Index:          index,
+				Good:           good,
+				Total:          strings.TrimSpace(slo.Labels[i.totalQueryLbl]),
+				TimestampField: i.timestampField,
+			},
+		},
+		TimeWindow: timeWindowJSON{
+			Duration: fmt.Sprintf("%dd", slo.TimeWindow/day),
+			Type:     timeWindowTypeRolling,
+		},
+		BudgetingMethod: budgetingMethodOccurrences,
+		// Same precision as the objective percent with 4 decimals (e.g: 99.9999%).
+		Objective: objectiveJSON{Target: math.Round(slo.Objective*10000) / 1000000},
+		Tags:      []string{slo.Service},
+	}, nil
+}
+
+type elasticJSON struct {
+	SLOs []sloJSON `json:"slos"`
+}
+
+// sloJSON is the Kibana SLO API create payload. The objective target is a ratio
+// (e.g: 99.9% is 0.999).
+type sloJSON struct {
+	Name            string         `json:"name"`
+	Description     string         `json:"description"`
+	Indicator       indicatorJSON  `json:"indicator"`
+	TimeWindow      timeWindowJSON `json:"timeWindow"`
+	BudgetingMethod string         `json:"budgetingMethod"`
+	Objective       objectiveJSON  `json:"objective"`
+	Tags            []string       `json:"tags"`
+}
+
+type indicatorJSON struct {
+	Type   string              `json:"type"`
+	Params indicatorParamsJSON `json:"params"`
+}
+
+// indicatorParamsJSON are the custom KQL indicator params, the total events query can
+// be empty to use all the index events.
+type indicatorParamsJSON struct {
+	Index          string `json:"index"`
+	Good           string `json:"good"`
+	Total          string `json:"total"`
+	TimestampField string `json:"timestampField"`
+}
+
+type timeWindowJSON struct {
+	Duration string `json:"duration"`
+	Type     string `json:"type"`
+}
+
+type objectiveJSON struct {
+	Target float64 `json:"target"`
+}
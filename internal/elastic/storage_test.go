@@ -0,0 +1,288 @@
+package elastic_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sloth/internal/elastic"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestIOWriterJSONRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config  elastic.IOWriterJSONRepoConfig
+		slos    []elastic.StorageSLO
+		expJSON string
+		expErr  bool
+	}{
+		"Having 0 SLOs should fail.": {
+			slos:   []elastic.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having an SLO without the SLI index should fail.": {
+			slos: []elastic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"elastic_good_query": "http.response.status_code < 500"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO without the SLI good events query should fail.": {
+			slos: []elastic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"elastic_index": "logs-*"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with a time window not supported by Elastic should fail.": {
+			slos: []elastic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 36 * time.Hour,
+					Labels:     map[string]string{"elastic_index": "logs-*", "elastic_good_query": "http.response.status_code < 500"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs should render the Kibana SLO API payloads.": {
+			slos: []elastic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:          "svc1-slo1",
+					Name:        "slo1",
+					Description: "Test SLO 1.",
+					Service:     "svc1",
+					TimeWindow:  30 * 24 * time.Hour,
+					Objective:   99.9,
+					Labels: map[string]string{
+						"elastic_index":       "logs-*",
+						"elastic_good_query":  "http.response.status_code < 500",
+						"elastic_total_query": "service.name : \"svc1\"",
+					},
+				}},
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo2",
+					Name:       "slo2",
+					Service:    "svc1",
+					TimeWindow: 7 * 24 * time.Hour,
+					Objective:  95.55,
+					Labels:     map[string]string{"elastic_index": "traces-*", "elastic_good_query": "event.outcome : \"success\""},
+				}},
+			},
+			expJSON: `{
+  "slos": [
+    {
+      "name": "slo1",
+      "description": "Test SLO 1.",
+      "indicator": {
+        "type": "sli.kql.custom",
+        "params": {
+          "index": "logs-*",
+          "good": "http.response.status_code < 500",
+          "total": "service.name : \"svc1\"",
+          "timestampField": "@timestamp"
+        }
+      },
+      "timeWindow": {
+        "duration": "30d",
+        "type": "rolling"
+      },
+      "budgetingMethod": "occurrences",
+      "objective": {
+        "target": 0.999
+      },
+      "tags": [
+        "svc1"
+      ]
+    },
+    {
+      "name": "slo2",
+      "description": "",
+      "indicator": {
+        "type": "sli.kql.custom",
+        "params": {
+          "index": "traces-*",
+          "good": "event.outcome : \"success\"",
+          "total": "",
+          "timestampField": "@timestamp"
+        }
+      },
+      "timeWindow": {
+        "duration": "7d",
+        "type": "rolling"
+      },
+      "budgetingMethod": "occurrences",
+      "objective": {
+        "target": 0.9555
+      },
+      "tags": [
+        "svc1"
+      ]
+    }
+  ]
+}
+`,
+		},
+
+		"Having custom labels and timestamp field should use them to get the indicator.": {
+			config: elastic.IOWriterJSONRepoConfig{
+				IndexLabel:      "es_index",
+				GoodQueryLabel:  "es_good",
+				TotalQueryLabel: "es_total",
+				TimestampField:  "event.created",
+			},
+			slos: []elastic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					Name:       "slo1",
+					Service:    "svc1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Objective:  99.99,
+					Labels:     map[string]string{"es_index": "logs-*", "es_good": "not error", "es_total": "*"},
+				}},
+			},
+			expJSON: `{
+  "slos": [
+    {
+      "name": "slo1",
+      "description": "",
+      "indicator": {
+        "type": "sli.kql.custom",
+        "params": {
+          "index": "logs-*",
+          "good": "not error",
+          "total": "*",
+          "timestampField": "event.created"
+        }
+      },
+      "timeWindow": {
+        "duration": "28d",
+        "type": "rolling"
+      },
+      "budgetingMethod": "occurrences",
+      "objective": {
+        "target": 0.9999
+      },
+      "tags": [
+        "svc1"
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotJSON bytes.Buffer
+			test.config.Writer = &gotJSON
+			test.config.Logger = log.Noop
+			repo, err := elastic.NewIOWriterJSONRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expJSON, gotJSON.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterJSONRepoStoreObjective(t *testing.T) {
+	tests := map[string]struct {
+		objective     float64
+		timeWindow    time.Duration
+		expTarget     float64
+		expTimeWindow string
+	}{
+		"A 99.9% objective over 30 days.": {
+			objective:     99.9,
+			timeWindow:    30 * 24 * time.Hour,
+			expTarget:     0.999,
+			expTimeWindow: "30d",
+		},
+
+		"A 99.95% objective over 7 days.": {
+			objective:     99.95,
+			timeWindow:    7 * 24 * time.Hour,
+			expTarget:     0.9995,
+			expTimeWindow: "7d",
+		},
+
+		"A 90% objective over 90 days.": {
+			objective:     90,
+			timeWindow:    90 * 24 * time.Hour,
+			expTarget:     0.9,
+			expTimeWindow: "90d",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var gotJSON bytes.Buffer
+			repo, err := elastic.NewIOWriterJSONRepo(elastic.IOWriterJSONRepoConfig{Writer: &gotJSON})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), []elastic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					Name:       "slo1",
+					Service:    "svc1",
+					TimeWindow: test.timeWindow,
+					Objective:  test.objective,
+					Labels:     map[string]string{"elastic_index": "logs-*", "elastic_good_query": "not error"},
+				}},
+			})
+			require.NoError(err)
+
+			var got struct {
+				SLOs []struct {
+					TimeWindow struct {
+						Duration string `json:"duration"`
+					} `json:"timeWindow"`
+					Objective struct {
+						Target float64 `json:"target"`
+					} `json:"objective"`
+				} `json:"slos"`
+			}
+			err = json.Unmarshal(gotJSON.Bytes(), &got)
+			require.NoError(err)
+			require.Len(got.SLOs, 1)
+			assert.Equal(test.expTarget, got.SLOs[0].Objective.Target)
+			assert.Equal(test.expTimeWindow, got.SLOs[0].TimeWindow.Duration)
+		})
+	}
+}
+
+func TestIOWriterJSONRepoInvalidConfig(t *testing.T) {
+	_, err := elastic.NewIOWriterJSONRepo(elastic.IOWriterJSONRepoConfig{
+		Writer:          &bytes.Buffer{},
+		GoodQueryLabel:  "es_query",
+		TotalQueryLabel: "es_query",
+	})
+	assert.Error(t, err)
+}
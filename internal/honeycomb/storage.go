@@ -0,0 +1,244 @@
+package honeycomb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slok/sloth/internal/alert"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLOs will be used when there are no SLOs to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLOs = fmt.Errorf("0 Honeycomb SLOs generated")
+)
+
+const (
+	defaultSLIExpressionLabel = "honeycomb_sli_expression"
+
+	sliAliasPrefix = "sloth_sli_"
+
+	alertTypeBudgetRate = "budget_rate"
+
+	day = 24 * time.Hour
+)
+
+type IOWriterJSONRepoConfig struct {
+	Writer io.Writer
+	Logger log.Logger
+	// SLIExpressionLabel is the SLO label that has the Honeycomb derived column expression of
+	// the SLI, that must return true for the good events (e.g: `LT($status_code, 500)`), by
+	// default `honeycomb_sli_expression`.
+	SLIExpressionLabel string
+}
+
+func (c *IOWriterJSONRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.SLIExpressionLabel == "" {
+		c.SLIExpressionLabel = defaultSLIExpressionLabel
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "json", "flavor": "honeycomb"})
+
+	return nil
+}
+
+// IOWriterJSONRepo knows to store the SLOs in an IOWriter as a JSON document with the Honeycomb
+// derived columns, SLOs and burn alerts definitions. Honeycomb SLIs are derived columns with its
+// own query language instead of PromQL, so the SLI expressions are taken from the SLO labels.
+type IOWriterJSONRepo struct {
+	writer           io.Writer
+	sliExpressionLbl string
+	logger           log.Logger
+}
+
+func NewIOWriterJSONRepo(config IOWriterJSONRepoConfig) (*IOWriterJSONRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &IOWriterJSONRepo{
+		writer:           config.Writer,
+		sliExpressionLbl: config.SLIExpressionLabel,
+		logger:           config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO prometheus.SLO
+	// Alerts are the SLO alerts, the quick ones will be used to create the Honeycomb burn alerts.
+	Alerts alert.MWMBAlertGroup
+}
+
+// StoreSLOs will store the SLOs as Honeycomb derived columns and SLOs definitions with their
+// burn alerts, each SLO will have its own SLI derived column.
+func (i IOWriterJSONRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	if len(slos) == 0 {
+		return ErrNoSLOs
+	}
+
+	doc := honeycombJSON{
+		DerivedColumns: []derivedColumnJSON{},
+		SLOs:           []sloJSON{},
+	}
+	burnAlerts := 0
+	for _, slo := range slos {
+		column, err := i.mapModelToDerivedColumn(slo.SLO)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to Honeycomb derived column: %w", slo.SLO.ID, err)
+		}
+
+		s, err := mapModelToSLO(slo, column.Alias)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to Honeycomb: %w", slo.SLO.ID, err)
+		}
+		doc.DerivedColumns = append(doc.DerivedColumns, *column)
+		doc.SLOs = append(doc.SLOs, *s)
+		burnAlerts += len(s.BurnAlerts)
+	}
+
+	// Don't escape the HTML characters, the expressions could have them.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("could not format SLOs: %w", err)
+	}
+
+	_, err = i.writer.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write SLOs: %w", err)
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{"slos": len(doc.SLOs), "burn-alerts": burnAlerts}).Infof("Honeycomb SLOs written")
+
+	return nil
+}
+
+// invalidAliasCharsRegexp matches the characters that are not valid on the derived column aliases.
+var invalidAliasCharsRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// mapModelToDerivedColumn returns the SLI derived column of the SLO, using the SLO SLI expression.
+func (i IOWriterJSONRepo) mapModelToDerivedColumn(slo prometheus.SLO) (*derivedColumnJSON, error) {
+	expr := strings.TrimSpace(slo.Labels[i.sliExpressionLbl])
+	if expr == "" {
+		return nil, fmt.Errorf("missing Honeycomb SLI derived column expression, %q label is required", i.sliExpressionLbl)
+	}
+
+	return &derivedColumnJSON{
+		Alias:       sliAliasPrefix + invalidAliasCharsRegexp.ReplaceAllString(slo.ID, "_"),
+		Expression:  expr,
+		Description: fmt.Sprintf("SLI of %q SLO (generated by Sloth)", slo.ID),
+	}, nil
+}
+
+func mapModelToSLO(storageSLO StorageSLO, sliAlias string) (*sloJSON, error) {
+	slo := storageSLO.SLO
+	if slo.TimeWindow < day || slo.TimeWindow%day != 0 {
+		return nil, fmt.Errorf("unsupported %s time window, Honeycomb only supports days", slo.TimeWindow)
+	}
+
+	// Use the same budget consumption as the Sloth quick multiwindow alerts, disabled alerts
+	// are not created.
+	burnAlerts := []burnAlertJSON{}
+	for _, a := range []struct {
+		meta  prometheus.AlertMeta
+		alert alert.MWMBAlert
+	}{
+		{meta: slo.PageAlertMeta, alert: storageSLO.Alerts.PageQuick},
+		{meta: slo.TicketAlertMeta, alert: storageSLO.Alerts.TicketQuick},
+	} {
+		if a.meta.Disable {
+			continue
+		}
+
+		burnAlert, err := mapAlertToBurnAlert(a.alert, a.meta, slo.TimeWindow)
+		if err != nil {
+			return nil, err
+		}
+		burnAlerts = append(burnAlerts, *burnAlert)
+	}
+
+	return &sloJSON{
+		Name:             slo.Name,
+		Description:      slo.Description,
+		SLI:              sloSLIJSON{Alias: sliAlias},
+		TimePeriodDays:   int(slo.TimeWindow / day),
+		TargetPerMillion: int(math.Round(slo.Objective * 10000)),
+		BurnAlerts:       burnAlerts,
+	}, nil
+}
+
+// mapAlertToBurnAlert returns the budget rate burn alert of the alert, the window is the alert long
+// window and the threshold the error budget consumed on that window with the alert burn rate.
+func mapAlertToBurnAlert(a alert.MWMBAlert, meta prometheus.AlertMeta, timeWindow time.Duration) (*burnAlertJSON, error) {
+	if a.LongWindow < time.Minute || a.LongWindow%time.Minute != 0 {
+		return nil, fmt.Errorf("unsupported %s alert window, Honeycomb burn alerts only support minutes", a.LongWindow)
+	}
+
+	if a.BurnRateFactor <= 0 {
+		return nil, fmt.Errorf("missing %q alert burn rate", a.ID)
+	}
+
+	consumed := a.BurnRateFactor * float64(a.LongWindow) / float64(timeWindow)
+	return &burnAlertJSON{
+		Description:                           meta.Name,
+		AlertType:                             alertTypeBudgetRate,
+		BudgetRateWindowMinutes:               int(a.LongWindow / time.Minute),
+		BudgetRateDecreaseThresholdPerMillion: int(math.Round(consumed * 1000000)),
+	}, nil
+}
+
+type honeycombJSON struct {
+	DerivedColumns []derivedColumnJSON `json:"derived_columns"`
+	SLOs           []sloJSON           `json:"slos"`
+}
+
+// derivedColumnJSON is the Honeycomb derived column definition.
+type derivedColumnJSON struct {
+	Alias       string `json:"alias"`
+	Expression  string `json:"expression"`
+	Description string `json:"description"`
+}
+
+// sloJSON is the Honeycomb SLO definition, based on an SLI derived column. The target
+// is in per million (e.g: 99.9% is 999000).
+type sloJSON struct {
+	Name             string          `json:"name"`
+	Description      string          `json:"description,omitempty"`
+	SLI              sloSLIJSON      `json:"sli"`
+	TimePeriodDays   int             `json:"time_period_days"`
+	TargetPerMillion int             `json:"target_per_million"`
+	BurnAlerts       []burnAlertJSON `json:"burn_alerts"`
+}
+
+type sloSLIJSON struct {
+	Alias string `json:"alias"`
+}
+
+// burnAlertJSON is the Honeycomb SLO budget rate burn alert definition.
+type burnAlertJSON struct {
+	Description                           string `json:"description,omitempty"`
+	AlertType                             string `json:"alert_type"`
+	BudgetRateWindowMinutes               int    `json:"budget_rate_window_minutes"`
+	BudgetRateDecreaseThresholdPerMillion int    `json:"budget_rate_decrease_threshold_per_million"`
+}
@@ -0,0 +1,277 @@
+package honeycomb_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sloth/internal/alert"
+	"github.com/slok/sloth/internal/honeycomb"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestIOWriterJSONRepoStore(t *testing.T) {
+	alerts30d := alert.MWMBAlertGroup{
+		PageQuick:   alert.MWMBAlert{ID: "page-quick", ShortWindow: 5 * time.Minute, LongWindow: time.Hour, BurnRateFactor: 14.4},
+		TicketQuick: alert.MWMBAlert{ID: "ticket-quick", ShortWindow: 2 * time.Hour, LongWindow: 24 * time.Hour, BurnRateFactor: 3},
+	}
+
+	tests := map[string]struct {
+		config  honeycomb.IOWriterJSONRepoConfig
+		slos    []honeycomb.StorageSLO
+		expJSON string
+		expErr  bool
+	}{
+		"Having 0 SLOs should fail.": {
+			slos:   []honeycomb.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having an SLO without the SLI derived column expression should fail.": {
+			slos: []honeycomb.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"owner": "team-a"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with a time window not supported by Honeycomb should fail.": {
+			slos: []honeycomb.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 36 * time.Hour,
+					Labels:     map[string]string{"honeycomb_sli_expression": "LT($status_code, 500)"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO without the alerts should fail.": {
+			slos: []honeycomb.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"honeycomb_sli_expression": "LT($status_code, 500)"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with an alert window not supported by Honeycomb should fail.": {
+			slos: []honeycomb.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"honeycomb_sli_expression": "LT($status_code, 500)"},
+				}, Alerts: alert.MWMBAlertGroup{
+					PageQuick:   alert.MWMBAlert{ID: "page-quick", LongWindow: 90 * time.Second, BurnRateFactor: 14.4},
+					TicketQuick: alerts30d.TicketQuick,
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs with custom alert windows should use their budget consumption on the burn alerts.": {
+			slos: []honeycomb.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:              "svc1-slo1",
+					Name:            "slo1",
+					Service:         "svc1",
+					TimeWindow:      7 * 24 * time.Hour,
+					Objective:       99.9,
+					Labels:          map[string]string{"honeycomb_sli_expression": "LT($status_code, 500)"},
+					PageAlertMeta:   prometheus.AlertMeta{Name: "Slo1Page"},
+					TicketAlertMeta: prometheus.AlertMeta{Name: "Slo1Ticket"},
+				}, Alerts: alert.MWMBAlertGroup{
+					PageQuick:   alert.MWMBAlert{ID: "page-quick", ShortWindow: 5 * time.Minute, LongWindow: 30 * time.Minute, BurnRateFactor: 14},
+					TicketQuick: alert.MWMBAlert{ID: "ticket-quick", ShortWindow: 30 * time.Minute, LongWindow: 6 * time.Hour, BurnRateFactor: 2},
+				}},
+			},
+			expJSON: `{
+  "derived_columns": [
+    {
+      "alias": "sloth_sli_svc1_slo1",
+      "expression": "LT($status_code, 500)",
+      "description": "SLI of \"svc1-slo1\" SLO (generated by Sloth)"
+    }
+  ],
+  "slos": [
+    {
+      "name": "slo1",
+      "sli": {
+        "alias": "sloth_sli_svc1_slo1"
+      },
+      "time_period_days": 7,
+      "target_per_million": 999000,
+      "burn_alerts": [
+        {
+          "description": "Slo1Page",
+          "alert_type": "budget_rate",
+          "budget_rate_window_minutes": 30,
+          "budget_rate_decrease_threshold_per_million": 41667
+        },
+        {
+          "description": "Slo1Ticket",
+          "alert_type": "budget_rate",
+          "budget_rate_window_minutes": 360,
+          "budget_rate_decrease_threshold_per_million": 71429
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+
+		"Having SLOs should render the Honeycomb derived columns, SLOs and burn alerts.": {
+			slos: []honeycomb.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:              "svc1-slo1",
+					Name:            "slo1",
+					Description:     "Test SLO 1.",
+					Service:         "svc1",
+					TimeWindow:      30 * 24 * time.Hour,
+					Objective:       99.9,
+					Labels:          map[string]string{"honeycomb_sli_expression": "LT($status_code, 500)"},
+					PageAlertMeta:   prometheus.AlertMeta{Name: "Slo1Page"},
+					TicketAlertMeta: prometheus.AlertMeta{Name: "Slo1Ticket"},
+				}, Alerts: alerts30d},
+				{SLO: prometheus.SLO{
+					ID:              "svc1-slo2",
+					Name:            "slo2",
+					Service:         "svc1",
+					TimeWindow:      7 * 24 * time.Hour,
+					Objective:       95.55,
+					Labels:          map[string]string{"honeycomb_sli_expression": "AND(EXISTS($duration_ms), LT($duration_ms, 300))"},
+					PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+					TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+				}},
+			},
+			expJSON: `{
+  "derived_columns": [
+    {
+      "alias": "sloth_sli_svc1_slo1",
+      "expression": "LT($status_code, 500)",
+      "description": "SLI of \"svc1-slo1\" SLO (generated by Sloth)"
+    },
+    {
+      "alias": "sloth_sli_svc1_slo2",
+      "expression": "AND(EXISTS($duration_ms), LT($duration_ms, 300))",
+      "description": "SLI of \"svc1-slo2\" SLO (generated by Sloth)"
+    }
+  ],
+  "slos": [
+    {
+      "name": "slo1",
+      "description": "Test SLO 1.",
+      "sli": {
+        "alias": "sloth_sli_svc1_slo1"
+      },
+      "time_period_days": 30,
+      "target_per_million": 999000,
+      "burn_alerts": [
+        {
+          "description": "Slo1Page",
+          "alert_type": "budget_rate",
+          "budget_rate_window_minutes": 60,
+          "budget_rate_decrease_threshold_per_million": 20000
+        },
+        {
+          "description": "Slo1Ticket",
+          "alert_type": "budget_rate",
+          "budget_rate_window_minutes": 1440,
+          "budget_rate_decrease_threshold_per_million": 100000
+        }
+      ]
+    },
+    {
+      "name": "slo2",
+      "sli": {
+        "alias": "sloth_sli_svc1_slo2"
+      },
+      "time_period_days": 7,
+      "target_per_million": 955500,
+      "burn_alerts": []
+    }
+  ]
+}
+`,
+		},
+
+		"Having a custom SLI expression label should use it to get the derived column expression.": {
+			config: honeycomb.IOWriterJSONRepoConfig{
+				SLIExpressionLabel: "hny_sli",
+			},
+			slos: []honeycomb.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:              "svc1-slo1",
+					Name:            "slo1",
+					Service:         "svc1",
+					TimeWindow:      28 * 24 * time.Hour,
+					Objective:       99.99,
+					Labels:          map[string]string{"hny_sli": "EQUALS($error, false)"},
+					PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+					TicketAlertMeta: prometheus.AlertMeta{Name: "Slo1Ticket"},
+				}, Alerts: alert.MWMBAlertGroup{
+					TicketQuick: alert.MWMBAlert{ID: "ticket-quick", ShortWindow: 2 * time.Hour, LongWindow: 24 * time.Hour, BurnRateFactor: 2.8},
+				}},
+			},
+			expJSON: `{
+  "derived_columns": [
+    {
+      "alias": "sloth_sli_svc1_slo1",
+      "expression": "EQUALS($error, false)",
+      "description": "SLI of \"svc1-slo1\" SLO (generated by Sloth)"
+    }
+  ],
+  "slos": [
+    {
+      "name": "slo1",
+      "sli": {
+        "alias": "sloth_sli_svc1_slo1"
+      },
+      "time_period_days": 28,
+      "target_per_million": 999900,
+      "burn_alerts": [
+        {
+          "description": "Slo1Ticket",
+          "alert_type": "budget_rate",
+          "budget_rate_window_minutes": 1440,
+          "budget_rate_decrease_threshold_per_million": 100000
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotJSON bytes.Buffer
+			test.config.Writer = &gotJSON
+			test.config.Logger = log.Noop
+			repo, err := honeycomb.NewIOWriterJSONRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expJSON, gotJSON.String())
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	prommodel "github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -52,8 +53,23 @@ func (i IOWriterPrometheusOperatorYAMLRepo) StoreSLOs(ctx context.Context, kmeta
 		return fmt.Errorf("could not map model to Prometheus operator CR: %w", err)
 	}
 
+	return i.write(rule)
+}
+
+// StoreRuleGroups stores already built Prometheus rule groups (e.g: with the options of the
+// Prometheus storage `BuildRuleGroups`) in the same Prometheus operator CR as StoreSLOs.
+func (i IOWriterPrometheusOperatorYAMLRepo) StoreRuleGroups(ctx context.Context, kmeta K8sMeta, groups []prometheus.RuleGroup) error {
+	rule, err := mapRuleGroupsToPrometheusOperator(ctx, kmeta, groups)
+	if err != nil {
+		return fmt.Errorf("could not map rule groups to Prometheus operator CR: %w", err)
+	}
+
+	return i.write(rule)
+}
+
+func (i IOWriterPrometheusOperatorYAMLRepo) write(rule *monitoringv1.PrometheusRule) error {
 	var b bytes.Buffer
-	err = i.encoder.Encode(rule, &b)
+	err := i.encoder.Encode(rule, &b)
 	if err != nil {
 		return fmt.Errorf("could encode prometheus operator object: %w", err)
 	}
@@ -68,27 +84,7 @@ func (i IOWriterPrometheusOperatorYAMLRepo) StoreSLOs(ctx context.Context, kmeta
 }
 
 func mapModelToPrometheusOperator(ctx context.Context, kmeta K8sMeta, slos []StorageSLO) (*monitoringv1.PrometheusRule, error) {
-	// Add extra labels.
-	labels := map[string]string{
-		"app.kubernetes.io/component":  "SLO",
-		"app.kubernetes.io/managed-by": "sloth",
-	}
-	for k, v := range kmeta.Labels {
-		labels[k] = v
-	}
-
-	rule := &monitoringv1.PrometheusRule{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: "monitoring.coreos.com/v1",
-			Kind:       "PrometheusRule",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        kmeta.Name,
-			Namespace:   kmeta.Namespace,
-			Labels:      labels,
-			Annotations: kmeta.Annotations,
-		},
-	}
+	rule := newPrometheusRule(kmeta)
 
 	if len(slos) == 0 {
 		return nil, fmt.Errorf("slo rules required")
@@ -126,6 +122,90 @@ func mapModelToPrometheusOperator(ctx context.Context, kmeta K8sMeta, slos []Sto
 	return rule, nil
 }
 
+// mapRuleGroupsToPrometheusOperator maps the rule groups to the Prometheus operator CR, the group
+// options that the CR can't represent will fail instead of being lost.
+func mapRuleGroupsToPrometheusOperator(ctx context.Context, kmeta K8sMeta, groups []prometheus.RuleGroup) (*monitoringv1.PrometheusRule, error) {
+	rule := newPrometheusRule(kmeta)
+
+	for _, g := range groups {
+		switch {
+		case g.QueryOffset != 0:
+			return nil, fmt.Errorf("%q group query offset is not supported by Prometheus operator rules", g.Name)
+		case g.Limit != 0:
+			return nil, fmt.Errorf("%q group limit is not supported by Prometheus operator rules", g.Name)
+		case len(g.Labels) > 0:
+			return nil, fmt.Errorf("%q group labels are not supported by Prometheus operator rules", g.Name)
+		case g.Type != "", g.EvalOffset != 0, g.Tenant != "", len(g.SourceTenants) > 0:
+			return nil, fmt.Errorf("%q group flavor options are not supported by Prometheus operator rules", g.Name)
+		}
+
+		rules := make([]monitoringv1.Rule, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			if r.KeepFiringFor != 0 {
+				return nil, fmt.Errorf("%q group keep firing for is not supported by Prometheus operator rules", g.Name)
+			}
+
+			forS := ""
+			if r.For != 0 {
+				forS = prommodel.Duration(r.For).String()
+			}
+
+			rules = append(rules, monitoringv1.Rule{
+				Record:      r.Record,
+				Alert:       r.Alert,
+				Expr:        intstr.FromString(r.Expr),
+				For:         monitoringv1.Duration(forS),
+				Labels:      r.Labels,
+				Annotations: r.Annotations,
+			})
+		}
+
+		interval := ""
+		if g.Interval != 0 {
+			interval = prommodel.Duration(g.Interval).String()
+		}
+
+		rule.Spec.Groups = append(rule.Spec.Groups, monitoringv1.RuleGroup{
+			Name:                    g.Name,
+			Interval:                monitoringv1.Duration(interval),
+			Rules:                   rules,
+			PartialResponseStrategy: g.PartialResponseStrategy,
+		})
+	}
+
+	if len(rule.Spec.Groups) == 0 {
+		return nil, ErrNoSLORules
+	}
+
+	return rule, nil
+}
+
+func newPrometheusRule(kmeta K8sMeta) *monitoringv1.PrometheusRule {
+	// Add extra labels.
+	labels := map[string]string{
+		"app.kubernetes.io/component":  "SLO",
+		"app.kubernetes.io/managed-by": "sloth",
+	}
+	for k, v := range kmeta.Labels {
+		labels[k] = v
+	}
+
+	rule := &monitoringv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "PrometheusRule",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        kmeta.Name,
+			Namespace:   kmeta.Namespace,
+			Labels:      labels,
+			Annotations: kmeta.Annotations,
+		},
+	}
+
+	return rule
+}
+
 func promRulesToKubeRules(rules []rulefmt.Rule) []monitoringv1.Rule {
 	res := make([]monitoringv1.Rule, 0, len(rules))
 	for _, r := range rules {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/prometheus/prometheus/model/rulefmt"
@@ -365,6 +366,126 @@ spec:
 	}
 }
 
+func TestIOWriterPrometheusOperatorYAMLRepoStoreRuleGroups(t *testing.T) {
+	tests := map[string]struct {
+		k8sMeta k8sprometheus.K8sMeta
+		groups  []prometheus.RuleGroup
+		expYAML string
+		expErr  bool
+	}{
+		"Having 0 rule groups should fail.": {
+			k8sMeta: k8sprometheus.K8sMeta{Name: "test-name"},
+			groups:  []prometheus.RuleGroup{},
+			expErr:  true,
+		},
+
+		"Having a rule group with query offset should fail.": {
+			k8sMeta: k8sprometheus.K8sMeta{Name: "test-name"},
+			groups: []prometheus.RuleGroup{
+				{Name: "test-group", QueryOffset: time.Minute, Rules: []prometheus.Rule{{Record: "test:record", Expr: "test-expr"}}},
+			},
+			expErr: true,
+		},
+
+		"Having a rule group with limit should fail.": {
+			k8sMeta: k8sprometheus.K8sMeta{Name: "test-name"},
+			groups: []prometheus.RuleGroup{
+				{Name: "test-group", Limit: 10, Rules: []prometheus.Rule{{Record: "test:record", Expr: "test-expr"}}},
+			},
+			expErr: true,
+		},
+
+		"Having a rule group with labels should fail.": {
+			k8sMeta: k8sprometheus.K8sMeta{Name: "test-name"},
+			groups: []prometheus.RuleGroup{
+				{Name: "test-group", Labels: map[string]string{"k1": "v1"}, Rules: []prometheus.Rule{{Record: "test:record", Expr: "test-expr"}}},
+			},
+			expErr: true,
+		},
+
+		"Having a rule with keep firing for should fail.": {
+			k8sMeta: k8sprometheus.K8sMeta{Name: "test-name"},
+			groups: []prometheus.RuleGroup{
+				{Name: "test-group", Rules: []prometheus.Rule{{Alert: "testAlert", Expr: "test-expr", KeepFiringFor: time.Minute}}},
+			},
+			expErr: true,
+		},
+
+		"Having rule groups should render them in the Prometheus operator object.": {
+			k8sMeta: k8sprometheus.K8sMeta{
+				Name:      "test-name",
+				Namespace: "test-ns",
+				Labels:    map[string]string{"lk1": "lv1"},
+			},
+			groups: []prometheus.RuleGroup{
+				{
+					Name:     "test-sli-recordings-test1",
+					Interval: 2 * time.Minute,
+					Rules: []prometheus.Rule{
+						{Record: "test:record", Expr: "test-expr", Labels: map[string]string{"test-label": "one"}},
+					},
+				},
+				{
+					Name:                    "test-alerts-test1",
+					PartialResponseStrategy: "warn",
+					Rules: []prometheus.Rule{
+						{Alert: "testAlert", Expr: "test-expr", For: 5 * time.Minute, Annotations: map[string]string{"test-annot": "one"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  creationTimestamp: null
+  labels:
+    app.kubernetes.io/component: SLO
+    app.kubernetes.io/managed-by: sloth
+    lk1: lv1
+  name: test-name
+  namespace: test-ns
+spec:
+  groups:
+  - interval: 2m
+    name: test-sli-recordings-test1
+    rules:
+    - expr: test-expr
+      labels:
+        test-label: one
+      record: test:record
+  - name: test-alerts-test1
+    partial_response_strategy: warn
+    rules:
+    - alert: testAlert
+      annotations:
+        test-annot: one
+      expr: test-expr
+      for: 5m
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotYAML bytes.Buffer
+			repo := k8sprometheus.NewIOWriterPrometheusOperatorYAMLRepo(&gotYAML, log.Noop)
+			err := repo.StoreRuleGroups(context.TODO(), test.k8sMeta, test.groups)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expYAML, gotYAML.String())
+			}
+		})
+	}
+}
+
 func TestPrometheusOperatorCRDRepo(t *testing.T) {
 	tests := map[string]struct {
 		k8sMeta k8sprometheus.K8sMeta
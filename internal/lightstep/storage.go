@@ -0,0 +1,198 @@
+package lightstep
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLOs will be used when there are no SLOs to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLOs = fmt.Errorf("0 Lightstep SLOs generated")
+)
+
+const (
+	defaultStreamQueryLabel = "lightstep_stream_query"
+	defaultOperationLabel   = "lightstep_operation"
+
+	indicatorErrorRatio = "error_ratio"
+
+	day = 24 * time.Hour
+)
+
+type IOWriterJSONRepoConfig struct {
+	Writer io.Writer
+	Logger log.Logger
+	// StreamQueryLabel is the SLO label that has the Lightstep stream query of the SLO spans
+	// (e.g: `service IN ("svc1") AND operation IN ("GET /api")`), by default `lightstep_stream_query`.
+	StreamQueryLabel string
+	// OperationLabel is the SLO label that has the Lightstep operation of the SLO spans, used to
+	// create the stream query with the SLO service when the SLO doesn't have the stream query label,
+	// by default `lightstep_operation`.
+	OperationLabel string
+}
+
+func (c *IOWriterJSONRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.StreamQueryLabel == "" {
+		c.StreamQueryLabel = defaultStreamQueryLabel
+	}
+
+	if c.OperationLabel == "" {
+		c.OperationLabel = defaultOperationLabel
+	}
+
+	if c.StreamQueryLabel == c.OperationLabel {
+		return fmt.Errorf("stream query and operation labels can't be the same")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "json", "flavor": "lightstep"})
+
+	return nil
+}
+
+// IOWriterJSONRepo knows to store the SLOs in an IOWriter as a JSON document with the Lightstep
+// (Cloud Observability) stream and SLO definitions. Lightstep SLOs are based on the errors of
+// the span streams instead of PromQL, so the stream queries are taken from the SLO labels.
+type IOWriterJSONRepo struct {
+	writer         io.Writer
+	streamQueryLbl string
+	operationLbl   string
+	logger         log.Logger
+}
+
+func NewIOWriterJSONRepo(config IOWriterJSONRepoConfig) (*IOWriterJSONRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &IOWriterJSONRepo{
+		writer:         config.Writer,
+		streamQueryLbl: config.StreamQueryLabel,
+		operationLbl:   config.OperationLabel,
+		logger:         config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO prometheus.SLO
+}
+
+// StoreSLOs will store the SLOs as Lightstep streams and SLOs definitions, each SLO will
+// have its own stream.
+func (i IOWriterJSONRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	if len(slos) == 0 {
+		return ErrNoSLOs
+	}
+
+	doc := lightstepJSON{
+		Streams: []streamJSON{},
+		SLOs:    []sloJSON{},
+	}
+	for _, slo := range slos {
+		stream, err := i.mapModelToStream(slo.SLO)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to Lightstep stream: %w", slo.SLO.ID, err)
+		}
+
+		s, err := mapModelToSLO(slo.SLO, stream.Name)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to Lightstep: %w", slo.SLO.ID, err)
+		}
+		doc.Streams = append(doc.Streams, *stream)
+		doc.SLOs = append(doc.SLOs, *s)
+	}
+
+	// Don't escape the HTML characters, the queries could have them.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("could not format SLOs: %w", err)
+	}
+
+	_, err = i.writer.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write SLOs: %w", err)
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{"slos": len(doc.SLOs), "streams": len(doc.Streams)}).Infof("Lightstep SLOs written")
+
+	return nil
+}
+
+// mapModelToStream returns the stream of the SLO spans, using the SLO stream query or
+// creating it from the SLO service and operation.
+func (i IOWriterJSONRepo) mapModelToStream(slo prometheus.SLO) (*streamJSON, error) {
+	query := slo.Labels[i.streamQueryLbl]
+	if query == "" {
+		op := slo.Labels[i.operationLbl]
+		if op == "" {
+			return nil, fmt.Errorf("missing Lightstep stream query source, %q or %q label is required", i.streamQueryLbl, i.operationLbl)
+		}
+		query = fmt.Sprintf("service IN (%s) AND operation IN (%s)", strconv.Quote(slo.Service), strconv.Quote(op))
+	}
+
+	return &streamJSON{
+		Name:  slo.ID,
+		Query: query,
+	}, nil
+}
+
+func mapModelToSLO(slo prometheus.SLO, stream string) (*sloJSON, error) {
+	if slo.TimeWindow < day || slo.TimeWindow%day != 0 {
+		return nil, fmt.Errorf("unsupported %s time window, Lightstep only supports days", slo.TimeWindow)
+	}
+
+	return &sloJSON{
+		Name:        slo.Name,
+		Description: slo.Description,
+		Stream:      stream,
+		Indicator:   indicatorErrorRatio,
+		Target:      slo.Objective,
+		TimeWindow:  sloTimeWindowJSON{Days: int(slo.TimeWindow / day)},
+	}, nil
+}
+
+type lightstepJSON struct {
+	Streams []streamJSON `json:"streams"`
+	SLOs    []sloJSON    `json:"slos"`
+}
+
+// streamJSON is the Lightstep stream definition.
+type streamJSON struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// sloJSON is the Lightstep SLO definition, based on a stream.
+type sloJSON struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Stream      string            `json:"stream"`
+	Indicator   string            `json:"indicator"`
+	Target      float64           `json:"target"`
+	TimeWindow  sloTimeWindowJSON `json:"timeWindow"`
+}
+
+type sloTimeWindowJSON struct {
+	Days int `json:"days"`
+}
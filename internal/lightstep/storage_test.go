@@ -0,0 +1,178 @@
+package lightstep_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sloth/internal/lightstep"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestIOWriterJSONRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config  lightstep.IOWriterJSONRepoConfig
+		slos    []lightstep.StorageSLO
+		expJSON string
+		expErr  bool
+	}{
+		"Having 0 SLOs should fail.": {
+			slos:   []lightstep.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having an SLO without the stream query and operation should fail.": {
+			slos: []lightstep.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					Service:    "svc1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"owner": "team-a"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with a time window not supported by Lightstep should fail.": {
+			slos: []lightstep.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					Service:    "svc1",
+					TimeWindow: 12 * time.Hour,
+					Labels:     map[string]string{"lightstep_operation": "GET /api"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs should render the Lightstep streams and SLOs.": {
+			slos: []lightstep.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:          "svc1-slo1",
+					Name:        "slo1",
+					Description: "Test SLO 1.",
+					Service:     "svc1",
+					TimeWindow:  30 * 24 * time.Hour,
+					Objective:   99.9,
+					Labels: map[string]string{
+						"lightstep_stream_query": `service IN ("svc1") AND "http.status_code" >= 200`,
+						"lightstep_operation":    "ignored",
+					},
+				}},
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo2",
+					Name:       "slo2",
+					Service:    "svc1",
+					TimeWindow: 7 * 24 * time.Hour,
+					Objective:  95,
+					Labels:     map[string]string{"lightstep_operation": `GET "/api"`},
+				}},
+			},
+			expJSON: `{
+  "streams": [
+    {
+      "name": "svc1-slo1",
+      "query": "service IN (\"svc1\") AND \"http.status_code\" >= 200"
+    },
+    {
+      "name": "svc1-slo2",
+      "query": "service IN (\"svc1\") AND operation IN (\"GET \\\"/api\\\"\")"
+    }
+  ],
+  "slos": [
+    {
+      "name": "slo1",
+      "description": "Test SLO 1.",
+      "stream": "svc1-slo1",
+      "indicator": "error_ratio",
+      "target": 99.9,
+      "timeWindow": {
+        "days": 30
+      }
+    },
+    {
+      "name": "slo2",
+      "stream": "svc1-slo2",
+      "indicator": "error_ratio",
+      "target": 95,
+      "timeWindow": {
+        "days": 7
+      }
+    }
+  ]
+}
+`,
+		},
+
+		"Having custom labels should use them to get the stream queries.": {
+			config: lightstep.IOWriterJSONRepoConfig{
+				StreamQueryLabel: "ls_query",
+				OperationLabel:   "ls_op",
+			},
+			slos: []lightstep.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					Name:       "slo1",
+					Service:    "svc1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Objective:  99,
+					Labels:     map[string]string{"ls_op": "checkout"},
+				}},
+			},
+			expJSON: `{
+  "streams": [
+    {
+      "name": "svc1-slo1",
+      "query": "service IN (\"svc1\") AND operation IN (\"checkout\")"
+    }
+  ],
+  "slos": [
+    {
+      "name": "slo1",
+      "stream": "svc1-slo1",
+      "indicator": "error_ratio",
+      "target": 99,
+      "timeWindow": {
+        "days": 28
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotJSON bytes.Buffer
+			test.config.Writer = &gotJSON
+			test.config.Logger = log.Noop
+			repo, err := lightstep.NewIOWriterJSONRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expJSON, gotJSON.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterJSONRepoInvalidConfig(t *testing.T) {
+	_, err := lightstep.NewIOWriterJSONRepo(lightstep.IOWriterJSONRepoConfig{
+		Writer:           &bytes.Buffer{},
+		StreamQueryLabel: "ls",
+		OperationLabel:   "ls",
+	})
+	assert.Error(t, err)
+}
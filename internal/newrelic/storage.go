@@ -0,0 +1,323 @@
+package newrelic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/slok/sloth/internal/alert"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLOs will be used when there are no SLOs to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLOs = fmt.Errorf("0 New Relic SLOs generated")
+)
+
+const (
+	defaultValidEventsQueryLabel = "newrelic_valid_events_query"
+	defaultBadEventsQueryLabel   = "newrelic_bad_events_query"
+
+	timeWindowUnitDay        = "DAY"
+	conditionOperatorAbove   = "ABOVE"
+	conditionOccurrencesAll  = "ALL"
+	conditionPriorityPage    = "CRITICAL"
+	conditionPriorityTicket  = "WARNING"
+	conditionAggregationSecs = 60
+	defaultConditionText     = "%s SLO error budget is burning too fast."
+)
+
+// timeWindowDays are the SLO time windows (rolling days) New Relic supports.
+var timeWindowDays = map[time.Duration]int{
+	1 * 24 * time.Hour:  1,
+	7 * 24 * time.Hour:  7,
+	28 * 24 * time.Hour: 28,
+}
+
+// nrqlEventsQueryRegexp matches the NRQL events queries (e.g: `FROM Transaction WHERE appName = 'svc'`).
+var nrqlEventsQueryRegexp = regexp.MustCompile(`(?is)^\s*FROM\s+(\S+)(?:\s+WHERE\s+(.+?))?\s*$`)
+
+type IOWriterJSONRepoConfig struct {
+	Writer io.Writer
+	Logger log.Logger
+	// ValidEventsQueryLabel is the SLO label that has the NRQL query of the valid events
+	// (e.g: `FROM Transaction WHERE appName = 'svc'`), by default `newrelic_valid_events_query`.
+	ValidEventsQueryLabel string
+	// BadEventsQueryLabel is the SLO label that has the NRQL query of the bad events
+	// (e.g: `FROM Transaction WHERE appName = 'svc' AND error IS true`), by default
+	// `newrelic_bad_events_query`.
+	BadEventsQueryLabel string
+}
+
+func (c *IOWriterJSONRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.ValidEventsQueryLabel == "" {
+		c.ValidEventsQueryLabel = defaultValidEventsQueryLabel
+	}
+
+	if c.BadEventsQueryLabel == "" {
+		c.BadEventsQueryLabel = defaultBadEventsQueryLabel
+	}
+
+	if c.ValidEventsQueryLabel == c.BadEventsQueryLabel {
+		return fmt.Errorf("valid and bad events query labels can't be the same")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "json", "flavor": "newrelic"})
+
+	return nil
+}
+
+// IOWriterJSONRepo knows to store the SLOs in an IOWriter as a JSON document with the New Relic
+// service level and NRQL alert condition definitions. New Relic doesn't use PromQL, so the NRQL
+// queries are taken from the SLO labels instead of the SLI and the generated Prometheus rules.
+type IOWriterJSONRepo struct {
+	writer              io.Writer
+	validEventsQueryLbl string
+	badEventsQueryLbl   string
+	logger              log.Logger
+}
+
+func NewIOWriterJSONRepo(config IOWriterJSONRepoConfig) (*IOWriterJSONRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &IOWriterJSONRepo{
+		writer:              config.Writer,
+		validEventsQueryLbl: config.ValidEventsQueryLabel,
+		badEventsQueryLbl:   config.BadEventsQueryLabel,
+		logger:              config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO prometheus.SLO
+	// Alerts are the SLO alerts that will be used to create the New Relic alert conditions.
+	Alerts alert.MWMBAlertGroup
+}
+
+// StoreSLOs will store the SLOs as New Relic service levels and NRQL alert conditions definitions.
+func (i IOWriterJSONRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	if len(slos) == 0 {
+		return ErrNoSLOs
+	}
+
+	doc := newRelicJSON{
+		ServiceLevels:   []serviceLevelJSON{},
+		AlertConditions: []alertConditionJSON{},
+	}
+	for _, slo := range slos {
+		sl, err := i.mapModelToServiceLevel(slo.SLO)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to New Relic: %w", slo.SLO.ID, err)
+		}
+		doc.ServiceLevels = append(doc.ServiceLevels, *sl)
+		doc.AlertConditions = append(doc.AlertConditions, mapModelToAlertConditions(slo, sl.Events)...)
+	}
+
+	// Don't escape the HTML characters, the queries could have comparison operators.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("could not format SLOs: %w", err)
+	}
+
+	_, err = i.writer.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write SLOs: %w", err)
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{"slos": len(doc.ServiceLevels), "alert-conditions": len(doc.AlertConditions)}).Infof("New Relic SLOs written")
+
+	return nil
+}
+
+func (i IOWriterJSONRepo) mapModelToServiceLevel(slo prometheus.SLO) (*serviceLevelJSON, error) {
+	valid, err := parseEventsQuery(slo.Labels, i.validEventsQueryLbl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid New Relic valid events query: %w", err)
+	}
+
+	bad, err := parseEventsQuery(slo.Labels, i.badEventsQueryLbl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid New Relic bad events query: %w", err)
+	}
+
+	// The alert conditions query the error ratio of the events in a single NRQL query.
+	if bad.From != valid.From {
+		return nil, fmt.Errorf("bad events %q type must be the same as the valid events %q type", bad.From, valid.From)
+	}
+	if bad.Where == "" {
+		return nil, fmt.Errorf("bad events query requires a WHERE clause")
+	}
+
+	days, ok := timeWindowDays[slo.TimeWindow]
+	if !ok {
+		return nil, fmt.Errorf("unsupported %s time window, New Relic only supports 1d, 7d and 28d", slo.TimeWindow)
+	}
+
+	return &serviceLevelJSON{
+		Name:        slo.Name,
+		Description: slo.Description,
+		Events: serviceLevelEventsJSON{
+			ValidEvents: valid,
+			BadEvents:   bad,
+		},
+		Objectives: []serviceLevelObjectiveJSON{
+			{
+				Target: slo.Objective,
+				TimeWindow: serviceLevelTimeWindowJSON{
+					Rolling: serviceLevelRollingJSON{Count: days, Unit: timeWindowUnitDay},
+				},
+			},
+		},
+	}, nil
+}
+
+// parseEventsQuery gets the NRQL events query of the SLO label and splits it in the
+// event type (`FROM`) and the optional condition (`WHERE`).
+func parseEventsQuery(labels map[string]string, label string) (eventsJSON, error) {
+	query := labels[label]
+	if query == "" {
+		return eventsJSON{}, fmt.Errorf("missing NRQL query, %q label is required", label)
+	}
+
+	match := nrqlEventsQueryRegexp.FindStringSubmatch(query)
+	if match == nil {
+		return eventsJSON{}, fmt.Errorf("%q NRQL query must have the 'FROM <event type> [WHERE <condition>]' format", query)
+	}
+
+	return eventsJSON{From: match[1], Where: match[2]}, nil
+}
+
+// mapModelToAlertConditions maps the page and ticket alerts of the SLO to New Relic NRQL alert
+// conditions on the SLO error percentage, using the long window and burn rate of the quick alerts.
+func mapModelToAlertConditions(slo StorageSLO, events serviceLevelEventsJSON) []alertConditionJSON {
+	query := fmt.Sprintf("SELECT percentage(count(*), WHERE %s) FROM %s", events.BadEvents.Where, events.ValidEvents.From)
+	if events.ValidEvents.Where != "" {
+		query = fmt.Sprintf("%s WHERE %s", query, events.ValidEvents.Where)
+	}
+
+	conditions := []alertConditionJSON{}
+	alerts := []struct {
+		meta     prometheus.AlertMeta
+		alert    alert.MWMBAlert
+		priority string
+	}{
+		{meta: slo.SLO.PageAlertMeta, alert: slo.Alerts.PageQuick, priority: conditionPriorityPage},
+		{meta: slo.SLO.TicketAlertMeta, alert: slo.Alerts.TicketQuick, priority: conditionPriorityTicket},
+	}
+	for _, a := range alerts {
+		if a.meta.Disable {
+			continue
+		}
+
+		// The error budget is already a percentage, round to remove float multiplication artifacts.
+		threshold := math.Round(a.alert.BurnRateFactor*a.alert.ErrorBudget*1e10) / 1e10
+		description := a.meta.Annotations["summary"]
+		if description == "" {
+			description = fmt.Sprintf(defaultConditionText, slo.SLO.Name)
+		}
+
+		conditions = append(conditions, alertConditionJSON{
+			Name:        a.meta.Name,
+			Description: description,
+			Enabled:     true,
+			NRQL:        alertConditionNRQLJSON{Query: query},
+			Signal:      alertConditionSignalJSON{AggregationWindow: conditionAggregationSecs},
+			Terms: []alertConditionTermJSON{
+				{
+					Operator:             conditionOperatorAbove,
+					Priority:             a.priority,
+					Threshold:            threshold,
+					ThresholdDuration:    int(a.alert.LongWindow / time.Second),
+					ThresholdOccurrences: conditionOccurrencesAll,
+				},
+			},
+		})
+	}
+
+	return conditions
+}
+
+type newRelicJSON struct {
+	ServiceLevels   []serviceLevelJSON   `json:"serviceLevels"`
+	AlertConditions []alertConditionJSON `json:"alertConditions"`
+}
+
+// serviceLevelJSON is the New Relic NerdGraph service level definition.
+type serviceLevelJSON struct {
+	Name        string                      `json:"name"`
+	Description string                      `json:"description,omitempty"`
+	Events      serviceLevelEventsJSON      `json:"events"`
+	Objectives  []serviceLevelObjectiveJSON `json:"objectives"`
+}
+
+type serviceLevelEventsJSON struct {
+	ValidEvents eventsJSON `json:"validEvents"`
+	BadEvents   eventsJSON `json:"badEvents"`
+}
+
+type eventsJSON struct {
+	From  string `json:"from"`
+	Where string `json:"where,omitempty"`
+}
+
+type serviceLevelObjectiveJSON struct {
+	Target     float64                    `json:"target"`
+	TimeWindow serviceLevelTimeWindowJSON `json:"timeWindow"`
+}
+
+type serviceLevelTimeWindowJSON struct {
+	Rolling serviceLevelRollingJSON `json:"rolling"`
+}
+
+type serviceLevelRollingJSON struct {
+	Count int    `json:"count"`
+	Unit  string `json:"unit"`
+}
+
+// alertConditionJSON is the New Relic NerdGraph static NRQL alert condition definition.
+type alertConditionJSON struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Enabled     bool                     `json:"enabled"`
+	NRQL        alertConditionNRQLJSON   `json:"nrql"`
+	Signal      alertConditionSignalJSON `json:"signal"`
+	Terms       []alertConditionTermJSON `json:"terms"`
+}
+
+type alertConditionNRQLJSON struct {
+	Query string `json:"query"`
+}
+
+type alertConditionSignalJSON struct {
+	AggregationWindow int `json:"aggregationWindow"`
+}
+
+type alertConditionTermJSON struct {
+	Operator             string  `json:"operator"`
+	Priority             string  `json:"priority"`
+	Threshold            float64 `json:"threshold"`
+	ThresholdDuration    int     `json:"thresholdDuration"`
+	ThresholdOccurrences string  `json:"thresholdOccurrences"`
+}
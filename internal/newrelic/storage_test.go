@@ -0,0 +1,347 @@
+package newrelic_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sloth/internal/alert"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/newrelic"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func getAlertGroup() alert.MWMBAlertGroup {
+	return alert.MWMBAlertGroup{
+		PageQuick: alert.MWMBAlert{
+			ShortWindow:    5 * time.Minute,
+			LongWindow:     time.Hour,
+			BurnRateFactor: 14.4,
+			ErrorBudget:    0.1,
+			Severity:       alert.PageAlertSeverity,
+		},
+		TicketQuick: alert.MWMBAlert{
+			ShortWindow:    2 * time.Hour,
+			LongWindow:     6 * time.Hour,
+			BurnRateFactor: 6,
+			ErrorBudget:    0.1,
+			Severity:       alert.TicketAlertSeverity,
+		},
+	}
+}
+
+func TestIOWriterJSONRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config  newrelic.IOWriterJSONRepoConfig
+		slos    []newrelic.StorageSLO
+		expJSON string
+		expErr  bool
+	}{
+		"Having 0 SLOs should fail.": {
+			slos:   []newrelic.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having an SLO without the New Relic valid events query should fail.": {
+			slos: []newrelic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Labels:     map[string]string{"newrelic_bad_events_query": "FROM Transaction WHERE error IS true"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO without the New Relic bad events query should fail.": {
+			slos: []newrelic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Labels:     map[string]string{"newrelic_valid_events_query": "FROM Transaction"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with an invalid NRQL events query should fail.": {
+			slos: []newrelic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Labels: map[string]string{
+						"newrelic_valid_events_query": "SELECT count(*) FROM Transaction",
+						"newrelic_bad_events_query":   "FROM Transaction WHERE error IS true",
+					},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with different valid and bad events types should fail.": {
+			slos: []newrelic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Labels: map[string]string{
+						"newrelic_valid_events_query": "FROM Transaction",
+						"newrelic_bad_events_query":   "FROM TransactionError WHERE error IS true",
+					},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with a bad events query without condition should fail.": {
+			slos: []newrelic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Labels: map[string]string{
+						"newrelic_valid_events_query": "FROM Transaction",
+						"newrelic_bad_events_query":   "FROM Transaction",
+					},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with a time window not supported by New Relic should fail.": {
+			slos: []newrelic.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels: map[string]string{
+						"newrelic_valid_events_query": "FROM Transaction",
+						"newrelic_bad_events_query":   "FROM Transaction WHERE error IS true",
+					},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs should render the New Relic service levels and their alert conditions.": {
+			slos: []newrelic.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:          "svc1-slo1",
+						Name:        "slo1",
+						Description: "Test SLO 1.",
+						Service:     "svc1",
+						TimeWindow:  28 * 24 * time.Hour,
+						Objective:   99.9,
+						Labels: map[string]string{
+							"owner":                       "team-a",
+							"newrelic_valid_events_query": "FROM Transaction WHERE appName = 'svc1'",
+							"newrelic_bad_events_query":   "from Transaction where appName = 'svc1' AND httpResponseCode >= 500",
+						},
+						PageAlertMeta: prometheus.AlertMeta{
+							Name:        "Svc1SLO1HighErrorRate",
+							Annotations: map[string]string{"summary": "Svc1 is burning the error budget."},
+						},
+						TicketAlertMeta: prometheus.AlertMeta{
+							Name: "Svc1SLO1ErrorBudgetBurn",
+						},
+					},
+					Alerts: getAlertGroup(),
+				},
+				{
+					SLO: prometheus.SLO{
+						ID:         "svc1-slo2",
+						Name:       "slo2",
+						Service:    "svc1",
+						TimeWindow: 7 * 24 * time.Hour,
+						Objective:  99,
+						Labels: map[string]string{
+							"newrelic_valid_events_query": "FROM SyntheticCheck",
+							"newrelic_bad_events_query":   "FROM SyntheticCheck WHERE result != 'SUCCESS'",
+						},
+						PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+						TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+					},
+				},
+			},
+			expJSON: `{
+  "serviceLevels": [
+    {
+      "name": "slo1",
+      "description": "Test SLO 1.",
+      "events": {
+        "validEvents": {
+          "from": "Transaction",
+          "where": "appName = 'svc1'"
+        },
+        "badEvents": {
+          "from": "Transaction",
+          "where": "appName = 'svc1' AND httpResponseCode >= 500"
+        }
+      },
+      "objectives": [
+        {
+          "target": 99.9,
+          "timeWindow": {
+            "rolling": {
+              "count": 28,
+              "unit": "DAY"
+            }
+          }
+        }
+      ]
+    },
+    {
+      "name": "slo2",
+      "events": {
+        "validEvents": {
+          "from": "SyntheticCheck"
+        },
+        "badEvents": {
+          "from": "SyntheticCheck",
+          "where": "result != 'SUCCESS'"
+        }
+      },
+      "objectives": [
+        {
+          "target": 99,
+          "timeWindow": {
+            "rolling": {
+              "count": 7,
+              "unit": "DAY"
+            }
+          }
+        }
+      ]
+    }
+  ],
+  "alertConditions": [
+    {
+      "name": "Svc1SLO1HighErrorRate",
+      "description": "Svc1 is burning the error budget.",
+      "enabled": true,
+      "nrql": {
+        "query": "SELECT percentage(count(*), WHERE appName = 'svc1' AND httpResponseCode >= 500) FROM Transaction WHERE appName = 'svc1'"
+      },
+      "signal": {
+        "aggregationWindow": 60
+      },
+      "terms": [
+        {
+          "operator": "ABOVE",
+          "priority": "CRITICAL",
+          "threshold": 1.44,
+          "thresholdDuration": 3600,
+          "thresholdOccurrences": "ALL"
+        }
+      ]
+    },
+    {
+      "name": "Svc1SLO1ErrorBudgetBurn",
+      "description": "slo1 SLO error budget is burning too fast.",
+      "enabled": true,
+      "nrql": {
+        "query": "SELECT percentage(count(*), WHERE appName = 'svc1' AND httpResponseCode >= 500) FROM Transaction WHERE appName = 'svc1'"
+      },
+      "signal": {
+        "aggregationWindow": 60
+      },
+      "terms": [
+        {
+          "operator": "ABOVE",
+          "priority": "WARNING",
+          "threshold": 0.6,
+          "thresholdDuration": 21600,
+          "thresholdOccurrences": "ALL"
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+
+		"Having custom query labels should use them to get the NRQL queries.": {
+			config: newrelic.IOWriterJSONRepoConfig{
+				ValidEventsQueryLabel: "nr_valid",
+				BadEventsQueryLabel:   "nr_bad",
+			},
+			slos: []newrelic.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:         "svc1-slo1",
+						Name:       "slo1",
+						Service:    "svc1",
+						TimeWindow: 24 * time.Hour,
+						Objective:  95,
+						Labels: map[string]string{
+							"nr_valid": "FROM Transaction",
+							"nr_bad":   "FROM Transaction WHERE error IS true",
+						},
+						PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+						TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+					},
+				},
+			},
+			expJSON: `{
+  "serviceLevels": [
+    {
+      "name": "slo1",
+      "events": {
+        "validEvents": {
+          "from": "Transaction"
+        },
+        "badEvents": {
+          "from": "Transaction",
+          "where": "error IS true"
+        }
+      },
+      "objectives": [
+        {
+          "target": 95,
+          "timeWindow": {
+            "rolling": {
+              "count": 1,
+              "unit": "DAY"
+            }
+          }
+        }
+      ]
+    }
+  ],
+  "alertConditions": []
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotJSON bytes.Buffer
+			test.config.Writer = &gotJSON
+			test.config.Logger = log.Noop
+			repo, err := newrelic.NewIOWriterJSONRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expJSON, gotJSON.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterJSONRepoInvalidConfig(t *testing.T) {
+	_, err := newrelic.NewIOWriterJSONRepo(newrelic.IOWriterJSONRepoConfig{
+		Writer:                &bytes.Buffer{},
+		ValidEventsQueryLabel: "nr_query",
+		BadEventsQueryLabel:   "nr_query",
+	})
+	assert.Error(t, err)
+}
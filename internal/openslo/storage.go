@@ -0,0 +1,276 @@
+package openslo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/slok/sloth/internal/info"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLOs will be used when there are no SLOs to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLOs = fmt.Errorf("0 OpenSLO SLOs generated")
+)
+
+const (
+	// APIVersion is the OpenSLO version of the generated documents.
+	APIVersion = "openslo/v1"
+
+	metricSourceTypePrometheus = "Prometheus"
+)
+
+type IOWriterYAMLRepoConfig struct {
+	Writer io.Writer
+	Logger log.Logger
+	// DisableDisclaimer will disable the top disclaimer of the generated SLOs.
+	DisableDisclaimer bool
+	// DisclaimerVersion is the version that will be set on the disclaimer, by default
+	// the Sloth version.
+	DisclaimerVersion string
+	// DisableDisclaimerVersion will remove the version from the disclaimer, this is useful
+	// to have reproducible outputs between Sloth versions.
+	DisableDisclaimerVersion bool
+}
+
+func (c *IOWriterYAMLRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.DisableDisclaimerVersion {
+		c.DisclaimerVersion = ""
+	} else if c.DisclaimerVersion == "" {
+		c.DisclaimerVersion = info.Version
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "yaml", "flavor": "openslo"})
+
+	return nil
+}
+
+// IOWriterYAMLRepo knows to store the SLOs in an IOWriter as OpenSLO v1 YAML documents. OpenSLO
+// describes the objectives, not the rules, so the stored documents are independent of the
+// generated Prometheus rules.
+type IOWriterYAMLRepo struct {
+	writer            io.Writer
+	disableDisclaimer bool
+	disclaimerVersion string
+	logger            log.Logger
+}
+
+func NewIOWriterYAMLRepo(config IOWriterYAMLRepoConfig) (*IOWriterYAMLRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &IOWriterYAMLRepo{
+		writer:            config.Writer,
+		disableDisclaimer: config.DisableDisclaimer,
+		disclaimerVersion: config.DisclaimerVersion,
+		logger:            config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO prometheus.SLO
+}
+
+// StoreSLOs will store the SLOs as OpenSLO SLO documents, one for each SLO.
+func (i IOWriterYAMLRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	if len(slos) == 0 {
+		return ErrNoSLOs
+	}
+
+	out := []byte{}
+	for _, slo := range slos {
+		s, err := mapModelToSpec(slo.SLO)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to OpenSLO: %w", slo.SLO.ID, err)
+		}
+
+		sloYAML, err := yaml.Marshal(s)
+		if err != nil {
+			return fmt.Errorf("could not format %q SLO: %w", slo.SLO.ID, err)
+		}
+		out = append(out, []byte("---\n")...)
+		out = append(out, sloYAML...)
+	}
+
+	if !i.disableDisclaimer {
+		out = writeTopDisclaimer(out, i.disclaimerVersion)
+	}
+
+	_, err := i.writer.Write(out)
+	if err != nil {
+		return fmt.Errorf("could not write SLOs: %w", err)
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{"slos": len(slos)}).Infof("OpenSLO SLOs written")
+
+	return nil
+}
+
+// mapModelToSpec maps a Sloth SLO to an OpenSLO SLO with an inline ratio indicator. The SLI
+// queries are stored as they are, so they can have the Sloth `{{.window}}` template variable.
+func mapModelToSpec(slo prometheus.SLO) (*sloV1, error) {
+	indicator, err := mapSLIToRatioMetric(slo.SLI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sloV1{
+		APIVersion: APIVersion,
+		Kind:       "SLO",
+		Metadata: metadataV1{
+			Name:        slo.ID,
+			DisplayName: slo.Name,
+			Labels:      slo.Labels,
+		},
+		Spec: sloSpecV1{
+			Description:     slo.Description,
+			Service:         slo.Service,
+			BudgetingMethod: "Occurrences",
+			TimeWindow: []timeWindowV1{
+				{Duration: durationToOpenSLO(slo.TimeWindow), IsRolling: true},
+			},
+			Indicator: &indicatorV1{
+				Metadata: metadataV1{Name: fmt.Sprintf("%s-sli", slo.ID)},
+				Spec:     indicatorSpecV1{RatioMetric: indicator},
+			},
+			Objectives: []objectiveV1{
+				// OpenSLO uses ratios, we use percents (round to remove float division artifacts).
+				{DisplayName: slo.Name, Target: math.Round(slo.Objective/100*1e10) / 1e10},
+			},
+		},
+	}, nil
+}
+
+func mapSLIToRatioMetric(sli prometheus.SLI) (*ratioMetricV1, error) {
+	switch {
+	case sli.Events != nil:
+		return &ratioMetricV1{
+			Counter: true,
+			Bad:     newPrometheusMetric(sli.Events.ErrorQuery),
+			Total:   newPrometheusMetric(sli.Events.TotalQuery),
+		}, nil
+	case sli.Raw != nil:
+		return &ratioMetricV1{
+			RawType: "failure",
+			Raw:     newPrometheusMetric(sli.Raw.ErrorRatioQuery),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("missing SLI")
+}
+
+func newPrometheusMetric(query string) *metricV1 {
+	return &metricV1{
+		MetricSource: metricSourceV1{
+			Type: metricSourceTypePrometheus,
+			Spec: map[string]string{"query": query},
+		},
+	}
+}
+
+// durationToOpenSLO returns the duration with the OpenSLO shorthand format (e.g: `30d`)
+// using the biggest unit that represents the duration exactly.
+func durationToOpenSLO(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+const disclaimerFmt = `# Code generated by Sloth%s: https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+`
+
+// writeTopDisclaimer prepends the disclaimer, if the version is empty it will be omitted.
+func writeTopDisclaimer(bs []byte, version string) []byte {
+	if version != "" {
+		version = fmt.Sprintf(" (%s)", version)
+	}
+	disclaimer := fmt.Sprintf(disclaimerFmt, version)
+
+	return append([]byte(disclaimer), bs...)
+}
+
+// these types are defined to support OpenSLO v1, the OpenSLO library we use
+// only supports v1alpha.
+type sloV1 struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   metadataV1 `yaml:"metadata"`
+	Spec       sloSpecV1  `yaml:"spec"`
+}
+
+type metadataV1 struct {
+	Name        string            `yaml:"name"`
+	DisplayName string            `yaml:"displayName,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+}
+
+type sloSpecV1 struct {
+	Description     string         `yaml:"description,omitempty"`
+	Service         string         `yaml:"service"`
+	Indicator       *indicatorV1   `yaml:"indicator"`
+	TimeWindow      []timeWindowV1 `yaml:"timeWindow"`
+	BudgetingMethod string         `yaml:"budgetingMethod"`
+	Objectives      []objectiveV1  `yaml:"objectives"`
+}
+
+type indicatorV1 struct {
+	Metadata metadataV1      `yaml:"metadata"`
+	Spec     indicatorSpecV1 `yaml:"spec"`
+}
+
+type indicatorSpecV1 struct {
+	RatioMetric *ratioMetricV1 `yaml:"ratioMetric"`
+}
+
+type ratioMetricV1 struct {
+	Counter bool      `yaml:"counter"`
+	RawType string    `yaml:"rawType,omitempty"`
+	Raw     *metricV1 `yaml:"raw,omitempty"`
+	Bad     *metricV1 `yaml:"bad,omitempty"`
+	Total   *metricV1 `yaml:"total,omitempty"`
+}
+
+type metricV1 struct {
+	MetricSource metricSourceV1 `yaml:"metricSource"`
+}
+
+type metricSourceV1 struct {
+	Type string            `yaml:"type"`
+	Spec map[string]string `yaml:"spec"`
+}
+
+type timeWindowV1 struct {
+	Duration  string `yaml:"duration"`
+	IsRolling bool   `yaml:"isRolling"`
+}
+
+type objectiveV1 struct {
+	DisplayName string  `yaml:"displayName,omitempty"`
+	Target      float64 `yaml:"target"`
+}
@@ -0,0 +1,282 @@
+package openslo_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/openslo"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestIOWriterYAMLRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config  openslo.IOWriterYAMLRepoConfig
+		slos    []openslo.StorageSLO
+		expYAML string
+		expErr  bool
+	}{
+		"Having 0 SLOs should fail.": {
+			slos:   []openslo.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having an SLO without SLI should fail.": {
+			slos: []openslo.StorageSLO{
+				{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs with events and raw SLIs should render OpenSLO SLOs.": {
+			slos: []openslo.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:          "svc1-slo1",
+						Name:        "slo1",
+						Description: "Test SLO 1.",
+						Service:     "svc1",
+						TimeWindow:  30 * 24 * time.Hour,
+						Objective:   99.9,
+						Labels:      map[string]string{"owner": "team-a"},
+						SLI: prometheus.SLI{
+							Events: &prometheus.SLIEvents{
+								ErrorQuery: `sum(rate(http_requests_total{code=~"5.."}[{{.window}}]))`,
+								TotalQuery: `sum(rate(http_requests_total[{{.window}}]))`,
+							},
+						},
+					},
+				},
+				{
+					SLO: prometheus.SLO{
+						ID:         "svc1-slo2",
+						Name:       "slo2",
+						Service:    "svc1",
+						TimeWindow: 7 * 24 * time.Hour,
+						Objective:  95,
+						SLI: prometheus.SLI{
+							Raw: &prometheus.SLIRaw{
+								ErrorRatioQuery: `test:error_ratio:rate{{.window}}`,
+							},
+						},
+					},
+				},
+			},
+			expYAML: `# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+---
+apiVersion: openslo/v1
+kind: SLO
+metadata:
+  name: svc1-slo1
+  displayName: slo1
+  labels:
+    owner: team-a
+spec:
+  description: Test SLO 1.
+  service: svc1
+  indicator:
+    metadata:
+      name: svc1-slo1-sli
+    spec:
+      ratioMetric:
+        counter: true
+        bad:
+          metricSource:
+            type: Prometheus
+            spec:
+              query: sum(rate(http_requests_total{code=~"5.."}[{{.window}}]))
+        total:
+          metricSource:
+            type: Prometheus
+            spec:
+              query: sum(rate(http_requests_total[{{.window}}]))
+  timeWindow:
+  - duration: 30d
+    isRolling: true
+  budgetingMethod: Occurrences
+  objectives:
+  - displayName: slo1
+    target: 0.999
+---
+apiVersion: openslo/v1
+kind: SLO
+metadata:
+  name: svc1-slo2
+  displayName: slo2
+spec:
+  service: svc1
+  indicator:
+    metadata:
+      name: svc1-slo2-sli
+    spec:
+      ratioMetric:
+        counter: false
+        rawType: failure
+        raw:
+          metricSource:
+            type: Prometheus
+            spec:
+              query: test:error_ratio:rate{{.window}}
+  timeWindow:
+  - duration: 7d
+    isRolling: true
+  budgetingMethod: Occurrences
+  objectives:
+  - displayName: slo2
+    target: 0.95
+`,
+		},
+
+		"Having the disclaimer disabled should render the SLOs without it.": {
+			config: openslo.IOWriterYAMLRepoConfig{
+				DisableDisclaimer: true,
+			},
+			slos: []openslo.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:         "svc1-slo1",
+						Name:       "slo1",
+						Service:    "svc1",
+						TimeWindow: 36 * time.Hour,
+						Objective:  99,
+						SLI:        prometheus.SLI{Raw: &prometheus.SLIRaw{ErrorRatioQuery: "test"}},
+					},
+				},
+			},
+			expYAML: `---
+apiVersion: openslo/v1
+kind: SLO
+metadata:
+  name: svc1-slo1
+  displayName: slo1
+spec:
+  service: svc1
+  indicator:
+    metadata:
+      name: svc1-slo1-sli
+    spec:
+      ratioMetric:
+        counter: false
+        rawType: failure
+        raw:
+          metricSource:
+            type: Prometheus
+            spec:
+              query: test
+  timeWindow:
+  - duration: 36h
+    isRolling: true
+  budgetingMethod: Occurrences
+  objectives:
+  - displayName: slo1
+    target: 0.99
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotYAML bytes.Buffer
+			test.config.Writer = &gotYAML
+			test.config.Logger = log.Noop
+			repo, err := openslo.NewIOWriterYAMLRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expYAML, gotYAML.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterYAMLRepoStoreOpenSLOSchema(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var gotYAML bytes.Buffer
+	repo, err := openslo.NewIOWriterYAMLRepo(openslo.IOWriterYAMLRepoConfig{Writer: &gotYAML, Logger: log.Noop})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), []openslo.StorageSLO{
+		{
+			SLO: prometheus.SLO{
+				ID:         "svc1-slo1",
+				Name:       "slo1",
+				Service:    "svc1",
+				TimeWindow: 30 * 24 * time.Hour,
+				Objective:  99.5,
+				SLI: prometheus.SLI{Events: &prometheus.SLIEvents{
+					ErrorQuery: "errors",
+					TotalQuery: "total",
+				}},
+			},
+		},
+	})
+	require.NoError(err)
+
+	// Check the OpenSLO v1 SLO schema fields that the consumers rely on.
+	docs := strings.Split(gotYAML.String(), "---\n")
+	require.Len(docs, 2)
+	doc := struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+		Spec struct {
+			Service   string `yaml:"service"`
+			Indicator struct {
+				Spec struct {
+					RatioMetric struct {
+						Counter bool `yaml:"counter"`
+						Bad     struct {
+							MetricSource struct {
+								Type string            `yaml:"type"`
+								Spec map[string]string `yaml:"spec"`
+							} `yaml:"metricSource"`
+						} `yaml:"bad"`
+					} `yaml:"ratioMetric"`
+				} `yaml:"spec"`
+			} `yaml:"indicator"`
+			TimeWindow []struct {
+				Duration  string `yaml:"duration"`
+				IsRolling bool   `yaml:"isRolling"`
+			} `yaml:"timeWindow"`
+			BudgetingMethod string `yaml:"budgetingMethod"`
+			Objectives      []struct {
+				Target float64 `yaml:"target"`
+			} `yaml:"objectives"`
+		} `yaml:"spec"`
+	}{}
+	err = yaml.Unmarshal([]byte(docs[1]), &doc)
+	require.NoError(err)
+
+	assert.Equal("openslo/v1", doc.APIVersion)
+	assert.Equal("SLO", doc.Kind)
+	assert.Equal("svc1-slo1", doc.Metadata.Name)
+	assert.Equal("svc1", doc.Spec.Service)
+	assert.True(doc.Spec.Indicator.Spec.RatioMetric.Counter)
+	assert.Equal("Prometheus", doc.Spec.Indicator.Spec.RatioMetric.Bad.MetricSource.Type)
+	assert.Equal("errors", doc.Spec.Indicator.Spec.RatioMetric.Bad.MetricSource.Spec["query"])
+	require.Len(doc.Spec.TimeWindow, 1)
+	assert.Equal("30d", doc.Spec.TimeWindow[0].Duration)
+	assert.True(doc.Spec.TimeWindow[0].IsRolling)
+	assert.Contains([]string{"Occurrences", "Timeslices"}, doc.Spec.BudgetingMethod)
+	require.Len(doc.Spec.Objectives, 1)
+	assert.Equal(0.995, doc.Spec.Objectives[0].Target)
+}
@@ -2,7 +2,18 @@ package prometheus
 
 const (
 	// Metrics.
-	sliErrorMetricFmt = "slo:sli_error:ratio_rate%s"
+	sliErrorMetricFmt    = "slo:sli_error:ratio_rate%s"
+	sliErrorMetricPrefix = "slo:sli_error:"
+
+	// Metadata metrics.
+	metricSLOObjectiveRatio                  = "slo:objective:ratio"
+	metricSLOErrorBudgetRatio                = "slo:error_budget:ratio"
+	metricSLOTimePeriodDays                  = "slo:time_period:days"
+	metricSLOCurrentBurnRateRatio            = "slo:current_burn_rate:ratio"
+	metricSLOPeriodBurnRateRatio             = "slo:period_burn_rate:ratio"
+	metricSLOPeriodErrorBudgetRemainingRatio = "slo:period_error_budget_remaining:ratio"
+	metricSLOInfo                            = "sloth_slo_info"
+	metricSLOIndexInfo                       = "sloth_slo_index_info"
 
 	// Labels.
 	sloNameLabelName      = "sloth_slo"
@@ -0,0 +1,174 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DiffResult is the result of comparing the generated rules with the existing ones.
+type DiffResult struct {
+	// Changed is true when the generated rules are different from the existing ones.
+	Changed bool
+	// Diff is the unified diff between the existing and the generated rules, empty
+	// if there aren't changes.
+	Diff string
+	// Rules are the generated rules.
+	Rules []byte
+}
+
+// DiffFile compares the rules with the ones of the file on the path, if the file
+// doesn't exist all the rules will be handled as new.
+func DiffFile(path string, rules []byte) (*DiffResult, error) {
+	fromFile := path
+	current, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("could not read %q file: %w", path, err)
+		}
+		fromFile = "/dev/null"
+	}
+
+	res := &DiffResult{Rules: rules}
+	if bytes.Equal(current, rules) {
+		return res, nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(current),
+		B:        splitLines(rules),
+		FromFile: fromFile,
+		ToFile:   path,
+		Context:  3,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not diff rules: %w", err)
+	}
+	res.Changed = true
+	res.Diff = diff
+
+	return res, nil
+}
+
+// splitLines splits the content in lines keeping the line endings.
+func splitLines(bs []byte) []string {
+	lines := strings.SplitAfter(string(bs), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// SLOHashes are the content hashes of the SLOs by SLO ID, these can be stored as
+// the manifest of a generation to know the SLOs that changed on the next one.
+type SLOHashes map[string]string
+
+// HashStorageSLO returns a stable content hash of the SLO, its rules and its storage
+// options, the same SLO will have the same hash between generations.
+func HashStorageSLO(slo StorageSLO) (string, error) {
+	// JSON encoding is stable, the map keys are sorted.
+	data, err := json.Marshal(slo)
+	if err != nil {
+		return "", fmt.Errorf("could not encode %q SLO: %w", slo.SLO.ID, err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HashSLOs returns the content hashes of the SLOs.
+func HashSLOs(slos []StorageSLO) (SLOHashes, error) {
+	hashes := SLOHashes{}
+	for _, slo := range slos {
+		if _, ok := hashes[slo.SLO.ID]; ok {
+			return nil, fmt.Errorf("duplicated %q SLO", slo.SLO.ID)
+		}
+
+		h, err := HashStorageSLO(slo)
+		if err != nil {
+			return nil, err
+		}
+		hashes[slo.SLO.ID] = h
+	}
+
+	return hashes, nil
+}
+
+// ChangedSLOsResult is the result of storing only the changed SLOs of a generation.
+type ChangedSLOsResult struct {
+	// StoreResult is the result of the stored rules, nil if there weren't changes.
+	StoreResult *StoreResult
+	// Added are the IDs of the new SLOs.
+	Added []string
+	// Changed are the IDs of the SLOs that are different from the previous generation.
+	Changed []string
+	// Unchanged are the IDs of the SLOs that are the same as the previous generation,
+	// these rules haven't been stored.
+	Unchanged []string
+	// Removed are the IDs of the previous generation SLOs that are missing, the callers
+	// can use them to delete the stale rule groups.
+	Removed []string
+	// Hashes are the hashes of all the SLOs of the generation, to be used as the previous
+	// ones on the next generation.
+	Hashes SLOHashes
+}
+
+// StoreChangedSLOs is like StoreSLOsResult but it will only store the rules of the SLOs that have
+// been added or changed since the previous generation hashes. Without changes, nothing will be
+// written. The IDs on the result are sorted.
+func (i IOWriterGroupedRulesYAMLRepo) StoreChangedSLOs(ctx context.Context, previous SLOHashes, slos []StorageSLO) (*ChangedSLOsResult, error) {
+	hashes, err := HashSLOs(slos)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash SLOs: %w", err)
+	}
+
+	res := &ChangedSLOsResult{Hashes: hashes}
+	changedSLOs := []StorageSLO{}
+	for _, slo := range slos {
+		id := slo.SLO.ID
+		prevHash, ok := previous[id]
+		switch {
+		case !ok:
+			res.Added = append(res.Added, id)
+		case prevHash != hashes[id]:
+			res.Changed = append(res.Changed, id)
+		default:
+			res.Unchanged = append(res.Unchanged, id)
+			continue
+		}
+		changedSLOs = append(changedSLOs, slo)
+	}
+
+	for id := range previous {
+		if _, ok := hashes[id]; !ok {
+			res.Removed = append(res.Removed, id)
+		}
+	}
+
+	sort.Strings(res.Added)
+	sort.Strings(res.Changed)
+	sort.Strings(res.Unchanged)
+	sort.Strings(res.Removed)
+
+	if len(changedSLOs) == 0 {
+		return res, nil
+	}
+
+	res.StoreResult, err = i.StoreSLOsResult(ctx, changedSLOs)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
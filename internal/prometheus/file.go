@@ -0,0 +1,135 @@
+package prometheus
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultAtomicFileMode fs.FileMode = 0o644
+
+// AtomicFileWriter is an io.Writer that writes in a temporary file on the same directory of
+// the destination file and replaces the destination with it on commit, this way the readers
+// of the destination (e.g: Prometheus reloading the rules) never see a partially written file.
+// The destination file permissions are preserved.
+//
+// Can be used as the writer of any IOWriter repository.
+type AtomicFileWriter struct {
+	path string
+	mode fs.FileMode
+	tmp  *os.File
+	done bool
+}
+
+// NewAtomicFileWriter returns a new AtomicFileWriter for the destination path. Commit
+// must be called once all the data has been written, or Abort to discard it.
+func NewAtomicFileWriter(path string) (*AtomicFileWriter, error) {
+	mode := defaultAtomicFileMode
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%q is not a regular file", path)
+		}
+		mode = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("could not stat %q file: %w", path, err)
+	}
+
+	// Use the same directory so the rename is in the same filesystem.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary file: %w", err)
+	}
+
+	return &AtomicFileWriter{
+		path: path,
+		mode: mode,
+		tmp:  tmp,
+	}, nil
+}
+
+// Write satisfies io.Writer interface.
+func (a *AtomicFileWriter) Write(p []byte) (int, error) {
+	if a.done {
+		return 0, fmt.Errorf("atomic file writer is closed")
+	}
+
+	return a.tmp.Write(p)
+}
+
+// Commit replaces the destination file with the written data. On error the destination
+// file is unchanged and the temporary file removed.
+func (a *AtomicFileWriter) Commit() error {
+	if a.done {
+		return fmt.Errorf("atomic file writer is closed")
+	}
+	a.done = true
+
+	err := a.commit()
+	if err != nil {
+		_ = a.tmp.Close()
+		_ = os.Remove(a.tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+func (a *AtomicFileWriter) commit() error {
+	err := a.tmp.Chmod(a.mode)
+	if err != nil {
+		return fmt.Errorf("could not set temporary file permissions: %w", err)
+	}
+
+	err = a.tmp.Sync()
+	if err != nil {
+		return fmt.Errorf("could not sync temporary file: %w", err)
+	}
+
+	err = a.tmp.Close()
+	if err != nil {
+		return fmt.Errorf("could not close temporary file: %w", err)
+	}
+
+	err = os.Rename(a.tmp.Name(), a.path)
+	if err != nil {
+		return fmt.Errorf("could not replace %q file: %w", a.path, err)
+	}
+
+	return nil
+}
+
+// Abort discards the written data leaving the destination file unchanged. It's safe to
+// call it after a commit, so it can be deferred.
+func (a *AtomicFileWriter) Abort() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+
+	_ = a.tmp.Close()
+	err := os.Remove(a.tmp.Name())
+	if err != nil {
+		return fmt.Errorf("could not remove temporary file: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes the data in the file atomically using an AtomicFileWriter.
+func writeFileAtomic(path string, data []byte) error {
+	w, err := NewAtomicFileWriter(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = w.Abort() }()
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("could not write temporary file: %w", err)
+	}
+
+	return w.Commit()
+}
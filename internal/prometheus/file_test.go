@@ -0,0 +1,139 @@
+package prometheus_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestAtomicFileWriter(t *testing.T) {
+	validSLO := prometheus.StorageSLO{
+		SLO: prometheus.SLO{ID: "test1"},
+		Rules: prometheus.SLORules{
+			SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+		},
+	}
+	invalidSLO := prometheus.StorageSLO{
+		SLO: prometheus.SLO{ID: "test2"},
+		Rules: prometheus.SLORules{
+			SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "sum(rate(test[5m]"}},
+		},
+	}
+
+	tests := map[string]struct {
+		existingData string
+		existingMode fs.FileMode
+		slos         [][]prometheus.StorageSLO
+		expData      string
+		expMode      fs.FileMode
+		expErr       bool
+	}{
+		"Writing on a missing file should create it with the default permissions.": {
+			slos: [][]prometheus.StorageSLO{{validSLO}},
+			expData: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+`,
+			expMode: 0o644,
+		},
+
+		"Writing on an existing file should replace it preserving the permissions.": {
+			existingData: "old-data\n",
+			existingMode: 0o600,
+			slos:         [][]prometheus.StorageSLO{{validSLO}, {validSLO}},
+			expData: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+`,
+			expMode: 0o600,
+		},
+
+		"Failing after a partial write should leave the existing file unchanged.": {
+			existingData: "old-data\n",
+			existingMode: 0o640,
+			slos:         [][]prometheus.StorageSLO{{validSLO}, {invalidSLO}},
+			expData:      "old-data\n",
+			expMode:      0o640,
+			expErr:       true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, "rules.yaml")
+			if test.existingMode != 0 {
+				require.NoError(os.WriteFile(path, []byte(test.existingData), test.existingMode))
+				require.NoError(os.Chmod(path, test.existingMode))
+			}
+
+			w, err := prometheus.NewAtomicFileWriter(path)
+			require.NoError(err)
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:            w,
+				DisableDisclaimer: true,
+			})
+			require.NoError(err)
+
+			// Write like the CLI, only replace the file if all the stores succeed.
+			for _, slos := range test.slos {
+				err = repo.StoreSLOs(context.TODO(), slos)
+				if err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = w.Commit()
+			}
+			require.NoError(w.Abort())
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			gotData, err := os.ReadFile(path)
+			require.NoError(err)
+			assert.Equal(test.expData, string(gotData))
+			info, err := os.Stat(path)
+			require.NoError(err)
+			assert.Equal(test.expMode, info.Mode().Perm())
+
+			// The temporary files should be cleaned.
+			entries, err := os.ReadDir(dir)
+			require.NoError(err)
+			assert.Len(entries, 1)
+		})
+	}
+}
+
+func TestAtomicFileWriterWriteAfterCommit(t *testing.T) {
+	w, err := prometheus.NewAtomicFileWriter(filepath.Join(t.TempDir(), "rules.yaml"))
+	require.NoError(t, err)
+	require.NoError(t, w.Commit())
+
+	_, err = w.Write([]byte("test"))
+	assert.Error(t, err)
+	assert.Error(t, w.Commit())
+}
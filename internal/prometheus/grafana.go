@@ -0,0 +1,179 @@
+package prometheus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const grafanaDashboardSchemaVersion = 36
+
+// WriteGrafanaDashboard writes a Grafana dashboard JSON model of the SLO in the writer, with
+// panels of the error budget, burn rate and SLI errors that query the generated SLO recording
+// rules, so it's independent of the rules flavor. The SLO must have recording rules.
+func WriteGrafanaDashboard(w io.Writer, slo StorageSLO) error {
+	records := map[string]bool{}
+	sliRecords := []string{}
+	for _, r := range slo.Rules.SLIErrorRecRules {
+		if !records[r.Record] {
+			sliRecords = append(sliRecords, r.Record)
+		}
+		records[r.Record] = true
+	}
+	for _, r := range slo.Rules.MetadataRecRules {
+		records[r.Record] = true
+	}
+
+	if len(records) == 0 {
+		return fmt.Errorf("%q SLO doesn't have recording rules", slo.SLO.ID)
+	}
+
+	filter := labelsToPromFilter(slo.SLO.GetSLOIDPromLabels())
+	panels := []grafanaPanelJSON{}
+	addPanel := func(panelType, title, unit string, targets []grafanaTargetJSON) {
+		// Two panels per row.
+		idx := len(panels)
+		panels = append(panels, grafanaPanelJSON{
+			ID:         idx + 1,
+			Type:       panelType,
+			Title:      title,
+			Datasource: grafanaDatasource,
+			GridPos:    grafanaGridPosJSON{H: 8, W: 12, X: (idx % 2) * 12, Y: (idx / 2) * 8},
+			FieldConfig: grafanaFieldConfigJSON{
+				Defaults: grafanaFieldDefaultsJSON{Unit: unit},
+			},
+			Targets: targets,
+		})
+	}
+
+	if records[metricSLOPeriodErrorBudgetRemainingRatio] {
+		addPanel("stat", "Remaining error budget (period)", "percentunit", []grafanaTargetJSON{
+			{RefID: "A", Expr: metricSLOPeriodErrorBudgetRemainingRatio + filter, LegendFormat: "Remaining error budget"},
+		})
+	}
+
+	if records[metricSLOCurrentBurnRateRatio] {
+		targets := []grafanaTargetJSON{
+			{RefID: "A", Expr: metricSLOCurrentBurnRateRatio + filter, LegendFormat: "Current burn rate"},
+		}
+		if records[metricSLOPeriodBurnRateRatio] {
+			targets = append(targets, grafanaTargetJSON{RefID: "B", Expr: metricSLOPeriodBurnRateRatio + filter, LegendFormat: "Period burn rate"})
+		}
+		addPanel("timeseries", "Burn rate", "none", targets)
+	}
+
+	if len(sliRecords) > 0 {
+		targets := []grafanaTargetJSON{}
+		for idx, r := range sliRecords {
+			targets = append(targets, grafanaTargetJSON{RefID: grafanaRefID(idx), Expr: r + filter, LegendFormat: r})
+		}
+		addPanel("timeseries", "SLI error ratio", "percentunit", targets)
+	}
+
+	dashboard := grafanaDashboardJSON{
+		Title:         fmt.Sprintf("SLO / %s / %s", slo.SLO.Service, slo.SLO.Name),
+		Description:   slo.SLO.Description,
+		Tags:          []string{"sloth", "slo", slo.SLO.Service},
+		SchemaVersion: grafanaDashboardSchemaVersion,
+		Time:          grafanaTimeJSON{From: fmt.Sprintf("now-%s", timeDurationToPromStr(slo.SLO.TimeWindow)), To: "now"},
+		Templating: grafanaTemplatingJSON{
+			List: []grafanaVariableJSON{
+				{Name: "datasource", Label: "Datasource", Type: "datasource", Query: "prometheus"},
+			},
+		},
+		Panels: panels,
+	}
+
+	// Don't escape the HTML characters, the PromQL queries could have them.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(dashboard)
+	if err != nil {
+		return fmt.Errorf("could not format %q SLO dashboard: %w", slo.SLO.ID, err)
+	}
+
+	_, err = w.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write %q SLO dashboard: %w", slo.SLO.ID, err)
+	}
+
+	return nil
+}
+
+// grafanaRefID returns the Grafana panel target ref ID of the index (A, B, C...).
+func grafanaRefID(idx int) string {
+	id := ""
+	for idx >= 0 {
+		id = string(rune('A'+idx%26)) + id
+		idx = idx/26 - 1
+	}
+	return id
+}
+
+var grafanaDatasource = grafanaDatasourceJSON{Type: "prometheus", UID: "${datasource}"}
+
+// these types are defined to support a minimal Grafana dashboard JSON model.
+type grafanaDashboardJSON struct {
+	Title         string                `json:"title"`
+	Description   string                `json:"description,omitempty"`
+	Tags          []string              `json:"tags"`
+	SchemaVersion int                   `json:"schemaVersion"`
+	Time          grafanaTimeJSON       `json:"time"`
+	Templating    grafanaTemplatingJSON `json:"templating"`
+	Panels        []grafanaPanelJSON    `json:"panels"`
+}
+
+type grafanaTimeJSON struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplatingJSON struct {
+	List []grafanaVariableJSON `json:"list"`
+}
+
+type grafanaVariableJSON struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type grafanaPanelJSON struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Datasource  grafanaDatasourceJSON  `json:"datasource"`
+	GridPos     grafanaGridPosJSON     `json:"gridPos"`
+	FieldConfig grafanaFieldConfigJSON `json:"fieldConfig"`
+	Targets     []grafanaTargetJSON    `json:"targets"`
+}
+
+type grafanaDatasourceJSON struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaGridPosJSON struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaFieldConfigJSON struct {
+	Defaults grafanaFieldDefaultsJSON `json:"defaults"`
+}
+
+type grafanaFieldDefaultsJSON struct {
+	Unit string `json:"unit"`
+}
+
+type grafanaTargetJSON struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
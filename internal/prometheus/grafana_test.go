@@ -0,0 +1,136 @@
+package prometheus_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestWriteGrafanaDashboard(t *testing.T) {
+	slo := prometheus.SLO{
+		ID:         "svc1-slo1",
+		Name:       "slo1",
+		Service:    "svc1",
+		TimeWindow: 30 * 24 * time.Hour,
+	}
+
+	tests := map[string]struct {
+		slo         prometheus.StorageSLO
+		expTitle    string
+		expTime     string
+		expPanels   []string
+		expPanelExp map[string][]string
+		expErr      bool
+	}{
+		"Having an SLO without recording rules should fail.": {
+			slo: prometheus.StorageSLO{
+				SLO: slo,
+				Rules: prometheus.SLORules{
+					AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with recording rules should reference them on the dashboard panels.": {
+			slo: prometheus.StorageSLO{
+				SLO: slo,
+				Rules: prometheus.SLORules{
+					SLIErrorRecRules: []rulefmt.Rule{
+						{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr"},
+						{Record: "slo:sli_error:ratio_rate30d", Expr: "test-expr"},
+					},
+					MetadataRecRules: []rulefmt.Rule{
+						{Record: "slo:objective:ratio", Expr: "test-expr"},
+						{Record: "slo:current_burn_rate:ratio", Expr: "test-expr"},
+						{Record: "slo:period_burn_rate:ratio", Expr: "test-expr"},
+						{Record: "slo:period_error_budget_remaining:ratio", Expr: "test-expr"},
+					},
+				},
+			},
+			expTitle:  "SLO / svc1 / slo1",
+			expTime:   "now-30d",
+			expPanels: []string{"Remaining error budget (period)", "Burn rate", "SLI error ratio"},
+			expPanelExp: map[string][]string{
+				"Remaining error budget (period)": {
+					`slo:period_error_budget_remaining:ratio{sloth_id="svc1-slo1", sloth_service="svc1", sloth_slo="slo1"}`,
+				},
+				"Burn rate": {
+					`slo:current_burn_rate:ratio{sloth_id="svc1-slo1", sloth_service="svc1", sloth_slo="slo1"}`,
+					`slo:period_burn_rate:ratio{sloth_id="svc1-slo1", sloth_service="svc1", sloth_slo="slo1"}`,
+				},
+				"SLI error ratio": {
+					`slo:sli_error:ratio_rate5m{sloth_id="svc1-slo1", sloth_service="svc1", sloth_slo="slo1"}`,
+					`slo:sli_error:ratio_rate30d{sloth_id="svc1-slo1", sloth_service="svc1", sloth_slo="slo1"}`,
+				},
+			},
+		},
+
+		"Having an SLO only with SLI recording rules should only have the SLI panel.": {
+			slo: prometheus.StorageSLO{
+				SLO: slo,
+				Rules: prometheus.SLORules{
+					SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate1h", Expr: "test-expr"}},
+				},
+			},
+			expTitle:  "SLO / svc1 / slo1",
+			expTime:   "now-30d",
+			expPanels: []string{"SLI error ratio"},
+			expPanelExp: map[string][]string{
+				"SLI error ratio": {
+					`slo:sli_error:ratio_rate1h{sloth_id="svc1-slo1", sloth_service="svc1", sloth_slo="slo1"}`,
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var out bytes.Buffer
+			err := prometheus.WriteGrafanaDashboard(&out, test.slo)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			var dashboard struct {
+				Title string `json:"title"`
+				Time  struct {
+					From string `json:"from"`
+				} `json:"time"`
+				Panels []struct {
+					Title   string `json:"title"`
+					Targets []struct {
+						Expr string `json:"expr"`
+					} `json:"targets"`
+				} `json:"panels"`
+			}
+			err = json.Unmarshal(out.Bytes(), &dashboard)
+			require.NoError(err)
+
+			assert.Equal(test.expTitle, dashboard.Title)
+			assert.Equal(test.expTime, dashboard.Time.From)
+			gotPanels := []string{}
+			gotPanelExp := map[string][]string{}
+			for _, p := range dashboard.Panels {
+				gotPanels = append(gotPanels, p.Title)
+				for _, t := range p.Targets {
+					gotPanelExp[p.Title] = append(gotPanelExp[p.Title], t.Expr)
+				}
+			}
+			assert.Equal(test.expPanels, gotPanels)
+			assert.Equal(test.expPanelExp, gotPanelExp)
+		})
+	}
+}
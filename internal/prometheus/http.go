@@ -0,0 +1,145 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/slok/sloth/internal/log"
+)
+
+// maxHTTPErrorBodySize is the max size of the response body that will be added to the errors.
+const maxHTTPErrorBodySize = 4 * 1024
+
+type HTTPGroupedRulesRepoConfig struct {
+	// URL is the endpoint where the rules will be sent with a POST request.
+	URL string
+	// Headers are the headers that will be set on the requests (e.g: authentication), these
+	// take precedence over the content ones set by the repository.
+	Headers map[string]string
+	// Client is the HTTP client used to send the rules, by default http.DefaultClient.
+	Client *http.Client
+	// IOWriterConfig is the configuration used to serialize the rules (the writer will be
+	// set by the repository for each request).
+	IOWriterConfig IOWriterGroupedRulesYAMLRepoConfig
+}
+
+func (c *HTTPGroupedRulesRepoConfig) defaults() error {
+	if c.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid %q url scheme, must be http or https", u.Scheme)
+	}
+
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+
+	// Validate the serialization configuration using it with a fake writer.
+	ioConfig := c.IOWriterConfig
+	ioConfig.Writer = io.Discard
+	err = ioConfig.defaults()
+	if err != nil {
+		return err
+	}
+
+	if c.IOWriterConfig.Logger == nil {
+		c.IOWriterConfig.Logger = log.Noop
+	}
+
+	return nil
+}
+
+// HTTPGroupedRulesRepo knows to store the SLO rules sending them to an HTTP endpoint (e.g: a
+// ruler API) with a POST request, the rules are serialized in the same way as IOWriterGroupedRulesYAMLRepo.
+type HTTPGroupedRulesRepo struct {
+	url            string
+	headers        map[string]string
+	client         *http.Client
+	ioWriterConfig IOWriterGroupedRulesYAMLRepoConfig
+	logger         log.Logger
+}
+
+func NewHTTPGroupedRulesRepo(config HTTPGroupedRulesRepoConfig) (*HTTPGroupedRulesRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &HTTPGroupedRulesRepo{
+		url:            config.URL,
+		headers:        config.Headers,
+		client:         config.Client,
+		ioWriterConfig: config.IOWriterConfig,
+		logger:         config.IOWriterConfig.Logger.WithValues(log.Kv{"svc": "storage.HTTP"}),
+	}, nil
+}
+
+// StoreSLOs will serialize the SLO rules and send them to the endpoint, if there aren't rules
+// it will return ErrNoSLORules without sending anything.
+func (h HTTPGroupedRulesRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	var b bytes.Buffer
+	ioConfig := h.ioWriterConfig
+	ioConfig.Writer = &b
+	ioConfig.Writers = nil
+	repo, err := NewIOWriterGroupedRulesYAMLRepo(ioConfig)
+	if err != nil {
+		return fmt.Errorf("could not create rules serializer: %w", err)
+	}
+
+	err = repo.StoreSLOs(ctx, slos)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, &b)
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType(repo.format))
+	if repo.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send rules: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHTTPErrorBodySize))
+		return fmt.Errorf("could not send rules: unexpected %d status code: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	logger := h.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{"status": resp.StatusCode}).Infof("Prometheus rules sent")
+
+	return nil
+}
+
+// contentType returns the HTTP content type of the rules format.
+func contentType(format OutputFormat) string {
+	switch format {
+	case JSONFormat:
+		return "application/json"
+	case JSONLinesFormat:
+		return "application/x-ndjson"
+	}
+
+	return "application/yaml"
+}
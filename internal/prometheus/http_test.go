@@ -0,0 +1,182 @@
+package prometheus_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestHTTPGroupedRulesRepoStore(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		config         prometheus.HTTPGroupedRulesRepoConfig
+		slos           []prometheus.StorageSLO
+		status         int
+		respBody       string
+		expRequest     bool
+		expBody        string
+		expHeaders     map[string]string
+		expErr         error
+		expErrContains string
+	}{
+		"Storing the rules should send them with a POST request.": {
+			config: prometheus.HTTPGroupedRulesRepoConfig{
+				Headers: map[string]string{"Authorization": "Bearer test-token"},
+			},
+			slos:       slos,
+			status:     http.StatusAccepted,
+			expRequest: true,
+			expBody: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+			expHeaders: map[string]string{
+				"Authorization": "Bearer test-token",
+				"Content-Type":  "application/yaml",
+			},
+		},
+
+		"Storing the rules in JSON should send them with the JSON content type.": {
+			config: prometheus.HTTPGroupedRulesRepoConfig{
+				IOWriterConfig: prometheus.IOWriterGroupedRulesYAMLRepoConfig{Format: prometheus.JSONFormat},
+			},
+			slos:       slos,
+			status:     http.StatusOK,
+			expRequest: true,
+			expBody: `{
+  "groups": [
+    {
+      "name": "sloth-slo-sli-recordings-test1",
+      "rules": [
+        {
+          "record": "test:record",
+          "expr": "test-expr"
+        }
+      ]
+    }
+  ]
+}
+`,
+			expHeaders: map[string]string{"Content-Type": "application/json"},
+		},
+
+		"Not having rules should not send anything.": {
+			slos:   []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "test1"}}},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"A non 2xx response should fail with the response body.": {
+			slos:           slos,
+			status:         http.StatusBadRequest,
+			respBody:       "invalid rule group\n",
+			expRequest:     true,
+			expErrContains: "unexpected 400 status code: invalid rule group",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var (
+				gotRequest bool
+				gotBody    string
+				gotHeaders http.Header
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRequest = true
+				assert.Equal(http.MethodPost, r.Method)
+				b, _ := io.ReadAll(r.Body)
+				gotBody = string(b)
+				gotHeaders = r.Header
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.respBody))
+			}))
+			defer srv.Close()
+
+			test.config.URL = srv.URL
+			test.config.IOWriterConfig.DisableDisclaimer = true
+			repo, err := prometheus.NewHTTPGroupedRulesRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			switch {
+			case test.expErr != nil:
+				assert.ErrorIs(err, test.expErr)
+			case test.expErrContains != "":
+				assert.ErrorContains(err, test.expErrContains)
+			default:
+				assert.NoError(err)
+				assert.Equal(test.expBody, gotBody)
+				for k, v := range test.expHeaders {
+					assert.Equal(v, gotHeaders.Get(k))
+				}
+			}
+			assert.Equal(test.expRequest, gotRequest)
+		})
+	}
+}
+
+func TestHTTPGroupedRulesRepoStoreCancelled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Block until the client gives up.
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	repo, err := prometheus.NewHTTPGroupedRulesRepo(prometheus.HTTPGroupedRulesRepoConfig{URL: srv.URL})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = repo.StoreSLOs(ctx, []prometheus.StorageSLO{
+		{
+			SLO:   prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}}},
+		},
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestHTTPGroupedRulesRepoInvalidConfig(t *testing.T) {
+	tests := map[string]prometheus.HTTPGroupedRulesRepoConfig{
+		"Missing URL should fail.":        {},
+		"Invalid URL scheme should fail.": {URL: "ftp://localhost/rules"},
+		"Invalid rules configuration should fail.": {
+			URL:            "http://localhost/rules",
+			IOWriterConfig: prometheus.IOWriterGroupedRulesYAMLRepoConfig{Format: "xml"},
+		},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := prometheus.NewHTTPGroupedRulesRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -35,6 +35,9 @@ type AlertMeta struct {
 	Name        string            `validate:"required_if_enabled"`
 	Labels      map[string]string `validate:"dive,keys,prom_label_key,endkeys,required,prom_label_value"`
 	Annotations map[string]string `validate:"dive,keys,prom_annot_key,endkeys,required"`
+	// KeepFiringFor is the time the alert will keep firing after the alert condition
+	// has been resolved, if not set, the alert will be resolved immediately.
+	KeepFiringFor time.Duration `validate:"gte=0"`
 }
 
 // SLO represents a service level objective configuration.
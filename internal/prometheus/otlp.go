@@ -0,0 +1,136 @@
+package prometheus
+
+import (
+	"fmt"
+
+	prommodel "github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	promqlparser "github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v2"
+)
+
+// OTLPMapping maps the classic Prometheus metric and label names used by the SLI expressions to
+// the OpenTelemetry (OTLP) semantic conventions ones, as translated by the Prometheus OTLP receiver
+// (e.g: `http_request_duration_seconds_count` to `http_server_request_duration_seconds_count`).
+type OTLPMapping struct {
+	// Metrics are the classic metric names mapped to the OTLP metric names.
+	Metrics map[string]string `yaml:"metrics"`
+	// Labels are the classic label names mapped to the OTLP attribute labels (e.g: `job` to
+	// the `service_name` resource attribute).
+	Labels map[string]string `yaml:"labels"`
+}
+
+// ParseOTLPMapping parses the YAML OTLP mapping, with `metrics` and `labels` keys.
+func ParseOTLPMapping(data []byte) (OTLPMapping, error) {
+	m := OTLPMapping{}
+	err := yaml.UnmarshalStrict(data, &m)
+	if err != nil {
+		return OTLPMapping{}, fmt.Errorf("could not parse OTLP mapping: %w", err)
+	}
+
+	err = m.validate()
+	if err != nil {
+		return OTLPMapping{}, fmt.Errorf("invalid OTLP mapping: %w", err)
+	}
+
+	return m, nil
+}
+
+func (m OTLPMapping) isEmpty() bool { return len(m.Metrics) == 0 && len(m.Labels) == 0 }
+
+func (m OTLPMapping) validate() error {
+	for from, to := range m.Metrics {
+		if !prommodel.IsValidMetricName(prommodel.LabelValue(from)) || !prommodel.IsValidMetricName(prommodel.LabelValue(to)) {
+			return fmt.Errorf("invalid %q to %q metric mapping", from, to)
+		}
+	}
+
+	for from, to := range m.Labels {
+		if !prommodel.LabelName(from).IsValid() || !prommodel.LabelName(to).IsValid() || from == labels.MetricName || to == labels.MetricName {
+			return fmt.Errorf("invalid %q to %q label mapping", from, to)
+		}
+	}
+
+	return nil
+}
+
+// rewriteExpr returns the expression with the mapped metric selectors and labels (matchers,
+// aggregations and vector matching), the rewritten expressions are reformatted, the ones without
+// mapped names are returned as they are.
+func (m OTLPMapping) rewriteExpr(expr string) (string, error) {
+	e, err := promqlparser.ParseExpr(expr)
+	if err != nil {
+		return "", fmt.Errorf("could not parse expression: %w", err)
+	}
+
+	changed := false
+	rename := func(names []string) {
+		for idx, name := range names {
+			if to, ok := m.Labels[name]; ok {
+				names[idx] = to
+				changed = true
+			}
+		}
+	}
+
+	var inspectErr error
+	promqlparser.Inspect(e, func(node promqlparser.Node, _ []promqlparser.Node) error {
+		switch n := node.(type) {
+		case *promqlparser.VectorSelector:
+			if to, ok := m.Metrics[n.Name]; ok {
+				n.Name = to
+				changed = true
+			}
+			for idx, lm := range n.LabelMatchers {
+				name, value := lm.Name, lm.Value
+				if to, ok := m.Labels[name]; ok {
+					name = to
+				}
+				if to, ok := m.Metrics[value]; ok && name == labels.MetricName && lm.Type == labels.MatchEqual {
+					value = to
+				}
+				if name == lm.Name && value == lm.Value {
+					continue
+				}
+
+				matcher, err := labels.NewMatcher(lm.Type, name, value)
+				if err != nil {
+					inspectErr = err
+					return err
+				}
+				n.LabelMatchers[idx] = matcher
+				changed = true
+			}
+		case *promqlparser.AggregateExpr:
+			rename(n.Grouping)
+		case *promqlparser.BinaryExpr:
+			if n.VectorMatching != nil {
+				rename(n.VectorMatching.MatchingLabels)
+				rename(n.VectorMatching.Include)
+			}
+		}
+		return nil
+	})
+	if inspectErr != nil {
+		return "", fmt.Errorf("could not rewrite expression: %w", inspectErr)
+	}
+
+	if !changed {
+		return expr, nil
+	}
+
+	return e.String(), nil
+}
+
+// rewriteOTLPRules rewrites the rules expressions with the OTLP mapping.
+func rewriteOTLPRules(rules []ruleYAMLv2, mapping OTLPMapping) error {
+	for idx, r := range rules {
+		expr, err := mapping.rewriteExpr(r.Expr)
+		if err != nil {
+			return fmt.Errorf("invalid %q rule: %w", r.Record, err)
+		}
+		rules[idx].Expr = expr
+	}
+
+	return nil
+}
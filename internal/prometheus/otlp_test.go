@@ -0,0 +1,154 @@
+package prometheus_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestIOWriterGroupedRulesYAMLRepoStoreOTLPMapping(t *testing.T) {
+	mapping := prometheus.OTLPMapping{
+		Metrics: map[string]string{
+			"http_requests_total":                 "http_server_requests_total",
+			"http_request_duration_seconds_count": "http_server_request_duration_seconds_count",
+		},
+		Labels: map[string]string{
+			"job":  "service_name",
+			"code": "http_response_status_code",
+		},
+	}
+
+	tests := map[string]struct {
+		expr    string
+		expExpr string
+		expErr  bool
+	}{
+		"A classic metric selector should be rewritten to the OTLP one.": {
+			expr:    `sum(rate(http_requests_total{job="myservice",code=~"5.."}[5m]))`,
+			expExpr: `sum(rate(http_server_requests_total{http_response_status_code=~"5..",service_name="myservice"}[5m]))`,
+		},
+
+		"The metric name matchers, aggregations and vector matching labels should be rewritten.": {
+			expr:    `sum by (job) (rate({__name__="http_request_duration_seconds_count",code="500"}[5m])) / on (job) sum by (job) (rate(http_requests_total[5m]))`,
+			expExpr: `sum by (service_name) (rate({__name__="http_server_request_duration_seconds_count",http_response_status_code="500"}[5m])) / on (service_name) sum by (service_name) (rate(http_server_requests_total[5m]))`,
+		},
+
+		"Expressions without mapped names should not be changed.": {
+			expr:    "sum(rate(grpc_server_handled_total{grpc_code!=\"OK\"}[5m]))\n/\nsum(rate(grpc_server_handled_total[5m]))\n",
+			expExpr: "sum(rate(grpc_server_handled_total{grpc_code!=\"OK\"}[5m]))\n/\nsum(rate(grpc_server_handled_total[5m]))\n",
+		},
+
+		"Invalid expressions should fail.": {
+			expr:   `sum(rate(http_requests_total[5m]`,
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:      &b,
+				Logger:      log.Noop,
+				OTLPMapping: mapping,
+			})
+			require.NoError(err)
+
+			err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate5m", Expr: test.expr}},
+						// Sloth metrics are not mapped.
+						MetadataRecRules: []rulefmt.Rule{{Record: "slo:objective:ratio", Expr: `max(slo:sli_error:ratio_rate5m{job="myservice"})`}},
+					},
+				},
+			})
+
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			gotSLOs, err := prometheus.ParseGeneratedRules(&b)
+			require.NoError(err)
+			require.Len(gotSLOs, 1)
+			assert.Equal(test.expExpr, gotSLOs[0].Rules.SLIErrorRecRules[0].Expr)
+			assert.Equal(`max(slo:sli_error:ratio_rate5m{job="myservice"})`, gotSLOs[0].Rules.MetadataRecRules[0].Expr)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidOTLPMapping(t *testing.T) {
+	tests := map[string]prometheus.OTLPMapping{
+		"Invalid metric name should fail.":           {Metrics: map[string]string{"http_requests_total": "http.server.requests"}},
+		"Invalid label name should fail.":            {Labels: map[string]string{"job": "service.name"}},
+		"Mapping the metric name label should fail.": {Labels: map[string]string{"__name__": "name"}},
+	}
+
+	for name, mapping := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:      &bytes.Buffer{},
+				OTLPMapping: mapping,
+			})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseOTLPMapping(t *testing.T) {
+	tests := map[string]struct {
+		mapping    string
+		expMapping prometheus.OTLPMapping
+		expErr     bool
+	}{
+		"A valid mapping should be parsed.": {
+			mapping: `
+metrics:
+  http_requests_total: http_server_requests_total
+labels:
+  job: service_name
+`,
+			expMapping: prometheus.OTLPMapping{
+				Metrics: map[string]string{"http_requests_total": "http_server_requests_total"},
+				Labels:  map[string]string{"job": "service_name"},
+			},
+		},
+
+		"Unknown keys should fail.": {
+			mapping: "metric:\n  http_requests_total: http_server_requests_total\n",
+			expErr:  true,
+		},
+
+		"Invalid names should fail.": {
+			mapping: "labels:\n  job: service.name\n",
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotMapping, err := prometheus.ParseOTLPMapping([]byte(test.mapping))
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expMapping, gotMapping)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"gopkg.in/yaml.v2"
+
+	"github.com/slok/sloth/internal/alert"
+)
+
+// generatedGroupNameRegexp matches the rule group names of the SLOs generated with the default
+// group prefix (e.g: `sloth-slo-sli-recordings-<slo-id>`).
+var generatedGroupNameRegexp = regexp.MustCompile(`^` + defaultGroupPrefix + `-(sli-recordings|meta-recordings|alerts)-(.+)$`)
+
+// ParseGeneratedRules parses the Prometheus rules generated by Sloth (the inverse of StoreSLOs)
+// and returns the SLOs with their rules, regrouped by the SLO ID of the rule group names. The
+// disclaimer and the groups that are not from an SLO (e.g: index or custom groups) are ignored.
+//
+// Only the SLO ID and service (from the `sloth_service` rule label) and the rules storage
+// options (interval, query offset, limits, alerts `keep_firing_for`) can be recovered, the
+// SLOs are returned in the order of their first group.
+func ParseGeneratedRules(r io.Reader) ([]StorageSLO, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read rules: %w", err)
+	}
+
+	ruleGroups := ruleGroupsYAMLv2{}
+	err = yaml.Unmarshal(data, &ruleGroups)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse rules: %w", err)
+	}
+
+	slos := []*StorageSLO{}
+	slosByID := map[string]*StorageSLO{}
+	for _, g := range ruleGroups.Groups {
+		match := generatedGroupNameRegexp.FindStringSubmatch(g.Name)
+		if match == nil {
+			continue
+		}
+		kind, id := match[1], match[2]
+
+		slo, ok := slosByID[id]
+		if !ok {
+			slo = &StorageSLO{
+				SLO:         SLO{ID: id},
+				Interval:    time.Duration(g.Interval),
+				QueryOffset: time.Duration(g.QueryOffset),
+			}
+			slosByID[id] = slo
+			slos = append(slos, slo)
+		}
+
+		rules := make([]rulefmt.Rule, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			if slo.SLO.Service == "" {
+				slo.SLO.Service = r.Labels[sloServiceLabelName]
+			}
+			rules = append(rules, rulefmt.Rule{
+				Record:      r.Record,
+				Alert:       r.Alert,
+				Expr:        r.Expr,
+				For:         r.For,
+				Labels:      r.Labels,
+				Annotations: r.Annotations,
+			})
+		}
+
+		switch kind {
+		case "sli-recordings":
+			slo.Rules.SLIErrorRecRules = append(slo.Rules.SLIErrorRecRules, rules...)
+			slo.GroupLimits.SLIRecordings = g.Limit
+		case "meta-recordings":
+			slo.Rules.MetadataRecRules = append(slo.Rules.MetadataRecRules, rules...)
+			slo.GroupLimits.MetadataRecordings = g.Limit
+		case "alerts":
+			slo.Rules.AlertRules = append(slo.Rules.AlertRules, rules...)
+			slo.GroupLimits.Alerts = g.Limit
+			for _, r := range g.Rules {
+				switch r.Labels[sloSeverityLabelName] {
+				case alert.PageAlertSeverity.String():
+					slo.SLO.PageAlertMeta.KeepFiringFor = time.Duration(r.KeepFiringFor)
+				case alert.TicketAlertSeverity.String():
+					slo.SLO.TicketAlertMeta.KeepFiringFor = time.Duration(r.KeepFiringFor)
+				}
+			}
+		}
+	}
+
+	res := make([]StorageSLO, 0, len(slos))
+	for _, slo := range slos {
+		res = append(res, *slo)
+	}
+
+	return res, nil
+}
+
+// ParsePassthroughRuleGroups parses a Prometheus rules file (validated like Prometheus does) into
+// the rule groups that can be stored as passthrough rule groups along with the SLOs rules.
+func ParsePassthroughRuleGroups(data []byte) ([]RuleGroup, error) {
+	ruleGroups, errs := rulefmt.Parse(data)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid rules: %w", errs[0])
+	}
+
+	res := make([]RuleGroup, 0, len(ruleGroups.Groups))
+	for _, g := range ruleGroups.Groups {
+		rules := make([]Rule, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			rules = append(rules, Rule{
+				Record:      r.Record.Value,
+				Alert:       r.Alert.Value,
+				Expr:        r.Expr.Value,
+				For:         time.Duration(r.For),
+				Labels:      r.Labels,
+				Annotations: r.Annotations,
+			})
+		}
+
+		res = append(res, RuleGroup{
+			Name:     g.Name,
+			Interval: time.Duration(g.Interval),
+			Limit:    g.Limit,
+			Rules:    rules,
+		})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,224 @@
+package prometheus_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestParseGeneratedRulesRoundTrip(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{
+				ID:            "svc1-slo1",
+				Service:       "svc1",
+				PageAlertMeta: prometheus.AlertMeta{KeepFiringFor: 10 * time.Minute},
+			},
+			Interval:    30 * time.Second,
+			QueryOffset: time.Minute,
+			GroupLimits: prometheus.GroupLimits{Alerts: 5},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr", Labels: map[string]string{"sloth_id": "svc1-slo1", "sloth_service": "svc1"}},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "slo:objective:ratio", Expr: "vector(0.999)", Labels: map[string]string{"sloth_id": "svc1-slo1", "sloth_service": "svc1"}},
+				},
+				AlertRules: []rulefmt.Rule{
+					{
+						Alert:       "Slo1Page",
+						Expr:        "test-expr",
+						For:         prommodel.Duration(5 * time.Minute),
+						Labels:      map[string]string{"sloth_id": "svc1-slo1", "sloth_service": "svc1", "sloth_severity": "page"},
+						Annotations: map[string]string{"summary": "SLO 1 page."},
+					},
+					{
+						Alert:  "Slo1Ticket",
+						Expr:   "test-expr",
+						Labels: map[string]string{"sloth_id": "svc1-slo1", "sloth_service": "svc1", "sloth_severity": "ticket"},
+					},
+				},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "svc2-slo1", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{
+					{Alert: "Slo2Page", Expr: "test-expr", Labels: map[string]string{"sloth_id": "svc2-slo1", "sloth_service": "svc2", "sloth_severity": "page"}},
+				},
+			},
+		},
+	}
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var b bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:     &b,
+		Logger:     log.Noop,
+		IndexGroup: true,
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(err)
+
+	gotSLOs, err := prometheus.ParseGeneratedRules(&b)
+	require.NoError(err)
+	assert.Equal(slos, gotSLOs)
+}
+
+func TestParseGeneratedRules(t *testing.T) {
+	tests := map[string]struct {
+		rules   string
+		expSLOs []prometheus.StorageSLO
+		expErr  bool
+	}{
+		"Invalid YAML should fail.": {
+			rules:  "groups: {",
+			expErr: true,
+		},
+
+		"Non Sloth groups should be ignored.": {
+			rules: `
+# Code generated by Sloth: https://github.com/slok/sloth.
+groups:
+- name: custom
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-index
+  rules:
+  - record: sloth_slo_index_info
+    expr: vector(1)
+`,
+			expSLOs: []prometheus.StorageSLO{},
+		},
+
+		"The rules of the same SLO should be regrouped by the SLO ID.": {
+			rules: `
+groups:
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record2
+    expr: test-expr
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr
+`,
+			expSLOs: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr"}},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotSLOs, err := prometheus.ParseGeneratedRules(strings.NewReader(test.rules))
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expSLOs, gotSLOs)
+			}
+		})
+	}
+}
+
+func TestParsePassthroughRuleGroups(t *testing.T) {
+	tests := map[string]struct {
+		rules     string
+		expGroups []prometheus.RuleGroup
+		expErr    bool
+	}{
+		"Invalid rules should fail.": {
+			rules: `
+groups:
+- name: custom
+  rules:
+  - record: custom:record
+    expr: sum(up
+`,
+			expErr: true,
+		},
+
+		"Rule groups should be parsed.": {
+			rules: `
+groups:
+- name: custom
+  interval: 2m
+  limit: 10
+  rules:
+  - record: custom:record
+    expr: sum(up)
+  - alert: CustomAlert
+    expr: custom:record == 0
+    for: 5m
+    labels:
+      severity: page
+    annotations:
+      summary: Nothing is up.
+`,
+			expGroups: []prometheus.RuleGroup{
+				{
+					Name:     "custom",
+					Interval: 2 * time.Minute,
+					Limit:    10,
+					Rules: []prometheus.Rule{
+						{Record: "custom:record", Expr: "sum(up)"},
+						{
+							Alert:       "CustomAlert",
+							Expr:        "custom:record == 0",
+							For:         5 * time.Minute,
+							Labels:      map[string]string{"severity": "page"},
+							Annotations: map[string]string{"summary": "Nothing is up."},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotGroups, err := prometheus.ParsePassthroughRuleGroups([]byte(test.rules))
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expGroups, gotGroups)
+			}
+		})
+	}
+}
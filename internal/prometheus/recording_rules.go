@@ -205,17 +205,6 @@ const MetadataRecordingRulesGenerator = metadataRecordingRulesGenerator(false)
 func (m metadataRecordingRulesGenerator) GenerateMetadataRecordingRules(ctx context.Context, info info.Info, slo SLO, alerts alert.MWMBAlertGroup) ([]rulefmt.Rule, error) {
 	labels := mergeLabels(slo.GetSLOIDPromLabels(), slo.Labels)
 
-	// Metatada Recordings.
-	const (
-		metricSLOObjectiveRatio                  = "slo:objective:ratio"
-		metricSLOErrorBudgetRatio                = "slo:error_budget:ratio"
-		metricSLOTimePeriodDays                  = "slo:time_period:days"
-		metricSLOCurrentBurnRateRatio            = "slo:current_burn_rate:ratio"
-		metricSLOPeriodBurnRateRatio             = "slo:period_burn_rate:ratio"
-		metricSLOPeriodErrorBudgetRemainingRatio = "slo:period_error_budget_remaining:ratio"
-		metricSLOInfo                            = "sloth_slo_info"
-	)
-
 	sloObjectiveRatio := slo.Objective / 100
 
 	sloFilter := labelsToPromFilter(slo.GetSLOIDPromLabels())
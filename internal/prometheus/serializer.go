@@ -0,0 +1,223 @@
+package prometheus
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RuleSerializer knows how to serialize the SLO rules in an output flavor, this can be used
+// to store the rules in flavors that are not supported by Sloth (e.g: out-of-tree flavors).
+// The built-in flavors are registered RuleSerializers.
+type RuleSerializer interface {
+	// Serialize returns the serialized SLO rules and the number of rule groups (or the flavor
+	// equivalent) of them. 0 groups will be handled as no rules generated.
+	Serialize(slos []StorageSLO) (groups int, data []byte, err error)
+}
+
+// RuleGroupsSerializer is a RuleSerializer that serializes the Prometheus rule groups built by the
+// repositories, with all the repository options (e.g: prefix, limits or labels). Unlike the plain
+// RuleSerializers, the stored rules of these also have a manifest and rule groups.
+type RuleGroupsSerializer interface {
+	RuleSerializer
+	// SerializeRuleGroups returns the serialized rule groups.
+	SerializeRuleGroups(groups []RuleGroup) (data []byte, err error)
+}
+
+// RuleSerializerFunc is a helper to create RuleSerializers from functions.
+type RuleSerializerFunc func(slos []StorageSLO) (int, []byte, error)
+
+// Serialize satisfies RuleSerializer interface.
+func (r RuleSerializerFunc) Serialize(slos []StorageSLO) (int, []byte, error) {
+	return r(slos)
+}
+
+// ruleGroupsFlavor is a built-in flavor of the Prometheus rule groups. The repositories configured
+// with it build the rule groups and use the flavor to set its options on them and write them.
+type ruleGroupsFlavor struct {
+	id   OutputFlavor
+	name string
+	// federated is true when the flavor supports the SLOs source tenants.
+	federated bool
+	// defaults sets the defaults and validates the flavor options of the configuration.
+	defaults func(c *IOWriterGroupedRulesYAMLRepoConfig) error
+	// setGroupOptions sets the flavor options on the rule groups.
+	setGroupOptions func(i IOWriterGroupedRulesYAMLRepo, ruleGroups *ruleGroupsYAMLv2)
+	// header returns the flavor lines of the rules YAML header.
+	header func(i IOWriterGroupedRulesYAMLRepo) string
+	// streamYAML writes the YAML rule groups, if not set in Prometheus rule format.
+	streamYAML func(i IOWriterGroupedRulesYAMLRepo, w io.Writer, ruleGroups ruleGroupsYAMLv2) error
+}
+
+// Serialize satisfies RuleSerializer interface, it serializes the rules with the flavor
+// default configuration.
+func (f ruleGroupsFlavor) Serialize(slos []StorageSLO) (int, []byte, error) {
+	repo, err := f.defaultRepo()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	groups, data, err := repo.Serialize(slos)
+	if errors.Is(err, ErrNoSLORules) {
+		return 0, nil, nil
+	}
+
+	return groups, data, err
+}
+
+// SerializeRuleGroups satisfies RuleGroupsSerializer interface, it serializes the rule groups
+// with the flavor default configuration.
+func (f ruleGroupsFlavor) SerializeRuleGroups(groups []RuleGroup) ([]byte, error) {
+	repo, err := f.defaultRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.SerializeRuleGroups(groups)
+}
+
+func (f ruleGroupsFlavor) defaultRepo() (*IOWriterGroupedRulesYAMLRepo, error) {
+	repo, err := NewIOWriterGroupedRulesYAMLRepo(IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: io.Discard,
+		Flavor: f.id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create %q flavor storage: %w", f.id, err)
+	}
+
+	return repo, nil
+}
+
+var builtinFlavors = []ruleGroupsFlavor{
+	{
+		id:   PrometheusFlavor,
+		name: "Prometheus",
+	},
+	{
+		id:        MimirFlavor,
+		name:      "Mimir",
+		federated: true,
+		defaults: func(c *IOWriterGroupedRulesYAMLRepoConfig) error {
+			for _, t := range c.MimirSourceTenants {
+				if t == "" {
+					return fmt.Errorf("mimir source tenants can't be empty")
+				}
+			}
+			return nil
+		},
+		setGroupOptions: func(i IOWriterGroupedRulesYAMLRepo, ruleGroups *ruleGroupsYAMLv2) {
+			setMimirGroupOptions(*ruleGroups, i.mimirSourceTenants)
+		},
+		header: func(i IOWriterGroupedRulesYAMLRepo) string {
+			if i.mimirTenant == "" {
+				return ""
+			}
+			return fmt.Sprintf("# mimir-tenant: %s\n", i.mimirTenant)
+		},
+	},
+	{
+		id:   VictoriaMetricsFlavor,
+		name: "VictoriaMetrics",
+		defaults: func(c *IOWriterGroupedRulesYAMLRepoConfig) error {
+			if c.VictoriaMetricsEvalOffset < 0 {
+				return fmt.Errorf("victoriametrics eval offset can't be negative")
+			}
+			return nil
+		},
+		setGroupOptions: func(i IOWriterGroupedRulesYAMLRepo, ruleGroups *ruleGroupsYAMLv2) {
+			setVictoriaMetricsGroupOptions(*ruleGroups, i.vmTenant, i.vmEvalOffset)
+		},
+	},
+	{
+		id:        CortexFlavor,
+		name:      "Cortex",
+		federated: true,
+		setGroupOptions: func(i IOWriterGroupedRulesYAMLRepo, ruleGroups *ruleGroupsYAMLv2) {
+			ruleGroups.Namespace = i.cortexNamespace
+		},
+		header: func(i IOWriterGroupedRulesYAMLRepo) string {
+			if i.cortexTenant == "" {
+				return ""
+			}
+			return fmt.Sprintf("# cortex-tenant: %s\n", i.cortexTenant)
+		},
+	},
+	{
+		id:   ThanosFlavor,
+		name: "Thanos",
+		defaults: func(c *IOWriterGroupedRulesYAMLRepoConfig) error {
+			if c.ThanosPartialResponseStrategy == "" {
+				c.ThanosPartialResponseStrategy = ThanosPartialResponseAbort
+			}
+			if c.ThanosPartialResponseStrategy != ThanosPartialResponseWarn && c.ThanosPartialResponseStrategy != ThanosPartialResponseAbort {
+				return fmt.Errorf("invalid %q thanos partial response strategy", c.ThanosPartialResponseStrategy)
+			}
+			return nil
+		},
+		setGroupOptions: func(i IOWriterGroupedRulesYAMLRepo, ruleGroups *ruleGroupsYAMLv2) {
+			for idx := range ruleGroups.Groups {
+				ruleGroups.Groups[idx].PartialResponseStrategy = i.thanosPRStrategy
+			}
+		},
+	},
+	{
+		id:   CoralogixFlavor,
+		name: "Coralogix",
+		defaults: func(c *IOWriterGroupedRulesYAMLRepoConfig) error {
+			if c.CoralogixApplication == "" {
+				c.CoralogixApplication = defaultCoralogixApplication
+			}
+			if c.Format == JSONFormat || c.Format == JSONLinesFormat {
+				return fmt.Errorf("%s format is not supported by the coralogix flavor", c.Format)
+			}
+			return nil
+		},
+		streamYAML: IOWriterGroupedRulesYAMLRepo.streamCoralogixYAML,
+	},
+}
+
+var (
+	ruleSerializersMu sync.RWMutex
+	ruleSerializers   = map[OutputFlavor]RuleSerializer{}
+)
+
+func init() {
+	for _, f := range builtinFlavors {
+		ruleSerializers[f.id] = f
+	}
+}
+
+// RegisterRuleSerializer registers the serializer of an output flavor, so the repositories
+// configured with the flavor store the rules with it. The built-in flavors can't be replaced
+// and a flavor can only be registered once.
+func RegisterRuleSerializer(flavor OutputFlavor, s RuleSerializer) error {
+	if flavor == "" {
+		return fmt.Errorf("flavor is required")
+	}
+
+	if s == nil {
+		return fmt.Errorf("serializer is required")
+	}
+
+	ruleSerializersMu.Lock()
+	defer ruleSerializersMu.Unlock()
+
+	if current, ok := ruleSerializers[flavor]; ok {
+		if _, ok := current.(ruleGroupsFlavor); ok {
+			return fmt.Errorf("%q is a built-in flavor", flavor)
+		}
+		return fmt.Errorf("%q flavor serializer is already registered", flavor)
+	}
+	ruleSerializers[flavor] = s
+
+	return nil
+}
+
+func getRuleSerializer(flavor OutputFlavor) (RuleSerializer, bool) {
+	ruleSerializersMu.RLock()
+	defer ruleSerializersMu.RUnlock()
+
+	s, ok := ruleSerializers[flavor]
+	return s, ok
+}
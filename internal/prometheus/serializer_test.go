@@ -0,0 +1,271 @@
+package prometheus_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var testSerializer = prometheus.RuleSerializerFunc(func(slos []prometheus.StorageSLO) (int, []byte, error) {
+	var b bytes.Buffer
+	groups := 0
+	for _, slo := range slos {
+		for _, r := range slo.Rules.SLIErrorRecRules {
+			fmt.Fprintf(&b, "%s %s %s\n", slo.SLO.ID, r.Record, r.Expr)
+		}
+		if len(slo.Rules.SLIErrorRecRules) > 0 {
+			groups++
+		}
+	}
+	return groups, b.Bytes(), nil
+})
+
+// testGroupsSerializer writes a line with the name and rules of each rule group.
+type testGroupsSerializer struct{}
+
+func (testGroupsSerializer) Serialize(slos []prometheus.StorageSLO) (int, []byte, error) {
+	return 0, nil, fmt.Errorf("should serialize the rule groups")
+}
+
+func (testGroupsSerializer) SerializeRuleGroups(groups []prometheus.RuleGroup) ([]byte, error) {
+	var b bytes.Buffer
+	for _, g := range groups {
+		fmt.Fprintf(&b, "%s %d\n", g.Name, len(g.Rules))
+	}
+	return b.Bytes(), nil
+}
+
+// The serializers can only be registered once per process.
+func init() {
+	err := prometheus.RegisterRuleSerializer("test-store", testSerializer)
+	if err != nil {
+		panic(err)
+	}
+
+	err = prometheus.RegisterRuleSerializer("test-groups", testGroupsSerializer{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterRuleSerializer(t *testing.T) {
+	tests := map[string]struct {
+		flavor     prometheus.OutputFlavor
+		serializer prometheus.RuleSerializer
+	}{
+		"Registering an already registered flavor should fail.": {
+			flavor:     "test-store",
+			serializer: testSerializer,
+		},
+
+		"Registering a built-in flavor should fail.": {
+			flavor:     prometheus.MimirFlavor,
+			serializer: testSerializer,
+		},
+
+		"Registering without flavor should fail.": {
+			serializer: testSerializer,
+		},
+
+		"Registering without serializer should fail.": {
+			flavor: "test-nil",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := prometheus.RegisterRuleSerializer(test.flavor, test.serializer)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSerializer(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		config    prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		slos      []prometheus.StorageSLO
+		expOut    string
+		expGroups int
+		expErr    error
+		expAnyErr bool
+	}{
+		"Having a registered flavor should store the rules with its serializer.": {
+			config:    prometheus.IOWriterGroupedRulesYAMLRepoConfig{Flavor: "test-store"},
+			slos:      slos,
+			expOut:    "test1 test:record1 test-expr1\ntest2 test:record2 test-expr2\n",
+			expGroups: 2,
+		},
+
+		"Having a serializer should store the rules with it.": {
+			config:    prometheus.IOWriterGroupedRulesYAMLRepoConfig{Serializer: testSerializer},
+			slos:      slos[:1],
+			expOut:    "test1 test:record1 test-expr1\n",
+			expGroups: 1,
+		},
+
+		"Having a serializer that doesn't serialize groups should fail as no rules.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{Serializer: testSerializer},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						AlertRules: []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"Having a failing serializer should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Serializer: prometheus.RuleSerializerFunc(func([]prometheus.StorageSLO) (int, []byte, error) {
+					return 0, nil, fmt.Errorf("something")
+				}),
+			},
+			slos:      slos,
+			expAnyErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var out bytes.Buffer
+			test.config.Writer = &out
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+
+			res, err := repo.StoreSLOsResult(context.TODO(), test.slos)
+			switch {
+			case test.expErr != nil:
+				assert.ErrorIs(err, test.expErr)
+			case test.expAnyErr:
+				assert.Error(err)
+			case assert.NoError(err):
+				assert.Equal(test.expOut, out.String())
+				assert.Equal(test.expGroups, res.Groups)
+				assert.Equal(len(test.expOut), res.BytesWritten)
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreRuleGroupsSerializer(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr1"}, {Alert: "testAlert2", Expr: "test-expr2"}},
+			},
+		},
+	}
+
+	// The rule groups should be built with the repository options.
+	var out, manifest bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      &out,
+		Flavor:      "test-groups",
+		GroupPrefix: "custom",
+	})
+	require.NoError(err)
+	res, err := repo.StoreSLOsWithManifest(context.TODO(), slos, &manifest)
+	require.NoError(err)
+	assert.Equal("custom-sli-recordings-test1 1\ncustom-alerts-test1 2\n", out.String())
+	assert.Equal(2, res.Groups)
+	assert.Contains(manifest.String(), `"name": "custom-alerts-test1"`)
+
+	groups, err := repo.BuildRuleGroups(context.TODO(), slos)
+	require.NoError(err)
+	require.Len(groups, 2)
+	assert.Equal("custom-sli-recordings-test1", groups[0].Name)
+	assert.Equal("custom-alerts-test1", groups[1].Name)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoRuleSerializerWithoutRuleGroups(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+			},
+		},
+	}
+
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &bytes.Buffer{},
+		Flavor: "test-store",
+	})
+	require.NoError(t, err)
+
+	// The plain rule serializers don't have rule groups.
+	_, err = repo.StoreSLOsWithManifest(context.TODO(), slos, &bytes.Buffer{})
+	assert.Error(t, err)
+	_, err = repo.BuildRuleGroups(context.TODO(), slos)
+	assert.Error(t, err)
+	_, err = repo.ValidateSLOs(context.TODO(), slos)
+	assert.NoError(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoUnknownFlavor(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &bytes.Buffer{},
+		Flavor: "test-unknown",
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoSerialize(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+			},
+		},
+	}
+
+	// The repositories are serializers of the built-in flavors.
+	var out bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{Writer: &out})
+	require.NoError(err)
+	var serializer prometheus.RuleSerializer = repo
+
+	groups, data, err := serializer.Serialize(slos)
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(err)
+
+	assert.Equal(1, groups)
+	assert.Equal(out.String(), string(data))
+}
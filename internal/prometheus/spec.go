@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"time"
 
+	prommodel "github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
 
 	prometheusv1 "github.com/slok/sloth/pkg/prometheus/api/v1"
@@ -118,18 +119,30 @@ func (y YAMLSpecLoader) mapSpecToModel(ctx context.Context, spec prometheusv1.Sp
 
 		// Set alerts.
 		if !specSLO.Alerting.PageAlert.Disable {
+			keepFiringFor, err := parseOptionalDuration(specSLO.Alerting.PageAlert.KeepFiringFor)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %q SLO page alert keep firing for: %w", specSLO.Name, err)
+			}
+
 			slo.PageAlertMeta = AlertMeta{
-				Name:        specSLO.Alerting.Name,
-				Labels:      mergeLabels(specSLO.Alerting.Labels, specSLO.Alerting.PageAlert.Labels),
-				Annotations: mergeLabels(specSLO.Alerting.Annotations, specSLO.Alerting.PageAlert.Annotations),
+				Name:          specSLO.Alerting.Name,
+				Labels:        mergeLabels(specSLO.Alerting.Labels, specSLO.Alerting.PageAlert.Labels),
+				Annotations:   mergeLabels(specSLO.Alerting.Annotations, specSLO.Alerting.PageAlert.Annotations),
+				KeepFiringFor: keepFiringFor,
 			}
 		}
 
 		if !specSLO.Alerting.TicketAlert.Disable {
+			keepFiringFor, err := parseOptionalDuration(specSLO.Alerting.TicketAlert.KeepFiringFor)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %q SLO ticket alert keep firing for: %w", specSLO.Name, err)
+			}
+
 			slo.TicketAlertMeta = AlertMeta{
-				Name:        specSLO.Alerting.Name,
-				Labels:      mergeLabels(specSLO.Alerting.Labels, specSLO.Alerting.TicketAlert.Labels),
-				Annotations: mergeLabels(specSLO.Alerting.Annotations, specSLO.Alerting.TicketAlert.Annotations),
+				Name:          specSLO.Alerting.Name,
+				Labels:        mergeLabels(specSLO.Alerting.Labels, specSLO.Alerting.TicketAlert.Labels),
+				Annotations:   mergeLabels(specSLO.Alerting.Annotations, specSLO.Alerting.TicketAlert.Annotations),
+				KeepFiringFor: keepFiringFor,
 			}
 		}
 
@@ -138,3 +151,17 @@ func (y YAMLSpecLoader) mapSpecToModel(ctx context.Context, spec prometheusv1.Sp
 
 	return &SLOGroup{SLOs: models}, nil
 }
+
+// parseOptionalDuration parses a Prometheus duration, empty durations are zero.
+func parseOptionalDuration(d string) (time.Duration, error) {
+	if d == "" {
+		return 0, nil
+	}
+
+	pd, err := prommodel.ParseDuration(d)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(pd), nil
+}
@@ -206,6 +206,69 @@ slos:
 			}},
 		},
 
+		"Spec with alerts keep firing for should set it on the alerts.": {
+			windowPeriod: 30 * 24 * time.Hour,
+			specYaml: `
+service: test-svc
+version: "prometheus/v1"
+slos:
+  - name: "slo-test"
+    objective: 99
+    sli:
+      raw:
+        error_ratio_query: test_expr_ratio_2
+    alerting:
+      name: testAlert
+      page_alert:
+        keep_firing_for: 10m
+      ticket_alert:
+        disable: true
+`,
+			expModel: &prometheus.SLOGroup{SLOs: []prometheus.SLO{
+				{
+					ID:         "test-svc-slo-test",
+					Name:       "slo-test",
+					Service:    "test-svc",
+					TimeWindow: 30 * 24 * time.Hour,
+					SLI: prometheus.SLI{
+						Raw: &prometheus.SLIRaw{
+							ErrorRatioQuery: `test_expr_ratio_2`,
+						},
+					},
+					Objective: 99,
+					Labels:    map[string]string{},
+					PageAlertMeta: prometheus.AlertMeta{
+						Name:          "testAlert",
+						Labels:        map[string]string{},
+						Annotations:   map[string]string{},
+						KeepFiringFor: 10 * time.Minute,
+					},
+					TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+				},
+			}},
+		},
+
+		"Spec with an invalid alert keep firing for should fail.": {
+			windowPeriod: 30 * 24 * time.Hour,
+			specYaml: `
+service: test-svc
+version: "prometheus/v1"
+slos:
+  - name: "slo-test"
+    objective: 99
+    sli:
+      raw:
+        error_ratio_query: test_expr_ratio_2
+    alerting:
+      name: testAlert
+      page_alert:
+        disable: true
+      ticket_alert:
+        keep_firing_for: 10 minutes
+`,
+			expErr: true,
+		},
+
 		"Correct spec should return the models correctly.": {
 			windowPeriod: 30 * 24 * time.Hour,
 			specYaml: `
@@ -1,118 +1,3001 @@
 package prometheus
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	prommodel "github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
+	promqlparser "github.com/prometheus/prometheus/promql/parser"
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/labels"
 
+	"github.com/slok/sloth/internal/alert"
 	"github.com/slok/sloth/internal/info"
 	"github.com/slok/sloth/internal/log"
 )
 
+const defaultGroupPrefix = "sloth-slo"
+
+const defaultWriteBufferSize = 64 * 1024
+
+const defaultShadowLabel = "shadow"
+
+const defaultSeverityLabel = "severity"
+
+const (
+	defaultCoralogixApplication = "sloth"
+	coralogixAPIVersion         = "coralogix.com/v1"
+	coralogixRecordingKind      = "RecordingRuleGroup"
+	coralogixAlertKind          = "AlertRuleGroup"
+)
+
+// ctxCheckSLOs is the number of SLOs generated between context cancellation checks.
+const ctxCheckSLOs = 100
+
 var (
 	// ErrNoSLORules will be used when there are no rules to store. The upper layer
 	// could ignore or handle the error in cases where there wasn't an output.
 	ErrNoSLORules = fmt.Errorf("0 SLO Prometheus rules generated")
+	// ErrNoSLOAlertRules will be used when there are recording rules to store but not a single
+	// alert rule, with FailOnNoAlertRules.
+	ErrNoSLOAlertRules = fmt.Errorf("0 SLO Prometheus alert rules generated")
+)
+
+// OutputFlavor is the flavor of the Prometheus compatible rules output.
+type OutputFlavor string
+
+const (
+	// PrometheusFlavor will output regular Prometheus rule groups.
+	PrometheusFlavor OutputFlavor = "prometheus"
+	// MimirFlavor will output Prometheus rule groups with the Grafana Mimir ruler
+	// specific options (tenant and federated source tenants).
+	MimirFlavor OutputFlavor = "mimir"
+	// VictoriaMetricsFlavor will output Prometheus rule groups with the VictoriaMetrics
+	// vmalert specific options (type, tenant and evaluation offset).
+	VictoriaMetricsFlavor OutputFlavor = "victoriametrics"
+	// CortexFlavor will output Prometheus rule groups with the Cortex ruler namespace layout
+	// and tenant header, that `cortextool rules` commands understand.
+	CortexFlavor OutputFlavor = "cortex"
+	// ThanosFlavor will output Prometheus rule groups with the Thanos Ruler specific
+	// options (partial response strategy).
+	ThanosFlavor OutputFlavor = "thanos"
+	// CoralogixFlavor will output each of the Prometheus rule groups wrapped in a Coralogix
+	// rule group object with the Coralogix application and subsystem.
+	CoralogixFlavor OutputFlavor = "coralogix"
+)
+
+const (
+	// ThanosPartialResponseWarn will evaluate the Thanos rule groups on partial data, warning.
+	ThanosPartialResponseWarn = "warn"
+	// ThanosPartialResponseAbort will abort the Thanos rule groups evaluation on partial data.
+	ThanosPartialResponseAbort = "abort"
+)
+
+// EmptySLOsHandling is how the SLOs that don't produce any rule are handled.
+type EmptySLOsHandling string
+
+const (
+	// IgnoreEmptySLOs will skip the SLOs without rules.
+	IgnoreEmptySLOs EmptySLOsHandling = "ignore"
+	// WarnEmptySLOs will log a warning for each of the SLOs without rules.
+	WarnEmptySLOs EmptySLOsHandling = "warn"
+	// FailEmptySLOs will fail listing all the SLOs without rules.
+	FailEmptySLOs EmptySLOsHandling = "error"
+)
+
+// GroupsOrder is the order of the stored rule groups.
+type GroupsOrder string
+
+const (
+	// GroupedByServiceGroupsOrder will sort the groups by service, then SLO ID, then type (SLI
+	// recordings, metadata recordings and alerts), so the SLOs of a service are reviewed together.
+	GroupedByServiceGroupsOrder GroupsOrder = "grouped-by-service"
+	// LexicalGroupsOrder will sort the groups by name, so the order is stable for minimal diffs.
+	LexicalGroupsOrder GroupsOrder = "lexical"
+	// InputGroupsOrder will keep the groups in the SLOs input order.
+	InputGroupsOrder GroupsOrder = "input"
+)
+
+// OutputFormat is the serialization format of the rules output.
+type OutputFormat string
+
+const (
+	// YAMLFormat will output the rules in YAML (Prometheus rule files format).
+	YAMLFormat OutputFormat = "yaml"
+	// JSONFormat will output the rules in JSON, that has the same structure as the YAML format.
+	JSONFormat OutputFormat = "json"
+	// JSONLinesFormat will output the rules in JSON Lines, one JSON object per rule with the
+	// rule type (`recording` or `alert`) and group, so the rules can be stream processed.
+	JSONLinesFormat OutputFormat = "jsonl"
 )
 
-func NewIOWriterGroupedRulesYAMLRepo(writer io.Writer, logger log.Logger) IOWriterGroupedRulesYAMLRepo {
-	return IOWriterGroupedRulesYAMLRepo{
-		writer: writer,
-		logger: logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "yaml"}),
+type IOWriterGroupedRulesYAMLRepoConfig struct {
+	Writer io.Writer
+	// Writers are more writers where the rules will be written along with the Writer (e.g: stdout
+	// and a file), the Writer is not required if these are set. The writes on the writers are
+	// sequential, on a writer error the next writers will not be written.
+	Writers []io.Writer
+	Logger  log.Logger
+	// Flavor is the rules output flavor, by default Prometheus.
+	Flavor OutputFlavor
+	// Format is the rules output format, by default YAML. JSON doesn't support comments, so
+	// the disclaimer (and the Mimir tenant header) will be omitted when using JSON or JSON Lines.
+	Format OutputFormat
+	// MimirTenant is the tenant the rules belong to, it will be added as a header comment
+	// so tools like `mimirtool` can know where to load them (used with Mimir flavor).
+	MimirTenant string
+	// MimirSourceTenants are the tenants the rule groups will query as federated
+	// rule groups, for the groups that don't have SLO source tenants (used with Mimir flavor).
+	MimirSourceTenants []string
+	// VictoriaMetricsTenant is the tenant the rule groups belong to, with the form
+	// `accountID[:projectID]` (used with VictoriaMetrics flavor).
+	VictoriaMetricsTenant string
+	// VictoriaMetricsEvalOffset is the offset vmalert will use to evaluate the rule groups
+	// inside the group interval (used with VictoriaMetrics flavor).
+	VictoriaMetricsEvalOffset time.Duration
+	// Gzip will compress the output (including the disclaimer) with gzip.
+	Gzip bool
+	// GzipLevel is the gzip compression level (from gzip.HuffmanOnly to gzip.BestCompression),
+	// by default gzip.DefaultCompression.
+	GzipLevel int
+	// BufferedWrites will buffer the writes on the writer, the buffer is flushed at the end of
+	// each store. This is useful with slow or remote writers (e.g: network connections) to avoid
+	// many small writes.
+	BufferedWrites bool
+	// WriteBufferSize is the size in bytes of the writes buffer, by default 64KiB (used with
+	// BufferedWrites).
+	WriteBufferSize int
+	// GroupPrefix is the prefix of the rule group names (e.g `<prefix>-alerts-<slo-id>`), this is
+	// useful to avoid group name clashes with multiple Sloth instances, by default `sloth-slo`.
+	GroupPrefix string
+	// QueryOffset is the default offset the rule groups will use to query the data (e.g: late
+	// arriving metrics), for the SLOs that don't set it. Requires Prometheus 2.53 or newer.
+	QueryOffset time.Duration
+	// MinInterval is the floor of the SLO rule group intervals, the intervals below it will be
+	// clamped to it (e.g: avoid overloading the ruler). The groups without interval are not
+	// changed. By default disabled.
+	MinInterval time.Duration
+	// MaxInterval is the ceiling of the SLO rule group intervals, the intervals above it will
+	// be clamped to it. By default disabled.
+	MaxInterval time.Duration
+	// GroupLimits are the default rule group limits of the SLOs that don't set them.
+	GroupLimits GroupLimits
+	// GroupLabels are the labels of all the rule groups (`labels` group field), these are added by
+	// Prometheus to all the series and alerts of the group, instead of repeating them on every rule.
+	// The rule labels take precedence over these. Requires Prometheus 3.0 or newer.
+	GroupLabels map[string]string
+	// TargetPrometheusVersion is the Prometheus version (e.g: `2.41`) that will load the rules, the
+	// optional rule fields not supported by it (`limit`, `keep_firing_for`, `query_offset` and group
+	// `labels`) will fail, so older rulers don't break reloading the rules. By default all the fields are allowed.
+	TargetPrometheusVersion string
+	// OmitUnsupportedFields will remove the rule fields not supported by the target Prometheus
+	// version instead of failing (used with TargetPrometheusVersion).
+	OmitUnsupportedFields bool
+	// ExtraLabels are the labels that will be added to all the rules of all the SLOs (e.g:
+	// alert routing labels), along with the SLO extra labels that take precedence over these.
+	ExtraLabels map[string]string
+	// ExtraLabelsOverride will make the extra labels override the rules labels on conflicts, by
+	// default the rules labels (computed by Sloth) win.
+	ExtraLabelsOverride bool
+	// RecordingsOnly will store only the SLI and metadata recording rule groups, skipping
+	// the alert rule groups (e.g: alerts managed by another system).
+	RecordingsOnly bool
+	// AlertsOnly will store only the alert rule groups, skipping the SLI and metadata recording
+	// rule groups (e.g: recording rules managed by another system), it can't be used with RecordingsOnly.
+	AlertsOnly bool
+	// DisableMetadataRecordings will skip the metadata recording rule groups (error budget,
+	// objective...) and store the SLI recording and alert rule groups, e.g: to reduce the
+	// cardinality when the metadata is not used.
+	DisableMetadataRecordings bool
+	// IndexGroup will add an extra rule group (`<prefix>-index`) with an info recording rule per
+	// SLO (`sloth_slo_index_info`), labeled with the SLO ID, name, service and objective, so all
+	// the SLOs can be listed with a single query (e.g: dashboards). Not used with AlertsOnly.
+	IndexGroup bool
+	// DeduplicateRecordingRules will store only once the recording rules that are the same (name,
+	// expression and labels) on multiple SLOs (e.g: SLOs sharing the same SLI), the first one is kept
+	// and the groups without rules are skipped. The rules with different labels are not deduplicated.
+	DeduplicateRecordingRules bool
+	// OTLPMapping will rewrite the metric and label names of the SLI recording rules expressions
+	// to the OpenTelemetry (OTLP) ones, e.g: for metrics ingested with the Prometheus OTLP receiver.
+	// The rewritten expressions are reformatted. By default disabled.
+	OTLPMapping OTLPMapping
+	// SingleLineExpressions will write the rule expressions in a single line on YAML formats, the
+	// multi-line and long expressions are double quoted (e.g: `expr: "a\n/\nb"`) so the diffs are
+	// stable and greppable, the expressions are the same.
+	SingleLineExpressions bool
+	// Strict will fail on the rules with empty label or annotation values (e.g: a label from a spec
+	// field that wasn't set), or with unresolved template values (`<no value>`), instead of storing them.
+	Strict bool
+	// SLOSelector is a label selector (e.g: `env!=staging,tier in (1,2)`) that the SLO labels
+	// must match to store their rules, the SLOs that don't match are skipped. Supports the equality
+	// (`=`, `==`, `!=`), set (`in`, `notin`) and existence (`key`, `!key`) matchers. By default
+	// all the SLOs are stored.
+	SLOSelector string
+	// ShadowSLOIDs are the IDs of the SLOs whose alert rules will have the shadow labels, so the
+	// alerts of new SLOs can be routed to a non paging receiver before promoting them.
+	ShadowSLOIDs []string
+	// ShadowSLOSelector is a label selector (same syntax as SLOSelector) of the SLOs whose alert
+	// rules will have the shadow labels, along with the ShadowSLOIDs ones.
+	ShadowSLOSelector string
+	// ShadowLabels are the labels added to the alert rules of the shadow SLOs, overriding the
+	// alert rules labels, by default `shadow=true`.
+	ShadowLabels map[string]string
+	// SeverityMapping maps the alert rules severity label synonyms to the canonical severities
+	// (e.g: `critical` to `page`), so the alerts of all the SLOs are routed the same way.
+	SeverityMapping map[string]string
+	// DefaultSeverity is the severity set on the alert rules without severity label, if not set
+	// the alert rules without severity are stored as they are.
+	DefaultSeverity string
+	// SeverityLabel is the alert rules label normalized with the SeverityMapping and the
+	// DefaultSeverity, by default `severity`.
+	SeverityLabel string
+	// RecordingRulesPrefix is the prefix added to the metric names of all the recording rules
+	// (e.g: `team_a_` for `team_a_slo:sli_error:ratio_rate5m`), the references to them on the rules
+	// expressions are rewritten with the prefix too. The prefix must be a valid metric name.
+	RecordingRulesPrefix string
+	// PassthroughRuleGroups are hand-written rule groups stored as they are after the generated
+	// ones (no prefix, extra labels or group labels), so they can live in the same rules file. Their
+	// names can't collide with the generated groups names.
+	PassthroughRuleGroups []RuleGroup
+	// SLOComments will write a YAML comment before the first rule group of each SLO, summarizing
+	// the service, name, objective and time window of the SLO (used with Prometheus based flavors,
+	// not Kubernetes or Coralogix). It can't be used with SingleGroup.
+	SLOComments bool
+	// SingleGroup will merge the rules of all the SLOs in a single rule group, the SLO groups
+	// must have the same interval, query offset and limit.
+	SingleGroup bool
+	// SingleGroupName is the name of the single rule group, by default `<prefix>-slos`.
+	SingleGroupName string
+	// DisableGroupsSorting will keep the rule groups in the SLOs input order, it can't be used
+	// with other groups order than InputGroupsOrder.
+	DisableGroupsSorting bool
+	// GroupsOrder is the order of the rule groups (the index group is always after them), by
+	// default InputGroupsOrder for backward compatibility.
+	GroupsOrder GroupsOrder
+	// Sync will sync the writer after writing the rules if it supports it (has a `Sync() error`
+	// method, like files), so the written rules are durable.
+	Sync bool
+	// DisableValidation will disable the validation of the rules (PromQL expressions, names,
+	// labels...) that is made before writing them, with the same rules as Prometheus.
+	DisableValidation bool
+	// CheckSLIReferences will check that the SLI error metrics (`slo:sli_error:...`) used by the
+	// metadata recording rules of each SLO are generated by its SLI recording rules.
+	CheckSLIReferences bool
+	// FailOnNoAlertRules will fail (ErrNoSLOAlertRules) when there are recording rules to store but
+	// none of the SLOs has alert rules, that is usually a misconfiguration (e.g: disabled alerts or
+	// alert windows) that would leave the SLOs without burn rate alerts.
+	FailOnNoAlertRules bool
+	// EmptySLOs is how the SLOs that don't produce any rule (e.g: disabled alerts without
+	// recordings) are handled, so the misconfigured SLOs of a batch are known. By default
+	// IgnoreEmptySLOs.
+	EmptySLOs EmptySLOsHandling
+	// RemoteWriteKeepRegex will add a header comment with the form of `# remote-write-keep-regex: <regex>`
+	// with a regex matching exactly the recording rules metric names, to be used on the
+	// remote write `write_relabel_configs` keep action of the `__name__` label.
+	RemoteWriteKeepRegex bool
+	// CortexTenant is the tenant the rules belong to, it will be added as a header comment
+	// with the form of `# cortex-tenant: <tenant>` (used with Cortex flavor).
+	CortexTenant string
+	// CortexNamespace is the ruler namespace of the rule groups, it will be set as the top
+	// `namespace` key of the rules file, as `cortextool` expects (used with Cortex flavor).
+	CortexNamespace string
+	// ThanosPartialResponseStrategy is the partial response strategy (`warn` or `abort`) of the
+	// rule groups, by default `abort` so alerts are not evaluated with partial data (used with Thanos flavor).
+	ThanosPartialResponseStrategy string
+	// CoralogixApplication is the Coralogix application of the rule groups, by default `sloth`
+	// (used with Coralogix flavor).
+	CoralogixApplication string
+	// CoralogixSubsystem is the Coralogix subsystem of the rule groups, by default the service
+	// of the SLO group (used with Coralogix flavor).
+	CoralogixSubsystem string
+	// HeaderTemplate is the Go template of the header that will replace the default disclaimer
+	// (e.g: license or SPDX headers), it receives the Version, Timestamp and SLOs count. Every
+	// non empty line of the header must be a YAML comment (start with `#`).
+	HeaderTemplate string
+	// Observer will be notified of the stores results and errors, by default a noop observer.
+	Observer StoreObserver
+	// Serializer will serialize the rules instead of the flavor, if not set the serializer registered
+	// for the flavor will be used (RegisterRuleSerializer), the built-in flavors are registered.
+	// The serialized rules can be gzip compressed, but only the RuleGroupsSerializers rules have
+	// a manifest and rule groups.
+	Serializer RuleSerializer
+	// Now returns the current time, by default time.Now (e.g: used for the header timestamp).
+	Now func() time.Time
+	// DisableDisclaimer will disable the top disclaimer of the generated rules.
+	DisableDisclaimer bool
+	// DisclaimerVersion is the version that will be set on the disclaimer, by default
+	// the Sloth version.
+	DisclaimerVersion string
+	// DisableDisclaimerVersion will remove the version from the disclaimer, this is useful
+	// to have reproducible outputs between Sloth versions.
+	DisableDisclaimerVersion bool
+	// DisclaimerTimestamp will add the generation timestamp (using Now) to the disclaimer, this
+	// makes the outputs not reproducible. Not used with the header template, it has the timestamp.
+	DisclaimerTimestamp bool
+}
+
+func (c *IOWriterGroupedRulesYAMLRepoConfig) defaults() error {
+	writers := []io.Writer{}
+	for _, w := range append([]io.Writer{c.Writer}, c.Writers...) {
+		if w != nil {
+			writers = append(writers, w)
+		}
+	}
+	switch len(writers) {
+	case 0:
+		return fmt.Errorf("writer is required")
+	case 1:
+		c.Writer = writers[0]
+	default:
+		c.Writer = teeWriter(writers)
+	}
+
+	if c.Flavor == "" {
+		c.Flavor = PrometheusFlavor
+	}
+
+	serializer, err := c.flavorSerializer()
+	if err != nil {
+		return err
+	}
+	if f, ok := serializer.(ruleGroupsFlavor); ok && f.defaults != nil {
+		err := f.defaults(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.GroupPrefix == "" {
+		c.GroupPrefix = defaultGroupPrefix
+	}
+	if !nameRegexp.MatchString(c.GroupPrefix) {
+		return fmt.Errorf("invalid %q group prefix", c.GroupPrefix)
+	}
+
+	if c.QueryOffset < 0 {
+		return fmt.Errorf("query offset can't be negative")
+	}
+
+	if c.RecordingRulesPrefix != "" && !prommodel.IsValidMetricName(prommodel.LabelValue(c.RecordingRulesPrefix)) {
+		return fmt.Errorf("invalid %q recording rules prefix", c.RecordingRulesPrefix)
+	}
+
+	passthroughNames := map[string]bool{}
+	for _, g := range c.PassthroughRuleGroups {
+		if g.Name == "" {
+			return fmt.Errorf("passthrough rule groups name is required")
+		}
+		if passthroughNames[g.Name] {
+			return fmt.Errorf("duplicated %q passthrough rule group", g.Name)
+		}
+		passthroughNames[g.Name] = true
+	}
+
+	for k := range c.GroupLabels {
+		if !prommodel.LabelName(k).IsValid() {
+			return fmt.Errorf("invalid %q group label name", k)
+		}
+	}
+
+	if len(c.ShadowLabels) == 0 {
+		c.ShadowLabels = map[string]string{defaultShadowLabel: "true"}
+	}
+	for k := range c.ShadowLabels {
+		if !prommodel.LabelName(k).IsValid() {
+			return fmt.Errorf("invalid %q shadow label name", k)
+		}
+	}
+
+	if c.SeverityLabel == "" {
+		c.SeverityLabel = defaultSeverityLabel
+	}
+	if !prommodel.LabelName(c.SeverityLabel).IsValid() {
+		return fmt.Errorf("invalid %q severity label name", c.SeverityLabel)
+	}
+	for from, to := range c.SeverityMapping {
+		if from == "" || to == "" {
+			return fmt.Errorf("invalid %q to %q severity mapping", from, to)
+		}
+	}
+
+	if c.MinInterval < 0 || c.MaxInterval < 0 {
+		return fmt.Errorf("min and max intervals can't be negative")
+	}
+	if c.MinInterval != 0 && c.MaxInterval != 0 && c.MinInterval > c.MaxInterval {
+		return fmt.Errorf("min interval can't be greater than the max interval")
+	}
+
+	switch {
+	case c.GroupsOrder == "":
+		c.GroupsOrder = InputGroupsOrder
+	case c.DisableGroupsSorting && c.GroupsOrder != InputGroupsOrder:
+		return fmt.Errorf("disabled groups sorting can't be used with %q groups order", c.GroupsOrder)
+	}
+	switch c.GroupsOrder {
+	case GroupedByServiceGroupsOrder, LexicalGroupsOrder, InputGroupsOrder:
+	default:
+		return fmt.Errorf("invalid %q groups order", c.GroupsOrder)
+	}
+
+	if c.RecordingsOnly && c.AlertsOnly {
+		return fmt.Errorf("recordings only and alerts only can't be used at the same time")
+	}
+	if c.EmptySLOs == "" {
+		c.EmptySLOs = IgnoreEmptySLOs
+	}
+	switch c.EmptySLOs {
+	case IgnoreEmptySLOs, WarnEmptySLOs, FailEmptySLOs:
+	default:
+		return fmt.Errorf("invalid %q empty SLOs handling", c.EmptySLOs)
+	}
+
+	if c.RecordingsOnly && c.FailOnNoAlertRules {
+		return fmt.Errorf("recordings only and fail on no alert rules can't be used at the same time")
+	}
+
+	err = c.GroupLimits.validate()
+	if err != nil {
+		return fmt.Errorf("invalid group limits: %w", err)
+	}
+
+	err = c.OTLPMapping.validate()
+	if err != nil {
+		return fmt.Errorf("invalid OTLP mapping: %w", err)
+	}
+
+	if c.SingleGroupName == "" {
+		c.SingleGroupName = c.GroupPrefix + "-slos"
+	}
+	if !nameRegexp.MatchString(c.SingleGroupName) {
+		return fmt.Errorf("invalid %q single group name", c.SingleGroupName)
+	}
+	if c.SingleGroup && c.SLOComments {
+		return fmt.Errorf("SLO comments can't be used with a single group")
+	}
+
+	if c.Format == "" {
+		c.Format = YAMLFormat
+	}
+	if c.Format != YAMLFormat && c.Format != JSONFormat && c.Format != JSONLinesFormat {
+		return fmt.Errorf("unknown %q output format", c.Format)
+	}
+
+	if c.GzipLevel == 0 {
+		c.GzipLevel = gzip.DefaultCompression
+	}
+	if c.GzipLevel < gzip.HuffmanOnly || c.GzipLevel > gzip.BestCompression {
+		return fmt.Errorf("invalid %d gzip level", c.GzipLevel)
+	}
+
+	if c.WriteBufferSize == 0 {
+		c.WriteBufferSize = defaultWriteBufferSize
+	}
+	if c.WriteBufferSize < 0 {
+		return fmt.Errorf("write buffer size can't be negative")
+	}
+
+	if c.DisableDisclaimerVersion {
+		c.DisclaimerVersion = ""
+	} else if c.DisclaimerVersion == "" {
+		c.DisclaimerVersion = info.Version
+	}
+
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	if c.Observer == nil {
+		c.Observer = NoopStoreObserver
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": c.Format, "flavor": c.Flavor})
+
+	return nil
+}
+
+// flavorSerializer returns the configured serializer or the registered one of the flavor.
+func (c IOWriterGroupedRulesYAMLRepoConfig) flavorSerializer() (RuleSerializer, error) {
+	if c.Serializer != nil {
+		return c.Serializer, nil
+	}
+
+	s, ok := getRuleSerializer(c.Flavor)
+	if !ok {
+		return nil, fmt.Errorf("unknown %q output flavor", c.Flavor)
+	}
+
+	return s, nil
+}
+
+func NewIOWriterGroupedRulesYAMLRepo(config IOWriterGroupedRulesYAMLRepoConfig) (*IOWriterGroupedRulesYAMLRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// The built-in flavors and the rule groups serializers are written from the rule groups
+	// built by the repository, the rest of the serializers serialize the SLOs.
+	serializer, err := config.flavorSerializer()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	rulesFlavor := ruleGroupsFlavor{id: config.Flavor, name: string(config.Flavor)}
+	var groupsSerializer RuleGroupsSerializer
+	switch s := serializer.(type) {
+	case ruleGroupsFlavor:
+		rulesFlavor, serializer = s, nil
+	case RuleGroupsSerializer:
+		groupsSerializer, serializer = s, nil
+	}
+
+	singleGroupName := ""
+	if config.SingleGroup {
+		singleGroupName = config.SingleGroupName
 	}
+
+	sloSelector := labels.Everything()
+	if config.SLOSelector != "" {
+		sloSelector, err = labels.Parse(config.SLOSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: invalid SLO selector: %w", err)
+		}
+	}
+
+	var shadowSelector labels.Selector
+	if config.ShadowSLOSelector != "" {
+		shadowSelector, err = labels.Parse(config.ShadowSLOSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: invalid shadow SLO selector: %w", err)
+		}
+	}
+	shadowSLOIDs := map[string]bool{}
+	for _, id := range config.ShadowSLOIDs {
+		shadowSLOIDs[id] = true
+	}
+
+	var targetPromVersion *promVersion
+	if config.TargetPrometheusVersion != "" {
+		v, err := parsePromVersion(config.TargetPrometheusVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+		targetPromVersion = &v
+	}
+
+	var headerTpl *template.Template
+	if config.HeaderTemplate != "" {
+		headerTpl, err = template.New("header").Option("missingkey=error").Parse(config.HeaderTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: invalid header template: %w", err)
+		}
+	}
+
+	return &IOWriterGroupedRulesYAMLRepo{
+		writer:             config.Writer,
+		flavor:             config.Flavor,
+		format:             config.Format,
+		mimirTenant:        config.MimirTenant,
+		mimirSourceTenants: config.MimirSourceTenants,
+		vmTenant:           config.VictoriaMetricsTenant,
+		vmEvalOffset:       config.VictoriaMetricsEvalOffset,
+		cortexTenant:       config.CortexTenant,
+		cortexNamespace:    config.CortexNamespace,
+		thanosPRStrategy:   config.ThanosPartialResponseStrategy,
+		coralogixApp:       config.CoralogixApplication,
+		coralogixSubsystem: config.CoralogixSubsystem,
+		gzip:               config.Gzip,
+		gzipLevel:          config.GzipLevel,
+		bufferedWrites:     config.BufferedWrites,
+		writeBufferSize:    config.WriteBufferSize,
+		groupPrefix:        config.GroupPrefix,
+		groupLimits:        config.GroupLimits,
+		groupLabels:        config.GroupLabels,
+		targetPromVersion:  targetPromVersion,
+		omitUnsupported:    config.OmitUnsupportedFields,
+		extraLabels:        config.ExtraLabels,
+		extraLabelsOver:    config.ExtraLabelsOverride,
+		queryOffset:        config.QueryOffset,
+		minInterval:        config.MinInterval,
+		maxInterval:        config.MaxInterval,
+		recordingsOnly:     config.RecordingsOnly,
+		singleGroupName:    singleGroupName,
+		alertsOnly:         config.AlertsOnly,
+		disableMetaRecs:    config.DisableMetadataRecordings,
+		indexGroup:         config.IndexGroup,
+		dedupRecRules:      config.DeduplicateRecordingRules,
+		singleLineExprs:    config.SingleLineExpressions,
+		otlpMapping:        config.OTLPMapping,
+		strict:             config.Strict,
+		sloSelector:        sloSelector,
+		shadowSLOIDs:       shadowSLOIDs,
+		shadowSelector:     shadowSelector,
+		shadowLabels:       config.ShadowLabels,
+		severityMapping:    config.SeverityMapping,
+		defaultSeverity:    config.DefaultSeverity,
+		severityLabel:      config.SeverityLabel,
+		groupsOrder:        config.GroupsOrder,
+		sync:               config.Sync,
+		disableValidation:  config.DisableValidation,
+		checkSLIRefs:       config.CheckSLIReferences,
+		failOnNoAlerts:     config.FailOnNoAlertRules,
+		emptySLOs:          config.EmptySLOs,
+		rwKeepRegex:        config.RemoteWriteKeepRegex,
+		recRulesPrefix:     config.RecordingRulesPrefix,
+		passthroughGroups:  config.PassthroughRuleGroups,
+		sloComments:        config.SLOComments,
+		disclaimerVersion:  config.DisclaimerVersion,
+		disableDisclaimer:  config.DisableDisclaimer,
+		headerTpl:          headerTpl,
+		now:                config.Now,
+		disclaimerTs:       config.DisclaimerTimestamp,
+		observer:           config.Observer,
+		rulesFlavor:        rulesFlavor,
+		groupsSerializer:   groupsSerializer,
+		serializer:         serializer,
+		logger:             config.Logger,
+	}, nil
 }
 
 // IOWriterGroupedRulesYAMLRepo knows to store all the SLO rules (recordings and alerts)
 // grouped in an IOWriter in YAML format, that is compatible with Prometheus.
 type IOWriterGroupedRulesYAMLRepo struct {
-	writer io.Writer
-	logger log.Logger
+	writer             io.Writer
+	flavor             OutputFlavor
+	format             OutputFormat
+	mimirTenant        string
+	mimirSourceTenants []string
+	vmTenant           string
+	vmEvalOffset       time.Duration
+	cortexTenant       string
+	cortexNamespace    string
+	thanosPRStrategy   string
+	coralogixApp       string
+	coralogixSubsystem string
+	gzip               bool
+	gzipLevel          int
+	bufferedWrites     bool
+	writeBufferSize    int
+	groupPrefix        string
+	groupLimits        GroupLimits
+	groupLabels        map[string]string
+	targetPromVersion  *promVersion
+	omitUnsupported    bool
+	extraLabels        map[string]string
+	extraLabelsOver    bool
+	queryOffset        time.Duration
+	minInterval        time.Duration
+	maxInterval        time.Duration
+	recordingsOnly     bool
+	singleGroupName    string
+	alertsOnly         bool
+	disableMetaRecs    bool
+	indexGroup         bool
+	dedupRecRules      bool
+	singleLineExprs    bool
+	otlpMapping        OTLPMapping
+	strict             bool
+	sloSelector        labels.Selector
+	shadowSLOIDs       map[string]bool
+	shadowSelector     labels.Selector
+	shadowLabels       map[string]string
+	severityMapping    map[string]string
+	defaultSeverity    string
+	severityLabel      string
+	groupsOrder        GroupsOrder
+	sync               bool
+	disableValidation  bool
+	checkSLIRefs       bool
+	failOnNoAlerts     bool
+	emptySLOs          EmptySLOsHandling
+	rwKeepRegex        bool
+	recRulesPrefix     string
+	passthroughGroups  []RuleGroup
+	sloComments        bool
+	disableDisclaimer  bool
+	disclaimerVersion  string
+	headerTpl          *template.Template
+	now                func() time.Time
+	disclaimerTs       bool
+	observer           StoreObserver
+	rulesFlavor        ruleGroupsFlavor
+	groupsSerializer   RuleGroupsSerializer
+	serializer         RuleSerializer
+	logger             log.Logger
 }
 
 type StorageSLO struct {
 	SLO   SLO
 	Rules SLORules
+	// Interval is the evaluation interval of the SLO rule groups, if not set
+	// the groups will use the Prometheus global evaluation interval.
+	Interval time.Duration
+	// QueryOffset is the offset the SLO rule groups will use to query the data, if not set
+	// the repository default query offset will be used.
+	QueryOffset time.Duration
+	// AlertAnnotationTemplates are the Go templates of the annotations that will be set on
+	// the SLO alert rules, they receive the SLO (e.g: `https://runbooks.io/{{ .Service }}/{{ .ID }}`).
+	AlertAnnotationTemplates map[string]string
+	// GroupLimits are the limits of the SLO rule groups, the ones not set will use
+	// the repository default group limits.
+	GroupLimits GroupLimits
+	// ExtraLabels are the labels that will be added to all the SLO rules, on conflicts these
+	// take precedence over the repository extra labels.
+	ExtraLabels map[string]string
+	// SourceTenants are the tenants the SLO rule groups will query as federated rule
+	// groups (used with Mimir and Cortex flavors).
+	SourceTenants GroupSourceTenants
+}
+
+// GroupLimits are the Prometheus rule group `limit`s by group type, these cap the number
+// of series (recordings) or alerts a rule group can produce, if the limit is exceeded all
+// the rules of the group will fail. 0 means no limit.
+type GroupLimits struct {
+	SLIRecordings      int
+	MetadataRecordings int
+	Alerts             int
+}
+
+func (g GroupLimits) validate() error {
+	if g.SLIRecordings < 0 || g.MetadataRecordings < 0 || g.Alerts < 0 {
+		return fmt.Errorf("limits can't be negative")
+	}
+
+	return nil
+}
+
+// withDefaults returns the group limits using the default ones for the not set limits.
+func (g GroupLimits) withDefaults(defaults GroupLimits) GroupLimits {
+	if g.SLIRecordings == 0 {
+		g.SLIRecordings = defaults.SLIRecordings
+	}
+	if g.MetadataRecordings == 0 {
+		g.MetadataRecordings = defaults.MetadataRecordings
+	}
+	if g.Alerts == 0 {
+		g.Alerts = defaults.Alerts
+	}
+
+	return g
+}
+
+// GroupSourceTenants are the federated rule groups `source_tenants` by group type, usually
+// only the recording rule groups need to query the data of multiple tenants.
+type GroupSourceTenants struct {
+	SLIRecordings      []string
+	MetadataRecordings []string
+	Alerts             []string
+}
+
+func (g GroupSourceTenants) validate() error {
+	for _, tenants := range [][]string{g.SLIRecordings, g.MetadataRecordings, g.Alerts} {
+		for _, t := range tenants {
+			if t == "" {
+				return fmt.Errorf("source tenants can't be empty")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g GroupSourceTenants) isEmpty() bool {
+	return len(g.SLIRecordings) == 0 && len(g.MetadataRecordings) == 0 && len(g.Alerts) == 0
+}
+
+// StoreResult is the result of storing the SLO rules.
+type StoreResult struct {
+	Groups         int
+	RecordingRules int
+	AlertRules     int
+	BytesWritten   int
 }
 
 // StoreSLOs will store the recording and alert prometheus rules, if grouped is false it will
 // split and store as 2 different groups the alerts and the recordings, if true
 // it will be save as a single group.
 func (i IOWriterGroupedRulesYAMLRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
-	if len(slos) == 0 {
-		return fmt.Errorf("slo rules required")
+	_, err := i.StoreSLOsResult(ctx, slos)
+	return err
+}
+
+// StoreSLOsResult is like StoreSLOs but returns the result of the stored rules.
+func (i IOWriterGroupedRulesYAMLRepo) StoreSLOsResult(ctx context.Context, slos []StorageSLO) (*StoreResult, error) {
+	res, _, err := i.store(ctx, slos)
+	return res, err
+}
+
+// StoreSLOsWithManifest is like StoreSLOsResult but after storing the rules, it will write
+// on the manifest writer the JSON manifest of the stored SLOs and their rule groups.
+func (i IOWriterGroupedRulesYAMLRepo) StoreSLOsWithManifest(ctx context.Context, slos []StorageSLO, manifest io.Writer) (*StoreResult, error) {
+	if i.serializer != nil {
+		return nil, fmt.Errorf("manifest is only supported with rule groups serializers")
 	}
 
-	ruleGroups := ruleGroupsYAMLv2{}
-	for _, slo := range slos {
-		if len(slo.Rules.SLIErrorRecRules) > 0 {
-			ruleGroups.Groups = append(ruleGroups.Groups, ruleGroupYAMLv2{
-				Name:  fmt.Sprintf("sloth-slo-sli-recordings-%s", slo.SLO.ID),
-				Rules: slo.Rules.SLIErrorRecRules,
-			})
+	res, stored, err := i.store(ctx, slos)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := json.MarshalIndent(newManifest(stored.ruleGroups), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not format manifest: %w", err)
+	}
+
+	_, err = manifest.Write(append(m, '\n'))
+	if err != nil {
+		return nil, fmt.Errorf("could not write manifest: %w", err)
+	}
+
+	return res, nil
+}
+
+// StoreSLOsWithChecksum is like StoreSLOsResult but it also returns the hex encoded SHA-256
+// checksum of the stored rules, e.g: to only apply the rules when they change. The checksum
+// is calculated without compressing the rules and without the disclaimer version and timestamp,
+// so the same rules have the same checksum regardless of the Sloth version that generated them.
+// The rule serializers checksum is the one of the serialized rules.
+func (i IOWriterGroupedRulesYAMLRepo) StoreSLOsWithChecksum(ctx context.Context, slos []StorageSLO) (*StoreResult, string, error) {
+	res, stored, err := i.store(ctx, slos)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum, err := i.checksum(stored)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, sum, nil
+}
+
+// checksum returns the hex encoded SHA-256 of the encoded rule groups with the volatile
+// disclaimer data (version and timestamp) normalized, or of the serialized rules.
+func (i IOWriterGroupedRulesYAMLRepo) checksum(stored storedRules) (string, error) {
+	h := sha256.New()
+	if i.serializer != nil {
+		_, _ = h.Write(stored.data)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	i.disclaimerVersion = ""
+	i.disclaimerTs = false
+	i.now = func() time.Time { return time.Time{} }
+
+	err := i.encode(h, stored.ruleGroups)
+	if err != nil {
+		return "", fmt.Errorf("could not calculate checksum: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GenerateString is a helper that returns the SLO rules that StoreSLOs would write with the
+// flavor default configuration (including the disclaimer) and the number of rule groups.
+// If there aren't rules it will return ErrNoSLORules.
+func GenerateString(ctx context.Context, slos []StorageSLO, flavor OutputFlavor) (string, int, error) {
+	var b strings.Builder
+	repo, err := NewIOWriterGroupedRulesYAMLRepo(IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &b,
+		Flavor: flavor,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("could not create storage: %w", err)
+	}
+
+	res, err := repo.StoreSLOsResult(ctx, slos)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return b.String(), res.Groups, nil
+}
+
+// StoreObserver is notified of the results of storing the SLO rules, e.g: to instrument
+// the generation with metrics.
+type StoreObserver interface {
+	// OnStored is called when the SLO rules have been stored.
+	OnStored(ctx context.Context, flavor OutputFlavor, res StoreResult)
+	// OnEmpty is called when there weren't SLO rules to store (ErrNoSLORules).
+	OnEmpty(ctx context.Context, flavor OutputFlavor, slos int)
+	// OnError is called when the SLO rules couldn't be stored.
+	OnError(ctx context.Context, flavor OutputFlavor, slos int, err error)
+}
+
+// NoopStoreObserver is an observer that doesn't do anything.
+const NoopStoreObserver = noopStoreObserver(0)
+
+type noopStoreObserver int
+
+func (noopStoreObserver) OnStored(context.Context, OutputFlavor, StoreResult) {}
+func (noopStoreObserver) OnEmpty(context.Context, OutputFlavor, int)          {}
+func (noopStoreObserver) OnError(context.Context, OutputFlavor, int, error)   {}
+
+// storedRules are the stored rule groups, or the serialized rules with the rule serializers.
+type storedRules struct {
+	ruleGroups ruleGroupsYAMLv2
+	data       []byte
+}
+
+// store stores the SLO rules notifying the observer of the result.
+func (i IOWriterGroupedRulesYAMLRepo) store(ctx context.Context, slos []StorageSLO) (*StoreResult, storedRules, error) {
+	res, stored, err := i.storeRules(ctx, slos)
+	switch {
+	case errors.Is(err, ErrNoSLORules):
+		i.observer.OnEmpty(ctx, i.flavor, len(slos))
+	case err != nil:
+		i.observer.OnError(ctx, i.flavor, len(slos), err)
+	default:
+		i.observer.OnStored(ctx, i.flavor, *res)
+	}
+
+	return res, stored, err
+}
+
+func (i IOWriterGroupedRulesYAMLRepo) storeRules(ctx context.Context, slos []StorageSLO) (*StoreResult, storedRules, error) {
+	var (
+		res    *StoreResult
+		stored storedRules
+		err    error
+	)
+	if i.serializer != nil {
+		res, stored.data, err = i.serialize(ctx, slos)
+		if err != nil {
+			return nil, stored, err
 		}
 
-		if len(slo.Rules.MetadataRecRules) > 0 {
-			ruleGroups.Groups = append(ruleGroups.Groups, ruleGroupYAMLv2{
-				Name:  fmt.Sprintf("sloth-slo-meta-recordings-%s", slo.SLO.ID),
-				Rules: slo.Rules.MetadataRecRules,
-			})
+		res.BytesWritten, err = i.writeData(i.writer, stored.data)
+		if err != nil {
+			return nil, stored, err
+		}
+	} else {
+		res, stored.ruleGroups, err = i.prepare(ctx, slos)
+		if err != nil {
+			return nil, stored, err
 		}
 
-		if len(slo.Rules.AlertRules) > 0 {
-			ruleGroups.Groups = append(ruleGroups.Groups, ruleGroupYAMLv2{
-				Name:  fmt.Sprintf("sloth-slo-alerts-%s", slo.SLO.ID),
-				Rules: slo.Rules.AlertRules,
-			})
+		res.BytesWritten, err = i.write(i.writer, stored.ruleGroups)
+		if err != nil {
+			return nil, stored, err
 		}
 	}
 
-	// If we don't have anything to store, error so we can increase the reliability
-	// because maybe this was due to an unintended error (typos, misconfig, too many disable...).
-	if len(ruleGroups.Groups) == 0 {
-		return ErrNoSLORules
+	if i.sync {
+		if s, ok := i.writer.(syncer); ok {
+			err := s.Sync()
+			if err != nil {
+				return nil, stored, fmt.Errorf("could not sync rules: %w", err)
+			}
+		}
 	}
 
-	// Convert to YAML (Prometheus rule format).
-	rulesYaml, err := yaml.Marshal(ruleGroups)
-	if err != nil {
-		return fmt.Errorf("could not format rules: %w", err)
+	logger := i.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{
+		"groups":          res.Groups,
+		"recording-rules": res.RecordingRules,
+		"alert-rules":     res.AlertRules,
+		"services":        i.sloServices(slos),
+	}).Infof("%s rules written", i.rulesFlavor.name)
+
+	return res, stored, nil
+}
+
+// ValidateSLOs will generate the SLO rules in the same way StoreSLOs does, but without
+// writing them, returning the result (without written bytes) or the error that StoreSLOs
+// would return (including ErrNoSLORules).
+func (i IOWriterGroupedRulesYAMLRepo) ValidateSLOs(ctx context.Context, slos []StorageSLO) (*StoreResult, error) {
+	if i.serializer != nil {
+		res, _, err := i.serialize(ctx, slos)
+		return res, err
 	}
 
-	rulesYaml = writeTopDisclaimer(rulesYaml)
-	_, err = i.writer.Write(rulesYaml)
+	res, _, err := i.prepare(ctx, slos)
 	if err != nil {
-		return fmt.Errorf("could not write top disclaimer: %w", err)
+		return nil, err
 	}
 
-	logger := i.logger.WithCtxValues(ctx)
-	logger.WithValues(log.Kv{"groups": len(ruleGroups.Groups)}).Infof("Prometheus rules written")
+	return res, nil
+}
 
-	return nil
+// BuildRuleGroups will build the SLO rule groups in the same way StoreSLOs does, but instead of
+// serializing them, it will return them, e.g: to load them on an embedded ruler.
+func (i IOWriterGroupedRulesYAMLRepo) BuildRuleGroups(ctx context.Context, slos []StorageSLO) ([]RuleGroup, error) {
+	if i.serializer != nil {
+		return nil, fmt.Errorf("rule groups are only supported with rule groups serializers")
+	}
+
+	_, ruleGroups, err := i.prepare(ctx, slos)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRuleGroups(ruleGroups), nil
 }
 
-var disclaimer = fmt.Sprintf(`
----
-# Code generated by Sloth (%s): https://github.com/slok/sloth.
-# DO NOT EDIT.
+// DiffSLOs will generate the SLO rules in the same way StoreSLOs does, but instead of writing
+// them, it will compare them with the rules of the file on the path. A missing file is handled
+// as an empty one.
+func (i IOWriterGroupedRulesYAMLRepo) DiffSLOs(ctx context.Context, path string, slos []StorageSLO) (*DiffResult, error) {
+	if i.gzip {
+		return nil, fmt.Errorf("diff of gzip compressed rules is not supported")
+	}
+
+	if i.serializer != nil {
+		_, data, err := i.serialize(ctx, slos)
+		if err != nil {
+			return nil, err
+		}
+		return DiffFile(path, data)
+	}
 
-`, info.Version)
+	_, ruleGroups, err := i.prepare(ctx, slos)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	_, err = i.write(&b, ruleGroups)
+	if err != nil {
+		return nil, err
+	}
 
-func writeTopDisclaimer(bs []byte) []byte {
-	return append([]byte(disclaimer), bs...)
+	return DiffFile(path, b.Bytes())
 }
 
-// these types are defined to support yaml v2 (instead of the new Prometheus
-// YAML v3 that has some problems with marshaling).
-type ruleGroupsYAMLv2 struct {
-	Groups []ruleGroupYAMLv2 `yaml:"groups"`
+// Serialize satisfies RuleSerializer interface, it returns the rules that StoreSLOs would
+// write, without compressing them.
+func (i IOWriterGroupedRulesYAMLRepo) Serialize(slos []StorageSLO) (int, []byte, error) {
+	if i.serializer != nil {
+		return i.serializer.Serialize(slos)
+	}
+
+	res, ruleGroups, err := i.prepare(context.Background(), slos)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var b bytes.Buffer
+	err = i.encode(&b, ruleGroups)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return res.Groups, b.Bytes(), nil
 }
 
-type ruleGroupYAMLv2 struct {
-	Name     string             `yaml:"name"`
-	Interval prommodel.Duration `yaml:"interval,omitempty"`
-	Rules    []rulefmt.Rule     `yaml:"rules"`
+// SerializeRuleGroups satisfies RuleGroupsSerializer interface, it returns the rule groups with the
+// flavor options as StoreSLOs would write them, without compressing them.
+func (i IOWriterGroupedRulesYAMLRepo) SerializeRuleGroups(groups []RuleGroup) ([]byte, error) {
+	if i.serializer != nil {
+		return nil, fmt.Errorf("rule groups are only supported with rule groups serializers")
+	}
+
+	ruleGroups := ruleGroupsYAMLv2{Groups: newRuleGroupsYAMLv2(groups)}
+	if i.rulesFlavor.setGroupOptions != nil {
+		i.rulesFlavor.setGroupOptions(i, &ruleGroups)
+	}
+
+	var b bytes.Buffer
+	err := i.encode(&b, ruleGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// serialize returns the result and the rules serialized with the repository serializer.
+func (i IOWriterGroupedRulesYAMLRepo) serialize(ctx context.Context, slos []StorageSLO) (*StoreResult, []byte, error) {
+	if len(slos) == 0 {
+		return nil, nil, fmt.Errorf("slo rules required")
+	}
+
+	slos = i.selectSLOs(ctx, slos)
+	if len(slos) == 0 {
+		return nil, nil, ErrNoSLORules
+	}
+
+	groups, data, err := i.serializer.Serialize(slos)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not serialize rules: %w", err)
+	}
+
+	// Same as the built-in flavors, increase the reliability failing without rules.
+	if groups == 0 {
+		return nil, nil, ErrNoSLORules
+	}
+
+	return &StoreResult{Groups: groups}, data, nil
+}
+
+// selectSLOs returns the SLOs that match the SLO selector.
+func (i IOWriterGroupedRulesYAMLRepo) selectSLOs(ctx context.Context, slos []StorageSLO) []StorageSLO {
+	if i.sloSelector.Empty() {
+		return slos
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	selected := make([]StorageSLO, 0, len(slos))
+	for _, slo := range slos {
+		if !i.sloSelector.Matches(labels.Set(slo.SLO.Labels)) {
+			logger.Infof("%q SLO doesn't match the %q SLO selector, skipping", slo.SLO.ID, i.sloSelector)
+			continue
+		}
+		selected = append(selected, slo)
+	}
+
+	return selected
+}
+
+// isShadowSLO returns true if the SLO alert rules must have the shadow labels, selected by
+// the shadow SLO IDs or selector.
+func (i IOWriterGroupedRulesYAMLRepo) isShadowSLO(slo SLO) bool {
+	if i.shadowSLOIDs[slo.ID] {
+		return true
+	}
+
+	return i.shadowSelector != nil && i.shadowSelector.Matches(labels.Set(slo.Labels))
+}
+
+// sloServices returns the sorted distinct services of the SLOs that match the SLO selector.
+func (i IOWriterGroupedRulesYAMLRepo) sloServices(slos []StorageSLO) []string {
+	set := map[string]bool{}
+	services := []string{}
+	for _, slo := range slos {
+		if set[slo.SLO.Service] || !i.sloSelector.Matches(labels.Set(slo.SLO.Labels)) {
+			continue
+		}
+		set[slo.SLO.Service] = true
+		services = append(services, slo.SLO.Service)
+	}
+	sort.Strings(services)
+
+	return services
+}
+
+// prepare returns the validated rule groups that will be written and the result of them.
+func (i IOWriterGroupedRulesYAMLRepo) prepare(ctx context.Context, slos []StorageSLO) (*StoreResult, ruleGroupsYAMLv2, error) {
+	if len(slos) == 0 {
+		return nil, ruleGroupsYAMLv2{}, fmt.Errorf("slo rules required")
+	}
+
+	slos = i.selectSLOs(ctx, slos)
+	if len(slos) == 0 {
+		return nil, ruleGroupsYAMLv2{}, ErrNoSLORules
+	}
+
+	if i.groupsOrder == GroupedByServiceGroupsOrder {
+		slos = sortSLOs(slos)
+	}
+
+	ruleGroups, err := i.buildRuleGroups(ctx, slos)
+	if err != nil {
+		return nil, ruleGroups, err
+	}
+
+	err = i.checkEmptySLOs(ctx, slos, ruleGroups)
+	if err != nil {
+		return nil, ruleGroups, err
+	}
+
+	if i.groupsOrder == LexicalGroupsOrder {
+		sort.SliceStable(ruleGroups.Groups, func(x, y int) bool {
+			return ruleGroups.Groups[x].Name < ruleGroups.Groups[y].Name
+		})
+	}
+
+	// Prometheus rejects the rule files with duplicated group names, fail fast with the SLOs.
+	err = checkDuplicatedGroups(ruleGroups)
+	if err != nil {
+		return nil, ruleGroups, err
+	}
+
+	if i.dedupRecRules {
+		ruleGroups = dedupRecordingRules(ruleGroups)
+	}
+
+	if i.singleGroupName != "" {
+		ruleGroups, err = mergeRuleGroups(ruleGroups, i.singleGroupName)
+		if err != nil {
+			return nil, ruleGroups, fmt.Errorf("could not merge the rule groups: %w", err)
+		}
+	}
+
+	// If we don't have anything to store, error so we can increase the reliability
+	// because maybe this was due to an unintended error (typos, misconfig, too many disable...).
+	if len(ruleGroups.Groups) == 0 {
+		return nil, ruleGroups, ErrNoSLORules
+	}
+
+	if i.failOnNoAlerts {
+		err := checkAlertRules(ruleGroups)
+		if err != nil {
+			return nil, ruleGroups, err
+		}
+	}
+
+	// The index lists the stored SLOs, so it's only added when there are SLO rules.
+	if i.indexGroup && !i.alertsOnly {
+		ruleGroups.Groups = append(ruleGroups.Groups, newIndexRuleGroup(i.groupPrefix, slos))
+	}
+
+	if i.recRulesPrefix != "" {
+		err := prefixRecordingRules(ruleGroups, i.recRulesPrefix)
+		if err != nil {
+			return nil, ruleGroups, fmt.Errorf("could not prefix the recording rules: %w", err)
+		}
+	}
+
+	if len(i.groupLabels) > 0 {
+		for idx := range ruleGroups.Groups {
+			ruleGroups.Groups[idx].Labels = i.groupLabels
+		}
+	}
+
+	if len(i.passthroughGroups) > 0 {
+		// Converted on each store, so the passthrough groups are not changed by the next steps.
+		passthroughGroups := newRuleGroupsYAMLv2(i.passthroughGroups)
+		err := checkPassthroughGroups(ruleGroups, passthroughGroups)
+		if err != nil {
+			return nil, ruleGroups, err
+		}
+		ruleGroups.Groups = append(ruleGroups.Groups, passthroughGroups...)
+	}
+
+	if i.targetPromVersion != nil {
+		err := gateRuleGroupsFeatures(ruleGroups, *i.targetPromVersion, i.omitUnsupported, i.logger.WithCtxValues(ctx))
+		if err != nil {
+			return nil, ruleGroups, fmt.Errorf("unsupported rules: %w", err)
+		}
+	}
+
+	if i.strict {
+		err := checkStrictRuleGroups(ruleGroups)
+		if err != nil {
+			return nil, ruleGroups, fmt.Errorf("invalid rules: %w", err)
+		}
+	}
+
+	if !i.disableValidation {
+		err := validateRuleGroups(ruleGroups)
+		if err != nil {
+			return nil, ruleGroups, fmt.Errorf("invalid rules: %w", err)
+		}
+	}
+
+	if i.rulesFlavor.setGroupOptions != nil {
+		i.rulesFlavor.setGroupOptions(i, &ruleGroups)
+	}
+
+	res := &StoreResult{Groups: len(ruleGroups.Groups)}
+	for _, g := range ruleGroups.Groups {
+		for _, r := range g.Rules {
+			if r.Record != "" {
+				res.RecordingRules++
+			} else {
+				res.AlertRules++
+			}
+		}
+	}
+
+	return res, ruleGroups, nil
+}
+
+// write streams the rule groups to the writer (one group at a time instead of the
+// whole file) and returns the written bytes.
+func (i IOWriterGroupedRulesYAMLRepo) write(writer io.Writer, ruleGroups ruleGroupsYAMLv2) (int, error) {
+	return i.compressedWrite(writer, func(w io.Writer) error {
+		return i.encode(w, ruleGroups)
+	})
+}
+
+// writeData writes the serialized rules to the writer and returns the written bytes.
+func (i IOWriterGroupedRulesYAMLRepo) writeData(writer io.Writer, data []byte) (int, error) {
+	return i.compressedWrite(writer, func(w io.Writer) error {
+		_, err := w.Write(data)
+		if err != nil {
+			return fmt.Errorf("could not write rules: %w", err)
+		}
+		return nil
+	})
+}
+
+// encode encodes the rule groups with the repository format and flavor, or with the
+// rule groups serializer.
+func (i IOWriterGroupedRulesYAMLRepo) encode(w io.Writer, ruleGroups ruleGroupsYAMLv2) error {
+	if i.groupsSerializer != nil {
+		data, err := i.groupsSerializer.SerializeRuleGroups(newRuleGroups(ruleGroups))
+		if err != nil {
+			return fmt.Errorf("could not serialize rules: %w", err)
+		}
+		_, err = w.Write(data)
+		if err != nil {
+			return fmt.Errorf("could not write rules: %w", err)
+		}
+		return nil
+	}
+
+	switch i.format {
+	case JSONFormat:
+		return writePrometheusJSON(w, ruleGroups)
+	case JSONLinesFormat:
+		return writePrometheusJSONLines(w, ruleGroups)
+	}
+
+	return i.writePrometheusYAML(w, ruleGroups)
+}
+
+// compressedWrite calls the write function with the writer, compressing and buffering the
+// written data if required, and returns the bytes written to the writer.
+func (i IOWriterGroupedRulesYAMLRepo) compressedWrite(writer io.Writer, write func(w io.Writer) error) (int, error) {
+	cw := &countWriter{w: writer}
+	var w io.Writer = cw
+
+	var bw *bufio.Writer
+	if i.bufferedWrites {
+		bw = bufio.NewWriterSize(cw, i.writeBufferSize)
+		w = bw
+	}
+
+	var gw *gzip.Writer
+	if i.gzip {
+		var err error
+		gw, err = gzip.NewWriterLevel(w, i.gzipLevel)
+		if err != nil {
+			return 0, fmt.Errorf("could not create gzip writer: %w", err)
+		}
+		w = gw
+	}
+
+	err := write(w)
+	if err == nil && gw != nil {
+		err = gw.Close()
+		if err != nil {
+			err = fmt.Errorf("could not compress rules: %w", err)
+		}
+	}
+
+	// Flush even on errors, so the writer has the same data as without the buffer.
+	if bw != nil {
+		ferr := bw.Flush()
+		if err == nil && ferr != nil {
+			err = fmt.Errorf("could not flush rules: %w", ferr)
+		}
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return cw.n, nil
+}
+
+// syncer is implemented by the writers that can commit the written data to a durable
+// storage (e.g: os.File).
+type syncer interface {
+	Sync() error
+}
+
+// teeWriter writes on all the writers, the errors will have the writer that failed.
+type teeWriter []io.Writer
+
+func (t teeWriter) Write(p []byte) (int, error) {
+	for idx, w := range t {
+		n, err := w.Write(p)
+		if err == nil && n != len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return n, fmt.Errorf("could not write on writer %d: %w", idx, err)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Sync syncs the writers that support it.
+func (t teeWriter) Sync() error {
+	for idx, w := range t {
+		if s, ok := w.(syncer); ok {
+			err := s.Sync()
+			if err != nil {
+				return fmt.Errorf("could not sync writer %d: %w", idx, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (i IOWriterGroupedRulesYAMLRepo) writePrometheusYAML(w io.Writer, ruleGroups ruleGroupsYAMLv2) error {
+	header := ""
+	if !i.disableDisclaimer {
+		d, err := i.disclaimer(ruleGroups)
+		if err != nil {
+			return err
+		}
+		header += d
+	}
+
+	if i.rulesFlavor.header != nil {
+		header += i.rulesFlavor.header(i)
+	}
+
+	if i.rwKeepRegex {
+		header += fmt.Sprintf("# remote-write-keep-regex: %s\n", remoteWriteKeepRegex(ruleGroups))
+	}
+
+	_, err := io.WriteString(w, header)
+	if err != nil {
+		return fmt.Errorf("could not write rules header: %w", err)
+	}
+
+	if i.singleLineExprs {
+		ruleGroups = withSingleLineExprs(ruleGroups)
+	}
+
+	if i.rulesFlavor.streamYAML != nil {
+		return i.rulesFlavor.streamYAML(i, w, ruleGroups)
+	}
+
+	return streamPrometheusYAML(w, ruleGroups)
+}
+
+// streamCoralogixYAML writes each rule group as a Coralogix rule group YAML document.
+func (i IOWriterGroupedRulesYAMLRepo) streamCoralogixYAML(w io.Writer, ruleGroups ruleGroupsYAMLv2) error {
+	for idx, g := range ruleGroups.Groups {
+		// Only separate the documents, the disclaimer already has the separator.
+		if idx > 0 {
+			_, err := io.WriteString(w, "---\n")
+			if err != nil {
+				return fmt.Errorf("could not write rules: %w", err)
+			}
+		}
+
+		kind := coralogixRecordingKind
+		for _, r := range g.Rules {
+			if r.Alert != "" {
+				kind = coralogixAlertKind
+				break
+			}
+		}
+
+		subsystem := i.coralogixSubsystem
+		if subsystem == "" {
+			subsystem = g.sloService
+		}
+
+		err := encodeYAML(w, coralogixRuleGroupYAMLv2{
+			APIVersion: coralogixAPIVersion,
+			Kind:       kind,
+			Metadata: coralogixMetaYAMLv2{
+				Application: i.coralogixApp,
+				Subsystem:   subsystem,
+			},
+			Spec: g,
+		})
+		if err != nil {
+			return fmt.Errorf("could not format %q group rules: %w", g.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// streamPrometheusYAML writes the Prometheus rule groups in YAML (Prometheus rule format)
+// encoding each of the groups independently, the result is the same as encoding all at once.
+func streamPrometheusYAML(w io.Writer, ruleGroups ruleGroupsYAMLv2) error {
+	if ruleGroups.Namespace != "" {
+		err := encodeYAML(w, struct {
+			Namespace string `yaml:"namespace"`
+		}{Namespace: ruleGroups.Namespace})
+		if err != nil {
+			return fmt.Errorf("could not format rules: %w", err)
+		}
+	}
+
+	_, err := io.WriteString(w, "groups:\n")
+	if err != nil {
+		return fmt.Errorf("could not write rules: %w", err)
+	}
+
+	// The SLO groups could be not contiguous (e.g: lexical order), comment only the first one.
+	commented := map[string]bool{}
+	for _, g := range ruleGroups.Groups {
+		if g.sloComment != "" && !commented[g.sloID] {
+			_, err := fmt.Fprintf(w, "# %s\n", g.sloComment)
+			if err != nil {
+				return fmt.Errorf("could not write rules: %w", err)
+			}
+			commented[g.sloID] = true
+		}
+
+		err := encodeYAML(w, []ruleGroupYAMLv2{g})
+		if err != nil {
+			return fmt.Errorf("could not format %q group rules: %w", g.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func encodeYAML(w io.Writer, v interface{}) error {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
+
+	data := b.Bytes()
+	if bytes.Contains(data, []byte(singleLineExprPrefix)) {
+		data = replaceSingleLineExprs(data)
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// singleLineExprPrefix is the prefix of the single line expressions placeholders. YAML v2 folds
+// the long scalars on the spaces and it can't be disabled per encoder, so the expressions are
+// encoded as placeholders without spaces and replaced with the quoted expressions afterwards.
+const singleLineExprPrefix = "sloth-single-line-expr-"
+
+var singleLineExprRegexp = regexp.MustCompile(singleLineExprPrefix + `[A-Za-z0-9_-]*`)
+
+// withSingleLineExprs returns a copy of the rule groups with the expressions that could be
+// written in multiple lines (have spaces or new lines) replaced with placeholders.
+func withSingleLineExprs(ruleGroups ruleGroupsYAMLv2) ruleGroupsYAMLv2 {
+	res := ruleGroupsYAMLv2{Namespace: ruleGroups.Namespace, Groups: make([]ruleGroupYAMLv2, 0, len(ruleGroups.Groups))}
+	for _, g := range ruleGroups.Groups {
+		rules := make([]ruleYAMLv2, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			if strings.ContainsAny(r.Expr, " \t\n") {
+				r.Expr = singleLineExprPrefix + base64.RawURLEncoding.EncodeToString([]byte(r.Expr))
+			}
+			rules = append(rules, r)
+		}
+		g.Rules = rules
+		res.Groups = append(res.Groups, g)
+	}
+
+	return res
+}
+
+// replaceSingleLineExprs replaces the single line expressions placeholders with the double
+// quoted expressions, the Go escape sequences are valid YAML escape sequences.
+func replaceSingleLineExprs(data []byte) []byte {
+	return singleLineExprRegexp.ReplaceAllFunc(data, func(placeholder []byte) []byte {
+		// The placeholders are always valid, we created them.
+		expr, _ := base64.RawURLEncoding.DecodeString(string(placeholder[len(singleLineExprPrefix):]))
+		return []byte(strconv.Quote(string(expr)))
+	})
+}
+
+// writePrometheusJSON writes the Prometheus rule groups in JSON using the same structure
+// as the YAML rule files, encoding each of the groups independently.
+func writePrometheusJSON(w io.Writer, ruleGroups ruleGroupsYAMLv2) error {
+	const indent = "  "
+
+	header := "{\n"
+	if ruleGroups.Namespace != "" {
+		ns, err := json.Marshal(ruleGroups.Namespace)
+		if err != nil {
+			return fmt.Errorf("could not format rules: %w", err)
+		}
+		header += fmt.Sprintf("%s\"namespace\": %s,\n", indent, ns)
+	}
+	header += indent + "\"groups\": [\n"
+
+	_, err := io.WriteString(w, header)
+	if err != nil {
+		return fmt.Errorf("could not write rules: %w", err)
+	}
+
+	for idx, g := range ruleGroups.Groups {
+		rules := make([]ruleJSON, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			rules = append(rules, ruleJSON(r))
+		}
+
+		groupJSON, err := json.MarshalIndent(ruleGroupJSON{
+			Name:                    g.Name,
+			Type:                    g.Type,
+			Interval:                g.Interval,
+			QueryOffset:             g.QueryOffset,
+			Limit:                   g.Limit,
+			EvalOffset:              g.EvalOffset,
+			Tenant:                  g.Tenant,
+			SourceTenants:           g.SourceTenants,
+			PartialResponseStrategy: g.PartialResponseStrategy,
+			Labels:                  g.Labels,
+			Rules:                   rules,
+		}, indent+indent, indent)
+		if err != nil {
+			return fmt.Errorf("could not format %q group rules: %w", g.Name, err)
+		}
+
+		sep := ",\n"
+		if idx == len(ruleGroups.Groups)-1 {
+			sep = "\n"
+		}
+		_, err = io.WriteString(w, indent+indent+string(groupJSON)+sep)
+		if err != nil {
+			return fmt.Errorf("could not write rules: %w", err)
+		}
+	}
+
+	_, err = io.WriteString(w, indent+"]\n}\n")
+	if err != nil {
+		return fmt.Errorf("could not write rules: %w", err)
+	}
+
+	return nil
+}
+
+// writePrometheusJSONLines writes the rules as JSON Lines, each line is an independent JSON
+// object with a rule and its group, without disclaimer.
+func writePrometheusJSONLines(w io.Writer, ruleGroups ruleGroupsYAMLv2) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, g := range ruleGroups.Groups {
+		for _, r := range g.Rules {
+			typ := ruleLineTypeRecording
+			if r.Alert != "" {
+				typ = ruleLineTypeAlert
+			}
+
+			err := enc.Encode(ruleLineJSON{
+				Type:      typ,
+				Namespace: ruleGroups.Namespace,
+				Group:     g.Name,
+				ruleJSON:  ruleJSON(r),
+			})
+			if err != nil {
+				return fmt.Errorf("could not write %q group rules: %w", g.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// countWriter counts the bytes written on the wrapped writer.
+type countWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// validateRuleGroups validates the rules in the same way Prometheus does when loading them.
+func validateRuleGroups(ruleGroups ruleGroupsYAMLv2) error {
+	for _, g := range ruleGroups.Groups {
+		for _, r := range g.Rules {
+			node := rulefmt.RuleNode{
+				Record:      yamlv3.Node{Value: r.Record},
+				Alert:       yamlv3.Node{Value: r.Alert},
+				Expr:        yamlv3.Node{Value: r.Expr},
+				For:         r.For,
+				Labels:      r.Labels,
+				Annotations: r.Annotations,
+			}
+
+			errs := node.Validate()
+			if len(errs) > 0 {
+				name := r.Record
+				if name == "" {
+					name = r.Alert
+				}
+				// Unwrap to remove the YAML node positions, we don't have them.
+				return fmt.Errorf("invalid %q rule on %q group: %w", name, g.Name, errs[0].Unwrap())
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkStrictRuleGroups checks the rules labels and annotations values with CheckStrictRuleValues.
+func checkStrictRuleGroups(ruleGroups ruleGroupsYAMLv2) error {
+	for _, g := range ruleGroups.Groups {
+		for _, r := range g.Rules {
+			err := CheckStrictRuleValues(r.Labels, r.Annotations)
+			if err != nil {
+				name := r.Record
+				if name == "" {
+					name = r.Alert
+				}
+				return fmt.Errorf("invalid %q rule on %q group: %w", name, g.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// unresolvedTemplateValue is what the Go templates render for the missing values.
+const unresolvedTemplateValue = "<no value>"
+
+// CheckStrictRuleValues fails if any of the rule labels or annotations has an empty value
+// or an unresolved template value (`<no value>`).
+func CheckStrictRuleValues(labels, annotations map[string]string) error {
+	check := func(kind string, m map[string]string) error {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // Deterministic errors.
+
+		for _, k := range keys {
+			v := m[k]
+			switch {
+			case strings.TrimSpace(v) == "":
+				return fmt.Errorf("%s %q has an empty value", kind, k)
+			case strings.Contains(v, unresolvedTemplateValue):
+				return fmt.Errorf("%s %q has an unresolved template value: %q", kind, k, v)
+			}
+		}
+		return nil
+	}
+
+	err := check("label", labels)
+	if err != nil {
+		return err
+	}
+
+	return check("annotation", annotations)
+}
+
+// setMimirGroupOptions sets the Mimir specific group options on the rule groups.
+func setMimirGroupOptions(ruleGroups ruleGroupsYAMLv2, sourceTenants []string) {
+	for i := range ruleGroups.Groups {
+		if len(ruleGroups.Groups[i].SourceTenants) == 0 {
+			ruleGroups.Groups[i].SourceTenants = sourceTenants
+		}
+	}
+}
+
+// setVictoriaMetricsGroupOptions sets the vmalert specific group options on the rule groups.
+func setVictoriaMetricsGroupOptions(ruleGroups ruleGroupsYAMLv2, tenant string, evalOffset time.Duration) {
+	for i := range ruleGroups.Groups {
+		ruleGroups.Groups[i].Type = "prometheus"
+		ruleGroups.Groups[i].Tenant = tenant
+		ruleGroups.Groups[i].EvalOffset = prommodel.Duration(evalOffset)
+	}
+}
+
+// sortSLOs returns the SLOs sorted by service and then by ID, the groups of each SLO
+// are always built in the same type order.
+func sortSLOs(slos []StorageSLO) []StorageSLO {
+	sorted := make([]StorageSLO, len(slos))
+	copy(sorted, slos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].SLO.Service != sorted[j].SLO.Service {
+			return sorted[i].SLO.Service < sorted[j].SLO.Service
+		}
+		return sorted[i].SLO.ID < sorted[j].SLO.ID
+	})
+
+	return sorted
+}
+
+// clampInterval returns the SLO interval clamped to the min and max intervals, the SLOs
+// without interval use the Prometheus global evaluation interval, so they are not clamped.
+func (i IOWriterGroupedRulesYAMLRepo) clampInterval(ctx context.Context, sloID string, interval time.Duration) time.Duration {
+	if interval == 0 {
+		return interval
+	}
+
+	clamped := interval
+	switch {
+	case i.minInterval != 0 && interval < i.minInterval:
+		clamped = i.minInterval
+	case i.maxInterval != 0 && interval > i.maxInterval:
+		clamped = i.maxInterval
+	default:
+		return interval
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	logger.Warningf("%q SLO interval %s is out of the allowed range, using %s", sloID, interval, clamped)
+
+	return clamped
+}
+
+func (i IOWriterGroupedRulesYAMLRepo) buildRuleGroups(ctx context.Context, slos []StorageSLO) (ruleGroupsYAMLv2, error) {
+	prefix := i.groupPrefix
+	ruleGroups := ruleGroupsYAMLv2{}
+	for idx, slo := range slos {
+		// Checking every SLO would add overhead for nothing, the SLOs are fast to generate.
+		if idx%ctxCheckSLOs == 0 {
+			if err := ctx.Err(); err != nil {
+				return ruleGroups, err
+			}
+		}
+
+		if slo.Interval < 0 {
+			return ruleGroups, fmt.Errorf("invalid %q SLO interval %s: must be positive", slo.SLO.ID, slo.Interval)
+		}
+		interval := prommodel.Duration(i.clampInterval(ctx, slo.SLO.ID, slo.Interval))
+
+		if slo.QueryOffset < 0 {
+			return ruleGroups, fmt.Errorf("invalid %q SLO query offset %s: must be positive", slo.SLO.ID, slo.QueryOffset)
+		}
+		queryOffset := prommodel.Duration(i.queryOffset)
+		if slo.QueryOffset != 0 {
+			queryOffset = prommodel.Duration(slo.QueryOffset)
+		}
+
+		err := slo.GroupLimits.validate()
+		if err != nil {
+			return ruleGroups, fmt.Errorf("invalid %q SLO group limits: %w", slo.SLO.ID, err)
+		}
+		limits := slo.GroupLimits.withDefaults(i.groupLimits)
+
+		err = slo.SourceTenants.validate()
+		if err != nil {
+			return ruleGroups, fmt.Errorf("invalid %q SLO source tenants: %w", slo.SLO.ID, err)
+		}
+		if !slo.SourceTenants.isEmpty() && !i.rulesFlavor.federated {
+			return ruleGroups, fmt.Errorf("invalid %q SLO source tenants: only supported by mimir and cortex flavors", slo.SLO.ID)
+		}
+		extraLabels := mergeLabels(i.extraLabels, slo.ExtraLabels)
+
+		if i.checkSLIRefs {
+			err := checkSLIReferences(slo.Rules)
+			if err != nil {
+				return ruleGroups, fmt.Errorf("invalid %q SLO metadata recording rules: %w", slo.SLO.ID, err)
+			}
+		}
+
+		sloGroupsStart := len(ruleGroups.Groups)
+		if len(slo.Rules.SLIErrorRecRules) > 0 && !i.alertsOnly {
+			rules := setExtraLabels(newRulesYAMLv2(slo.Rules.SLIErrorRecRules, SLO{}), extraLabels, i.extraLabelsOver)
+			if !i.otlpMapping.isEmpty() {
+				err := rewriteOTLPRules(rules, i.otlpMapping)
+				if err != nil {
+					return ruleGroups, fmt.Errorf("invalid %q SLO SLI recording rules: %w", slo.SLO.ID, err)
+				}
+			}
+
+			ruleGroups.Groups = append(ruleGroups.Groups, ruleGroupYAMLv2{
+				Name:          fmt.Sprintf("%s-sli-recordings-%s", prefix, slo.SLO.ID),
+				Interval:      interval,
+				QueryOffset:   queryOffset,
+				Limit:         limits.SLIRecordings,
+				SourceTenants: slo.SourceTenants.SLIRecordings,
+				Rules:         rules,
+				sloID:         slo.SLO.ID,
+				sloService:    slo.SLO.Service,
+			})
+		}
+
+		if len(slo.Rules.MetadataRecRules) > 0 && !i.alertsOnly && !i.disableMetaRecs {
+			ruleGroups.Groups = append(ruleGroups.Groups, ruleGroupYAMLv2{
+				Name:          fmt.Sprintf("%s-meta-recordings-%s", prefix, slo.SLO.ID),
+				Interval:      interval,
+				QueryOffset:   queryOffset,
+				Limit:         limits.MetadataRecordings,
+				SourceTenants: slo.SourceTenants.MetadataRecordings,
+				Rules:         setExtraLabels(newRulesYAMLv2(slo.Rules.MetadataRecRules, SLO{}), extraLabels, i.extraLabelsOver),
+				sloID:         slo.SLO.ID,
+				sloService:    slo.SLO.Service,
+			})
+		}
+
+		if len(slo.Rules.AlertRules) > 0 && !i.recordingsOnly {
+			rules := setExtraLabels(newRulesYAMLv2(slo.Rules.AlertRules, slo.SLO), extraLabels, i.extraLabelsOver)
+			err = setAlertAnnotationTemplates(rules, slo.SLO, slo.AlertAnnotationTemplates)
+			if err != nil {
+				return ruleGroups, fmt.Errorf("invalid %q SLO alert annotation templates: %w", slo.SLO.ID, err)
+			}
+			rules = normalizeSeverities(rules, i.severityLabel, i.severityMapping, i.defaultSeverity)
+			if i.isShadowSLO(slo.SLO) {
+				rules = setExtraLabels(rules, i.shadowLabels, true)
+			}
+
+			ruleGroups.Groups = append(ruleGroups.Groups, ruleGroupYAMLv2{
+				Name:          fmt.Sprintf("%s-alerts-%s", prefix, slo.SLO.ID),
+				Interval:      interval,
+				QueryOffset:   queryOffset,
+				Limit:         limits.Alerts,
+				SourceTenants: slo.SourceTenants.Alerts,
+				Rules:         rules,
+				sloID:         slo.SLO.ID,
+				sloService:    slo.SLO.Service,
+			})
+		}
+
+		if i.sloComments {
+			comment := sloComment(slo.SLO)
+			for idx := sloGroupsStart; idx < len(ruleGroups.Groups); idx++ {
+				ruleGroups.Groups[idx].sloComment = comment
+			}
+		}
+	}
+
+	return ruleGroups, nil
+}
+
+// sloComment returns the summary of the SLO for the YAML comments, the names are quoted so the
+// comment is a single line.
+func sloComment(slo SLO) string {
+	return fmt.Sprintf("SLO %q of %q service: %s%% objective over %s window.",
+		slo.Name, slo.Service, strconv.FormatFloat(slo.Objective, 'f', -1, 64), prommodel.Duration(slo.TimeWindow))
+}
+
+// checkPassthroughGroups returns an error if the passthrough rule groups names collide with the
+// generated rule groups.
+func checkPassthroughGroups(ruleGroups ruleGroupsYAMLv2, passthroughGroups []ruleGroupYAMLv2) error {
+	groups := map[string]ruleGroupYAMLv2{}
+	for _, g := range ruleGroups.Groups {
+		groups[g.Name] = g
+	}
+
+	for _, g := range passthroughGroups {
+		if og, ok := groups[g.Name]; ok {
+			return fmt.Errorf("passthrough %q rule group collides with the rule group generated by %q SLO of %q service", g.Name, og.sloID, og.sloService)
+		}
+	}
+
+	return nil
+}
+
+// prefixRecordingRules adds the prefix to the recording rules metric names, rewriting the
+// references to them on all the rules expressions (e.g: the metadata recordings and alerts
+// using the SLI recordings), so the expressions are consistent.
+func prefixRecordingRules(ruleGroups ruleGroupsYAMLv2, prefix string) error {
+	names := map[string]string{}
+	for _, g := range ruleGroups.Groups {
+		for _, r := range g.Rules {
+			if r.Record != "" {
+				names[r.Record] = prefix + r.Record
+			}
+		}
+	}
+
+	// Same metric names rewrite as the OTLP mapping.
+	mapping := OTLPMapping{Metrics: names}
+	for _, g := range ruleGroups.Groups {
+		for idx, r := range g.Rules {
+			expr, err := mapping.rewriteExpr(r.Expr)
+			if err != nil {
+				return fmt.Errorf("invalid %q group rule expression: %w", g.Name, err)
+			}
+			g.Rules[idx].Expr = expr
+			if r.Record != "" {
+				g.Rules[idx].Record = names[r.Record]
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkEmptySLOs handles the SLOs that don't have any rule group, based on the empty SLOs handling.
+func (i IOWriterGroupedRulesYAMLRepo) checkEmptySLOs(ctx context.Context, slos []StorageSLO, ruleGroups ruleGroupsYAMLv2) error {
+	if i.emptySLOs == IgnoreEmptySLOs {
+		return nil
+	}
+
+	withRules := map[string]bool{}
+	for _, g := range ruleGroups.Groups {
+		withRules[g.sloID] = true
+	}
+
+	empty := []string{}
+	for _, slo := range slos {
+		if !withRules[slo.SLO.ID] {
+			empty = append(empty, slo.SLO.ID)
+		}
+	}
+	if len(empty) == 0 {
+		return nil
+	}
+
+	if i.emptySLOs == FailEmptySLOs {
+		return fmt.Errorf("SLOs without rules: %s", strings.Join(empty, ", "))
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	for _, id := range empty {
+		logger.WithValues(log.Kv{"slo": id}).Warningf("%q SLO doesn't have rules", id)
+	}
+
+	return nil
+}
+
+// remoteWriteKeepRegex returns the regex that matches exactly the recording rules metric
+// names (sorted and deduplicated), Prometheus relabel regexes are already fully anchored.
+func remoteWriteKeepRegex(ruleGroups ruleGroupsYAMLv2) string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, g := range ruleGroups.Groups {
+		for _, r := range g.Rules {
+			if r.Record == "" || seen[r.Record] {
+				continue
+			}
+			seen[r.Record] = true
+			names = append(names, regexp.QuoteMeta(r.Record))
+		}
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, "|")
+}
+
+// checkAlertRules returns ErrNoSLOAlertRules when the rule groups have recording rules but
+// not a single alert rule.
+func checkAlertRules(ruleGroups ruleGroupsYAMLv2) error {
+	recordings := 0
+	for _, g := range ruleGroups.Groups {
+		for _, r := range g.Rules {
+			if r.Alert != "" {
+				return nil
+			}
+			recordings++
+		}
+	}
+
+	if recordings == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d recording rules generated without alert rules, check that the SLOs alerts are not disabled and the alert windows are valid", ErrNoSLOAlertRules, recordings)
+}
+
+// checkSLIReferences returns an error listing the SLI error metrics referenced by the metadata
+// recording rules that are not generated by the SLI recording rules.
+func checkSLIReferences(rules SLORules) error {
+	records := map[string]bool{}
+	for _, r := range rules.SLIErrorRecRules {
+		records[r.Record] = true
+	}
+
+	dangling := []string{}
+	found := map[string]bool{}
+	for _, r := range rules.MetadataRecRules {
+		expr, err := promqlparser.ParseExpr(r.Expr)
+		if err != nil {
+			return fmt.Errorf("could not parse %q rule expression: %w", r.Record, err)
+		}
+
+		promqlparser.Inspect(expr, func(node promqlparser.Node, _ []promqlparser.Node) error {
+			vs, ok := node.(*promqlparser.VectorSelector)
+			if !ok || !strings.HasPrefix(vs.Name, sliErrorMetricPrefix) || records[vs.Name] || found[vs.Name] {
+				return nil
+			}
+			found[vs.Name] = true
+			dangling = append(dangling, vs.Name)
+			return nil
+		})
+	}
+
+	if len(dangling) > 0 {
+		sort.Strings(dangling)
+		return fmt.Errorf("dangling SLI error metric references, not generated by the SLI recording rules: %s", strings.Join(dangling, ", "))
+	}
+
+	return nil
+}
+
+// checkDuplicatedGroups returns an error if multiple SLOs generate the same rule group name.
+func checkDuplicatedGroups(ruleGroups ruleGroupsYAMLv2) error {
+	groups := map[string]ruleGroupYAMLv2{}
+	for _, g := range ruleGroups.Groups {
+		if og, ok := groups[g.Name]; ok {
+			return fmt.Errorf("duplicated %q rule group, generated by %q SLO of %q service and %q SLO of %q service",
+				g.Name, og.sloID, og.sloService, g.sloID, g.sloService)
+		}
+		groups[g.Name] = g
+	}
+
+	return nil
+}
+
+// mergeRuleGroups merges all the rule groups in a single group with the name. The same rule on
+// multiple groups will be deduplicated, however rules with the same name and labels but different
+// expressions would generate the same series, so these will fail.
+func mergeRuleGroups(ruleGroups ruleGroupsYAMLv2, name string) (ruleGroupsYAMLv2, error) {
+	if len(ruleGroups.Groups) == 0 {
+		return ruleGroups, nil
+	}
+
+	first := ruleGroups.Groups[0]
+	merged := ruleGroupYAMLv2{
+		Name:          name,
+		Interval:      first.Interval,
+		QueryOffset:   first.QueryOffset,
+		Limit:         first.Limit,
+		SourceTenants: first.SourceTenants,
+	}
+	rules := map[string]ruleYAMLv2{}
+	for _, g := range ruleGroups.Groups {
+		sameTenants := strings.Join(g.SourceTenants, ",") == strings.Join(merged.SourceTenants, ",")
+		if g.Interval != merged.Interval || g.QueryOffset != merged.QueryOffset || g.Limit != merged.Limit || !sameTenants {
+			return ruleGroups, fmt.Errorf("%q group interval, query offset, limit or source tenants are different from the %q group ones", g.Name, first.Name)
+		}
+
+		for _, r := range g.Rules {
+			key := ruleSeriesKey(r)
+			if or, ok := rules[key]; ok {
+				if or.Expr != r.Expr || or.For != r.For || or.KeepFiringFor != r.KeepFiringFor {
+					name := r.Record
+					if name == "" {
+						name = r.Alert
+					}
+					return ruleGroups, fmt.Errorf("%q rules with the same labels and different expressions collide on %q group", name, g.Name)
+				}
+				continue
+			}
+			rules[key] = r
+			merged.Rules = append(merged.Rules, r)
+		}
+	}
+
+	ruleGroups.Groups = []ruleGroupYAMLv2{merged}
+	return ruleGroups, nil
+}
+
+// dedupRecordingRules removes the recording rules that are the same as a previous one (name,
+// expression and labels), the groups that don't have rules after it are removed.
+func dedupRecordingRules(ruleGroups ruleGroupsYAMLv2) ruleGroupsYAMLv2 {
+	seen := map[string]bool{}
+	groups := make([]ruleGroupYAMLv2, 0, len(ruleGroups.Groups))
+	for _, g := range ruleGroups.Groups {
+		rules := make([]ruleYAMLv2, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			if r.Record != "" {
+				key := ruleSeriesKey(r) + " " + r.Expr
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			rules = append(rules, r)
+		}
+
+		if len(rules) == 0 {
+			continue
+		}
+		g.Rules = rules
+		groups = append(groups, g)
+	}
+
+	ruleGroups.Groups = groups
+	return ruleGroups
+}
+
+// ruleSeriesKey returns the key that identifies the series a rule generates, its name and labels.
+func ruleSeriesKey(r ruleYAMLv2) string {
+	labels := make([]string, 0, len(r.Labels))
+	for k, v := range r.Labels {
+		labels = append(labels, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(labels)
+
+	return fmt.Sprintf("%s/%s{%s}", r.Record, r.Alert, strings.Join(labels, ","))
+}
+
+// newIndexRuleGroup returns the rule group with the info recording rules of all the SLOs.
+func newIndexRuleGroup(prefix string, slos []StorageSLO) ruleGroupYAMLv2 {
+	rules := make([]ruleYAMLv2, 0, len(slos))
+	for _, slo := range slos {
+		rules = append(rules, ruleYAMLv2{
+			Record: metricSLOIndexInfo,
+			Expr:   "vector(1)",
+			Labels: mergeLabels(slo.SLO.GetSLOIDPromLabels(), map[string]string{
+				sloObjectiveLabelName: strconv.FormatFloat(slo.SLO.Objective, 'f', -1, 64),
+			}),
+		})
+	}
+
+	return ruleGroupYAMLv2{
+		Name:  prefix + "-index",
+		Rules: rules,
+	}
+}
+
+// newRulesYAMLv2 converts the rules setting the alert options of the SLO that the
+// rules don't have, based on the severity of the alerts.
+func newRulesYAMLv2(rules []rulefmt.Rule, slo SLO) []ruleYAMLv2 {
+	res := make([]ruleYAMLv2, 0, len(rules))
+	for _, r := range rules {
+		rule := ruleYAMLv2{
+			Record:      r.Record,
+			Alert:       r.Alert,
+			Expr:        r.Expr,
+			For:         r.For,
+			Labels:      r.Labels,
+			Annotations: r.Annotations,
+		}
+
+		if r.Alert != "" {
+			switch r.Labels[sloSeverityLabelName] {
+			case alert.PageAlertSeverity.String():
+				rule.KeepFiringFor = prommodel.Duration(slo.PageAlertMeta.KeepFiringFor)
+			case alert.TicketAlertSeverity.String():
+				rule.KeepFiringFor = prommodel.Duration(slo.TicketAlertMeta.KeepFiringFor)
+			}
+		}
+
+		res = append(res, rule)
+	}
+
+	return res
+}
+
+// setExtraLabels adds the extra labels to the rules, on conflicts the rules labels win
+// unless override is used.
+func setExtraLabels(rules []ruleYAMLv2, extraLabels map[string]string, override bool) []ruleYAMLv2 {
+	if len(extraLabels) == 0 {
+		return rules
+	}
+
+	for idx, r := range rules {
+		if override {
+			rules[idx].Labels = mergeLabels(r.Labels, extraLabels)
+		} else {
+			rules[idx].Labels = mergeLabels(extraLabels, r.Labels)
+		}
+	}
+
+	return rules
+}
+
+// normalizeSeverities maps the severity label synonyms of the alert rules to the canonical
+// severities, setting the default severity on the rules without severity label.
+func normalizeSeverities(rules []ruleYAMLv2, label string, mapping map[string]string, defaultSeverity string) []ruleYAMLv2 {
+	if len(mapping) == 0 && defaultSeverity == "" {
+		return rules
+	}
+
+	for idx, r := range rules {
+		severity, ok := r.Labels[label]
+		switch {
+		case !ok && defaultSeverity != "":
+			severity = defaultSeverity
+		case ok && mapping[severity] != "":
+			severity = mapping[severity]
+		default:
+			continue
+		}
+
+		// The rule labels could be shared with the SLO rules.
+		rules[idx].Labels = mergeLabels(r.Labels, map[string]string{label: severity})
+	}
+
+	return rules
+}
+
+// setAlertAnnotationTemplates renders the annotation templates with the SLO and sets them on the
+// alert rules, replacing the annotations with the same name.
+func setAlertAnnotationTemplates(rules []ruleYAMLv2, slo SLO, tpls map[string]string) error {
+	if len(tpls) == 0 {
+		return nil
+	}
+
+	// Sorted so the errors are deterministic.
+	names := make([]string, 0, len(tpls))
+	for name := range tpls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	annotations := make(map[string]string, len(tpls))
+	for _, name := range names {
+		tpl, err := template.New(name).Option("missingkey=error").Parse(tpls[name])
+		if err != nil {
+			return fmt.Errorf("could not parse %q annotation template: %w", name, err)
+		}
+
+		var b bytes.Buffer
+		err = tpl.Execute(&b, slo)
+		if err != nil {
+			return fmt.Errorf("could not render %q annotation template: %w", name, err)
+		}
+		annotations[name] = b.String()
+	}
+
+	for idx, r := range rules {
+		if r.Alert == "" {
+			continue
+		}
+
+		// Don't mutate the annotations of the SLO rules.
+		ruleAnnotations := make(map[string]string, len(r.Annotations)+len(annotations))
+		for k, v := range r.Annotations {
+			ruleAnnotations[k] = v
+		}
+		for k, v := range annotations {
+			ruleAnnotations[k] = v
+		}
+		rules[idx].Annotations = ruleAnnotations
+	}
+
+	return nil
+}
+
+type FSGroupedRulesYAMLRepoConfig struct {
+	// Path is the directory where the rule files will be stored.
+	Path string
+	// IOWriterConfig is the configuration used to store the rules of each of the
+	// files (the writer will be set by the repository for each file, the extra writers
+	// are ignored).
+	IOWriterConfig IOWriterGroupedRulesYAMLRepoConfig
+}
+
+func (c *FSGroupedRulesYAMLRepoConfig) defaults() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	// Validate the file storage configuration using it with a fake writer.
+	ioConfig := c.IOWriterConfig
+	ioConfig.Writer = io.Discard
+	err := ioConfig.defaults()
+	if err != nil {
+		return err
+	}
+
+	if c.IOWriterConfig.Logger == nil {
+		c.IOWriterConfig.Logger = log.Noop
+	}
+
+	return nil
+}
+
+// FSGroupedRulesYAMLRepo knows to store the SLO rules in the file system, splitting them
+// in one file per service (`sloth-<service>.yaml` or `sloth-<service>.json` on JSON format,
+// with `.gz` suffix if compressed), that are compatible with Prometheus. On Kubernetes flavor
+// the service will be added as a suffix of each file object name.
+type FSGroupedRulesYAMLRepo struct {
+	path           string
+	ioWriterConfig IOWriterGroupedRulesYAMLRepoConfig
+	logger         log.Logger
+}
+
+func NewFSGroupedRulesYAMLRepo(config FSGroupedRulesYAMLRepoConfig) (*FSGroupedRulesYAMLRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &FSGroupedRulesYAMLRepo{
+		path:           config.Path,
+		ioWriterConfig: config.IOWriterConfig,
+		logger:         config.IOWriterConfig.Logger.WithValues(log.Kv{"svc": "storage.FS"}),
+	}, nil
+}
+
+// StoreSLOs will store the SLO rules of each service in its own file, the existing files will
+// be replaced atomically.
+func (f FSGroupedRulesYAMLRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	if len(slos) == 0 {
+		return fmt.Errorf("slo rules required")
+	}
+
+	// Group by service maintaining the order.
+	services := []string{}
+	slosByService := map[string][]StorageSLO{}
+	for _, slo := range slos {
+		svc := slo.SLO.Service
+		if !nameRegexp.MatchString(svc) {
+			return fmt.Errorf("invalid %q service name for a file", svc)
+		}
+
+		if _, ok := slosByService[svc]; !ok {
+			services = append(services, svc)
+		}
+		slosByService[svc] = append(slosByService[svc], slo)
+	}
+
+	err := os.MkdirAll(f.path, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not create %q directory: %w", f.path, err)
+	}
+
+	ext := rulesFileExtension(f.ioWriterConfig)
+	logger := f.logger.WithCtxValues(ctx)
+	files := 0
+	for _, svc := range services {
+		var b bytes.Buffer
+		ioConfig := f.ioWriterConfig
+		ioConfig.Writer = &b
+		ioConfig.Writers = nil
+		repo, err := NewIOWriterGroupedRulesYAMLRepo(ioConfig)
+		if err != nil {
+			return fmt.Errorf("could not create storage: %w", err)
+		}
+
+		err = repo.StoreSLOs(ctx, slosByService[svc])
+		if err != nil {
+			// A service without rules doesn't need a file.
+			if errors.Is(err, ErrNoSLORules) {
+				logger.Warningf("Service %q doesn't have rules, ignoring", svc)
+				continue
+			}
+			return fmt.Errorf("could not store %q service SLOs: %w", svc, err)
+		}
+
+		filePath := filepath.Join(f.path, fmt.Sprintf("sloth-%s.%s", svc, ext))
+		err = writeFileAtomic(filePath, b.Bytes())
+		if err != nil {
+			return fmt.Errorf("could not write %q file: %w", filePath, err)
+		}
+		files++
+	}
+
+	if files == 0 {
+		return ErrNoSLORules
+	}
+
+	logger.WithValues(log.Kv{"files": files}).Infof("Prometheus rule files written")
+
+	return nil
+}
+
+// rulesFileExtension returns the rule files extension of the format and compression.
+func rulesFileExtension(config IOWriterGroupedRulesYAMLRepoConfig) string {
+	ext := "yaml"
+	switch config.Format {
+	case JSONFormat:
+		ext = "json"
+	case JSONLinesFormat:
+		ext = "jsonl"
+	}
+	if config.Gzip {
+		ext += ".gz"
+	}
+
+	return ext
+}
+
+type FSSplitRulesYAMLRepoConfig struct {
+	// Path is the directory where the numbered rule files will be stored.
+	Path string
+	// MaxGroupsPerFile is the maximum number of rule groups of each file, 0 means no limit.
+	MaxGroupsPerFile int
+	// MaxBytesPerFile is the maximum size in bytes of each file (with the disclaimer and the
+	// compression), 0 means no limit. The groups that are bigger are stored alone in a file.
+	MaxBytesPerFile int
+	// IOWriterConfig is the configuration used to store the rules of each of the
+	// files (the writer will be set by the repository for each file).
+	IOWriterConfig IOWriterGroupedRulesYAMLRepoConfig
+}
+
+func (c *FSSplitRulesYAMLRepoConfig) defaults() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if c.MaxGroupsPerFile < 0 || c.MaxBytesPerFile < 0 {
+		return fmt.Errorf("max groups and bytes per file can't be negative")
+	}
+	if c.MaxGroupsPerFile == 0 && c.MaxBytesPerFile == 0 {
+		return fmt.Errorf("max groups or bytes per file is required")
+	}
+
+	// Validate the file storage configuration using it with a fake writer.
+	ioConfig := c.IOWriterConfig
+	ioConfig.Writer = io.Discard
+	err := ioConfig.defaults()
+	if err != nil {
+		return err
+	}
+
+	// The split files are rendered from the rule groups.
+	serializer, err := ioConfig.flavorSerializer()
+	if err != nil {
+		return err
+	}
+	if _, ok := serializer.(RuleGroupsSerializer); !ok {
+		return fmt.Errorf("only the rule groups serializers can be split")
+	}
+
+	if c.IOWriterConfig.Logger == nil {
+		c.IOWriterConfig.Logger = log.Noop
+	}
+
+	return nil
+}
+
+// FSSplitRulesYAMLRepo knows to store the SLO rules in the file system, splitting the rule groups
+// in numbered files (`sloth-001.yaml`, `sloth-002.yaml`...) with a maximum number of groups or
+// bytes each (e.g: rulers with file limits). The groups are never split and each file has its
+// own disclaimer. On Kubernetes flavor the file number will be added as a suffix of each file
+// object name.
+type FSSplitRulesYAMLRepo struct {
+	path      string
+	maxGroups int
+	maxBytes  int
+	repo      *IOWriterGroupedRulesYAMLRepo
+	ext       string
+	logger    log.Logger
+}
+
+func NewFSSplitRulesYAMLRepo(config FSSplitRulesYAMLRepoConfig) (*FSSplitRulesYAMLRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// The repository only prepares and encodes the groups of each file.
+	ioConfig := config.IOWriterConfig
+	ioConfig.Writer = io.Discard
+	repo, err := NewIOWriterGroupedRulesYAMLRepo(ioConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FSSplitRulesYAMLRepo{
+		path:      config.Path,
+		maxGroups: config.MaxGroupsPerFile,
+		maxBytes:  config.MaxBytesPerFile,
+		repo:      repo,
+		ext:       rulesFileExtension(config.IOWriterConfig),
+		logger:    config.IOWriterConfig.Logger.WithValues(log.Kv{"svc": "storage.FSSplit"}),
+	}, nil
+}
+
+// StoreSLOs will store the SLO rules split in the numbered files, the existing files will
+// be replaced atomically and the numbered files of previous stores that are not used anymore
+// will be removed.
+func (f FSSplitRulesYAMLRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	_, ruleGroups, err := f.repo.prepare(ctx, slos)
+	if err != nil {
+		return err
+	}
+
+	logger := f.logger.WithCtxValues(ctx)
+	files, err := f.split(ruleGroups, logger)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(f.path, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not create %q directory: %w", f.path, err)
+	}
+
+	for idx, data := range files {
+		filePath := filepath.Join(f.path, fmt.Sprintf("sloth-%03d.%s", idx+1, f.ext))
+		err = writeFileAtomic(filePath, data)
+		if err != nil {
+			return fmt.Errorf("could not write %q file: %w", filePath, err)
+		}
+	}
+
+	err = f.removeStaleFiles(len(files))
+	if err != nil {
+		return err
+	}
+
+	logger.WithValues(log.Kv{"files": len(files), "groups": len(ruleGroups.Groups)}).Infof("Prometheus rule files written")
+
+	return nil
+}
+
+// split returns the data of each of the files, adding the groups to a file until one of the
+// limits is exceeded. To not render the files on each added group, the file sizes are estimated
+// with the size of each group rendered alone (without the file header), and only if the rendered
+// file is bigger than the estimation (e.g: compression), its last groups are moved to the next file.
+func (f FSSplitRulesYAMLRepo) split(ruleGroups ruleGroupsYAMLv2, logger log.Logger) ([][]byte, error) {
+	render := func(groups []ruleGroupYAMLv2) ([]byte, error) {
+		var b bytes.Buffer
+		_, err := f.repo.write(&b, ruleGroupsYAMLv2{Namespace: ruleGroups.Namespace, Groups: groups})
+		if err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	groups := ruleGroups.Groups
+	headerSize := 0
+	groupSizes := make([]int, len(groups))
+	if f.maxBytes > 0 {
+		data, err := render(nil)
+		if err != nil {
+			return nil, err
+		}
+		headerSize = len(data)
+
+		for idx, g := range groups {
+			data, err := render([]ruleGroupYAMLv2{g})
+			if err != nil {
+				return nil, err
+			}
+			groupSizes[idx] = len(data) - headerSize
+		}
+	}
+
+	files := [][]byte{}
+	for start := 0; start < len(groups); {
+		end := start + 1
+		size := headerSize + groupSizes[start]
+		for end < len(groups) {
+			if f.maxGroups > 0 && end-start == f.maxGroups {
+				break
+			}
+			if f.maxBytes > 0 && size+groupSizes[end] > f.maxBytes {
+				break
+			}
+			size += groupSizes[end]
+			end++
+		}
+
+		data, err := render(groups[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for f.maxBytes > 0 && len(data) > f.maxBytes && end-start > 1 {
+			end--
+			data, err = render(groups[start:end])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if f.maxBytes > 0 && len(data) > f.maxBytes {
+			logger.Warningf("%q rule group file size %d is bigger than the max bytes per file, the groups can't be split", groups[start].Name, len(data))
+		}
+
+		files = append(files, data)
+		start = end
+	}
+
+	return files, nil
+}
+
+// removeStaleFiles removes the numbered rule files after the stored ones, from previous stores
+// with more files, otherwise the rules would be loaded twice.
+func (f FSSplitRulesYAMLRepo) removeStaleFiles(stored int) error {
+	entries, err := os.ReadDir(f.path)
+	if err != nil {
+		return fmt.Errorf("could not read %q directory: %w", f.path, err)
+	}
+
+	fileRegexp := regexp.MustCompile(`^sloth-(\d{3,})\.` + regexp.QuoteMeta(f.ext) + `$`)
+	for _, e := range entries {
+		match := fileRegexp.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+
+		// The regexp only matches digits.
+		n, _ := strconv.Atoi(match[1])
+		if n <= stored {
+			continue
+		}
+
+		filePath := filepath.Join(f.path, e.Name())
+		err := os.Remove(filePath)
+		if err != nil {
+			return fmt.Errorf("could not remove %q stale file: %w", filePath, err)
+		}
+	}
+
+	return nil
+}
+
+const disclaimerFmt = `
+---
+# Code generated by Sloth%s: https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+`
+
+const disclaimerTimestampFmt = `# Generated at: %s
+
+`
+
+// HeaderData is the data the header template receives.
+type HeaderData struct {
+	Version   string
+	Timestamp time.Time
+	SLOs      int
+}
+
+// disclaimer returns the rendered header template if there is one, otherwise the default disclaimer.
+func (i IOWriterGroupedRulesYAMLRepo) disclaimer(ruleGroups ruleGroupsYAMLv2) (string, error) {
+	if i.headerTpl == nil {
+		d := disclaimer(i.disclaimerVersion)
+		if i.disclaimerTs {
+			d = strings.TrimSuffix(d, "\n") + fmt.Sprintf(disclaimerTimestampFmt, i.now().UTC().Format(time.RFC3339))
+		}
+		return d, nil
+	}
+
+	slos := map[string]struct{}{}
+	for _, g := range ruleGroups.Groups {
+		slos[g.sloID] = struct{}{}
+	}
+
+	var b bytes.Buffer
+	err := i.headerTpl.Execute(&b, HeaderData{
+		Version:   i.disclaimerVersion,
+		Timestamp: i.now(),
+		SLOs:      len(slos),
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not render header template: %w", err)
+	}
+
+	header := strings.TrimRight(b.String(), "\n")
+	for _, line := range strings.Split(header, "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return "", fmt.Errorf("invalid header, %q line is not a YAML comment", line)
+		}
+	}
+
+	return header + "\n\n", nil
+}
+
+// disclaimer returns the disclaimer, if the version is empty it will be omitted.
+func disclaimer(version string) string {
+	if version != "" {
+		version = fmt.Sprintf(" (%s)", version)
+	}
+
+	return fmt.Sprintf(disclaimerFmt, version)
+}
+
+// these types are defined to support yaml v2 (instead of the new Prometheus
+// YAML v3 that has some problems with marshaling).
+type ruleGroupsYAMLv2 struct {
+	Namespace string            `yaml:"namespace,omitempty"`
+	Groups    []ruleGroupYAMLv2 `yaml:"groups"`
+}
+
+type ruleGroupYAMLv2 struct {
+	Name                    string             `yaml:"name"`
+	Type                    string             `yaml:"type,omitempty"`
+	Interval                prommodel.Duration `yaml:"interval,omitempty"`
+	QueryOffset             prommodel.Duration `yaml:"query_offset,omitempty"`
+	Limit                   int                `yaml:"limit,omitempty"`
+	EvalOffset              prommodel.Duration `yaml:"eval_offset,omitempty"`
+	Tenant                  string             `yaml:"tenant,omitempty"`
+	SourceTenants           []string           `yaml:"source_tenants,omitempty"`
+	PartialResponseStrategy string             `yaml:"partial_response_strategy,omitempty"`
+	Labels                  map[string]string  `yaml:"labels,omitempty"`
+	Rules                   []ruleYAMLv2       `yaml:"rules"`
+
+	// The SLO of the group, not part of the rules.
+	sloID      string
+	sloService string
+	// sloComment is written as a YAML comment before the SLO groups.
+	sloComment string
+}
+
+// ruleYAMLv2 is the Prometheus rule with the fields that the Prometheus rule type
+// we depend on doesn't have yet (e.g: `keep_firing_for`).
+type ruleYAMLv2 struct {
+	Record        string             `yaml:"record,omitempty"`
+	Alert         string             `yaml:"alert,omitempty"`
+	Expr          string             `yaml:"expr"`
+	For           prommodel.Duration `yaml:"for,omitempty"`
+	KeepFiringFor prommodel.Duration `yaml:"keep_firing_for,omitempty"`
+	Labels        map[string]string  `yaml:"labels,omitempty"`
+	Annotations   map[string]string  `yaml:"annotations,omitempty"`
+}
+
+// RuleGroup is a built SLO rule group, with the same fields as the stored one.
+type RuleGroup struct {
+	Name        string
+	Interval    time.Duration
+	QueryOffset time.Duration
+	Limit       int
+	Labels      map[string]string
+	// Type, EvalOffset and Tenant are only set on VictoriaMetrics flavor.
+	Type       string
+	EvalOffset time.Duration
+	Tenant     string
+	// SourceTenants are only set on Mimir and Cortex flavors.
+	SourceTenants []string
+	// PartialResponseStrategy is only set on Thanos flavor.
+	PartialResponseStrategy string
+	Rules                   []Rule
+	// SLOID and SLOService are the SLO of the group, empty on the groups that are not from
+	// a single SLO (e.g: index or single group).
+	SLOID      string
+	SLOService string
+}
+
+// newRuleGroups converts the stored rule groups into the rule groups.
+func newRuleGroups(ruleGroups ruleGroupsYAMLv2) []RuleGroup {
+	res := make([]RuleGroup, 0, len(ruleGroups.Groups))
+	for _, g := range ruleGroups.Groups {
+		rules := make([]Rule, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			rules = append(rules, Rule{
+				Record:        r.Record,
+				Alert:         r.Alert,
+				Expr:          r.Expr,
+				For:           time.Duration(r.For),
+				KeepFiringFor: time.Duration(r.KeepFiringFor),
+				Labels:        r.Labels,
+				Annotations:   r.Annotations,
+			})
+		}
+
+		res = append(res, RuleGroup{
+			Name:                    g.Name,
+			Interval:                time.Duration(g.Interval),
+			QueryOffset:             time.Duration(g.QueryOffset),
+			Limit:                   g.Limit,
+			Labels:                  g.Labels,
+			Type:                    g.Type,
+			EvalOffset:              time.Duration(g.EvalOffset),
+			Tenant:                  g.Tenant,
+			SourceTenants:           g.SourceTenants,
+			PartialResponseStrategy: g.PartialResponseStrategy,
+			Rules:                   rules,
+			SLOID:                   g.sloID,
+			SLOService:              g.sloService,
+		})
+	}
+
+	return res
+}
+
+// newRuleGroupsYAMLv2 converts the rule groups into the stored rule groups.
+func newRuleGroupsYAMLv2(groups []RuleGroup) []ruleGroupYAMLv2 {
+	res := make([]ruleGroupYAMLv2, 0, len(groups))
+	for _, g := range groups {
+		rules := make([]ruleYAMLv2, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			rules = append(rules, ruleYAMLv2{
+				Record:        r.Record,
+				Alert:         r.Alert,
+				Expr:          r.Expr,
+				For:           prommodel.Duration(r.For),
+				KeepFiringFor: prommodel.Duration(r.KeepFiringFor),
+				Labels:        r.Labels,
+				Annotations:   r.Annotations,
+			})
+		}
+
+		res = append(res, ruleGroupYAMLv2{
+			Name:                    g.Name,
+			Type:                    g.Type,
+			Interval:                prommodel.Duration(g.Interval),
+			QueryOffset:             prommodel.Duration(g.QueryOffset),
+			Limit:                   g.Limit,
+			EvalOffset:              prommodel.Duration(g.EvalOffset),
+			Tenant:                  g.Tenant,
+			SourceTenants:           g.SourceTenants,
+			PartialResponseStrategy: g.PartialResponseStrategy,
+			Labels:                  g.Labels,
+			Rules:                   rules,
+			sloID:                   g.SLOID,
+			sloService:              g.SLOService,
+		})
+	}
+
+	return res
+}
+
+// Rule is a built SLO recording or alert rule.
+type Rule struct {
+	Record        string
+	Alert         string
+	Expr          string
+	For           time.Duration
+	KeepFiringFor time.Duration
+	Labels        map[string]string
+	Annotations   map[string]string
+}
+
+// Manifest describes the stored SLOs and the rule groups generated for each of them.
+type Manifest struct {
+	Version string        `json:"version"`
+	SLOs    []ManifestSLO `json:"slos"`
+}
+
+type ManifestSLO struct {
+	ID      string          `json:"id"`
+	Service string          `json:"service"`
+	Groups  []ManifestGroup `json:"groups"`
+}
+
+type ManifestGroup struct {
+	Name  string `json:"name"`
+	Rules int    `json:"rules"`
+}
+
+// newManifest returns the manifest of the rule groups, the SLOs will have the same
+// order as the groups.
+func newManifest(ruleGroups ruleGroupsYAMLv2) Manifest {
+	m := Manifest{Version: info.Version, SLOs: []ManifestSLO{}}
+	idx := map[string]int{}
+	for _, g := range ruleGroups.Groups {
+		i, ok := idx[g.sloID]
+		if !ok {
+			i = len(m.SLOs)
+			idx[g.sloID] = i
+			m.SLOs = append(m.SLOs, ManifestSLO{ID: g.sloID, Service: g.sloService})
+		}
+		m.SLOs[i].Groups = append(m.SLOs[i].Groups, ManifestGroup{Name: g.Name, Rules: len(g.Rules)})
+	}
+
+	return m
+}
+
+type coralogixRuleGroupYAMLv2 struct {
+	APIVersion string              `yaml:"apiVersion"`
+	Kind       string              `yaml:"kind"`
+	Metadata   coralogixMetaYAMLv2 `yaml:"metadata"`
+	Spec       ruleGroupYAMLv2     `yaml:"spec"`
+}
+
+type coralogixMetaYAMLv2 struct {
+	Application string `yaml:"application"`
+	Subsystem   string `yaml:"subsystem,omitempty"`
+}
+
+// these types are defined to support JSON, the Prometheus rule types only have YAML tags.
+type ruleGroupJSON struct {
+	Name                    string             `json:"name"`
+	Type                    string             `json:"type,omitempty"`
+	Interval                prommodel.Duration `json:"interval,omitempty"`
+	QueryOffset             prommodel.Duration `json:"query_offset,omitempty"`
+	Limit                   int                `json:"limit,omitempty"`
+	EvalOffset              prommodel.Duration `json:"eval_offset,omitempty"`
+	Tenant                  string             `json:"tenant,omitempty"`
+	SourceTenants           []string           `json:"source_tenants,omitempty"`
+	PartialResponseStrategy string             `json:"partial_response_strategy,omitempty"`
+	Labels                  map[string]string  `json:"labels,omitempty"`
+	Rules                   []ruleJSON         `json:"rules"`
+}
+
+const (
+	ruleLineTypeRecording = "recording"
+	ruleLineTypeAlert     = "alert"
+)
+
+// ruleLineJSON is a JSON Lines rule, it has the group of the rule so it can be processed alone.
+type ruleLineJSON struct {
+	Type      string `json:"type"`
+	Namespace string `json:"namespace,omitempty"`
+	Group     string `json:"group"`
+	ruleJSON
+}
+
+type ruleJSON struct {
+	Record        string             `json:"record,omitempty"`
+	Alert         string             `json:"alert,omitempty"`
+	Expr          string             `json:"expr"`
+	For           prommodel.Duration `json:"for,omitempty"`
+	KeepFiringFor prommodel.Duration `json:"keep_firing_for,omitempty"`
+	Labels        map[string]string  `json:"labels,omitempty"`
+	Annotations   map[string]string  `json:"annotations,omitempty"`
 }
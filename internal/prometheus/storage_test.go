@@ -2,11 +2,26 @@ package prometheus_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	prommodel "github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	"github.com/slok/sloth/internal/log"
 	"github.com/slok/sloth/internal/prometheus"
@@ -14,6 +29,7 @@ import (
 
 func TestIOWriterGroupedRulesYAMLRepoStore(t *testing.T) {
 	tests := map[string]struct {
+		config  prometheus.IOWriterGroupedRulesYAMLRepoConfig
 		slos    []prometheus.StorageSLO
 		expYAML string
 		expErr  bool
@@ -39,7 +55,7 @@ func TestIOWriterGroupedRulesYAMLRepoStore(t *testing.T) {
 							{
 								Record: "test:record",
 								Expr:   "test-expr",
-								Labels: map[string]string{"test-label": "one"},
+								Labels: map[string]string{"test_label": "one"},
 							},
 						},
 					},
@@ -56,7 +72,7 @@ groups:
   - record: test:record
     expr: test-expr
     labels:
-      test-label: one
+      test_label: one
 `,
 		},
 		"Having a single metadata recording rule should render correctly.": {
@@ -68,7 +84,7 @@ groups:
 							{
 								Record: "test:record",
 								Expr:   "test-expr",
-								Labels: map[string]string{"test-label": "one"},
+								Labels: map[string]string{"test_label": "one"},
 							},
 						},
 					},
@@ -85,7 +101,7 @@ groups:
   - record: test:record
     expr: test-expr
     labels:
-      test-label: one
+      test_label: one
 `,
 		},
 		"Having a single SLO alert rule should render correctly.": {
@@ -97,8 +113,8 @@ groups:
 							{
 								Alert:       "testAlert",
 								Expr:        "test-expr",
-								Labels:      map[string]string{"test-label": "one"},
-								Annotations: map[string]string{"test-annot": "one"},
+								Labels:      map[string]string{"test_label": "one"},
+								Annotations: map[string]string{"test_annot": "one"},
 							},
 						},
 					},
@@ -115,12 +131,138 @@ groups:
   - alert: testAlert
     expr: test-expr
     labels:
-      test-label: one
+      test_label: one
     annotations:
-      test-annot: one
+      test_annot: one
 `,
 		},
 
+		"Having SLO alerts with keep firing for should render it on the alert rules.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:              "test1",
+						PageAlertMeta:   prometheus.AlertMeta{KeepFiringFor: 10 * time.Minute},
+						TicketAlertMeta: prometheus.AlertMeta{KeepFiringFor: 0},
+					},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+								Labels: map[string]string{"sloth_severity": "page"},
+							},
+						},
+						AlertRules: []rulefmt.Rule{
+							{
+								Alert:  "testAlert",
+								Expr:   "test-expr",
+								For:    prommodel.Duration(5 * time.Minute),
+								Labels: map[string]string{"sloth_severity": "page"},
+							},
+							{
+								Alert:  "testAlert",
+								Expr:   "test-expr",
+								Labels: map[string]string{"sloth_severity": "ticket"},
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+    labels:
+      sloth_severity: page
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    for: 5m
+    keep_firing_for: 10m
+    labels:
+      sloth_severity: page
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      sloth_severity: ticket
+`,
+		},
+
+		"Having SLO alert annotation templates should render them with the SLO on the alert rules.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1", Objective: 99.9},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules: []rulefmt.Rule{
+							{
+								Alert:       "testAlert",
+								Expr:        "test-expr",
+								Annotations: map[string]string{"test_annot": "one", "runbook": "override"},
+							},
+						},
+					},
+					AlertAnnotationTemplates: map[string]string{
+						"runbook":   "https://runbooks.io/{{ .Service }}/{{ .ID }}",
+						"objective": "{{ .Objective }}",
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-svc1-slo1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-svc1-slo1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    annotations:
+      objective: "99.9"
+      runbook: https://runbooks.io/svc1/svc1-slo1
+      test_annot: one
+`,
+		},
+
+		"Having SLO alert annotation templates with an invalid syntax should fail.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+					AlertAnnotationTemplates: map[string]string{"runbook": "https://runbooks.io/{{ .Service }"},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having SLO alert annotation templates with a missing SLO label should fail.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+					AlertAnnotationTemplates: map[string]string{"runbook": "https://runbooks.io/{{ .Labels.team }}"},
+				},
+			},
+			expErr: true,
+		},
+
 		"Having a multiple SLO alert and recording rules should render correctly.": {
 			slos: []prometheus.StorageSLO{
 				{
@@ -128,40 +270,40 @@ groups:
 					Rules: prometheus.SLORules{
 						SLIErrorRecRules: []rulefmt.Rule{
 							{
-								Record: "test:record-a1",
+								Record: "test:record_a1",
 								Expr:   "test-expr-a1",
-								Labels: map[string]string{"test-label": "a-1"},
+								Labels: map[string]string{"test_label": "a-1"},
 							},
 							{
-								Record: "test:record-a2",
+								Record: "test:record_a2",
 								Expr:   "test-expr-a2",
-								Labels: map[string]string{"test-label": "a-2"},
+								Labels: map[string]string{"test_label": "a-2"},
 							},
 						},
 						MetadataRecRules: []rulefmt.Rule{
 							{
-								Record: "test:record-a3",
+								Record: "test:record_a3",
 								Expr:   "test-expr-a3",
-								Labels: map[string]string{"test-label": "a-3"},
+								Labels: map[string]string{"test_label": "a-3"},
 							},
 							{
-								Record: "test:record-a4",
+								Record: "test:record_a4",
 								Expr:   "test-expr-a4",
-								Labels: map[string]string{"test-label": "a-4"},
+								Labels: map[string]string{"test_label": "a-4"},
 							},
 						},
 						AlertRules: []rulefmt.Rule{
 							{
 								Alert:       "testAlertA1",
 								Expr:        "test-expr-a1",
-								Labels:      map[string]string{"test-label": "a-1"},
-								Annotations: map[string]string{"test-annot": "a-1"},
+								Labels:      map[string]string{"test_label": "a-1"},
+								Annotations: map[string]string{"test_annot": "a-1"},
 							},
 							{
 								Alert:       "testAlertA2",
 								Expr:        "test-expr-a2",
-								Labels:      map[string]string{"test-label": "a-2"},
-								Annotations: map[string]string{"test-annot": "a-2"},
+								Labels:      map[string]string{"test_label": "a-2"},
+								Annotations: map[string]string{"test_annot": "a-2"},
 							},
 						},
 					},
@@ -171,24 +313,24 @@ groups:
 					Rules: prometheus.SLORules{
 						SLIErrorRecRules: []rulefmt.Rule{
 							{
-								Record: "test:record-b1",
+								Record: "test:record_b1",
 								Expr:   "test-expr-b1",
-								Labels: map[string]string{"test-label": "b-1"},
+								Labels: map[string]string{"test_label": "b-1"},
 							},
 						},
 						MetadataRecRules: []rulefmt.Rule{
 							{
-								Record: "test:record-b2",
+								Record: "test:record_b2",
 								Expr:   "test-expr-b2",
-								Labels: map[string]string{"test-label": "b-2"},
+								Labels: map[string]string{"test_label": "b-2"},
 							},
 						},
 						AlertRules: []rulefmt.Rule{
 							{
 								Alert:       "testAlertB1",
 								Expr:        "test-expr-b1",
-								Labels:      map[string]string{"test-label": "b-1"},
-								Annotations: map[string]string{"test-annot": "b-1"},
+								Labels:      map[string]string{"test_label": "b-1"},
+								Annotations: map[string]string{"test_annot": "b-1"},
 							},
 						},
 					},
@@ -202,74 +344,5210 @@ groups:
 groups:
 - name: sloth-slo-sli-recordings-testa
   rules:
-  - record: test:record-a1
+  - record: test:record_a1
     expr: test-expr-a1
     labels:
-      test-label: a-1
-  - record: test:record-a2
+      test_label: a-1
+  - record: test:record_a2
     expr: test-expr-a2
     labels:
-      test-label: a-2
+      test_label: a-2
 - name: sloth-slo-meta-recordings-testa
   rules:
-  - record: test:record-a3
+  - record: test:record_a3
     expr: test-expr-a3
     labels:
-      test-label: a-3
-  - record: test:record-a4
+      test_label: a-3
+  - record: test:record_a4
     expr: test-expr-a4
     labels:
-      test-label: a-4
+      test_label: a-4
 - name: sloth-slo-alerts-testa
   rules:
   - alert: testAlertA1
     expr: test-expr-a1
     labels:
-      test-label: a-1
+      test_label: a-1
     annotations:
-      test-annot: a-1
+      test_annot: a-1
   - alert: testAlertA2
     expr: test-expr-a2
     labels:
-      test-label: a-2
+      test_label: a-2
     annotations:
-      test-annot: a-2
+      test_annot: a-2
 - name: sloth-slo-sli-recordings-testb
   rules:
-  - record: test:record-b1
+  - record: test:record_b1
     expr: test-expr-b1
     labels:
-      test-label: b-1
+      test_label: b-1
 - name: sloth-slo-meta-recordings-testb
   rules:
-  - record: test:record-b2
+  - record: test:record_b2
     expr: test-expr-b2
     labels:
-      test-label: b-2
+      test_label: b-2
 - name: sloth-slo-alerts-testb
   rules:
   - alert: testAlertB1
     expr: test-expr-b1
     labels:
-      test-label: b-1
+      test_label: b-1
     annotations:
-      test-annot: b-1
+      test_annot: b-1
 `,
 		},
-	}
 
-	for name, test := range tests {
-		t.Run(name, func(t *testing.T) {
-			assert := assert.New(t)
+		"Having the disclaimer disabled should render the rules without it.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableDisclaimer: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+		},
 
-			var gotYAML bytes.Buffer
-			repo := prometheus.NewIOWriterGroupedRulesYAMLRepo(&gotYAML, log.Noop)
-			err := repo.StoreSLOs(context.TODO(), test.slos)
+		"Having a custom disclaimer version should render the disclaimer with it.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				DisclaimerVersion: "v1.2.3",
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (v1.2.3): https://github.com/slok/sloth.
+# DO NOT EDIT.
 
-			if test.expErr {
-				assert.Error(err)
-			} else if assert.NoError(err) {
-				assert.Equal(test.expYAML, gotYAML.String())
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+		},
+
+		"Having the disclaimer version disabled should render the disclaimer without version.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				DisclaimerVersion:        "v1.2.3",
+				DisableDisclaimerVersion: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth: https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+		},
+
+		"Having SLOs with and without interval should set the interval only on the groups of the SLOs with interval.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1"},
+					Interval: 2 * time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr3"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record3", Expr: "test-expr4"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  interval: 2m
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-meta-recordings-test1
+  interval: 2m
+  rules:
+  - record: test:record2
+    expr: test-expr2
+- name: sloth-slo-alerts-test1
+  interval: 2m
+  rules:
+  - alert: testAlert1
+    expr: test-expr3
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record3
+    expr: test-expr4
+`,
+		},
+
+		"Having an SLO with a negative interval should fail.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1"},
+					Interval: -2 * time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having a default query offset and an SLO with a custom one should set them on the groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				QueryOffset: time.Minute,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:         prometheus.SLO{ID: "test1"},
+					QueryOffset: 5 * time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr2"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr3"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  query_offset: 5m
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-alerts-test1
+  query_offset: 5m
+  rules:
+  - alert: testAlert1
+    expr: test-expr2
+- name: sloth-slo-sli-recordings-test2
+  query_offset: 1m
+  rules:
+  - record: test:record2
+    expr: test-expr3
+`,
+		},
+
+		"Having an SLO with a negative query offset should fail.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:         prometheus.SLO{ID: "test1"},
+					QueryOffset: -time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having extra labels should add them to all the rules, with the SLO ones and the rule labels taking precedence.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				ExtraLabels: map[string]string{"team": "team-a", "tier": "2", "environment": "prod"},
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:         prometheus.SLO{ID: "test1"},
+					ExtraLabels: map[string]string{"tier": "1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1", Labels: map[string]string{"sloth_id": "test1"}}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr3", Labels: map[string]string{"team": "team-b"}}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+    labels:
+      environment: prod
+      sloth_id: test1
+      team: team-a
+      tier: "1"
+- name: sloth-slo-meta-recordings-test1
+  rules:
+  - record: test:record2
+    expr: test-expr2
+    labels:
+      environment: prod
+      team: team-a
+      tier: "1"
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr3
+    labels:
+      environment: prod
+      team: team-b
+      tier: "1"
+`,
+		},
+
+		"Having extra labels with override should replace the rule labels.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				ExtraLabels:         map[string]string{"team": "team-a"},
+				ExtraLabelsOverride: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						AlertRules: []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr1", Labels: map[string]string{"team": "team-b"}}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr1
+    labels:
+      team: team-a
+`,
+		},
+
+		"Having an SLO with a SLI recordings group limit should set the limit only on the SLI recordings group.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:         prometheus.SLO{ID: "test1"},
+					GroupLimits: prometheus.GroupLimits{SLIRecordings: 100},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr2"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  limit: 100
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr2
+`,
+		},
+
+		"Having default group limits should set them on the groups of the SLOs that don't have them.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				GroupLimits: prometheus.GroupLimits{SLIRecordings: 100, Alerts: 10},
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:         prometheus.SLO{ID: "test1"},
+					GroupLimits: prometheus.GroupLimits{SLIRecordings: 50},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr3"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record3", Expr: "test-expr4"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  limit: 50
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-meta-recordings-test1
+  rules:
+  - record: test:record2
+    expr: test-expr2
+- name: sloth-slo-alerts-test1
+  limit: 10
+  rules:
+  - alert: testAlert1
+    expr: test-expr3
+- name: sloth-slo-sli-recordings-test2
+  limit: 100
+  rules:
+  - record: test:record3
+    expr: test-expr4
+`,
+		},
+
+		"Having an SLO with a negative group limit should fail.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:         prometheus.SLO{ID: "test1"},
+					GroupLimits: prometheus.GroupLimits{Alerts: -1},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having the disclaimer timestamp enabled should add the generation timestamp to the disclaimer.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				DisclaimerTimestamp: true,
+				Now:                 func() time.Time { return time.Date(2022, 10, 14, 12, 11, 12, 0, time.FixedZone("CEST", 2*60*60)) },
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+# Generated at: 2022-10-14T10:11:12Z
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+`,
+		},
+
+		"Having a header template should replace the default disclaimer.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				HeaderTemplate: "# SPDX-License-Identifier: Apache-2.0\n#\n# Sloth {{ .Version }} generated {{ .SLOs }} SLOs at {{ .Timestamp.Format \"2006-01-02T15:04:05Z07:00\" }}.\n",
+				Now:            func() time.Time { return time.Date(2022, 10, 14, 10, 11, 12, 0, time.UTC) },
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr2"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr3"}},
+					},
+				},
+			},
+			expYAML: `# SPDX-License-Identifier: Apache-2.0
+#
+# Sloth dev generated 2 SLOs at 2022-10-14T10:11:12Z.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr2
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record2
+    expr: test-expr3
+`,
+		},
+
+		"Having a header template with non comment lines should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				HeaderTemplate: "# Generated by Sloth {{ .Version }}.\nDO NOT EDIT.",
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having recordings only should skip the alert rule groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				RecordingsOnly: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr3"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-meta-recordings-test1
+  rules:
+  - record: test:record2
+    expr: test-expr2
+`,
+		},
+
+		"Having alerts only should skip the recording rule groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				AlertsOnly: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr3"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr3
+`,
+		},
+
+		"Having the metadata recordings disabled should skip the metadata recording rule groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableMetadataRecordings: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr3"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr3
+`,
+		},
+
+		"Having a single group should merge the rules of all the SLOs in one group.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				SingleGroup: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1", Labels: map[string]string{"sloth_id": "test1"}}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr3"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr4", Labels: map[string]string{"sloth_id": "test2"}}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert2", Expr: "test-expr5"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-slos
+  rules:
+  - record: test:record1
+    expr: test-expr1
+    labels:
+      sloth_id: test1
+  - record: test:record2
+    expr: test-expr2
+  - alert: testAlert1
+    expr: test-expr3
+  - record: test:record1
+    expr: test-expr4
+    labels:
+      sloth_id: test2
+  - alert: testAlert2
+    expr: test-expr5
+`,
+		},
+
+		"Having a single group with a custom name should use it.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				SingleGroup:     true,
+				SingleGroupName: "my-slos",
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1"},
+					Interval: time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr2"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: my-slos
+  interval: 1m
+  rules:
+  - record: test:record1
+    expr: test-expr1
+  - alert: testAlert1
+    expr: test-expr2
+`,
+		},
+
+		"Having a single group with rules with the same name and labels but different expressions should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				SingleGroup: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr2"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having a single group with SLOs with different intervals should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				SingleGroup: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1"},
+					Interval: time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having SLO rules with Mimir flavor should render the tenant header and the source tenants.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:             prometheus.MimirFlavor,
+				MimirTenant:        "tenant-a",
+				MimirSourceTenants: []string{"tenant-b", "tenant-c"},
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+						AlertRules: []rulefmt.Rule{
+							{
+								Alert: "testAlert",
+								Expr:  "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+# mimir-tenant: tenant-a
+groups:
+- name: sloth-slo-sli-recordings-test1
+  source_tenants:
+  - tenant-b
+  - tenant-c
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test1
+  source_tenants:
+  - tenant-b
+  - tenant-c
+  rules:
+  - alert: testAlert
+    expr: test-expr
+`,
+		},
+
+		"Having SLO source tenants with Mimir flavor should render them on the SLO groups over the repository ones.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:             prometheus.MimirFlavor,
+				MimirSourceTenants: []string{"tenant-b"},
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					SourceTenants: prometheus.GroupSourceTenants{
+						SLIRecordings:      []string{"tenant-c", "tenant-d"},
+						MetadataRecordings: []string{"tenant-c"},
+					},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr2"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr3"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record3", Expr: "test-expr4"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  source_tenants:
+  - tenant-c
+  - tenant-d
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-meta-recordings-test1
+  source_tenants:
+  - tenant-c
+  rules:
+  - record: test:record2
+    expr: test-expr2
+- name: sloth-slo-alerts-test1
+  source_tenants:
+  - tenant-b
+  rules:
+  - alert: testAlert1
+    expr: test-expr3
+- name: sloth-slo-sli-recordings-test2
+  source_tenants:
+  - tenant-b
+  rules:
+  - record: test:record3
+    expr: test-expr4
+`,
+		},
+
+		"Having SLO source tenants with Cortex flavor should render them only on the configured groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor: prometheus.CortexFlavor,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:           prometheus.SLO{ID: "test1"},
+					SourceTenants: prometheus.GroupSourceTenants{SLIRecordings: []string{"tenant-b"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr2"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  source_tenants:
+  - tenant-b
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr2
+`,
+		},
+
+		"Having SLO source tenants with an empty tenant should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor: prometheus.MimirFlavor,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:           prometheus.SLO{ID: "test1"},
+					SourceTenants: prometheus.GroupSourceTenants{SLIRecordings: []string{"tenant-b", ""}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having SLO source tenants with a flavor without federated rule groups should fail.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:           prometheus.SLO{ID: "test1"},
+					SourceTenants: prometheus.GroupSourceTenants{Alerts: []string{"tenant-b"}},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having SLO rules with Mimir flavor without tenants should render regular groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor: prometheus.MimirFlavor,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{
+							{
+								Record: "test:record",
+								Expr:   "test-expr",
+							},
+						},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+		},
+
+		"Having SLO rules with VictoriaMetrics flavor should render the vmalert group options.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:                    prometheus.VictoriaMetricsFlavor,
+				VictoriaMetricsTenant:     "123:456",
+				VictoriaMetricsEvalOffset: 30 * time.Second,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1"},
+					Interval: time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  type: prometheus
+  interval: 1m
+  eval_offset: 30s
+  tenant: 123:456
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test1
+  type: prometheus
+  interval: 1m
+  eval_offset: 30s
+  tenant: 123:456
+  rules:
+  - alert: testAlert
+    expr: test-expr
+`,
+		},
+
+		"Having SLO rules with VictoriaMetrics flavor without options should render only the group type.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor: prometheus.VictoriaMetricsFlavor,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  type: prometheus
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+		},
+
+		"Having 0 SLO rules generated with VictoriaMetrics flavor should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor: prometheus.VictoriaMetricsFlavor,
+			},
+			slos: []prometheus.StorageSLO{
+				{},
+			},
+			expErr: true,
+		},
+
+		"Having SLO rules with JSON format should render the rules in JSON without the disclaimer.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Format:             prometheus.JSONFormat,
+				Flavor:             prometheus.MimirFlavor,
+				MimirTenant:        "tenant-a",
+				MimirSourceTenants: []string{"tenant-b"},
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1"},
+					Interval: time.Minute,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr", Labels: map[string]string{"test_label": "one"}}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", For: prommodel.Duration(5 * time.Minute)}},
+					},
+				},
+			},
+			expYAML: `{
+  "groups": [
+    {
+      "name": "sloth-slo-sli-recordings-test1",
+      "interval": "1m",
+      "source_tenants": [
+        "tenant-b"
+      ],
+      "rules": [
+        {
+          "record": "test:record",
+          "expr": "test-expr",
+          "labels": {
+            "test_label": "one"
+          }
+        }
+      ]
+    },
+    {
+      "name": "sloth-slo-alerts-test1",
+      "interval": "1m",
+      "source_tenants": [
+        "tenant-b"
+      ],
+      "rules": [
+        {
+          "alert": "testAlert",
+          "expr": "test-expr",
+          "for": "5m"
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+
+		"Having a rule with an invalid expression should fail.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "sum(rate(test[5m]"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having a rule with an invalid expression and the validation disabled should render the rules.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				DisableValidation: true,
+				DisableDisclaimer: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "sum(rate(test[5m]"}},
+					},
+				},
+			},
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: sum(rate(test[5m]
+`,
+		},
+
+		"Having SLO rules with Cortex flavor should render the tenant header and the namespace.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:          prometheus.CortexFlavor,
+				CortexTenant:    "tenant-a",
+				CortexNamespace: "slos",
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+# cortex-tenant: tenant-a
+namespace: slos
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+		},
+
+		"Having SLO rules with Cortex flavor without options should render regular Prometheus rules.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:            prometheus.CortexFlavor,
+				DisableDisclaimer: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+		},
+
+		"Having a custom group prefix should use it on the group names.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				GroupPrefix:       "team-a-slo",
+				DisableDisclaimer: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `groups:
+- name: team-a-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: team-a-slo-meta-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: team-a-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+`,
+		},
+
+		"Having SLO rules with Thanos flavor and the default partial response strategy should set it on the groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:            prometheus.ThanosFlavor,
+				DisableDisclaimer: true,
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test1
+  partial_response_strategy: abort
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test1
+  partial_response_strategy: abort
+  rules:
+  - alert: testAlert
+    expr: test-expr
+`,
+		},
+
+		"Having SLO rules with Thanos flavor and a custom partial response strategy should set it on the groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:                        prometheus.ThanosFlavor,
+				DisableDisclaimer:             true,
+				ThanosPartialResponseStrategy: "warn",
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+			},
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test1
+  partial_response_strategy: warn
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test1
+  partial_response_strategy: warn
+  rules:
+  - alert: testAlert
+    expr: test-expr
+`,
+		},
+
+		"Having 0 SLO rules generated with Mimir flavor should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:      prometheus.MimirFlavor,
+				MimirTenant: "tenant-a",
+			},
+			slos: []prometheus.StorageSLO{
+				{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotYAML bytes.Buffer
+			test.config.Writer = &gotYAML
+			test.config.Logger = log.Noop
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expYAML, gotYAML.String())
+			}
+		})
+	}
+}
+
+func TestFSGroupedRulesYAMLRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config   prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		slos     []prometheus.StorageSLO
+		expFiles map[string]string
+		expErr   bool
+	}{
+		"Having 0 SLO rules should fail.": {
+			slos:   []prometheus.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having 0 SLO rules generated should fail.": {
+			slos: []prometheus.StorageSLO{
+				{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}},
+			},
+			expErr: true,
+		},
+
+		"Having an invalid service name should fail.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "../svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs of multiple services should store each service on its own file.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test2", Service: "svc2"},
+					Rules: prometheus.SLORules{
+						AlertRules: []rulefmt.Rule{{Alert: "testAlert2", Expr: "test-expr2"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test3", Service: "svc1"},
+					Rules: prometheus.SLORules{
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record3", Expr: "test-expr3"}},
+					},
+				},
+				{
+					SLO: prometheus.SLO{ID: "test4", Service: "svc3"},
+				},
+			},
+			expFiles: map[string]string{
+				"sloth-svc1.yaml": `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: test-expr1
+- name: sloth-slo-meta-recordings-test3
+  rules:
+  - record: test:record3
+    expr: test-expr3
+`,
+				"sloth-svc2.yaml": `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-alerts-test2
+  rules:
+  - alert: testAlert2
+    expr: test-expr2
+`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Have an already existing file that should be replaced.
+			path := filepath.Join(t.TempDir(), "rules")
+			err := os.MkdirAll(path, os.ModePerm)
+			require.NoError(err)
+			err = os.WriteFile(filepath.Join(path, "sloth-svc1.yaml"), []byte("old"), 0644)
+			require.NoError(err)
+
+			test.config.Logger = log.Noop
+			repo, err := prometheus.NewFSGroupedRulesYAMLRepo(prometheus.FSGroupedRulesYAMLRepoConfig{
+				Path:           path,
+				IOWriterConfig: test.config,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			entries, err := os.ReadDir(path)
+			require.NoError(err)
+			gotFiles := map[string]string{}
+			for _, e := range entries {
+				data, err := os.ReadFile(filepath.Join(path, e.Name()))
+				require.NoError(err)
+				gotFiles[e.Name()] = string(data)
+			}
+			assert.Equal(test.expFiles, gotFiles)
+		})
+	}
+}
+
+func TestFSGroupedRulesYAMLRepoStoreIgnoresExtraWriters(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var extra bytes.Buffer
+	path := t.TempDir()
+	repo, err := prometheus.NewFSGroupedRulesYAMLRepo(prometheus.FSGroupedRulesYAMLRepoConfig{
+		Path: path,
+		IOWriterConfig: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+			Writers:           []io.Writer{&extra},
+			Logger:            log.Noop,
+			DisableDisclaimer: true,
+		},
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{
+		{
+			SLO:   prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}}},
+		},
+		{
+			SLO:   prometheus.SLO{ID: "test2", Service: "svc2"},
+			Rules: prometheus.SLORules{SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}}},
+		},
+	})
+	require.NoError(err)
+
+	// Each service file should only have its rules and the extra writers nothing.
+	gotSvc1, err := os.ReadFile(filepath.Join(path, "sloth-svc1.yaml"))
+	require.NoError(err)
+	assert.Contains(string(gotSvc1), "- name: sloth-slo-sli-recordings-test1\n")
+	assert.NotContains(string(gotSvc1), "test2")
+	assert.Empty(extra.String())
+}
+
+func TestFSSplitRulesYAMLRepoStore(t *testing.T) {
+	newSLO := func(id string) prometheus.StorageSLO {
+		return prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: id, Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		}
+	}
+	slos := []prometheus.StorageSLO{newSLO("test1"), newSLO("test2"), newSLO("test3")}
+
+	tests := map[string]struct {
+		config        prometheus.FSSplitRulesYAMLRepoConfig
+		slos          []prometheus.StorageSLO
+		expFileGroups map[string][]string
+		expErr        bool
+	}{
+		"Having 0 SLO rules generated should fail.": {
+			config: prometheus.FSSplitRulesYAMLRepoConfig{MaxGroupsPerFile: 2},
+			slos:   []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}}},
+			expErr: true,
+		},
+
+		"Having a max groups per file should split the groups in numbered files.": {
+			config: prometheus.FSSplitRulesYAMLRepoConfig{MaxGroupsPerFile: 4},
+			slos:   slos,
+			expFileGroups: map[string][]string{
+				"sloth-001.yaml": {"sloth-slo-sli-recordings-test1", "sloth-slo-alerts-test1", "sloth-slo-sli-recordings-test2", "sloth-slo-alerts-test2"},
+				"sloth-002.yaml": {"sloth-slo-sli-recordings-test3", "sloth-slo-alerts-test3"},
+			},
+		},
+
+		"Having a max groups per file bigger than the groups should store a single file.": {
+			config: prometheus.FSSplitRulesYAMLRepoConfig{MaxGroupsPerFile: 100},
+			slos:   slos,
+			expFileGroups: map[string][]string{
+				"sloth-001.yaml": {"sloth-slo-sli-recordings-test1", "sloth-slo-alerts-test1", "sloth-slo-sli-recordings-test2", "sloth-slo-alerts-test2", "sloth-slo-sli-recordings-test3", "sloth-slo-alerts-test3"},
+			},
+		},
+
+		"Having a max bytes per file should split the groups without exceeding it.": {
+			config: prometheus.FSSplitRulesYAMLRepoConfig{MaxBytesPerFile: 400},
+			slos:   slos,
+			expFileGroups: map[string][]string{
+				"sloth-001.yaml": {"sloth-slo-sli-recordings-test1", "sloth-slo-alerts-test1", "sloth-slo-sli-recordings-test2"},
+				"sloth-002.yaml": {"sloth-slo-alerts-test2", "sloth-slo-sli-recordings-test3", "sloth-slo-alerts-test3"},
+			},
+		},
+
+		"Having groups bigger than the max bytes per file should store them alone.": {
+			config: prometheus.FSSplitRulesYAMLRepoConfig{MaxBytesPerFile: 10},
+			slos:   slos[:1],
+			expFileGroups: map[string][]string{
+				"sloth-001.yaml": {"sloth-slo-sli-recordings-test1"},
+				"sloth-002.yaml": {"sloth-slo-alerts-test1"},
+			},
+		},
+
+		"Having both limits should split by the first one exceeded.": {
+			config: prometheus.FSSplitRulesYAMLRepoConfig{MaxGroupsPerFile: 2, MaxBytesPerFile: 400},
+			slos:   slos,
+			expFileGroups: map[string][]string{
+				"sloth-001.yaml": {"sloth-slo-sli-recordings-test1", "sloth-slo-alerts-test1"},
+				"sloth-002.yaml": {"sloth-slo-sli-recordings-test2", "sloth-slo-alerts-test2"},
+				"sloth-003.yaml": {"sloth-slo-sli-recordings-test3", "sloth-slo-alerts-test3"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Have a stale file of a previous store that should be removed and a not
+			// numbered one that should be kept.
+			path := filepath.Join(t.TempDir(), "rules")
+			err := os.MkdirAll(path, os.ModePerm)
+			require.NoError(err)
+			err = os.WriteFile(filepath.Join(path, "sloth-009.yaml"), []byte("old"), 0644)
+			require.NoError(err)
+			err = os.WriteFile(filepath.Join(path, "custom.yaml"), []byte("custom"), 0644)
+			require.NoError(err)
+
+			test.config.Path = path
+			test.config.IOWriterConfig.Logger = log.Noop
+			test.config.IOWriterConfig.DisclaimerVersion = "test"
+			repo, err := prometheus.NewFSSplitRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			entries, err := os.ReadDir(path)
+			require.NoError(err)
+			gotFileGroups := map[string][]string{}
+			allGroups := []string{}
+			for _, e := range entries {
+				data, err := os.ReadFile(filepath.Join(path, e.Name()))
+				require.NoError(err)
+				if e.Name() == "custom.yaml" {
+					assert.Equal("custom", string(data))
+					continue
+				}
+
+				// Each file has its own disclaimer and groups.
+				assert.Contains(string(data), "# Code generated by Sloth (test): https://github.com/slok/sloth.")
+				if test.config.MaxBytesPerFile > 10 {
+					assert.LessOrEqual(len(data), test.config.MaxBytesPerFile)
+				}
+				for _, m := range regexp.MustCompile(`(?m)^- name: (.*)$`).FindAllStringSubmatch(string(data), -1) {
+					gotFileGroups[e.Name()] = append(gotFileGroups[e.Name()], m[1])
+					allGroups = append(allGroups, m[1])
+				}
+			}
+			assert.Equal(test.expFileGroups, gotFileGroups)
+
+			// Every group is stored exactly once.
+			expGroups := []string{}
+			for _, slo := range test.slos {
+				expGroups = append(expGroups, "sloth-slo-sli-recordings-"+slo.SLO.ID, "sloth-slo-alerts-"+slo.SLO.ID)
+			}
+			assert.ElementsMatch(expGroups, allGroups)
+		})
+	}
+}
+
+func TestFSSplitRulesYAMLRepoStoreMaxBytesEstimation(t *testing.T) {
+	slos := []prometheus.StorageSLO{}
+	expGroups := []string{}
+	for i := 0; i < 30; i++ {
+		id := fmt.Sprintf("test%02d", i)
+		slos = append(slos, prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: id, Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: fmt.Sprintf("sum(rate(http_requests_total{id=%q}[5m]))", id)}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		})
+		expGroups = append(expGroups, "sloth-slo-sli-recordings-"+id, "sloth-slo-alerts-"+id)
+	}
+
+	// The file sizes are estimated with the groups sizes, the rendered files sizes could be different.
+	tests := map[string]struct {
+		config prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		ext    string
+	}{
+		"YAML format.":      {ext: "yaml"},
+		"JSON format.":      {config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{Format: prometheus.JSONFormat}, ext: "json"},
+		"Gzip compression.": {config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{Gzip: true}, ext: "yaml.gz"},
+	}
+
+	groupRegexp := regexp.MustCompile(`sloth-slo-(?:sli-recordings|alerts)-test\d+`)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			const maxBytes = 1024
+			path := t.TempDir()
+			test.config.Logger = log.Noop
+			repo, err := prometheus.NewFSSplitRulesYAMLRepo(prometheus.FSSplitRulesYAMLRepoConfig{
+				Path:            path,
+				MaxBytesPerFile: maxBytes,
+				IOWriterConfig:  test.config,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			entries, err := os.ReadDir(path)
+			require.NoError(err)
+			require.Greater(len(entries), 1)
+
+			gotGroups := []string{}
+			for idx, e := range entries {
+				assert.Equal(fmt.Sprintf("sloth-%03d.%s", idx+1, test.ext), e.Name())
+				data, err := os.ReadFile(filepath.Join(path, e.Name()))
+				require.NoError(err)
+				assert.LessOrEqual(len(data), maxBytes, e.Name())
+
+				if test.config.Gzip {
+					r, err := gzip.NewReader(bytes.NewReader(data))
+					require.NoError(err)
+					data, err = io.ReadAll(r)
+					require.NoError(err)
+				}
+				gotGroups = append(gotGroups, groupRegexp.FindAllString(string(data), -1)...)
+			}
+			assert.Equal(expGroups, gotGroups)
+		})
+	}
+}
+
+func TestFSSplitRulesYAMLRepoInvalidConfig(t *testing.T) {
+	tests := map[string]prometheus.FSSplitRulesYAMLRepoConfig{
+		"Missing path should fail.":                {MaxGroupsPerFile: 2},
+		"Missing limits should fail.":              {Path: "/tmp/rules"},
+		"Negative limits should fail.":             {Path: "/tmp/rules", MaxGroupsPerFile: -1},
+		"Invalid rules configuration should fail.": {Path: "/tmp/rules", MaxGroupsPerFile: 2, IOWriterConfig: prometheus.IOWriterGroupedRulesYAMLRepoConfig{Format: "xml"}},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := prometheus.NewFSSplitRulesYAMLRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreResult(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "test:record1", Expr: "test-expr"},
+					{Record: "test:record2", Expr: "test-expr"},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "test:record3", Expr: "test-expr"},
+				},
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlert1", Expr: "test-expr"},
+				},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2"},
+			Rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlert2", Expr: "test-expr"},
+					{Alert: "testAlert3", Expr: "test-expr"},
+				},
+			},
+		},
+	}
+
+	var gotYAML bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &gotYAML,
+		Logger: log.Noop,
+	})
+	require.NoError(err)
+
+	gotRes, err := repo.StoreSLOsResult(context.TODO(), slos)
+	require.NoError(err)
+
+	expRes := &prometheus.StoreResult{
+		Groups:         4,
+		RecordingRules: 3,
+		AlertRules:     3,
+		BytesWritten:   gotYAML.Len(),
+	}
+	assert.Equal(expRes, gotRes)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoBuildRuleGroups(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{
+				ID:            "test1",
+				Service:       "svc1",
+				PageAlertMeta: prometheus.AlertMeta{KeepFiringFor: 10 * time.Minute},
+			},
+			Interval:    30 * time.Second,
+			QueryOffset: time.Minute,
+			GroupLimits: prometheus.GroupLimits{Alerts: 5},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "test:record1", Expr: "test-expr", Labels: map[string]string{"sloth_id": "test1"}},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "test:record2", Expr: "test-expr"},
+				},
+				AlertRules: []rulefmt.Rule{
+					{
+						Alert:       "testAlert1",
+						Expr:        "test-expr",
+						For:         prommodel.Duration(5 * time.Minute),
+						Labels:      map[string]string{"sloth_severity": "page"},
+						Annotations: map[string]string{"summary": "test"},
+					},
+				},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlert2", Expr: "test-expr"},
+				},
+			},
+		},
+	}
+
+	config := prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      io.Discard,
+		Logger:      log.Noop,
+		IndexGroup:  true,
+		GroupLabels: map[string]string{"team": "a-team"},
+	}
+
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(config)
+	require.NoError(err)
+	gotGroups, err := repo.BuildRuleGroups(context.TODO(), slos)
+	require.NoError(err)
+
+	var gotYAML bytes.Buffer
+	config.Writer = &gotYAML
+	repo, err = prometheus.NewIOWriterGroupedRulesYAMLRepo(config)
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(err)
+
+	// The built groups should have the same structure as the serialized ones.
+	type rule struct {
+		Record        string            `yaml:"record"`
+		Alert         string            `yaml:"alert"`
+		Expr          string            `yaml:"expr"`
+		For           time.Duration     `yaml:"for"`
+		KeepFiringFor time.Duration     `yaml:"keep_firing_for"`
+		Labels        map[string]string `yaml:"labels"`
+		Annotations   map[string]string `yaml:"annotations"`
+	}
+	type group struct {
+		Name        string            `yaml:"name"`
+		Interval    time.Duration     `yaml:"interval"`
+		QueryOffset time.Duration     `yaml:"query_offset"`
+		Limit       int               `yaml:"limit"`
+		Labels      map[string]string `yaml:"labels"`
+		Rules       []rule            `yaml:"rules"`
+	}
+	expGroups := struct {
+		Groups []group `yaml:"groups"`
+	}{}
+	err = yaml.Unmarshal(gotYAML.Bytes(), &expGroups)
+	require.NoError(err)
+
+	require.Len(gotGroups, len(expGroups.Groups))
+	for idx, g := range gotGroups {
+		rules := []rule{}
+		for _, r := range g.Rules {
+			rules = append(rules, rule(r))
+		}
+		assert.Equal(expGroups.Groups[idx], group{
+			Name:        g.Name,
+			Interval:    g.Interval,
+			QueryOffset: g.QueryOffset,
+			Limit:       g.Limit,
+			Labels:      g.Labels,
+			Rules:       rules,
+		})
+	}
+
+	// The groups should have their SLO.
+	assert.Equal("sloth-slo-sli-recordings-test1", gotGroups[0].Name)
+	assert.Equal("test1", gotGroups[0].SLOID)
+	assert.Equal("svc1", gotGroups[0].SLOService)
+	assert.Equal("sloth-slo-index", gotGroups[len(gotGroups)-1].Name)
+	assert.Equal("", gotGroups[len(gotGroups)-1].SLOID)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoBuildRuleGroupsWithSerializer(t *testing.T) {
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:     &bytes.Buffer{},
+		Serializer: testSerializer,
+	})
+	require.NoError(t, err)
+
+	_, err = repo.BuildRuleGroups(context.TODO(), []prometheus.StorageSLO{})
+	assert.Error(t, err)
+}
+
+func TestGenerateString(t *testing.T) {
+	tests := map[string]struct {
+		slos      []prometheus.StorageSLO
+		flavor    prometheus.OutputFlavor
+		expGroups int
+		expErr    error
+	}{
+		"Having 0 SLO rules generated should fail with no rules error.": {
+			slos:   []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "test1"}}},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"Having SLO rules should return the same rules as the writer.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr2"}},
+					},
+				},
+			},
+			expGroups: 2,
+		},
+
+		"Having SLO rules with a flavor should return the same rules as the writer.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+					},
+				},
+			},
+			flavor:    prometheus.ThanosFlavor,
+			expGroups: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			got, gotGroups, err := prometheus.GenerateString(context.TODO(), test.slos, test.flavor)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+				assert.Empty(got)
+				return
+			}
+			require.NoError(err)
+
+			var expYAML bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer: &expYAML,
+				Flavor: test.flavor,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+			require.NoError(err)
+
+			assert.Equal(expYAML.String(), got)
+			assert.Equal(test.expGroups, gotGroups)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreInvalidExpression(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: failWriter{},
+		Logger: log.Noop,
+	})
+	require.NoError(err)
+
+	err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "sum(rate(test[5m]"}},
+			},
+		},
+	})
+
+	// The error should have the group, the rule and the PromQL parse error position.
+	assert.EqualError(err, `invalid rules: invalid "test:record" rule on "sloth-slo-sli-recordings-test1" group: could not parse expression: 1:18: parse error: unclosed left parenthesis`)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreJSONRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO:      prometheus.SLO{ID: "test1"},
+			Interval: 30 * time.Second,
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "slo:sli_error:ratio_rate5m", Expr: "sum(rate(errors[5m])) / sum(rate(total[5m]))", Labels: map[string]string{"sloth_id": "test1"}},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "slo:objective:ratio", Expr: "vector(0.99)"},
+				},
+				AlertRules: []rulefmt.Rule{
+					{
+						Alert:       "SLOErrorBudgetBurn",
+						Expr:        "slo:sli_error:ratio_rate5m > 0.1",
+						For:         prommodel.Duration(5 * time.Minute),
+						Labels:      map[string]string{"severity": "page"},
+						Annotations: map[string]string{"summary": "High burn"},
+					},
+				},
+			},
+		},
+	}
+
+	var gotJSON bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &gotJSON,
+		Format: prometheus.JSONFormat,
+		Logger: log.Noop,
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(err)
+
+	// JSON is valid YAML, so it should be loaded by Prometheus as a regular rule file.
+	gotGroups, errs := rulefmt.Parse(gotJSON.Bytes())
+	require.Empty(errs)
+	require.Len(gotGroups.Groups, 3)
+
+	expGroups := []struct {
+		name  string
+		rules []rulefmt.Rule
+	}{
+		{name: "sloth-slo-sli-recordings-test1", rules: slos[0].Rules.SLIErrorRecRules},
+		{name: "sloth-slo-meta-recordings-test1", rules: slos[0].Rules.MetadataRecRules},
+		{name: "sloth-slo-alerts-test1", rules: slos[0].Rules.AlertRules},
+	}
+	for i, exp := range expGroups {
+		gotGroup := gotGroups.Groups[i]
+		assert.Equal(exp.name, gotGroup.Name)
+		assert.Equal(prommodel.Duration(30*time.Second), gotGroup.Interval)
+		require.Len(gotGroup.Rules, len(exp.rules))
+		for j, expRule := range exp.rules {
+			gotRule := gotGroup.Rules[j]
+			assert.Equal(expRule, rulefmt.Rule{
+				Record:      gotRule.Record.Value,
+				Alert:       gotRule.Alert.Value,
+				Expr:        gotRule.Expr.Value,
+				For:         gotRule.For,
+				Labels:      gotRule.Labels,
+				Annotations: gotRule.Annotations,
+			})
+		}
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreJSONLines(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "slo:sli_error:ratio_rate5m", Expr: "sum(rate(errors[5m])) / sum(rate(total[5m]))", Labels: map[string]string{"sloth_id": "test1"}},
+					{Record: "slo:sli_error:ratio_rate1h", Expr: "sum(rate(errors[1h])) / sum(rate(total[1h]))", Labels: map[string]string{"sloth_id": "test1"}},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "slo:objective:ratio", Expr: "vector(0.99)"},
+				},
+				AlertRules: []rulefmt.Rule{
+					{
+						Alert:       "SLOErrorBudgetBurn",
+						Expr:        "slo:sli_error:ratio_rate5m > 0.1",
+						For:         prommodel.Duration(5 * time.Minute),
+						Labels:      map[string]string{"severity": "page"},
+						Annotations: map[string]string{"summary": "High <burn>"},
+					},
+				},
+			},
+		},
+	}
+
+	var got bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &got,
+		Format: prometheus.JSONLinesFormat,
+		Logger: log.Noop,
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(err)
+
+	// Each line should be an independent JSON rule (without disclaimer).
+	type ruleLine struct {
+		Type        string             `json:"type"`
+		Group       string             `json:"group"`
+		Record      string             `json:"record"`
+		Alert       string             `json:"alert"`
+		Expr        string             `json:"expr"`
+		For         prommodel.Duration `json:"for"`
+		Labels      map[string]string  `json:"labels"`
+		Annotations map[string]string  `json:"annotations"`
+	}
+	gotRules := map[string][]rulefmt.Rule{}
+	gotTypes := []string{}
+	lines := strings.Split(strings.TrimSuffix(got.String(), "\n"), "\n")
+	require.Len(lines, 4)
+	for _, line := range lines {
+		var r ruleLine
+		err := json.Unmarshal([]byte(line), &r)
+		require.NoError(err)
+		gotTypes = append(gotTypes, r.Type)
+		gotRules[r.Group] = append(gotRules[r.Group], rulefmt.Rule{
+			Record:      r.Record,
+			Alert:       r.Alert,
+			Expr:        r.Expr,
+			For:         r.For,
+			Labels:      r.Labels,
+			Annotations: r.Annotations,
+		})
+	}
+
+	expRules := map[string][]rulefmt.Rule{
+		"sloth-slo-sli-recordings-test1":  slos[0].Rules.SLIErrorRecRules,
+		"sloth-slo-meta-recordings-test1": slos[0].Rules.MetadataRecRules,
+		"sloth-slo-alerts-test1":          slos[0].Rules.AlertRules,
+	}
+	assert.Equal(expRules, gotRules)
+	assert.Equal([]string{"recording", "recording", "recording", "alert"}, gotTypes)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreValidationError(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &bytes.Buffer{},
+		Logger: log.Noop,
+	})
+	require.NoError(err)
+
+	err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "sum(rate(test[5m]"}},
+			},
+		},
+	})
+	require.Error(err)
+	assert.Contains(err.Error(), `invalid "testAlert" rule on "sloth-slo-alerts-test1" group: could not parse expression`)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreStrict(t *testing.T) {
+	tests := map[string]struct {
+		strict    bool
+		rules     prometheus.SLORules
+		expErrMsg string
+	}{
+		"Empty label values without strict mode should be stored.": {
+			rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr", Labels: map[string]string{"team": ""}}},
+			},
+		},
+
+		"Empty label values in strict mode should fail.": {
+			strict: true,
+			rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr", Labels: map[string]string{"team": ""}}},
+			},
+			expErrMsg: `invalid "test:record" rule on "sloth-slo-sli-recordings-test1" group: label "team" has an empty value`,
+		},
+
+		"Unresolved template values in strict mode should fail.": {
+			strict: true,
+			rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{{
+					Alert:       "testAlert",
+					Expr:        "test-expr",
+					Labels:      map[string]string{"team": "a"},
+					Annotations: map[string]string{"runbook": "https://runbooks/<no value>"},
+				}},
+			},
+			expErrMsg: `invalid "testAlert" rule on "sloth-slo-alerts-test1" group: annotation "runbook" has an unresolved template value: "https://runbooks/<no value>"`,
+		},
+
+		"Valid values in strict mode should be stored.": {
+			strict: true,
+			rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{{
+					Alert:       "testAlert",
+					Expr:        "test-expr",
+					Labels:      map[string]string{"team": "a"},
+					Annotations: map[string]string{"summary": "{{$labels.sloth_service}} is burning budget"},
+				}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:            &bytes.Buffer{},
+				Logger:            log.Noop,
+				DisableValidation: true,
+				Strict:            test.strict,
+			})
+			require.NoError(err)
+
+			err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "test1"}, Rules: test.rules}})
+			if test.expErrMsg != "" {
+				assert.EqualError(err, "invalid rules: "+test.expErrMsg)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreGzip(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		level int
+	}{
+		"Using the default compression level should compress the rules.": {},
+		"Using the best speed compression level should compress the rules.": {
+			level: gzip.BestSpeed,
+		},
+		"Using the best compression level should compress the rules.": {
+			level: gzip.BestCompression,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Uncompressed.
+			var expYAML bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer: &expYAML,
+				Logger: log.Noop,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			// Compressed.
+			var gotGzip bytes.Buffer
+			repo, err = prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:    &gotGzip,
+				Logger:    log.Noop,
+				Gzip:      true,
+				GzipLevel: test.level,
+			})
+			require.NoError(err)
+			res, err := repo.StoreSLOsResult(context.TODO(), slos)
+			require.NoError(err)
+			assert.Equal(gotGzip.Len(), res.BytesWritten)
+
+			gr, err := gzip.NewReader(&gotGzip)
+			require.NoError(err)
+			gotYAML, err := io.ReadAll(gr)
+			require.NoError(err)
+			assert.Equal(expYAML.String(), string(gotYAML))
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidGzipLevel(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:    &bytes.Buffer{},
+		Gzip:      true,
+		GzipLevel: 10,
+	})
+	assert.Error(t, err)
+}
+
+// countCallsWriter counts the write calls on the wrapped buffer.
+type countCallsWriter struct {
+	bytes.Buffer
+	calls int
+}
+
+func (c *countCallsWriter) Write(p []byte) (int, error) {
+	c.calls++
+	return c.Buffer.Write(p)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreBufferedWrites(t *testing.T) {
+	slos := []prometheus.StorageSLO{}
+	for i := 0; i < 10; i++ {
+		slos = append(slos, prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: fmt.Sprintf("test%d", i)},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		})
+	}
+
+	tests := map[string]struct {
+		config   prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		slos     []prometheus.StorageSLO
+		expCalls func(t *testing.T, calls int)
+		expErr   error
+	}{
+		"Without buffering the rules should be written with multiple writes.": {
+			slos:     slos,
+			expCalls: func(t *testing.T, calls int) { assert.Greater(t, calls, 20) },
+		},
+
+		"With buffering the rules should be written with a single write.": {
+			config:   prometheus.IOWriterGroupedRulesYAMLRepoConfig{BufferedWrites: true},
+			slos:     slos,
+			expCalls: func(t *testing.T, calls int) { assert.Equal(t, 1, calls) },
+		},
+
+		"With a small buffer the rules should be written with multiple writes.": {
+			config:   prometheus.IOWriterGroupedRulesYAMLRepoConfig{BufferedWrites: true, WriteBufferSize: 256},
+			slos:     slos,
+			expCalls: func(t *testing.T, calls int) { assert.Greater(t, calls, 1) },
+		},
+
+		"With buffering and gzip the rules should be written with a single write.": {
+			config:   prometheus.IOWriterGroupedRulesYAMLRepoConfig{BufferedWrites: true, Gzip: true},
+			slos:     slos,
+			expCalls: func(t *testing.T, calls int) { assert.Equal(t, 1, calls) },
+		},
+
+		"With buffering and no rules it should not write anything.": {
+			config:   prometheus.IOWriterGroupedRulesYAMLRepoConfig{BufferedWrites: true},
+			slos:     []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "test1"}}},
+			expCalls: func(t *testing.T, calls int) { assert.Equal(t, 0, calls) },
+			expErr:   prometheus.ErrNoSLORules,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// The same rules should be written with and without buffering.
+			var expOut bytes.Buffer
+			expConfig := test.config
+			expConfig.Writer = &expOut
+			expConfig.BufferedWrites = false
+			expRepo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(expConfig)
+			require.NoError(err)
+			_ = expRepo.StoreSLOs(context.TODO(), test.slos)
+
+			w := &countCallsWriter{}
+			test.config.Writer = w
+			test.config.Logger = log.Noop
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			res, err := repo.StoreSLOsResult(context.TODO(), test.slos)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(w.Len(), res.BytesWritten)
+			}
+			assert.Equal(expOut.String(), w.String())
+			test.expCalls(t, w.calls)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreBufferedWritesError(t *testing.T) {
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:         failWriter{},
+		BufferedWrites: true,
+	})
+	require.NoError(t, err)
+	err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{
+		{
+			SLO:   prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}}},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidWriteBufferSize(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:          &bytes.Buffer{},
+		BufferedWrites:  true,
+		WriteBufferSize: -1,
+	})
+	assert.Error(t, err)
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, fmt.Errorf("should not write") }
+
+func TestIOWriterGroupedRulesYAMLRepoStoreMultipleWriters(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+			},
+		},
+	}
+
+	t.Run("All the writers should have the same rules.", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var gotYAML1, gotYAML2 bytes.Buffer
+		repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+			Writers: []io.Writer{&gotYAML1, &gotYAML2},
+			Logger:  log.Noop,
+		})
+		require.NoError(err)
+
+		res, err := repo.StoreSLOsResult(context.TODO(), slos)
+		require.NoError(err)
+
+		assert.NotEmpty(gotYAML1.String())
+		assert.Equal(gotYAML1.String(), gotYAML2.String())
+		assert.Equal(gotYAML1.Len(), res.BytesWritten)
+	})
+
+	t.Run("A writer failure should fail with the failed writer.", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var gotYAML bytes.Buffer
+		repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+			Writer:  &gotYAML,
+			Writers: []io.Writer{failWriter{}},
+			Logger:  log.Noop,
+		})
+		require.NoError(err)
+
+		err = repo.StoreSLOs(context.TODO(), slos)
+		assert.ErrorContains(err, "could not write on writer 1: should not write")
+	})
+}
+
+func TestIOWriterGroupedRulesYAMLRepoValidate(t *testing.T) {
+	tests := map[string]struct {
+		config prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		slos   []prometheus.StorageSLO
+		expRes *prometheus.StoreResult
+		expErr error
+	}{
+		"Having 0 SLO rules should fail.": {
+			slos:   []prometheus.StorageSLO{},
+			expErr: fmt.Errorf("slo rules required"),
+		},
+
+		"Having 0 SLO rules generated should fail.": {
+			slos:   []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "test1"}}},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"Having recordings only with SLOs that only have alert rules should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{RecordingsOnly: true},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+			},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"Having alerts only with SLOs without alert rules should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{AlertsOnly: true},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"Having the metadata recordings disabled with SLOs only with metadata recording rules should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{DisableMetadataRecordings: true},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						MetadataRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"Having valid SLO rules should return the result without writing.": {
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+			},
+			expRes: &prometheus.StoreResult{Groups: 2, RecordingRules: 1, AlertRules: 1},
+		},
+
+		"Having valid SLO rules with a flavor should return the result without writing.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Flavor:      prometheus.MimirFlavor,
+				MimirTenant: "tenant-a",
+			},
+			slos: []prometheus.StorageSLO{
+				{
+					SLO: prometheus.SLO{ID: "test1"},
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+					},
+				},
+			},
+			expRes: &prometheus.StoreResult{Groups: 1, RecordingRules: 1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			test.config.Writer = failWriter{}
+			test.config.Logger = log.Noop
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+
+			gotRes, err := repo.ValidateSLOs(context.TODO(), test.slos)
+
+			if test.expErr != nil {
+				assert.Equal(test.expErr, err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expRes, gotRes)
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreIndexGroup(t *testing.T) {
+	newSLO := func(svc, id string, objective float64) prometheus.StorageSLO {
+		return prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: id, Name: id + "-name", Service: svc, Objective: objective},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		config  prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		slos    []prometheus.StorageSLO
+		expErr  error
+		expYAML string
+	}{
+		"Having the index group disabled should not add it.": {
+			slos: []prometheus.StorageSLO{newSLO("svc1", "svc1-slo1", 99.9)},
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-svc1-slo1
+  rules:
+  - record: test:record
+    expr: test-expr
+`,
+		},
+
+		"Having the index group enabled without SLO rules should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{IndexGroup: true},
+			slos:   []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "svc1-slo1"}}},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"Having the index group enabled should add an info rule per SLO after the SLO groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{IndexGroup: true, GroupPrefix: "test"},
+			slos: []prometheus.StorageSLO{
+				newSLO("svc2", "svc2-slo1", 95),
+				newSLO("svc1", "svc1-slo1", 99.9),
+			},
+			expYAML: `groups:
+- name: test-sli-recordings-svc1-slo1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: test-sli-recordings-svc2-slo1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: test-index
+  rules:
+  - record: sloth_slo_index_info
+    expr: vector(1)
+    labels:
+      sloth_id: svc1-slo1
+      sloth_objective: "99.9"
+      sloth_service: svc1
+      sloth_slo: svc1-slo1-name
+  - record: sloth_slo_index_info
+    expr: vector(1)
+    labels:
+      sloth_id: svc2-slo1
+      sloth_objective: "95"
+      sloth_service: svc2
+      sloth_slo: svc2-slo1-name
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			test.config.Writer = &b
+			test.config.Logger = log.Noop
+			test.config.DisableDisclaimer = true
+			test.config.GroupsOrder = prometheus.GroupedByServiceGroupsOrder
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expYAML, b.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSLOSelector(t *testing.T) {
+	newSLO := func(id string, labels map[string]string) prometheus.StorageSLO {
+		return prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: id, Labels: labels},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		}
+	}
+	slos := []prometheus.StorageSLO{
+		newSLO("slo-prod", map[string]string{"env": "prod", "tier": "1"}),
+		newSLO("slo-staging", map[string]string{"env": "staging", "tier": "2"}),
+		newSLO("slo-dev", map[string]string{"env": "dev"}),
+		newSLO("slo-nolabels", nil),
+	}
+
+	tests := map[string]struct {
+		selector  string
+		expGroups []string
+		expErr    error
+	}{
+		"Without selector all the SLOs should be stored.": {
+			expGroups: []string{
+				"sloth-slo-sli-recordings-slo-dev",
+				"sloth-slo-sli-recordings-slo-nolabels",
+				"sloth-slo-sli-recordings-slo-prod",
+				"sloth-slo-sli-recordings-slo-staging",
+			},
+		},
+
+		"An equality selector should store the matching SLOs.": {
+			selector:  "env=prod",
+			expGroups: []string{"sloth-slo-sli-recordings-slo-prod"},
+		},
+
+		"An inequality selector should skip the matching SLOs.": {
+			selector: "env!=staging",
+			expGroups: []string{
+				"sloth-slo-sli-recordings-slo-dev",
+				"sloth-slo-sli-recordings-slo-nolabels",
+				"sloth-slo-sli-recordings-slo-prod",
+			},
+		},
+
+		"An in selector should store the SLOs with any of the values.": {
+			selector: "env in (prod,dev)",
+			expGroups: []string{
+				"sloth-slo-sli-recordings-slo-dev",
+				"sloth-slo-sli-recordings-slo-prod",
+			},
+		},
+
+		"A notin selector should skip the SLOs with any of the values.": {
+			selector: "env notin (staging,dev)",
+			expGroups: []string{
+				"sloth-slo-sli-recordings-slo-nolabels",
+				"sloth-slo-sli-recordings-slo-prod",
+			},
+		},
+
+		"Multiple matchers should store the SLOs that match all of them.": {
+			selector:  "env in (prod,staging),tier!=2",
+			expGroups: []string{"sloth-slo-sli-recordings-slo-prod"},
+		},
+
+		"A selector that doesn't match any SLO should return the no rules error.": {
+			selector: "env=test",
+			expErr:   prometheus.ErrNoSLORules,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:      &b,
+				Logger:      log.Noop,
+				SLOSelector: test.selector,
+				GroupsOrder: prometheus.GroupedByServiceGroupsOrder,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+				assert.Empty(b.String())
+				return
+			}
+			require.NoError(err)
+
+			gotGroups, errs := rulefmt.Parse(b.Bytes())
+			require.Empty(errs)
+			gotNames := []string{}
+			for _, g := range gotGroups.Groups {
+				gotNames = append(gotNames, g.Name)
+			}
+			assert.Equal(test.expGroups, gotNames)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidSLOSelector(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      &bytes.Buffer{},
+		SLOSelector: "env in (prod",
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreDeduplicatedRecordingRules(t *testing.T) {
+	sharedRule := rulefmt.Rule{Record: "test:availability", Expr: "sum(rate(ok[5m])) / sum(rate(total[5m]))", Labels: map[string]string{"team": "a"}}
+	newSLO := func(id string) prometheus.StorageSLO {
+		return prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: id},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					sharedRule,
+					// Only the labels are different, these are different series.
+					{Record: "test:availability", Expr: sharedRule.Expr, Labels: map[string]string{"team": "a", "sloth_id": id}},
+				},
+				MetadataRecRules: []rulefmt.Rule{{Record: "test:objective", Expr: "vector(0.99)"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		}
+	}
+	slos := []prometheus.StorageSLO{newSLO("test1"), newSLO("test2")}
+
+	tests := map[string]struct {
+		dedup   bool
+		expYAML string
+	}{
+		"Without deduplication all the rules should be stored.": {
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:availability
+    expr: sum(rate(ok[5m])) / sum(rate(total[5m]))
+    labels:
+      team: a
+  - record: test:availability
+    expr: sum(rate(ok[5m])) / sum(rate(total[5m]))
+    labels:
+      sloth_id: test1
+      team: a
+- name: sloth-slo-meta-recordings-test1
+  rules:
+  - record: test:objective
+    expr: vector(0.99)
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:availability
+    expr: sum(rate(ok[5m])) / sum(rate(total[5m]))
+    labels:
+      team: a
+  - record: test:availability
+    expr: sum(rate(ok[5m])) / sum(rate(total[5m]))
+    labels:
+      sloth_id: test2
+      team: a
+- name: sloth-slo-meta-recordings-test2
+  rules:
+  - record: test:objective
+    expr: vector(0.99)
+- name: sloth-slo-alerts-test2
+  rules:
+  - alert: testAlert
+    expr: test-expr
+`,
+		},
+
+		"With deduplication the identical recording rules should be stored once.": {
+			dedup: true,
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:availability
+    expr: sum(rate(ok[5m])) / sum(rate(total[5m]))
+    labels:
+      team: a
+  - record: test:availability
+    expr: sum(rate(ok[5m])) / sum(rate(total[5m]))
+    labels:
+      sloth_id: test1
+      team: a
+- name: sloth-slo-meta-recordings-test1
+  rules:
+  - record: test:objective
+    expr: vector(0.99)
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:availability
+    expr: sum(rate(ok[5m])) / sum(rate(total[5m]))
+    labels:
+      sloth_id: test2
+      team: a
+- name: sloth-slo-alerts-test2
+  rules:
+  - alert: testAlert
+    expr: test-expr
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:                    &b,
+				Logger:                    log.Noop,
+				DisableDisclaimer:         true,
+				DeduplicateRecordingRules: test.dedup,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			assert.Equal(test.expYAML, b.String())
+		})
+	}
+}
+
+type infoLog struct {
+	msg    string
+	values log.Kv
+}
+
+// infoLogger captures the info logs with their values.
+type infoLogger struct {
+	log.Logger
+	values log.Kv
+	logs   *[]infoLog
+}
+
+func (l infoLogger) Infof(format string, args ...interface{}) {
+	*l.logs = append(*l.logs, infoLog{msg: fmt.Sprintf(format, args...), values: l.values})
+}
+
+func (l infoLogger) WithValues(values map[string]interface{}) log.Logger {
+	kv := log.Kv{}
+	for k, v := range l.values {
+		kv[k] = v
+	}
+	for k, v := range values {
+		kv[k] = v
+	}
+	l.values = kv
+	return l
+}
+
+func (l infoLogger) WithCtxValues(context.Context) log.Logger { return l }
+
+func TestIOWriterGroupedRulesYAMLRepoStoreLog(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test3", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				MetadataRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		flavor prometheus.OutputFlavor
+		expMsg string
+	}{
+		"The Prometheus flavor should log the Prometheus rules.": {
+			flavor: prometheus.PrometheusFlavor,
+			expMsg: "Prometheus rules written",
+		},
+
+		"The Mimir flavor should log the Mimir rules.": {
+			flavor: prometheus.MimirFlavor,
+			expMsg: "Mimir rules written",
+		},
+
+		"The Thanos flavor should log the Thanos rules.": {
+			flavor: prometheus.ThanosFlavor,
+			expMsg: "Thanos rules written",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			logs := []infoLog{}
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer: &bytes.Buffer{},
+				Logger: infoLogger{Logger: log.Noop, logs: &logs},
+				Flavor: test.flavor,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			require.Len(logs, 1)
+			assert.Equal(test.expMsg, logs[0].msg)
+			assert.Equal(test.flavor, logs[0].values["flavor"])
+			assert.Equal(4, logs[0].values["groups"])
+			assert.Equal(3, logs[0].values["recording-rules"])
+			assert.Equal(1, logs[0].values["alert-rules"])
+			assert.Equal([]string{"svc1", "svc2"}, logs[0].values["services"])
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreGroupLabels(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr", Labels: map[string]string{"sloth_id": "test1"}}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"severity": "page"}}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		format prometheus.OutputFormat
+		expOut string
+	}{
+		"The group labels should be set on all the YAML groups keeping the rule labels.": {
+			format: prometheus.YAMLFormat,
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  labels:
+    slo_service: svc1
+    team: team-a
+  rules:
+  - record: test:record
+    expr: test-expr
+    labels:
+      sloth_id: test1
+- name: sloth-slo-alerts-test1
+  labels:
+    slo_service: svc1
+    team: team-a
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      severity: page
+`,
+		},
+
+		"The group labels should be set on all the JSON groups keeping the rule labels.": {
+			format: prometheus.JSONFormat,
+			expOut: `{
+  "groups": [
+    {
+      "name": "sloth-slo-sli-recordings-test1",
+      "labels": {
+        "slo_service": "svc1",
+        "team": "team-a"
+      },
+      "rules": [
+        {
+          "record": "test:record",
+          "expr": "test-expr",
+          "labels": {
+            "sloth_id": "test1"
+          }
+        }
+      ]
+    },
+    {
+      "name": "sloth-slo-alerts-test1",
+      "labels": {
+        "slo_service": "svc1",
+        "team": "team-a"
+      },
+      "rules": [
+        {
+          "alert": "testAlert",
+          "expr": "test-expr",
+          "labels": {
+            "severity": "page"
+          }
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:            &b,
+				Logger:            log.Noop,
+				Format:            test.format,
+				DisableDisclaimer: true,
+				GroupLabels:       map[string]string{"slo_service": "svc1", "team": "team-a"},
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			assert.Equal(test.expOut, b.String())
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidGroupLabels(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      &bytes.Buffer{},
+		GroupLabels: map[string]string{"slo-service": "svc1"},
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreShadowSLOs(t *testing.T) {
+	newSLO := func(id string, labels map[string]string) prometheus.StorageSLO {
+		return prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: id, Labels: labels},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"severity": "page"}}},
+			},
+		}
+	}
+	slos := []prometheus.StorageSLO{
+		newSLO("test1", nil),
+		newSLO("test2", map[string]string{"rollout": "canary"}),
+	}
+
+	tests := map[string]struct {
+		config prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		expOut string
+	}{
+		"Without shadow SLOs the alert rules should not have the shadow label.": {
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      severity: page
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test2
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      severity: page
+`,
+		},
+
+		"The shadow SLO IDs should add the default shadow label only to their alert rules.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{ShadowSLOIDs: []string{"test1"}},
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      severity: page
+      shadow: "true"
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test2
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      severity: page
+`,
+		},
+
+		"The shadow SLO selector should add the custom shadow labels to the matching SLOs alert rules, overriding the rule labels.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				ShadowSLOSelector: "rollout=canary",
+				ShadowLabels:      map[string]string{"severity": "shadow"},
+			},
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      severity: page
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-test2
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      severity: shadow
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			test.config.Writer = &b
+			test.config.Logger = log.Noop
+			test.config.DisableDisclaimer = true
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			assert.Equal(test.expOut, b.String())
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidShadowConfig(t *testing.T) {
+	tests := map[string]prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		"Invalid shadow SLO selector should fail.": {ShadowSLOSelector: "rollout in canary"},
+		"Invalid shadow label name should fail.":   {ShadowLabels: map[string]string{"shadow-alert": "true"}},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config.Writer = &bytes.Buffer{}
+			_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSeverityNormalization(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr", Labels: map[string]string{"severity": "critical"}}},
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlert1", Expr: "test-expr", Labels: map[string]string{"severity": "critical"}},
+					{Alert: "testAlert2", Expr: "test-expr", Labels: map[string]string{"severity": "warning"}},
+					{Alert: "testAlert3", Expr: "test-expr", Labels: map[string]string{"severity": "ticket"}},
+					{Alert: "testAlert4", Expr: "test-expr", Labels: map[string]string{"team": "a-team"}},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		config prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		expOut string
+	}{
+		"Without severity normalization the alert rules should be stored as they are.": {
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+    labels:
+      severity: critical
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr
+    labels:
+      severity: critical
+  - alert: testAlert2
+    expr: test-expr
+    labels:
+      severity: warning
+  - alert: testAlert3
+    expr: test-expr
+    labels:
+      severity: ticket
+  - alert: testAlert4
+    expr: test-expr
+    labels:
+      team: a-team
+`,
+		},
+
+		"The severity synonyms should be mapped to the canonical severities only on the alert rules.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				SeverityMapping: map[string]string{"critical": "page", "warning": "ticket"},
+			},
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+    labels:
+      severity: critical
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr
+    labels:
+      severity: page
+  - alert: testAlert2
+    expr: test-expr
+    labels:
+      severity: ticket
+  - alert: testAlert3
+    expr: test-expr
+    labels:
+      severity: ticket
+  - alert: testAlert4
+    expr: test-expr
+    labels:
+      team: a-team
+`,
+		},
+
+		"The default severity should be set on the alert rules without severity.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				SeverityMapping: map[string]string{"critical": "page", "warning": "ticket"},
+				DefaultSeverity: "ticket",
+			},
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+    labels:
+      severity: critical
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr
+    labels:
+      severity: page
+  - alert: testAlert2
+    expr: test-expr
+    labels:
+      severity: ticket
+  - alert: testAlert3
+    expr: test-expr
+    labels:
+      severity: ticket
+  - alert: testAlert4
+    expr: test-expr
+    labels:
+      severity: ticket
+      team: a-team
+`,
+		},
+
+		"A custom severity label should be normalized.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				SeverityLabel:   "team",
+				SeverityMapping: map[string]string{"a-team": "b-team"},
+				DefaultSeverity: "c-team",
+			},
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+    labels:
+      severity: critical
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert1
+    expr: test-expr
+    labels:
+      severity: critical
+      team: c-team
+  - alert: testAlert2
+    expr: test-expr
+    labels:
+      severity: warning
+      team: c-team
+  - alert: testAlert3
+    expr: test-expr
+    labels:
+      severity: ticket
+      team: c-team
+  - alert: testAlert4
+    expr: test-expr
+    labels:
+      team: b-team
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			test.config.Writer = &b
+			test.config.Logger = log.Noop
+			test.config.DisableDisclaimer = true
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			assert.Equal(test.expOut, b.String())
+			// The SLO rules should not be changed.
+			assert.Equal(map[string]string{"team": "a-team"}, slos[0].Rules.AlertRules[3].Labels)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidSeverityConfig(t *testing.T) {
+	tests := map[string]prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		"Invalid severity label name should fail.":      {SeverityLabel: "alert-severity"},
+		"Empty canonical severity mapping should fail.": {SeverityMapping: map[string]string{"critical": ""}},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config.Writer = &bytes.Buffer{}
+			_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreFailOnNoAlertRules(t *testing.T) {
+	recordingsSLO := prometheus.StorageSLO{
+		SLO: prometheus.SLO{ID: "test1"},
+		Rules: prometheus.SLORules{
+			SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr"}},
+			MetadataRecRules: []rulefmt.Rule{{Record: "test:record2", Expr: "test-expr"}},
+		},
+	}
+	alertsSLO := prometheus.StorageSLO{
+		SLO: prometheus.SLO{ID: "test2"},
+		Rules: prometheus.SLORules{
+			SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr"}},
+			AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+		},
+	}
+
+	tests := map[string]struct {
+		config prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		slos   []prometheus.StorageSLO
+		expErr bool
+	}{
+		"Recordings without alerts should be stored by default.": {
+			slos: []prometheus.StorageSLO{recordingsSLO},
+		},
+
+		"Recordings without alerts should fail when failing on no alert rules.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{FailOnNoAlertRules: true, IndexGroup: true},
+			slos:   []prometheus.StorageSLO{recordingsSLO},
+			expErr: true,
+		},
+
+		"Recordings with the alerts of any SLO should be stored when failing on no alert rules.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{FailOnNoAlertRules: true},
+			slos:   []prometheus.StorageSLO{recordingsSLO, alertsSLO},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var b bytes.Buffer
+			test.config.Writer = &b
+			test.config.Logger = log.Noop
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(t, err)
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.ErrorIs(err, prometheus.ErrNoSLOAlertRules)
+				assert.Empty(b.String())
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidFailOnNoAlertRules(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:             &bytes.Buffer{},
+		RecordingsOnly:     true,
+		FailOnNoAlertRules: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreRemoteWriteKeepRegex(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr"},
+					{Record: "slo:sli_error:ratio_rate30m", Expr: "test-expr"},
+				},
+				MetadataRecRules: []rulefmt.Rule{{Record: "slo:objective:ratio", Expr: "vector(0.999)"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test2"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:               &b,
+		Logger:               log.Noop,
+		DisableDisclaimer:    true,
+		RemoteWriteKeepRegex: true,
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(err)
+
+	header, _, ok := strings.Cut(b.String(), "\n")
+	require.True(ok)
+	require.True(strings.HasPrefix(header, "# remote-write-keep-regex: "))
+	gotRegex := strings.TrimPrefix(header, "# remote-write-keep-regex: ")
+	assert.Equal(`slo:objective:ratio|slo:sli_error:ratio_rate30m|slo:sli_error:ratio_rate5m`, gotRegex)
+
+	// Like the Prometheus relabel regexes, fully anchored.
+	re := regexp.MustCompile("^(?:" + gotRegex + ")$")
+	gotSLOs, err := prometheus.ParseGeneratedRules(&b)
+	require.NoError(err)
+	for _, slo := range gotSLOs {
+		for _, r := range append(slo.Rules.SLIErrorRecRules, slo.Rules.MetadataRecRules...) {
+			assert.True(re.MatchString(r.Record), r.Record)
+		}
+	}
+	for _, name := range []string{"testAlert", "slo:sli_error:ratio_rate5", "slo:sli_error:ratio_rate5m_total", "slo_objective_ratio"} {
+		assert.False(re.MatchString(name), name)
+	}
+}
+
+type warningsLogger struct {
+	log.Logger
+	warnings *[]string
+}
+
+func (w warningsLogger) Warningf(format string, args ...interface{}) {
+	*w.warnings = append(*w.warnings, fmt.Sprintf(format, args...))
+}
+
+func (w warningsLogger) WithValues(map[string]interface{}) log.Logger { return w }
+func (w warningsLogger) WithCtxValues(context.Context) log.Logger     { return w }
+
+func TestIOWriterGroupedRulesYAMLRepoStoreEmptySLOs(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+		{SLO: prometheus.SLO{ID: "test2"}},
+		{
+			SLO: prometheus.SLO{ID: "test3"},
+			Rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+		{SLO: prometheus.SLO{ID: "test4"}},
+	}
+
+	tests := map[string]struct {
+		config      prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		expWarnings []string
+		expErr      string
+	}{
+		"By default the empty SLOs should be ignored.": {
+			expWarnings: []string{},
+		},
+
+		"The warn mode should log a warning for each empty SLO.": {
+			config:      prometheus.IOWriterGroupedRulesYAMLRepoConfig{EmptySLOs: prometheus.WarnEmptySLOs},
+			expWarnings: []string{`"test2" SLO doesn't have rules`, `"test4" SLO doesn't have rules`},
+		},
+
+		"The error mode should fail listing all the empty SLOs.": {
+			config:      prometheus.IOWriterGroupedRulesYAMLRepoConfig{EmptySLOs: prometheus.FailEmptySLOs},
+			expWarnings: []string{},
+			expErr:      "SLOs without rules: test2, test4",
+		},
+
+		"The SLOs without rules after filtering their kind should be empty.": {
+			config:      prometheus.IOWriterGroupedRulesYAMLRepoConfig{EmptySLOs: prometheus.FailEmptySLOs, RecordingsOnly: true},
+			expWarnings: []string{},
+			expErr:      "SLOs without rules: test2, test3, test4",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			warnings := []string{}
+			test.config.Writer = &bytes.Buffer{}
+			test.config.Logger = warningsLogger{Logger: log.Noop, warnings: &warnings}
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+
+			if test.expErr != "" {
+				assert.EqualError(err, test.expErr)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(test.expWarnings, warnings)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidEmptySLOs(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:    &bytes.Buffer{},
+		EmptySLOs: "panic",
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreRecordingRulesPrefix(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1", Objective: 99.9},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "slo:sli_error:ratio_rate5m", Expr: `sum(rate(http_requests_total{code=~"5.."}[5m])) / sum(rate(http_requests_total[5m]))`},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "slo:error_budget:ratio", Expr: "vector(1 - 0.999)"},
+					{Record: "slo:current_burn_rate:ratio", Expr: `slo:sli_error:ratio_rate5m{sloth_id="test1"} / on (sloth_id) group_left () slo:error_budget:ratio{sloth_id="test1"}`},
+				},
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlert", Expr: `{__name__="slo:sli_error:ratio_rate5m",sloth_id="test1"} > (14.4 * 0.001)`},
+				},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:               &b,
+		Logger:               log.Noop,
+		DisableDisclaimer:    true,
+		IndexGroup:           true,
+		RecordingRulesPrefix: "team_a_",
+	})
+	require.NoError(t, err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(t, err)
+
+	expOut := `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: team_a_slo:sli_error:ratio_rate5m
+    expr: sum(rate(http_requests_total{code=~"5.."}[5m])) / sum(rate(http_requests_total[5m]))
+- name: sloth-slo-meta-recordings-test1
+  rules:
+  - record: team_a_slo:error_budget:ratio
+    expr: vector(1 - 0.999)
+  - record: team_a_slo:current_burn_rate:ratio
+    expr: team_a_slo:sli_error:ratio_rate5m{sloth_id="test1"} / on (sloth_id) group_left
+      () team_a_slo:error_budget:ratio{sloth_id="test1"}
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: '{__name__="team_a_slo:sli_error:ratio_rate5m",sloth_id="test1"} > (14.4
+      * 0.001)'
+- name: sloth-slo-index
+  rules:
+  - record: team_a_sloth_slo_index_info
+    expr: vector(1)
+    labels:
+      sloth_id: test1
+      sloth_objective: "99.9"
+      sloth_service: svc1
+      sloth_slo: ""
+`
+	assert.Equal(t, expOut, b.String())
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidRecordingRulesPrefix(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:               &bytes.Buffer{},
+		RecordingRulesPrefix: "team-a:",
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStorePassthroughRuleGroups(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	customGroup := prometheus.RuleGroup{
+		Name:     "custom",
+		Interval: 2 * time.Minute,
+		Rules: []prometheus.Rule{
+			{Record: "custom:record", Expr: "sum(up)"},
+			{Alert: "CustomAlert", Expr: "custom:record == 0", For: 5 * time.Minute, Labels: map[string]string{"severity": "page"}},
+		},
+	}
+
+	tests := map[string]struct {
+		config prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		expOut string
+		expErr bool
+	}{
+		"The passthrough groups should be stored as they are after the generated groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				GroupLabels:           map[string]string{"team": "a-team"},
+				ExtraLabels:           map[string]string{"env": "prod"},
+				PassthroughRuleGroups: []prometheus.RuleGroup{customGroup},
+			},
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  labels:
+    team: a-team
+  rules:
+  - record: test:record
+    expr: test-expr
+    labels:
+      env: prod
+- name: sloth-slo-alerts-test1
+  labels:
+    team: a-team
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      env: prod
+- name: custom
+  interval: 2m
+  rules:
+  - record: custom:record
+    expr: sum(up)
+  - alert: CustomAlert
+    expr: custom:record == 0
+    for: 5m
+    labels:
+      severity: page
+`,
+		},
+
+		"The passthrough groups colliding with the generated groups should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				PassthroughRuleGroups: []prometheus.RuleGroup{{Name: "sloth-slo-alerts-test1", Rules: customGroup.Rules}},
+			},
+			expErr: true,
+		},
+
+		"The passthrough groups with invalid rules should fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				PassthroughRuleGroups: []prometheus.RuleGroup{{Name: "custom", Rules: []prometheus.Rule{{Record: "custom:record", Expr: "sum(up"}}}},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			test.config.Writer = &b
+			test.config.Logger = log.Noop
+			test.config.DisableDisclaimer = true
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expOut, b.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidPassthroughRuleGroups(t *testing.T) {
+	tests := map[string][]prometheus.RuleGroup{
+		"Passthrough groups without name should fail.": {{Rules: []prometheus.Rule{{Record: "custom:record", Expr: "sum(up)"}}}},
+		"Duplicated passthrough groups should fail.":   {{Name: "custom"}, {Name: "custom"}},
+	}
+
+	for name, groups := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:                &bytes.Buffer{},
+				PassthroughRuleGroups: groups,
+			})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSLOComments(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "svc1-slo1", Name: "slo1", Service: "svc1", Objective: 99.9, TimeWindow: 30 * 24 * time.Hour},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "svc2-slo1", Name: "slo1", Service: "svc2", Objective: 95, TimeWindow: 7 * 24 * time.Hour},
+			Rules: prometheus.SLORules{
+				MetadataRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:            &b,
+		Logger:            log.Noop,
+		DisableDisclaimer: true,
+		IndexGroup:        true,
+		SLOComments:       true,
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(err)
+
+	expOut := `groups:
+# SLO "slo1" of "svc1" service: 99.9% objective over 30d window.
+- name: sloth-slo-sli-recordings-svc1-slo1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-alerts-svc1-slo1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+# SLO "slo1" of "svc2" service: 95% objective over 1w window.
+- name: sloth-slo-meta-recordings-svc2-slo1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-index
+  rules:
+  - record: sloth_slo_index_info
+    expr: vector(1)
+    labels:
+      sloth_id: svc1-slo1
+      sloth_objective: "99.9"
+      sloth_service: svc1
+      sloth_slo: slo1
+  - record: sloth_slo_index_info
+    expr: vector(1)
+    labels:
+      sloth_id: svc2-slo1
+      sloth_objective: "95"
+      sloth_service: svc2
+      sloth_slo: slo1
+`
+	assert.Equal(expOut, b.String())
+
+	// The comments should not break the rules parsing.
+	gotGroups, errs := rulefmt.Parse(b.Bytes())
+	require.Empty(errs)
+	assert.Len(gotGroups.Groups, 4)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSLOCommentsLexicalOrder(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	newSLO := func(svc string) prometheus.StorageSLO {
+		return prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: svc + "-slo1", Name: "slo1", Service: svc, Objective: 99.9, TimeWindow: 30 * 24 * time.Hour},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		}
+	}
+
+	var b bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:            &b,
+		Logger:            log.Noop,
+		DisableDisclaimer: true,
+		SLOComments:       true,
+		GroupsOrder:       prometheus.LexicalGroupsOrder,
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{newSLO("svc1"), newSLO("svc2")})
+	require.NoError(err)
+
+	// The SLO groups are not contiguous, each SLO comment should be written once before its first group.
+	expOut := `groups:
+# SLO "slo1" of "svc1" service: 99.9% objective over 30d window.
+- name: sloth-slo-alerts-svc1-slo1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+# SLO "slo1" of "svc2" service: 99.9% objective over 30d window.
+- name: sloth-slo-alerts-svc2-slo1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+- name: sloth-slo-sli-recordings-svc1-slo1
+  rules:
+  - record: test:record
+    expr: test-expr
+- name: sloth-slo-sli-recordings-svc2-slo1
+  rules:
+  - record: test:record
+    expr: test-expr
+`
+	assert.Equal(expOut, b.String())
+}
+
+func TestIOWriterGroupedRulesYAMLRepoSLOCommentsSingleGroup(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      &bytes.Buffer{},
+		SLOComments: true,
+		SingleGroup: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSingleLineExpressions(t *testing.T) {
+	multiLineExpr := "(sum(rate(http_request_duration_seconds_count{job=\"myservice\",code=~\"(5..|429)\"}[5m])))\n/\n(sum(rate(http_request_duration_seconds_count{job=\"myservice\"}[5m])))\n"
+	longExpr := `slo:sli_error:ratio_rate5m{sloth_id="myservice-requests-availability", sloth_service="myservice"} > (14.4 * 0.001)`
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "test:record1", Expr: multiLineExpr},
+					{Record: "test:record2", Expr: "test_expr"},
+				},
+				AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: longExpr}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		config prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		expOut string
+	}{
+		"The expressions should be written in a single line.": {
+			expOut: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record1
+    expr: "(sum(rate(http_request_duration_seconds_count{job=\"myservice\",code=~\"(5..|429)\"}[5m])))\n/\n(sum(rate(http_request_duration_seconds_count{job=\"myservice\"}[5m])))\n"
+  - record: test:record2
+    expr: test_expr
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: "slo:sli_error:ratio_rate5m{sloth_id=\"myservice-requests-availability\", sloth_service=\"myservice\"} > (14.4 * 0.001)"
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			test.config.Writer = &b
+			test.config.Logger = log.Noop
+			test.config.DisableDisclaimer = true
+			test.config.SingleLineExpressions = true
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			assert.Equal(test.expOut, b.String())
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSingleLineExpressionsRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "test:record1", Expr: "(sum(rate(test_errors[5m])))\n/\n(sum(rate(test_total[5m])))\n"},
+					{Record: "test:record2", Expr: "sum(rate(test_total{path=\"/api/é\"}[5m]))  \t by (code)"},
+				},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:                &b,
+		Logger:                log.Noop,
+		SingleLineExpressions: true,
+	})
+	require.NoError(err)
+	err = repo.StoreSLOs(context.TODO(), slos)
+	require.NoError(err)
+
+	for _, l := range strings.Split(b.String(), "\n") {
+		assert.NotContains(l, "expr: |")
+	}
+	gotSLOs, err := prometheus.ParseGeneratedRules(&b)
+	require.NoError(err)
+	assert.Equal(slos, gotSLOs)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreTargetPrometheusVersion(t *testing.T) {
+	newSLOs := func(queryOffset time.Duration) []prometheus.StorageSLO {
+		return []prometheus.StorageSLO{
+			{
+				SLO: prometheus.SLO{
+					ID:            "test1",
+					PageAlertMeta: prometheus.AlertMeta{KeepFiringFor: 10 * time.Minute},
+				},
+				QueryOffset: queryOffset,
+				Rules: prometheus.SLORules{
+					AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}}},
+				},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		version   string
+		omit      bool
+		slos      []prometheus.StorageSLO
+		expYAML   string
+		expErrMsg string
+	}{
+		"Without target version all the fields should be rendered.": {
+			slos: newSLOs(time.Minute),
+			expYAML: `groups:
+- name: sloth-slo-alerts-test1
+  query_offset: 1m
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    keep_firing_for: 10m
+    labels:
+      sloth_severity: page
+`,
+		},
+
+		"A target version before 2.42 should reject keep firing for.": {
+			version:   "2.41.0",
+			slos:      newSLOs(0),
+			expErrMsg: `unsupported rules: "sloth-slo-alerts-test1" rule group "testAlert" rule keep_firing_for requires Prometheus 2.42 or newer, target version is 2.41`,
+		},
+
+		"A target version before 2.42 should omit keep firing for if omitting the unsupported fields.": {
+			version: "v2.41",
+			omit:    true,
+			slos:    newSLOs(0),
+			expYAML: `groups:
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    labels:
+      sloth_severity: page
+`,
+		},
+
+		"A target version of 2.42 should allow keep firing for.": {
+			version: "2.42",
+			slos:    newSLOs(0),
+			expYAML: `groups:
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    keep_firing_for: 10m
+    labels:
+      sloth_severity: page
+`,
+		},
+
+		"A target version before 2.53 should reject the query offset.": {
+			version:   "2.52",
+			slos:      newSLOs(time.Minute),
+			expErrMsg: `unsupported rules: "sloth-slo-alerts-test1" rule group query_offset requires Prometheus 2.53 or newer, target version is 2.52`,
+		},
+
+		"A target version before 2.53 should omit the query offset if omitting the unsupported fields.": {
+			version: "2.52",
+			omit:    true,
+			slos:    newSLOs(time.Minute),
+			expYAML: `groups:
+- name: sloth-slo-alerts-test1
+  rules:
+  - alert: testAlert
+    expr: test-expr
+    keep_firing_for: 10m
+    labels:
+      sloth_severity: page
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:                  &b,
+				Logger:                  log.Noop,
+				DisableDisclaimer:       true,
+				TargetPrometheusVersion: test.version,
+				OmitUnsupportedFields:   test.omit,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErrMsg != "" {
+				assert.EqualError(err, test.expErrMsg)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expYAML, b.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidTargetPrometheusVersion(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:                  &bytes.Buffer{},
+		TargetPrometheusVersion: "latest",
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreGroupsOrder(t *testing.T) {
+	newSLO := func(svc, id string) prometheus.StorageSLO {
+		return prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: id, Service: svc},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				MetadataRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		}
+	}
+	slos := []prometheus.StorageSLO{
+		newSLO("svc-b", "svc-b-slo2"),
+		newSLO("svc-a", "svc-a-slo2"),
+		newSLO("svc-b", "svc-b-slo1"),
+		newSLO("svc-a", "svc-a-slo1"),
+	}
+
+	tests := map[string]struct {
+		disableSorting bool
+		groupsOrder    prometheus.GroupsOrder
+		expGroups      []string
+	}{
+		"By default groups should keep the input order.": {
+			expGroups: []string{
+				"sloth-slo-sli-recordings-svc-b-slo2",
+				"sloth-slo-meta-recordings-svc-b-slo2",
+				"sloth-slo-alerts-svc-b-slo2",
+				"sloth-slo-sli-recordings-svc-a-slo2",
+				"sloth-slo-meta-recordings-svc-a-slo2",
+				"sloth-slo-alerts-svc-a-slo2",
+				"sloth-slo-sli-recordings-svc-b-slo1",
+				"sloth-slo-meta-recordings-svc-b-slo1",
+				"sloth-slo-alerts-svc-b-slo1",
+				"sloth-slo-sli-recordings-svc-a-slo1",
+				"sloth-slo-meta-recordings-svc-a-slo1",
+				"sloth-slo-alerts-svc-a-slo1",
+			},
+		},
+
+		"Disabling the sorting should keep the input order.": {
+			disableSorting: true,
+			expGroups: []string{
+				"sloth-slo-sli-recordings-svc-b-slo2",
+				"sloth-slo-meta-recordings-svc-b-slo2",
+				"sloth-slo-alerts-svc-b-slo2",
+				"sloth-slo-sli-recordings-svc-a-slo2",
+				"sloth-slo-meta-recordings-svc-a-slo2",
+				"sloth-slo-alerts-svc-a-slo2",
+				"sloth-slo-sli-recordings-svc-b-slo1",
+				"sloth-slo-meta-recordings-svc-b-slo1",
+				"sloth-slo-alerts-svc-b-slo1",
+				"sloth-slo-sli-recordings-svc-a-slo1",
+				"sloth-slo-meta-recordings-svc-a-slo1",
+				"sloth-slo-alerts-svc-a-slo1",
+			},
+		},
+
+		"The grouped by service order should sort the groups by service, SLO ID and type.": {
+			groupsOrder: prometheus.GroupedByServiceGroupsOrder,
+			expGroups: []string{
+				"sloth-slo-sli-recordings-svc-a-slo1",
+				"sloth-slo-meta-recordings-svc-a-slo1",
+				"sloth-slo-alerts-svc-a-slo1",
+				"sloth-slo-sli-recordings-svc-a-slo2",
+				"sloth-slo-meta-recordings-svc-a-slo2",
+				"sloth-slo-alerts-svc-a-slo2",
+				"sloth-slo-sli-recordings-svc-b-slo1",
+				"sloth-slo-meta-recordings-svc-b-slo1",
+				"sloth-slo-alerts-svc-b-slo1",
+				"sloth-slo-sli-recordings-svc-b-slo2",
+				"sloth-slo-meta-recordings-svc-b-slo2",
+				"sloth-slo-alerts-svc-b-slo2",
+			},
+		},
+
+		"The lexical order should sort the groups by name.": {
+			groupsOrder: prometheus.LexicalGroupsOrder,
+			expGroups: []string{
+				"sloth-slo-alerts-svc-a-slo1",
+				"sloth-slo-alerts-svc-a-slo2",
+				"sloth-slo-alerts-svc-b-slo1",
+				"sloth-slo-alerts-svc-b-slo2",
+				"sloth-slo-meta-recordings-svc-a-slo1",
+				"sloth-slo-meta-recordings-svc-a-slo2",
+				"sloth-slo-meta-recordings-svc-b-slo1",
+				"sloth-slo-meta-recordings-svc-b-slo2",
+				"sloth-slo-sli-recordings-svc-a-slo1",
+				"sloth-slo-sli-recordings-svc-a-slo2",
+				"sloth-slo-sli-recordings-svc-b-slo1",
+				"sloth-slo-sli-recordings-svc-b-slo2",
+			},
+		},
+
+		"The input order should keep the input order.": {
+			groupsOrder: prometheus.InputGroupsOrder,
+			expGroups: []string{
+				"sloth-slo-sli-recordings-svc-b-slo2",
+				"sloth-slo-meta-recordings-svc-b-slo2",
+				"sloth-slo-alerts-svc-b-slo2",
+				"sloth-slo-sli-recordings-svc-a-slo2",
+				"sloth-slo-meta-recordings-svc-a-slo2",
+				"sloth-slo-alerts-svc-a-slo2",
+				"sloth-slo-sli-recordings-svc-b-slo1",
+				"sloth-slo-meta-recordings-svc-b-slo1",
+				"sloth-slo-alerts-svc-b-slo1",
+				"sloth-slo-sli-recordings-svc-a-slo1",
+				"sloth-slo-meta-recordings-svc-a-slo1",
+				"sloth-slo-alerts-svc-a-slo1",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var gotYAML bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:               &gotYAML,
+				Logger:               log.Noop,
+				DisableGroupsSorting: test.disableSorting,
+				GroupsOrder:          test.groupsOrder,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			gotGroups, errs := rulefmt.Parse(gotYAML.Bytes())
+			require.Empty(errs)
+			gotNames := []string{}
+			for _, g := range gotGroups.Groups {
+				gotNames = append(gotNames, g.Name)
+			}
+			assert.Equal(test.expGroups, gotNames)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidGroupsOrder(t *testing.T) {
+	tests := map[string]prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		"Unknown groups order should fail.":                        {GroupsOrder: "random"},
+		"Disabled sorting with a sorted groups order should fail.": {GroupsOrder: prometheus.LexicalGroupsOrder, DisableGroupsSorting: true},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config.Writer = &bytes.Buffer{}
+			_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidGroupPrefix(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      &bytes.Buffer{},
+		GroupPrefix: "team a/",
+	})
+	assert.Error(t, err)
+}
+
+func BenchmarkIOWriterGroupedRulesYAMLRepoStore(b *testing.B) {
+	slos := []prometheus.StorageSLO{}
+	for i := 0; i < 500; i++ {
+		slos = append(slos, prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: fmt.Sprintf("slo-%d", i), Service: fmt.Sprintf("svc-%d", i%10)},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{
+					{Record: "slo:sli_error:ratio_rate5m", Expr: "sum(rate(errors[5m])) / sum(rate(total[5m]))", Labels: map[string]string{"sloth_id": "test"}},
+					{Record: "slo:sli_error:ratio_rate1h", Expr: "sum(rate(errors[1h])) / sum(rate(total[1h]))", Labels: map[string]string{"sloth_id": "test"}},
+				},
+				MetadataRecRules: []rulefmt.Rule{
+					{Record: "slo:objective:ratio", Expr: "vector(0.99)", Labels: map[string]string{"sloth_id": "test"}},
+				},
+				AlertRules: []rulefmt.Rule{
+					{Alert: "SLOErrorBudgetBurn", Expr: "slo:sli_error:ratio_rate5m > 0.1", Labels: map[string]string{"sloth_severity": "page"}},
+				},
+			},
+		})
+	}
+
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: io.Discard,
+		Logger: log.Noop,
+	})
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := repo.StoreSLOs(context.TODO(), slos)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidThanosPartialResponseStrategy(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:                        &bytes.Buffer{},
+		Flavor:                        prometheus.ThanosFlavor,
+		ThanosPartialResponseStrategy: "ignore",
+	})
+	assert.Error(t, err)
+}
+
+type fakeSyncWriter struct {
+	bytes.Buffer
+	syncs   int
+	syncErr error
+}
+
+func (f *fakeSyncWriter) Sync() error {
+	f.syncs++
+	return f.syncErr
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSync(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		sync     bool
+		syncErr  error
+		expSyncs int
+		expErr   bool
+	}{
+		"Without sync enabled, the writer should not be synced.": {
+			sync:     false,
+			expSyncs: 0,
+		},
+
+		"With sync enabled, the writer should be synced once after writing.": {
+			sync:     true,
+			expSyncs: 1,
+		},
+
+		"With sync enabled, a sync error should fail.": {
+			sync:     true,
+			syncErr:  fmt.Errorf("something"),
+			expSyncs: 1,
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			w := &fakeSyncWriter{syncErr: test.syncErr}
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer: w,
+				Logger: log.Noop,
+				Sync:   test.sync,
+			})
+			require.NoError(err)
+
+			res, err := repo.StoreSLOsResult(context.TODO(), slos)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(w.Len(), res.BytesWritten)
+			}
+			assert.Equal(test.expSyncs, w.syncs)
+		})
+	}
+
+	// Writers that can't be synced should not be affected.
+	var b bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &b,
+		Logger: log.Noop,
+		Sync:   true,
+	})
+	require.NoError(t, err)
+	res, err := repo.StoreSLOsResult(context.TODO(), slos)
+	require.NoError(t, err)
+	assert.Equal(t, b.Len(), res.BytesWritten)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoDiff(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		},
+	}
+	expRules := `
+---
+# Code generated by Sloth (dev): https://github.com/slok/sloth.
+# DO NOT EDIT.
+
+groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr
+`
+
+	tests := map[string]struct {
+		existing   *string
+		expChanged bool
+		expDiff    string
+	}{
+		"A missing file should be handled as all the rules being added.": {
+			existing:   nil,
+			expChanged: true,
+			expDiff: `--- /dev/null
++++ {{ .path }}
+@@ -0,0 +1,10 @@
++
++---
++# Code generated by Sloth (dev): https://github.com/slok/sloth.
++# DO NOT EDIT.
++
++groups:
++- name: sloth-slo-sli-recordings-test1
++  rules:
++  - record: test:record
++    expr: test-expr
+`,
+		},
+
+		"A file with the same rules should not have changes.": {
+			existing:   &expRules,
+			expChanged: false,
+		},
+
+		"A file with different rules should return the diff.": {
+			existing: func() *string {
+				s := strings.Replace(expRules, "test-expr", "test-old-expr", 1)
+				return &s
+			}(),
+			expChanged: true,
+			expDiff: `--- {{ .path }}
++++ {{ .path }}
+@@ -7,4 +7,4 @@
+ - name: sloth-slo-sli-recordings-test1
+   rules:
+   - record: test:record
+-    expr: test-old-expr
++    expr: test-expr
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			path := filepath.Join(t.TempDir(), "rules.yaml")
+			if test.existing != nil {
+				err := os.WriteFile(path, []byte(*test.existing), 0644)
+				require.NoError(err)
+			}
+
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer: failWriter{},
+				Logger: log.Noop,
+			})
+			require.NoError(err)
+
+			res, err := repo.DiffSLOs(context.TODO(), path, slos)
+			require.NoError(err)
+
+			assert.Equal(test.expChanged, res.Changed)
+			assert.Equal(strings.ReplaceAll(test.expDiff, "{{ .path }}", path), res.Diff)
+			assert.Equal(expRules, string(res.Rules))
+
+			// The existing file should not be touched.
+			if test.existing != nil {
+				got, err := os.ReadFile(path)
+				require.NoError(err)
+				assert.Equal(*test.existing, string(got))
+			} else {
+				assert.NoFileExists(path)
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreChangedSLOs(t *testing.T) {
+	newSLO := func(id, expr string) prometheus.StorageSLO {
+		return prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: id},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: expr}},
+			},
+		}
+	}
+	hash := func(slo prometheus.StorageSLO) string {
+		h, err := prometheus.HashStorageSLO(slo)
+		require.NoError(t, err)
+		return h
+	}
+
+	tests := map[string]struct {
+		previous     prometheus.SLOHashes
+		slos         []prometheus.StorageSLO
+		expYAML      string
+		expAdded     []string
+		expChanged   []string
+		expUnchanged []string
+		expRemoved   []string
+		expErr       bool
+	}{
+		"Having duplicated SLOs should fail.": {
+			slos:   []prometheus.StorageSLO{newSLO("test1", "test-expr1"), newSLO("test1", "test-expr2")},
+			expErr: true,
+		},
+
+		"Without previous generation all the SLOs should be added.": {
+			slos: []prometheus.StorageSLO{newSLO("test2", "test-expr2"), newSLO("test1", "test-expr1")},
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test1
+  rules:
+  - record: test:record
+    expr: test-expr1
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record
+    expr: test-expr2
+`,
+			expAdded: []string{"test1", "test2"},
+		},
+
+		"Having added, changed, removed and unchanged SLOs should only store the added and changed ones.": {
+			previous: prometheus.SLOHashes{
+				"test1": hash(newSLO("test1", "test-expr1")),
+				"test2": hash(newSLO("test2", "test-old-expr2")),
+				"test3": hash(newSLO("test3", "test-expr3")),
+			},
+			slos: []prometheus.StorageSLO{
+				newSLO("test1", "test-expr1"),
+				newSLO("test2", "test-expr2"),
+				newSLO("test4", "test-expr4"),
+			},
+			expYAML: `groups:
+- name: sloth-slo-sli-recordings-test2
+  rules:
+  - record: test:record
+    expr: test-expr2
+- name: sloth-slo-sli-recordings-test4
+  rules:
+  - record: test:record
+    expr: test-expr4
+`,
+			expAdded:     []string{"test4"},
+			expChanged:   []string{"test2"},
+			expUnchanged: []string{"test1"},
+			expRemoved:   []string{"test3"},
+		},
+
+		"Having only unchanged and removed SLOs should not store anything.": {
+			previous: prometheus.SLOHashes{
+				"test1": hash(newSLO("test1", "test-expr1")),
+				"test2": hash(newSLO("test2", "test-expr2")),
+			},
+			slos:         []prometheus.StorageSLO{newSLO("test1", "test-expr1")},
+			expUnchanged: []string{"test1"},
+			expRemoved:   []string{"test2"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var gotYAML bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:            &gotYAML,
+				Logger:            log.Noop,
+				DisableDisclaimer: true,
+				GroupsOrder:       prometheus.GroupedByServiceGroupsOrder,
+			})
+			require.NoError(err)
+
+			res, err := repo.StoreChangedSLOs(context.TODO(), test.previous, test.slos)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			assert.Equal(test.expYAML, gotYAML.String())
+			assert.Equal(test.expAdded, res.Added)
+			assert.Equal(test.expChanged, res.Changed)
+			assert.Equal(test.expUnchanged, res.Unchanged)
+			assert.Equal(test.expRemoved, res.Removed)
+			assert.Equal(test.expYAML == "", res.StoreResult == nil)
+
+			// The hashes should be usable as the previous ones of the next generation.
+			expHashes := prometheus.SLOHashes{}
+			for _, slo := range test.slos {
+				expHashes[slo.SLO.ID] = hash(slo)
+			}
+			assert.Equal(expHashes, res.Hashes)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreClampedIntervals(t *testing.T) {
+	tests := map[string]struct {
+		interval    time.Duration
+		expInterval string
+	}{
+		"Having an interval below the floor should be clamped to the floor.": {
+			interval:    5 * time.Second,
+			expInterval: "30s",
+		},
+
+		"Having an interval in the range should not be changed.": {
+			interval:    time.Minute,
+			expInterval: "1m",
+		},
+
+		"Having an interval above the ceiling should be clamped to the ceiling.": {
+			interval:    time.Hour,
+			expInterval: "5m",
+		},
+
+		"Not having an interval should not set it.": {
+			expInterval: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				Writer:            &b,
+				Logger:            log.Noop,
+				DisableDisclaimer: true,
+				MinInterval:       30 * time.Second,
+				MaxInterval:       5 * time.Minute,
+			})
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{
+				{
+					SLO:      prometheus.SLO{ID: "test1"},
+					Interval: test.interval,
+					Rules: prometheus.SLORules{
+						SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+						AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+					},
+				},
+			})
+			require.NoError(err)
+
+			groups := struct {
+				Groups []struct {
+					Interval string `yaml:"interval"`
+				} `yaml:"groups"`
+			}{}
+			err = yaml.Unmarshal(b.Bytes(), &groups)
+			require.NoError(err)
+			require.Len(groups.Groups, 2)
+			for _, g := range groups.Groups {
+				assert.Equal(test.expInterval, g.Interval)
+			}
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidIntervalsRange(t *testing.T) {
+	tests := map[string]prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		"Negative min interval should fail.":             {MinInterval: -1 * time.Second},
+		"Negative max interval should fail.":             {MaxInterval: -1 * time.Second},
+		"Min interval greater than the max should fail.": {MinInterval: 2 * time.Minute, MaxInterval: time.Minute},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config.Writer = &bytes.Buffer{}
+			_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidCoralogixConfig(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &bytes.Buffer{},
+		Flavor: prometheus.CoralogixFlavor,
+		Format: prometheus.JSONFormat,
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreCoralogixGroups(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr", For: prommodel.Duration(5 * time.Minute)}},
+			},
+		},
+	}
+
+	type coralogixGroup struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Application string `yaml:"application"`
+			Subsystem   string `yaml:"subsystem"`
+		} `yaml:"metadata"`
+		Spec rulefmt.RuleGroup `yaml:"spec"`
+	}
+
+	tests := map[string]struct {
+		config       prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		slos         []prometheus.StorageSLO
+		expApp       string
+		expSubsystem string
+		expErr       error
+	}{
+		"Having no rules should return the no rules error.": {
+			slos:   []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "test1"}}},
+			expErr: prometheus.ErrNoSLORules,
+		},
+
+		"Using the defaults should use the sloth application and the SLO service as subsystem.": {
+			slos:         slos,
+			expApp:       "sloth",
+			expSubsystem: "svc1",
+		},
+
+		"Using a custom application and subsystem should use them on all the groups.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+				CoralogixApplication: "test-app",
+				CoralogixSubsystem:   "test-subsystem",
+			},
+			slos:         slos,
+			expApp:       "test-app",
+			expSubsystem: "test-subsystem",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			test.config.Writer = &b
+			test.config.Logger = log.Noop
+			test.config.Flavor = prometheus.CoralogixFlavor
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+				return
+			}
+			require.NoError(err)
+
+			// Each group should be a Coralogix rule group document.
+			var groups []coralogixGroup
+			dec := yaml.NewDecoder(&b)
+			for {
+				var g coralogixGroup
+				err := dec.Decode(&g)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(err)
+				groups = append(groups, g)
+			}
+
+			require.Len(groups, 2)
+			for _, g := range groups {
+				assert.Equal("coralogix.com/v1", g.APIVersion)
+				assert.Equal(test.expApp, g.Metadata.Application)
+				assert.Equal(test.expSubsystem, g.Metadata.Subsystem)
+			}
+			assert.Equal("RecordingRuleGroup", groups[0].Kind)
+			assert.Equal("sloth-slo-sli-recordings-test1", groups[0].Spec.Name)
+			assert.Equal("test:record", groups[0].Spec.Rules[0].Record.Value)
+			assert.Equal("AlertRuleGroup", groups[1].Kind)
+			assert.Equal("sloth-slo-alerts-test1", groups[1].Spec.Name)
+			assert.Equal("testAlert", groups[1].Spec.Rules[0].Alert.Value)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreWithChecksum(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	store := func(t *testing.T, config prometheus.IOWriterGroupedRulesYAMLRepoConfig, slos []prometheus.StorageSLO) (string, string) {
+		var b bytes.Buffer
+		config.Writer = &b
+		config.Logger = log.Noop
+		repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(config)
+		require.NoError(t, err)
+		_, sum, err := repo.StoreSLOsWithChecksum(context.TODO(), slos)
+		require.NoError(t, err)
+		return b.String(), sum
+	}
+
+	t.Run("The checksum should be the SHA-256 of the rules without the disclaimer version.", func(t *testing.T) {
+		_, sum := store(t, prometheus.IOWriterGroupedRulesYAMLRepoConfig{DisclaimerVersion: "v1.2.3"}, slos)
+		rules, _ := store(t, prometheus.IOWriterGroupedRulesYAMLRepoConfig{DisableDisclaimerVersion: true}, slos)
+
+		expSum := sha256.Sum256([]byte(rules))
+		assert.Equal(t, hex.EncodeToString(expSum[:]), sum)
+	})
+
+	t.Run("The checksum should be stable across Sloth versions and generation timestamps.", func(t *testing.T) {
+		rules1, sum1 := store(t, prometheus.IOWriterGroupedRulesYAMLRepoConfig{DisclaimerVersion: "v1.2.3"}, slos)
+		rules2, sum2 := store(t, prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+			DisclaimerVersion:   "v1.3.0",
+			DisclaimerTimestamp: true,
+			Now:                 func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+		}, slos)
+
+		assert.NotEqual(t, rules1, rules2)
+		assert.Equal(t, sum1, sum2)
+	})
+
+	t.Run("Different rules should have different checksums.", func(t *testing.T) {
+		_, sum1 := store(t, prometheus.IOWriterGroupedRulesYAMLRepoConfig{}, slos)
+		_, sum2 := store(t, prometheus.IOWriterGroupedRulesYAMLRepoConfig{}, []prometheus.StorageSLO{
+			{
+				SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1"},
+				Rules: prometheus.SLORules{
+					SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr2"}},
+				},
+			},
+		})
+
+		assert.NotEqual(t, sum1, sum2)
+	})
+
+	t.Run("The checksum of the rule serializers should be the SHA-256 of the serialized rules.", func(t *testing.T) {
+		rules, sum := store(t, prometheus.IOWriterGroupedRulesYAMLRepoConfig{Serializer: testSerializer}, slos)
+
+		expSum := sha256.Sum256([]byte(rules))
+		assert.Equal(t, "svc1-slo1 test:record test-expr\n", rules)
+		assert.Equal(t, hex.EncodeToString(expSum[:]), sum)
+	})
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreWithManifest(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var rules, manifest bytes.Buffer
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      &rules,
+		Logger:      log.Noop,
+		GroupsOrder: prometheus.GroupedByServiceGroupsOrder,
+	})
+	require.NoError(err)
+	res, err := repo.StoreSLOsWithManifest(context.TODO(), []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "svc2-slo1", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "svc1-slo1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr"}, {Record: "test:record2", Expr: "test-expr"}},
+				MetadataRecRules: []rulefmt.Rule{{Record: "test:record3", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+	}, &manifest)
+	require.NoError(err)
+
+	// The rules should be stored as usual.
+	assert.Equal(rules.Len(), res.BytesWritten)
+	assert.Contains(rules.String(), "- name: sloth-slo-sli-recordings-svc1-slo1\n")
+
+	expManifest := `{
+  "version": "dev",
+  "slos": [
+    {
+      "id": "svc1-slo1",
+      "service": "svc1",
+      "groups": [
+        {
+          "name": "sloth-slo-sli-recordings-svc1-slo1",
+          "rules": 2
+        },
+        {
+          "name": "sloth-slo-meta-recordings-svc1-slo1",
+          "rules": 1
+        },
+        {
+          "name": "sloth-slo-alerts-svc1-slo1",
+          "rules": 1
+        }
+      ]
+    },
+    {
+      "id": "svc2-slo1",
+      "service": "svc2",
+      "groups": [
+        {
+          "name": "sloth-slo-alerts-svc2-slo1",
+          "rules": 1
+        }
+      ]
+    }
+  ]
+}
+`
+	assert.Equal(expManifest, manifest.String())
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidGroupLimits(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      &bytes.Buffer{},
+		GroupLimits: prometheus.GroupLimits{SLIRecordings: -1},
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidHeaderTemplate(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:         &bytes.Buffer{},
+		HeaderTemplate: "# {{ .Version ",
+	})
+	assert.Error(t, err)
+}
+
+type fakeStoreObserver struct {
+	calls []string
+}
+
+func (f *fakeStoreObserver) OnStored(_ context.Context, flavor prometheus.OutputFlavor, res prometheus.StoreResult) {
+	f.calls = append(f.calls, fmt.Sprintf("stored %s: %d groups, %d recordings, %d alerts", flavor, res.Groups, res.RecordingRules, res.AlertRules))
+}
+
+func (f *fakeStoreObserver) OnEmpty(_ context.Context, flavor prometheus.OutputFlavor, slos int) {
+	f.calls = append(f.calls, fmt.Sprintf("empty %s: %d SLOs", flavor, slos))
+}
+
+func (f *fakeStoreObserver) OnError(_ context.Context, flavor prometheus.OutputFlavor, slos int, err error) {
+	f.calls = append(f.calls, fmt.Sprintf("error %s: %d SLOs", flavor, slos))
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreObserver(t *testing.T) {
+	slo := prometheus.StorageSLO{
+		SLO: prometheus.SLO{ID: "test1"},
+		Rules: prometheus.SLORules{
+			SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr1"}},
+			AlertRules:       []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr2"}},
+		},
+	}
+
+	tests := map[string]struct {
+		config   prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		slos     []prometheus.StorageSLO
+		expCalls []string
+		expErr   bool
+	}{
+		"Storing the SLO rules should notify the stored result.": {
+			config:   prometheus.IOWriterGroupedRulesYAMLRepoConfig{Writer: &bytes.Buffer{}, Flavor: prometheus.ThanosFlavor},
+			slos:     []prometheus.StorageSLO{slo},
+			expCalls: []string{"stored thanos: 2 groups, 1 recordings, 1 alerts"},
+		},
+
+		"Storing 0 SLO rules should notify the empty result.": {
+			config:   prometheus.IOWriterGroupedRulesYAMLRepoConfig{Writer: &bytes.Buffer{}},
+			slos:     []prometheus.StorageSLO{{}, {}},
+			expCalls: []string{"empty prometheus: 2 SLOs"},
+			expErr:   true,
+		},
+
+		"Failing storing the SLO rules should notify the error.": {
+			config:   prometheus.IOWriterGroupedRulesYAMLRepoConfig{Writer: failWriter{}},
+			slos:     []prometheus.StorageSLO{slo},
+			expCalls: []string{"error prometheus: 1 SLOs"},
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			observer := &fakeStoreObserver{}
+			test.config.Observer = observer
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(test.expCalls, observer.calls)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoRecordingsAndAlertsOnly(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:         &bytes.Buffer{},
+		RecordingsOnly: true,
+		AlertsOnly:     true,
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidQueryOffset(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:      &bytes.Buffer{},
+		QueryOffset: -time.Minute,
+	})
+	assert.Error(t, err)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreDuplicatedGroups(t *testing.T) {
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: failWriter{},
+	})
+	require.NoError(t, err)
+
+	err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				AlertRules: []rulefmt.Rule{{Alert: "testAlert1", Expr: "test-expr1"}},
+			},
+		},
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc2"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record1", Expr: "test-expr2"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert2", Expr: "test-expr3"}},
+			},
+		},
+	})
+	assert.EqualError(t, err, `duplicated "sloth-slo-alerts-test1" rule group, generated by "test1" SLO of "svc1" service and "test1" SLO of "svc2" service`)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoInvalidSingleGroupName(t *testing.T) {
+	_, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer:          &bytes.Buffer{},
+		SingleGroup:     true,
+		SingleGroupName: "my slos",
+	})
+	assert.Error(t, err)
+}
+
+// cancelOnCheckCtx is a context that is cancelled after the first time its error is checked.
+type cancelOnCheckCtx struct {
+	context.Context
+	cancel func()
+}
+
+func (c cancelOnCheckCtx) Err() error {
+	err := c.Context.Err()
+	c.cancel()
+	return err
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreCancelled(t *testing.T) {
+	slos := []prometheus.StorageSLO{}
+	for i := 0; i < 250; i++ {
+		slos = append(slos, prometheus.StorageSLO{
+			SLO: prometheus.SLO{ID: fmt.Sprintf("slo-%d", i), Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+			},
+		})
+	}
+
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: failWriter{},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = repo.StoreSLOs(cancelOnCheckCtx{Context: ctx, cancel: cancel}, slos)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreYAMLDocuments(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules:       []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		config  prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		expDocs int
+	}{
+		"Having the disclaimer should generate a single document.": {
+			expDocs: 1,
+		},
+
+		"Having the disclaimer disabled should generate a single document.": {
+			config:  prometheus.IOWriterGroupedRulesYAMLRepoConfig{DisableDisclaimer: true},
+			expDocs: 1,
+		},
+
+		"Having a custom header should generate a single document.": {
+			config:  prometheus.IOWriterGroupedRulesYAMLRepoConfig{HeaderTemplate: "# Generated with {{ .SLOs }} SLOs."},
+			expDocs: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var out bytes.Buffer
+			test.config.Writer = &out
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(err)
+			err = repo.StoreSLOs(context.TODO(), slos)
+			require.NoError(err)
+
+			// Decode all the documents, empty documents would be decoded as nulls.
+			gotDocs := 0
+			dec := yaml.NewDecoder(&out)
+			for {
+				var doc map[string]interface{}
+				err := dec.Decode(&doc)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(err)
+				assert.NotEmpty(doc, "empty YAML document")
+				gotDocs++
+			}
+			assert.Equal(test.expDocs, gotDocs)
+		})
+	}
+}
+
+func TestIOWriterGroupedRulesYAMLRepoStoreSLIReferences(t *testing.T) {
+	rules := prometheus.SLORules{
+		SLIErrorRecRules: []rulefmt.Rule{
+			{Record: "slo:sli_error:ratio_rate5m", Expr: "test-expr1"},
+			{Record: "slo:sli_error:ratio_rate30d", Expr: "test-expr2"},
+		},
+		MetadataRecRules: []rulefmt.Rule{
+			{Record: "slo:objective:ratio", Expr: "vector(0.99)"},
+			{Record: "slo:current_burn_rate:ratio", Expr: `slo:sli_error:ratio_rate5m{sloth_id="test1"} / on(sloth_id) group_left slo:error_budget:ratio{sloth_id="test1"}`},
+			{Record: "slo:period_burn_rate:ratio", Expr: `slo:sli_error:ratio_rate30d{sloth_id="test1"} / on(sloth_id) group_left slo:error_budget:ratio{sloth_id="test1"}`},
+		},
+	}
+	danglingRules := prometheus.SLORules{
+		SLIErrorRecRules: rules.SLIErrorRecRules[:1],
+		MetadataRecRules: append(rules.MetadataRecRules, rulefmt.Rule{
+			Record: "slo:period_error_budget_remaining:ratio",
+			Expr:   `1 - (slo:sli_error:ratio_rate30d{sloth_id="test1"} + slo:sli_error:ratio_rate1h{sloth_id="test1"})`,
+		}),
+	}
+
+	tests := map[string]struct {
+		config prometheus.IOWriterGroupedRulesYAMLRepoConfig
+		rules  prometheus.SLORules
+		expErr string
+	}{
+		"Having metadata recording rules referencing generated SLI rules should not fail.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{CheckSLIReferences: true},
+			rules:  rules,
+		},
+
+		"Having metadata recording rules with dangling SLI references should fail listing them.": {
+			config: prometheus.IOWriterGroupedRulesYAMLRepoConfig{CheckSLIReferences: true},
+			rules:  danglingRules,
+			expErr: `invalid "test1" SLO metadata recording rules: dangling SLI error metric references, not generated by the SLI recording rules: slo:sli_error:ratio_rate1h, slo:sli_error:ratio_rate30d`,
+		},
+
+		"Having metadata recording rules with dangling SLI references without the check should not fail.": {
+			rules: danglingRules,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.config.Writer = io.Discard
+			repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(test.config)
+			require.NoError(t, err)
+
+			err = repo.StoreSLOs(context.TODO(), []prometheus.StorageSLO{{SLO: prometheus.SLO{ID: "test1"}, Rules: test.rules}})
+			if test.expErr != "" {
+				assert.EqualError(t, err, test.expErr)
+			} else {
+				assert.NoError(t, err)
 			}
 		})
 	}
@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// SummaryOptions are the options of the SLOs summary.
+type SummaryOptions struct {
+	// Labels are the SLO labels that will be added as columns of the summary, the SLOs
+	// without the label will have an empty value.
+	Labels []string
+	// TSV will use tabs as the field separator instead of commas.
+	TSV bool
+}
+
+// WriteSLOsSummary writes a CSV (or TSV) summary of the SLOs objectives in the writer, with a header
+// and one row per SLO (sorted by service and ID). The rules of the SLOs are not used, without SLOs
+// only the header will be written.
+func WriteSLOsSummary(w io.Writer, slos []StorageSLO, opts SummaryOptions) error {
+	cw := csv.NewWriter(w)
+	if opts.TSV {
+		cw.Comma = '\t'
+	}
+
+	header := []string{"service", "slo_id", "slo_name", "objective", "error_budget"}
+	header = append(header, opts.Labels...)
+	err := cw.Write(header)
+	if err != nil {
+		return fmt.Errorf("could not write summary header: %w", err)
+	}
+
+	for _, slo := range sortSLOs(slos) {
+		// Round to remove float subtraction artifacts (e.g: 100 - 99.9).
+		errorBudget := math.Round((100-slo.SLO.Objective)*1e10) / 1e10
+		row := []string{
+			slo.SLO.Service,
+			slo.SLO.ID,
+			slo.SLO.Name,
+			strconv.FormatFloat(slo.SLO.Objective, 'f', -1, 64),
+			strconv.FormatFloat(errorBudget, 'f', -1, 64),
+		}
+		for _, l := range opts.Labels {
+			row = append(row, slo.SLO.Labels[l])
+		}
+
+		err := cw.Write(row)
+		if err != nil {
+			return fmt.Errorf("could not write %q SLO summary: %w", slo.SLO.ID, err)
+		}
+	}
+
+	cw.Flush()
+	err = cw.Error()
+	if err != nil {
+		return fmt.Errorf("could not write summary: %w", err)
+	}
+
+	return nil
+}
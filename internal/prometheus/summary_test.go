@@ -0,0 +1,63 @@
+package prometheus_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+func TestWriteSLOsSummary(t *testing.T) {
+	slos := []prometheus.StorageSLO{
+		{SLO: prometheus.SLO{ID: "svc2-slo1", Name: "slo1", Service: "svc2", Objective: 99, Labels: map[string]string{"owner": "team-b"}}},
+		{SLO: prometheus.SLO{ID: "svc1-slo1", Name: "slo1", Service: "svc1", Objective: 99.9, Labels: map[string]string{"owner": "team-a, team-c", "tier": "1"}}},
+	}
+
+	tests := map[string]struct {
+		slos   []prometheus.StorageSLO
+		opts   prometheus.SummaryOptions
+		expOut string
+	}{
+		"Having no SLOs should only write the header.": {
+			slos:   []prometheus.StorageSLO{},
+			expOut: "service,slo_id,slo_name,objective,error_budget\n",
+		},
+
+		"Having SLOs should write a row for each SLO sorted.": {
+			slos: slos,
+			expOut: `service,slo_id,slo_name,objective,error_budget
+svc1,svc1-slo1,slo1,99.9,0.1
+svc2,svc2-slo1,slo1,99,1
+`,
+		},
+
+		"Having SLOs with labels should add the labels columns quoting the values when required.": {
+			slos: slos,
+			opts: prometheus.SummaryOptions{Labels: []string{"owner", "tier"}},
+			expOut: `service,slo_id,slo_name,objective,error_budget,owner,tier
+svc1,svc1-slo1,slo1,99.9,0.1,"team-a, team-c",1
+svc2,svc2-slo1,slo1,99,1,team-b,
+`,
+		},
+
+		"Having SLOs with TSV should use tabs as separator.": {
+			slos:   slos,
+			opts:   prometheus.SummaryOptions{Labels: []string{"owner"}, TSV: true},
+			expOut: "service\tslo_id\tslo_name\tobjective\terror_budget\towner\nsvc1\tsvc1-slo1\tslo1\t99.9\t0.1\tteam-a, team-c\nsvc2\tsvc2-slo1\tslo1\t99\t1\tteam-b\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var out bytes.Buffer
+			err := prometheus.WriteSLOsSummary(&out, test.slos, test.opts)
+			if assert.NoError(err) {
+				assert.Equal(test.expOut, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/slok/sloth/internal/log"
+)
+
+// promVersion is a Prometheus `<major>.<minor>` version, the patch versions don't add rule features.
+type promVersion struct {
+	major int
+	minor int
+}
+
+var promVersionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.\d+)?$`)
+
+// parsePromVersion parses Prometheus versions like `2.41`, `2.41.0` or `v3.0.1`.
+func parsePromVersion(v string) (promVersion, error) {
+	match := promVersionRegexp.FindStringSubmatch(v)
+	if match == nil {
+		return promVersion{}, fmt.Errorf("invalid %q Prometheus version, must be in '<major>.<minor>[.<patch>]' format", v)
+	}
+
+	// The regexp only matches digits.
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+
+	return promVersion{major: major, minor: minor}, nil
+}
+
+func (v promVersion) less(o promVersion) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	return v.minor < o.minor
+}
+
+func (v promVersion) String() string { return fmt.Sprintf("%d.%d", v.major, v.minor) }
+
+// The first Prometheus versions that support the optional rules fields.
+var (
+	groupLimitPromVersion    = promVersion{major: 2, minor: 31}
+	keepFiringForPromVersion = promVersion{major: 2, minor: 42}
+	queryOffsetPromVersion   = promVersion{major: 2, minor: 53}
+	groupLabelsPromVersion   = promVersion{major: 3, minor: 0}
+)
+
+// gateRuleGroupsFeatures checks the optional fields of the rule groups are supported by the target
+// Prometheus version, the unsupported ones will fail or be removed if omit is used.
+func gateRuleGroupsFeatures(ruleGroups ruleGroupsYAMLv2, target promVersion, omit bool, logger log.Logger) error {
+	unsupported := func(field string, required promVersion, group string) error {
+		if omit {
+			logger.Debugf("%q rule group %s is not supported by the %s target Prometheus version, omitting", group, field, target)
+			return nil
+		}
+		return fmt.Errorf("%q rule group %s requires Prometheus %s or newer, target version is %s", group, field, required, target)
+	}
+
+	for idx := range ruleGroups.Groups {
+		g := &ruleGroups.Groups[idx]
+
+		if g.Limit != 0 && target.less(groupLimitPromVersion) {
+			err := unsupported("limit", groupLimitPromVersion, g.Name)
+			if err != nil {
+				return err
+			}
+			g.Limit = 0
+		}
+
+		if g.QueryOffset != 0 && target.less(queryOffsetPromVersion) {
+			err := unsupported("query_offset", queryOffsetPromVersion, g.Name)
+			if err != nil {
+				return err
+			}
+			g.QueryOffset = 0
+		}
+
+		if len(g.Labels) > 0 && target.less(groupLabelsPromVersion) {
+			err := unsupported("labels", groupLabelsPromVersion, g.Name)
+			if err != nil {
+				return err
+			}
+			g.Labels = nil
+		}
+
+		if !target.less(keepFiringForPromVersion) {
+			continue
+		}
+		for ridx := range g.Rules {
+			r := &g.Rules[ridx]
+			if r.KeepFiringFor == 0 {
+				continue
+			}
+			err := unsupported(fmt.Sprintf("%q rule keep_firing_for", r.Alert), keepFiringForPromVersion, g.Name)
+			if err != nil {
+				return err
+			}
+			r.KeepFiringFor = 0
+		}
+	}
+
+	return nil
+}
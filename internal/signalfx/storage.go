@@ -0,0 +1,262 @@
+package signalfx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+
+	"github.com/slok/sloth/internal/alert"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLOs will be used when there are no SLOs to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLOs = fmt.Errorf("0 SignalFx detectors generated")
+)
+
+const (
+	defaultErrorStreamLabel = "signalfx_error_stream"
+	defaultTotalStreamLabel = "signalfx_total_stream"
+
+	severityPage   = "Critical"
+	severityTicket = "Warning"
+
+	defaultRuleText = "%s SLO error budget is burning too fast."
+)
+
+type IOWriterJSONRepoConfig struct {
+	Writer io.Writer
+	Logger log.Logger
+	// ErrorStreamLabel is the SLO label that has the SignalFlow stream of the error events
+	// (e.g: `data('http_requests', filter=filter('code', '5*')).sum()`), by default `signalfx_error_stream`.
+	ErrorStreamLabel string
+	// TotalStreamLabel is the SLO label that has the SignalFlow stream of all the events
+	// (e.g: `data('http_requests').sum()`), by default `signalfx_total_stream`.
+	TotalStreamLabel string
+}
+
+func (c *IOWriterJSONRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.ErrorStreamLabel == "" {
+		c.ErrorStreamLabel = defaultErrorStreamLabel
+	}
+
+	if c.TotalStreamLabel == "" {
+		c.TotalStreamLabel = defaultTotalStreamLabel
+	}
+
+	if c.ErrorStreamLabel == c.TotalStreamLabel {
+		return fmt.Errorf("error and total stream labels can't be the same")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "json", "flavor": "signalfx"})
+
+	return nil
+}
+
+// IOWriterJSONRepo knows to store the SLOs in an IOWriter as a JSON document with the Splunk
+// Observability (SignalFx) detector definitions. SignalFx doesn't use PromQL, so the SignalFlow
+// streams are taken from the SLO labels instead of the SLI and the generated Prometheus rules.
+type IOWriterJSONRepo struct {
+	writer         io.Writer
+	errorStreamLbl string
+	totalStreamLbl string
+	logger         log.Logger
+}
+
+func NewIOWriterJSONRepo(config IOWriterJSONRepoConfig) (*IOWriterJSONRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &IOWriterJSONRepo{
+		writer:         config.Writer,
+		errorStreamLbl: config.ErrorStreamLabel,
+		totalStreamLbl: config.TotalStreamLabel,
+		logger:         config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO prometheus.SLO
+	// Alerts are the SLO alerts that will be used to create the SignalFx detector rules.
+	Alerts alert.MWMBAlertGroup
+}
+
+// StoreSLOs will store the SLOs as SignalFx detectors definitions, one for each SLO with
+// a rule for each of the multiwindow multi-burn rate alerts. The SLOs with all the alerts
+// disabled don't have detector.
+func (i IOWriterJSONRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	logger := i.logger.WithCtxValues(ctx)
+
+	doc := signalFxJSON{Detectors: []detectorJSON{}}
+	rules := 0
+	for _, slo := range slos {
+		d, err := i.mapModelToDetector(slo)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to SignalFx: %w", slo.SLO.ID, err)
+		}
+		if len(d.Rules) == 0 {
+			logger.Warningf("%q SLO has all the alerts disabled, skipping detector", slo.SLO.ID)
+			continue
+		}
+		doc.Detectors = append(doc.Detectors, *d)
+		rules += len(d.Rules)
+	}
+
+	if len(doc.Detectors) == 0 {
+		return ErrNoSLOs
+	}
+
+	// Don't escape the HTML characters, the programs could have comparison operators.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("could not format SLOs: %w", err)
+	}
+
+	_, err = i.writer.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write SLOs: %w", err)
+	}
+
+	logger.WithValues(log.Kv{"detectors": len(doc.Detectors), "rules": rules}).Infof("SignalFx detectors written")
+
+	return nil
+}
+
+// detectorAlert is an SLO alert that will be detected with a detector rule.
+type detectorAlert struct {
+	label    string
+	meta     prometheus.AlertMeta
+	alert    alert.MWMBAlert
+	severity string
+}
+
+// mapModelToDetector maps the SLO to a SignalFx detector, the SignalFlow program computes the
+// error ratio of the alert windows and detects when both windows of an alert burn the error
+// budget faster than the alert burn rate factor.
+func (i IOWriterJSONRepo) mapModelToDetector(slo StorageSLO) (*detectorJSON, error) {
+	errorStream := strings.TrimSpace(slo.SLO.Labels[i.errorStreamLbl])
+	if errorStream == "" {
+		return nil, fmt.Errorf("missing SignalFlow error stream, %q label is required", i.errorStreamLbl)
+	}
+
+	totalStream := strings.TrimSpace(slo.SLO.Labels[i.totalStreamLbl])
+	if totalStream == "" {
+		return nil, fmt.Errorf("missing SignalFlow total stream, %q label is required", i.totalStreamLbl)
+	}
+
+	alerts := []detectorAlert{}
+	if !slo.SLO.PageAlertMeta.Disable {
+		alerts = append(alerts,
+			detectorAlert{label: "page_quick", meta: slo.SLO.PageAlertMeta, alert: slo.Alerts.PageQuick, severity: severityPage},
+			detectorAlert{label: "page_slow", meta: slo.SLO.PageAlertMeta, alert: slo.Alerts.PageSlow, severity: severityPage},
+		)
+	}
+	if !slo.SLO.TicketAlertMeta.Disable {
+		alerts = append(alerts,
+			detectorAlert{label: "ticket_quick", meta: slo.SLO.TicketAlertMeta, alert: slo.Alerts.TicketQuick, severity: severityTicket},
+			detectorAlert{label: "ticket_slow", meta: slo.SLO.TicketAlertMeta, alert: slo.Alerts.TicketSlow, severity: severityTicket},
+		)
+	}
+
+	detector := &detectorJSON{
+		Name:        slo.SLO.Name,
+		Description: slo.SLO.Description,
+		Rules:       []detectorRuleJSON{},
+		Tags:        []string{"sloth", "sloth_service:" + slo.SLO.Service, "sloth_id:" + slo.SLO.ID},
+	}
+	if len(alerts) == 0 {
+		return detector, nil
+	}
+
+	// The error ratio streams of all the windows used by the alerts, only once each.
+	windowSet := map[time.Duration]bool{}
+	for _, a := range alerts {
+		windowSet[a.alert.ShortWindow] = true
+		windowSet[a.alert.LongWindow] = true
+	}
+	windows := make([]time.Duration, 0, len(windowSet))
+	for w := range windowSet {
+		windows = append(windows, w)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+
+	var program strings.Builder
+	fmt.Fprintf(&program, "errors = %s\n", errorStream)
+	fmt.Fprintf(&program, "total = %s\n", totalStream)
+	for _, w := range windows {
+		d := prommodel.Duration(w).String()
+		fmt.Fprintf(&program, "%s = (errors.sum(over='%s') / total.sum(over='%s'))\n", ratioVar(w), d, d)
+	}
+
+	for _, a := range alerts {
+		// The error budget is a percentage, round to remove float multiplication artifacts.
+		threshold := strconv.FormatFloat(math.Round(a.alert.BurnRateFactor*a.alert.ErrorBudget/100*1e10)/1e10, 'f', -1, 64)
+		fmt.Fprintf(&program, "detect(when(%s > %s) and when(%s > %s)).publish('%s')\n",
+			ratioVar(a.alert.LongWindow), threshold, ratioVar(a.alert.ShortWindow), threshold, a.label)
+
+		description := a.meta.Annotations["summary"]
+		if description == "" {
+			description = fmt.Sprintf(defaultRuleText, slo.SLO.Name)
+		}
+		detector.Rules = append(detector.Rules, detectorRuleJSON{
+			Name:        a.meta.Name,
+			DetectLabel: a.label,
+			Severity:    a.severity,
+			Description: description,
+		})
+	}
+	detector.ProgramText = program.String()
+
+	return detector, nil
+}
+
+// ratioVar returns the SignalFlow variable name of the window error ratio stream (e.g: `ratio_5m`).
+func ratioVar(window time.Duration) string {
+	return "ratio_" + prommodel.Duration(window).String()
+}
+
+type signalFxJSON struct {
+	Detectors []detectorJSON `json:"detectors"`
+}
+
+// detectorJSON is the SignalFx detector API definition.
+type detectorJSON struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	ProgramText string             `json:"programText"`
+	Rules       []detectorRuleJSON `json:"rules"`
+	Tags        []string           `json:"tags"`
+}
+
+// detectorRuleJSON is the SignalFx detector rule, it's triggered by the program `detect`
+// with the same `publish` label.
+type detectorRuleJSON struct {
+	Name        string `json:"name,omitempty"`
+	DetectLabel string `json:"detectLabel"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,305 @@
+package signalfx_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sloth/internal/alert"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+	"github.com/slok/sloth/internal/signalfx"
+)
+
+func getAlertGroup() alert.MWMBAlertGroup {
+	return alert.MWMBAlertGroup{
+		PageQuick: alert.MWMBAlert{
+			ShortWindow:    5 * time.Minute,
+			LongWindow:     time.Hour,
+			BurnRateFactor: 14.4,
+			ErrorBudget:    0.1,
+			Severity:       alert.PageAlertSeverity,
+		},
+		PageSlow: alert.MWMBAlert{
+			ShortWindow:    30 * time.Minute,
+			LongWindow:     6 * time.Hour,
+			BurnRateFactor: 6,
+			ErrorBudget:    0.1,
+			Severity:       alert.PageAlertSeverity,
+		},
+		TicketQuick: alert.MWMBAlert{
+			ShortWindow:    2 * time.Hour,
+			LongWindow:     24 * time.Hour,
+			BurnRateFactor: 3,
+			ErrorBudget:    0.1,
+			Severity:       alert.TicketAlertSeverity,
+		},
+		TicketSlow: alert.MWMBAlert{
+			ShortWindow:    6 * time.Hour,
+			LongWindow:     3 * 24 * time.Hour,
+			BurnRateFactor: 1,
+			ErrorBudget:    0.1,
+			Severity:       alert.TicketAlertSeverity,
+		},
+	}
+}
+
+func TestIOWriterJSONRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config  signalfx.IOWriterJSONRepoConfig
+		slos    []signalfx.StorageSLO
+		expJSON string
+		expErr  bool
+	}{
+		"Having 0 SLOs should fail.": {
+			slos:   []signalfx.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having an SLO without the SignalFlow error stream should fail.": {
+			slos: []signalfx.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:     "svc1-slo1",
+						Labels: map[string]string{"signalfx_total_stream": "data('http_requests').sum()"},
+					},
+					Alerts: getAlertGroup(),
+				},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO without the SignalFlow total stream should fail.": {
+			slos: []signalfx.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:     "svc1-slo1",
+						Labels: map[string]string{"signalfx_error_stream": "data('http_requests', filter=filter('code', '5*')).sum()"},
+					},
+					Alerts: getAlertGroup(),
+				},
+			},
+			expErr: true,
+		},
+
+		"Having only SLOs with all the alerts disabled should fail.": {
+			slos: []signalfx.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID: "svc1-slo1",
+						Labels: map[string]string{
+							"signalfx_error_stream": "data('http_requests', filter=filter('code', '5*')).sum()",
+							"signalfx_total_stream": "data('http_requests').sum()",
+						},
+						PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+						TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+					},
+					Alerts: getAlertGroup(),
+				},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs should render the SignalFx detectors with the burn rate thresholds.": {
+			slos: []signalfx.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:          "svc1-slo1",
+						Name:        "slo1",
+						Description: "Test SLO 1.",
+						Service:     "svc1",
+						Labels: map[string]string{
+							"signalfx_error_stream": "data('http_requests', filter=filter('code', '5*')).sum()",
+							"signalfx_total_stream": "data('http_requests').sum()",
+						},
+						PageAlertMeta: prometheus.AlertMeta{
+							Name:        "Slo1Page",
+							Annotations: map[string]string{"summary": "SLO 1 page."},
+						},
+						TicketAlertMeta: prometheus.AlertMeta{Name: "Slo1Ticket"},
+					},
+					Alerts: getAlertGroup(),
+				},
+				{
+					SLO: prometheus.SLO{
+						ID:      "svc1-slo2",
+						Name:    "slo2",
+						Service: "svc1",
+						Labels: map[string]string{
+							"signalfx_error_stream": "data('latency_bad').sum()",
+							"signalfx_total_stream": "data('latency_total').sum()",
+						},
+						PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+						TicketAlertMeta: prometheus.AlertMeta{Name: "Slo2Ticket"},
+					},
+					Alerts: getAlertGroup(),
+				},
+				{
+					SLO: prometheus.SLO{
+						ID:      "svc1-slo3",
+						Name:    "slo3",
+						Service: "svc1",
+						Labels: map[string]string{
+							"signalfx_error_stream": "data('errors').sum()",
+							"signalfx_total_stream": "data('total').sum()",
+						},
+						PageAlertMeta:   prometheus.AlertMeta{Disable: true},
+						TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+					},
+					Alerts: getAlertGroup(),
+				},
+			},
+			expJSON: `{
+  "detectors": [
+    {
+      "name": "slo1",
+      "description": "Test SLO 1.",
+      "programText": "errors = data('http_requests', filter=filter('code', '5*')).sum()\ntotal = data('http_requests').sum()\nratio_5m = (errors.sum(over='5m') / total.sum(over='5m'))\nratio_30m = (errors.sum(over='30m') / total.sum(over='30m'))\nratio_1h = (errors.sum(over='1h') / total.sum(over='1h'))\nratio_2h = (errors.sum(over='2h') / total.sum(over='2h'))\nratio_6h = (errors.sum(over='6h') / total.sum(over='6h'))\nratio_1d = (errors.sum(over='1d') / total.sum(over='1d'))\nratio_3d = (errors.sum(over='3d') / total.sum(over='3d'))\ndetect(when(ratio_1h > 0.0144) and when(ratio_5m > 0.0144)).publish('page_quick')\ndetect(when(ratio_6h > 0.006) and when(ratio_30m > 0.006)).publish('page_slow')\ndetect(when(ratio_1d > 0.003) and when(ratio_2h > 0.003)).publish('ticket_quick')\ndetect(when(ratio_3d > 0.001) and when(ratio_6h > 0.001)).publish('ticket_slow')\n",
+      "rules": [
+        {
+          "name": "Slo1Page",
+          "detectLabel": "page_quick",
+          "severity": "Critical",
+          "description": "SLO 1 page."
+        },
+        {
+          "name": "Slo1Page",
+          "detectLabel": "page_slow",
+          "severity": "Critical",
+          "description": "SLO 1 page."
+        },
+        {
+          "name": "Slo1Ticket",
+          "detectLabel": "ticket_quick",
+          "severity": "Warning",
+          "description": "slo1 SLO error budget is burning too fast."
+        },
+        {
+          "name": "Slo1Ticket",
+          "detectLabel": "ticket_slow",
+          "severity": "Warning",
+          "description": "slo1 SLO error budget is burning too fast."
+        }
+      ],
+      "tags": [
+        "sloth",
+        "sloth_service:svc1",
+        "sloth_id:svc1-slo1"
+      ]
+    },
+    {
+      "name": "slo2",
+      "programText": "errors = data('latency_bad').sum()\ntotal = data('latency_total').sum()\nratio_2h = (errors.sum(over='2h') / total.sum(over='2h'))\nratio_6h = (errors.sum(over='6h') / total.sum(over='6h'))\nratio_1d = (errors.sum(over='1d') / total.sum(over='1d'))\nratio_3d = (errors.sum(over='3d') / total.sum(over='3d'))\ndetect(when(ratio_1d > 0.003) and when(ratio_2h > 0.003)).publish('ticket_quick')\ndetect(when(ratio_3d > 0.001) and when(ratio_6h > 0.001)).publish('ticket_slow')\n",
+      "rules": [
+        {
+          "name": "Slo2Ticket",
+          "detectLabel": "ticket_quick",
+          "severity": "Warning",
+          "description": "slo2 SLO error budget is burning too fast."
+        },
+        {
+          "name": "Slo2Ticket",
+          "detectLabel": "ticket_slow",
+          "severity": "Warning",
+          "description": "slo2 SLO error budget is burning too fast."
+        }
+      ],
+      "tags": [
+        "sloth",
+        "sloth_service:svc1",
+        "sloth_id:svc1-slo2"
+      ]
+    }
+  ]
+}
+`,
+		},
+
+		"Having custom labels should use them to get the SignalFlow streams.": {
+			config: signalfx.IOWriterJSONRepoConfig{
+				ErrorStreamLabel: "sfx_errors",
+				TotalStreamLabel: "sfx_total",
+			},
+			slos: []signalfx.StorageSLO{
+				{
+					SLO: prometheus.SLO{
+						ID:      "svc1-slo1",
+						Name:    "slo1",
+						Service: "svc1",
+						Labels: map[string]string{
+							"sfx_errors": "data('errors').sum()",
+							"sfx_total":  "data('total').sum()",
+						},
+						PageAlertMeta:   prometheus.AlertMeta{Name: "Slo1Page"},
+						TicketAlertMeta: prometheus.AlertMeta{Disable: true},
+					},
+					Alerts: alert.MWMBAlertGroup{
+						PageQuick: alert.MWMBAlert{ShortWindow: 5 * time.Minute, LongWindow: time.Hour, BurnRateFactor: 14.4, ErrorBudget: 1},
+						PageSlow:  alert.MWMBAlert{ShortWindow: 30 * time.Minute, LongWindow: 6 * time.Hour, BurnRateFactor: 6, ErrorBudget: 1},
+					},
+				},
+			},
+			expJSON: `{
+  "detectors": [
+    {
+      "name": "slo1",
+      "programText": "errors = data('errors').sum()\ntotal = data('total').sum()\nratio_5m = (errors.sum(over='5m') / total.sum(over='5m'))\nratio_30m = (errors.sum(over='30m') / total.sum(over='30m'))\nratio_1h = (errors.sum(over='1h') / total.sum(over='1h'))\nratio_6h = (errors.sum(over='6h') / total.sum(over='6h'))\ndetect(when(ratio_1h > 0.144) and when(ratio_5m > 0.144)).publish('page_quick')\ndetect(when(ratio_6h > 0.06) and when(ratio_30m > 0.06)).publish('page_slow')\n",
+      "rules": [
+        {
+          "name": "Slo1Page",
+          "detectLabel": "page_quick",
+          "severity": "Critical",
+          "description": "slo1 SLO error budget is burning too fast."
+        },
+        {
+          "name": "Slo1Page",
+          "detectLabel": "page_slow",
+          "severity": "Critical",
+          "description": "slo1 SLO error budget is burning too fast."
+        }
+      ],
+      "tags": [
+        "sloth",
+        "sloth_service:svc1",
+        "sloth_id:svc1-slo1"
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotJSON bytes.Buffer
+			test.config.Writer = &gotJSON
+			test.config.Logger = log.Noop
+			repo, err := signalfx.NewIOWriterJSONRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expJSON, gotJSON.String())
+			}
+		})
+	}
+}
+
+func TestIOWriterJSONRepoInvalidConfig(t *testing.T) {
+	_, err := signalfx.NewIOWriterJSONRepo(signalfx.IOWriterJSONRepoConfig{
+		Writer:           &bytes.Buffer{},
+		ErrorStreamLabel: "sfx",
+		TotalStreamLabel: "sfx",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/slok/sloth/internal/chronosphere"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLORules will be used when none of the flavors have rules to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLORules = fmt.Errorf("0 SLO rules generated on all the flavors")
+)
+
+// ChronosphereFlavor is the flavor of the Chronosphere collections, recording rules and monitors.
+const ChronosphereFlavor = string(chronosphere.Flavor)
+
+type MultiFlavorRepoConfig struct {
+	// PrometheusConfig is the configuration used to store the Prometheus flavors (the writer
+	// and the flavor will be set by the repository for each target).
+	PrometheusConfig prometheus.IOWriterGroupedRulesYAMLRepoConfig
+	// ChronosphereConfig is the configuration used to serialize the Chronosphere flavor (the
+	// writer is not used, the monitors are stored with the rest of the objects).
+	ChronosphereConfig chronosphere.IOWriterGroupedRulesYAMLRepoConfig
+	Logger             log.Logger
+}
+
+func (c *MultiFlavorRepoConfig) defaults() error {
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.MultiFlavor"})
+
+	if c.PrometheusConfig.Logger == nil {
+		c.PrometheusConfig.Logger = c.Logger
+	}
+	if c.ChronosphereConfig.Logger == nil {
+		c.ChronosphereConfig.Logger = c.Logger
+	}
+	if c.ChronosphereConfig.MonitorsWriter != nil {
+		return fmt.Errorf("chronosphere monitors writer is not supported")
+	}
+	c.ChronosphereConfig.Writer = io.Discard
+
+	return nil
+}
+
+// MultiFlavorRepo knows to store the same SLO rules in multiple output flavors, each of
+// them on its own writer.
+type MultiFlavorRepo struct {
+	promConfig prometheus.IOWriterGroupedRulesYAMLRepoConfig
+	// serializers are the flavors serializers with custom configuration, the rest of the
+	// flavors use the registered ones.
+	serializers map[prometheus.OutputFlavor]prometheus.RuleSerializer
+	logger      log.Logger
+}
+
+func NewMultiFlavorRepo(config MultiFlavorRepoConfig) (*MultiFlavorRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	chronoRepo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(config.ChronosphereConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: could not create Chronosphere storage: %w", err)
+	}
+
+	return &MultiFlavorRepo{
+		promConfig:  config.PrometheusConfig,
+		serializers: map[prometheus.OutputFlavor]prometheus.RuleSerializer{chronosphere.Flavor: chronoRepo},
+		logger:      config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO   prometheus.SLO
+	Rules prometheus.SLORules
+	// Interval is the evaluation interval of the SLO rules, if not set each flavor
+	// default will be used.
+	Interval time.Duration
+	// QueryOffset is the offset the SLO rule groups will use to query the data (used
+	// with the Prometheus flavors).
+	QueryOffset time.Duration
+	// AlertAnnotationTemplates are the Go templates of the annotations that will be set on
+	// the SLO alert rules (used with the Prometheus flavors).
+	AlertAnnotationTemplates map[string]string
+	// GroupLimits are the limits of the SLO rule groups (used with the Prometheus flavors).
+	GroupLimits prometheus.GroupLimits
+	// ExtraLabels are the labels that will be added to all the SLO rules, on conflicts these
+	// take precedence over the flavors extra labels.
+	ExtraLabels map[string]string
+	// SourceTenants are the tenants the SLO rule groups will query as federated rule
+	// groups (used with Mimir and Cortex flavors).
+	SourceTenants prometheus.GroupSourceTenants
+}
+
+// Target is the output of a flavor.
+type Target struct {
+	// Flavor is any of the registered output flavors (including `chronosphere`).
+	Flavor string
+	Writer io.Writer
+}
+
+// StoreSLOs will store the SLO rules on each of the targets with the target flavor. The flavors
+// without rules will be ignored, if none of the flavors have rules, it will return ErrNoSLORules.
+func (m MultiFlavorRepo) StoreSLOs(ctx context.Context, slos []StorageSLO, targets []Target) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("at least one target is required")
+	}
+
+	storageSLOs := make([]prometheus.StorageSLO, 0, len(slos))
+	for _, s := range slos {
+		storageSLOs = append(storageSLOs, prometheus.StorageSLO{
+			SLO:                      s.SLO,
+			Rules:                    s.Rules,
+			Interval:                 s.Interval,
+			QueryOffset:              s.QueryOffset,
+			AlertAnnotationTemplates: s.AlertAnnotationTemplates,
+			GroupLimits:              s.GroupLimits,
+			ExtraLabels:              s.ExtraLabels,
+			SourceTenants:            s.SourceTenants,
+		})
+	}
+
+	logger := m.logger.WithCtxValues(ctx)
+	stored := 0
+	for _, t := range targets {
+		err := m.store(ctx, storageSLOs, prometheus.OutputFlavor(t.Flavor), t.Writer)
+		if err != nil {
+			if errors.Is(err, prometheus.ErrNoSLORules) {
+				logger.Warningf("%q flavor doesn't have rules, ignoring", t.Flavor)
+				continue
+			}
+			return fmt.Errorf("could not store %q flavor SLOs: %w", t.Flavor, err)
+		}
+		stored++
+	}
+
+	if stored == 0 {
+		return ErrNoSLORules
+	}
+
+	return nil
+}
+
+func (m MultiFlavorRepo) store(ctx context.Context, slos []prometheus.StorageSLO, flavor prometheus.OutputFlavor, w io.Writer) error {
+	config := m.promConfig
+	config.Flavor = flavor
+	config.Writer = w
+	if s, ok := m.serializers[flavor]; ok {
+		config.Serializer = s
+	}
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(config)
+	if err != nil {
+		return fmt.Errorf("could not create storage: %w", err)
+	}
+
+	return repo.StoreSLOs(ctx, slos)
+}
@@ -0,0 +1,173 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sloth/internal/chronosphere"
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+	"github.com/slok/sloth/internal/storage"
+)
+
+func TestMultiFlavorRepoStore(t *testing.T) {
+	rules := prometheus.SLORules{
+		SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+		AlertRules: []rulefmt.Rule{
+			{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}},
+		},
+	}
+	alertsWithoutSeverity := prometheus.SLORules{
+		AlertRules: []rulefmt.Rule{{Alert: "testAlert", Expr: "test-expr"}},
+	}
+
+	tests := map[string]struct {
+		slos      []storage.StorageSLO
+		flavors   []string
+		expStored map[string]bool
+		expErr    error
+		expAnyErr bool
+	}{
+		"Having no targets should fail.": {
+			slos:      []storage.StorageSLO{{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}, Rules: rules}},
+			flavors:   []string{},
+			expAnyErr: true,
+		},
+
+		"Having an unknown flavor should fail.": {
+			slos:      []storage.StorageSLO{{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}, Rules: rules}},
+			flavors:   []string{"prometheus", "something"},
+			expAnyErr: true,
+		},
+
+		"Having multiple flavors should store the rules on each flavor.": {
+			slos:      []storage.StorageSLO{{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}, Rules: rules}},
+			flavors:   []string{"prometheus", "chronosphere", "thanos"},
+			expStored: map[string]bool{"prometheus": true, "chronosphere": true, "thanos": true},
+		},
+
+		"Having flavors without rules should ignore them and store the rest.": {
+			slos:      []storage.StorageSLO{{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}, Rules: alertsWithoutSeverity}},
+			flavors:   []string{"prometheus", "chronosphere"},
+			expStored: map[string]bool{"prometheus": true, "chronosphere": false},
+		},
+
+		"Having all the flavors without rules should fail.": {
+			slos:    []storage.StorageSLO{{SLO: prometheus.SLO{ID: "test1", Service: "svc1"}}},
+			flavors: []string{"prometheus", "chronosphere"},
+			expErr:  storage.ErrNoSLORules,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			repo, err := storage.NewMultiFlavorRepo(storage.MultiFlavorRepoConfig{Logger: log.Noop})
+			require.NoError(err)
+
+			outs := map[string]*bytes.Buffer{}
+			targets := []storage.Target{}
+			for _, f := range test.flavors {
+				outs[f] = &bytes.Buffer{}
+				targets = append(targets, storage.Target{Flavor: f, Writer: outs[f]})
+			}
+
+			err = repo.StoreSLOs(context.TODO(), test.slos, targets)
+			switch {
+			case test.expAnyErr:
+				assert.Error(err)
+				return
+			case test.expErr != nil:
+				assert.ErrorIs(err, test.expErr)
+				return
+			}
+			require.NoError(err)
+
+			// Every flavor should be the same as storing it on its own.
+			for flavor, stored := range test.expStored {
+				if !stored {
+					assert.Empty(outs[flavor].String(), flavor)
+					continue
+				}
+				assert.Equal(storeSingleFlavor(t, flavor, test.slos), outs[flavor].String(), flavor)
+			}
+		})
+	}
+}
+
+func TestMultiFlavorRepoStoreSLOOptions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	slos := []storage.StorageSLO{
+		{
+			SLO: prometheus.SLO{ID: "test1", Service: "svc1"},
+			Rules: prometheus.SLORules{
+				SLIErrorRecRules: []rulefmt.Rule{{Record: "test:record", Expr: "test-expr"}},
+				AlertRules: []rulefmt.Rule{
+					{Alert: "testAlert", Expr: "test-expr", Labels: map[string]string{"sloth_severity": "page"}},
+				},
+			},
+			QueryOffset:              time.Minute,
+			AlertAnnotationTemplates: map[string]string{"runbook": "https://runbooks.io/{{ .ID }}"},
+			GroupLimits:              prometheus.GroupLimits{Alerts: 5},
+			ExtraLabels:              map[string]string{"team": "team-a"},
+		},
+	}
+
+	repo, err := storage.NewMultiFlavorRepo(storage.MultiFlavorRepoConfig{Logger: log.Noop})
+	require.NoError(err)
+	var promOut, chronoOut, mimirOut bytes.Buffer
+	err = repo.StoreSLOs(context.TODO(), slos, []storage.Target{
+		{Flavor: "prometheus", Writer: &promOut},
+		{Flavor: storage.ChronosphereFlavor, Writer: &chronoOut},
+	})
+	require.NoError(err)
+
+	// Source tenants are only supported by the federated rule groups flavors.
+	slos[0].SourceTenants = prometheus.GroupSourceTenants{SLIRecordings: []string{"tenant-a"}}
+	err = repo.StoreSLOs(context.TODO(), slos, []storage.Target{{Flavor: "mimir", Writer: &mimirOut}})
+	require.NoError(err)
+
+	// The per SLO options should not be lost on any of the flavors.
+	assert.Contains(promOut.String(), "  query_offset: 1m\n")
+	assert.Contains(promOut.String(), "  limit: 5\n")
+	assert.Contains(promOut.String(), "      team: team-a\n")
+	assert.Contains(promOut.String(), "      runbook: https://runbooks.io/test1\n")
+	assert.Contains(mimirOut.String(), "  source_tenants:\n  - tenant-a\n")
+	assert.Contains(chronoOut.String(), "team-a")
+}
+
+func storeSingleFlavor(t *testing.T, flavor string, slos []storage.StorageSLO) string {
+	var b bytes.Buffer
+	if flavor == storage.ChronosphereFlavor {
+		repo, err := chronosphere.NewIOWriterGroupedRulesYAMLRepo(chronosphere.IOWriterGroupedRulesYAMLRepoConfig{Writer: &b})
+		require.NoError(t, err)
+		storageSLOs := []chronosphere.StorageSLO{}
+		for _, s := range slos {
+			storageSLOs = append(storageSLOs, chronosphere.StorageSLO{SLO: s.SLO, Rules: s.Rules})
+		}
+		require.NoError(t, repo.StoreSLOs(context.TODO(), storageSLOs))
+		return b.String()
+	}
+
+	repo, err := prometheus.NewIOWriterGroupedRulesYAMLRepo(prometheus.IOWriterGroupedRulesYAMLRepoConfig{
+		Writer: &b,
+		Flavor: prometheus.OutputFlavor(flavor),
+	})
+	require.NoError(t, err)
+	storageSLOs := []prometheus.StorageSLO{}
+	for _, s := range slos {
+		storageSLOs = append(storageSLOs, prometheus.StorageSLO{SLO: s.SLO, Rules: s.Rules})
+	}
+	require.NoError(t, repo.StoreSLOs(context.TODO(), storageSLOs))
+	return b.String()
+}
@@ -0,0 +1,178 @@
+package sysdig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+var (
+	// ErrNoSLOs will be used when there are no SLOs to store. The upper layer
+	// could ignore or handle the error in cases where there wasn't an output.
+	ErrNoSLOs = fmt.Errorf("0 Sysdig SLOs generated")
+)
+
+const (
+	defaultMetricQueryLabel = "sysdig_metric_query"
+
+	slothIDTag      = "sloth_id"
+	slothServiceTag = "sloth_service"
+	slothSLOTag     = "sloth_slo"
+
+	metricTypeErrorRatio = "errorRatio"
+
+	day = 24 * time.Hour
+)
+
+type IOWriterJSONRepoConfig struct {
+	Writer io.Writer
+	Logger log.Logger
+	// MetricQueryLabel is the SLO label that has the Sysdig PromQL query of the SLI error
+	// ratio (e.g: `sum(rate(http_errors[5m])) / sum(rate(http_requests[5m]))`), by default
+	// `sysdig_metric_query`.
+	MetricQueryLabel string
+}
+
+func (c *IOWriterJSONRepoConfig) defaults() error {
+	if c.Writer == nil {
+		return fmt.Errorf("writer is required")
+	}
+
+	if c.MetricQueryLabel == "" {
+		c.MetricQueryLabel = defaultMetricQueryLabel
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "json", "flavor": "sysdig"})
+
+	return nil
+}
+
+// IOWriterJSONRepo knows to store the SLOs in an IOWriter as a JSON document with the Sysdig
+// Monitor SLO definitions. Sysdig SLOs are based on a single SLI metric query, so the query is
+// taken from the SLO labels instead of the SLI (that can have multiple queries and templates).
+type IOWriterJSONRepo struct {
+	writer         io.Writer
+	metricQueryLbl string
+	logger         log.Logger
+}
+
+func NewIOWriterJSONRepo(config IOWriterJSONRepoConfig) (*IOWriterJSONRepo, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &IOWriterJSONRepo{
+		writer:         config.Writer,
+		metricQueryLbl: config.MetricQueryLabel,
+		logger:         config.Logger,
+	}, nil
+}
+
+type StorageSLO struct {
+	SLO prometheus.SLO
+}
+
+// StoreSLOs will store the SLOs as Sysdig Monitor SLO definitions.
+func (i IOWriterJSONRepo) StoreSLOs(ctx context.Context, slos []StorageSLO) error {
+	if len(slos) == 0 {
+		return ErrNoSLOs
+	}
+
+	doc := sysdigJSON{SLOs: []sloJSON{}}
+	for _, slo := range slos {
+		s, err := i.mapModelToSLO(slo.SLO)
+		if err != nil {
+			return fmt.Errorf("could not map %q SLO to Sysdig: %w", slo.SLO.ID, err)
+		}
+		doc.SLOs = append(doc.SLOs, *s)
+	}
+
+	// Don't escape the HTML characters, the queries could have comparison operators.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("could not format SLOs: %w", err)
+	}
+
+	_, err = i.writer.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write SLOs: %w", err)
+	}
+
+	logger := i.logger.WithCtxValues(ctx)
+	logger.WithValues(log.Kv{"slos": len(doc.SLOs)}).Infof("Sysdig SLOs written")
+
+	return nil
+}
+
+func (i IOWriterJSONRepo) mapModelToSLO(slo prometheus.SLO) (*sloJSON, error) {
+	query := slo.Labels[i.metricQueryLbl]
+	if query == "" {
+		return nil, fmt.Errorf("missing Sysdig metric query, %q label is required", i.metricQueryLbl)
+	}
+
+	if slo.TimeWindow < day || slo.TimeWindow%day != 0 {
+		return nil, fmt.Errorf("unsupported %s time window, Sysdig only supports days", slo.TimeWindow)
+	}
+
+	// The query is not a tag.
+	tags := map[string]string{
+		slothIDTag:      slo.ID,
+		slothServiceTag: slo.Service,
+		slothSLOTag:     slo.Name,
+	}
+	for k, v := range slo.Labels {
+		if k == i.metricQueryLbl {
+			continue
+		}
+		tags[k] = v
+	}
+
+	return &sloJSON{
+		Name:        slo.Name,
+		Description: slo.Description,
+		Metric: sloMetricJSON{
+			Type:  metricTypeErrorRatio,
+			Query: query,
+		},
+		Target:     slo.Objective,
+		TimeWindow: sloTimeWindowJSON{Days: int(slo.TimeWindow / day)},
+		Tags:       tags,
+	}, nil
+}
+
+type sysdigJSON struct {
+	SLOs []sloJSON `json:"slos"`
+}
+
+// sloJSON is the Sysdig Monitor SLO definition.
+type sloJSON struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Metric      sloMetricJSON     `json:"metric"`
+	Target      float64           `json:"target"`
+	TimeWindow  sloTimeWindowJSON `json:"timeWindow"`
+	Tags        map[string]string `json:"tags"`
+}
+
+type sloMetricJSON struct {
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type sloTimeWindowJSON struct {
+	Days int `json:"days"`
+}
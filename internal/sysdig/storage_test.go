@@ -0,0 +1,174 @@
+package sysdig_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sloth/internal/log"
+	"github.com/slok/sloth/internal/prometheus"
+	"github.com/slok/sloth/internal/sysdig"
+)
+
+func TestIOWriterJSONRepoStore(t *testing.T) {
+	tests := map[string]struct {
+		config  sysdig.IOWriterJSONRepoConfig
+		slos    []sysdig.StorageSLO
+		expJSON string
+		expErr  bool
+	}{
+		"Having 0 SLOs should fail.": {
+			slos:   []sysdig.StorageSLO{},
+			expErr: true,
+		},
+
+		"Having an SLO without the Sysdig metric query should fail.": {
+			slos: []sysdig.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 30 * 24 * time.Hour,
+					Labels:     map[string]string{"owner": "team-a"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having an SLO with a time window not supported by Sysdig should fail.": {
+			slos: []sysdig.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					TimeWindow: 36 * time.Hour,
+					Labels:     map[string]string{"sysdig_metric_query": "sum(rate(errors[5m])) / sum(rate(total[5m]))"},
+				}},
+			},
+			expErr: true,
+		},
+
+		"Having SLOs should render the Sysdig SLOs.": {
+			slos: []sysdig.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:          "svc1-slo1",
+					Name:        "slo1",
+					Description: "Test SLO 1.",
+					Service:     "svc1",
+					TimeWindow:  30 * 24 * time.Hour,
+					Objective:   99.9,
+					Labels: map[string]string{
+						"owner":               "team-a",
+						"sysdig_metric_query": `sum(rate(http_requests{code=~"5.."}[5m])) / sum(rate(http_requests[5m]))`,
+					},
+				}},
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo2",
+					Name:       "slo2",
+					Service:    "svc1",
+					TimeWindow: 7 * 24 * time.Hour,
+					Objective:  95,
+					Labels: map[string]string{
+						"sysdig_metric_query": "sum(rate(errors[5m])) / sum(rate(total[5m]))",
+					},
+				}},
+			},
+			expJSON: `{
+  "slos": [
+    {
+      "name": "slo1",
+      "description": "Test SLO 1.",
+      "metric": {
+        "type": "errorRatio",
+        "query": "sum(rate(http_requests{code=~\"5..\"}[5m])) / sum(rate(http_requests[5m]))"
+      },
+      "target": 99.9,
+      "timeWindow": {
+        "days": 30
+      },
+      "tags": {
+        "owner": "team-a",
+        "sloth_id": "svc1-slo1",
+        "sloth_service": "svc1",
+        "sloth_slo": "slo1"
+      }
+    },
+    {
+      "name": "slo2",
+      "metric": {
+        "type": "errorRatio",
+        "query": "sum(rate(errors[5m])) / sum(rate(total[5m]))"
+      },
+      "target": 95,
+      "timeWindow": {
+        "days": 7
+      },
+      "tags": {
+        "sloth_id": "svc1-slo2",
+        "sloth_service": "svc1",
+        "sloth_slo": "slo2"
+      }
+    }
+  ]
+}
+`,
+		},
+
+		"Having a custom metric query label should use it to get the query.": {
+			config: sysdig.IOWriterJSONRepoConfig{
+				MetricQueryLabel: "sysdig_query",
+			},
+			slos: []sysdig.StorageSLO{
+				{SLO: prometheus.SLO{
+					ID:         "svc1-slo1",
+					Name:       "slo1",
+					Service:    "svc1",
+					TimeWindow: 28 * 24 * time.Hour,
+					Objective:  99.5,
+					Labels:     map[string]string{"sysdig_query": "test-query"},
+				}},
+			},
+			expJSON: `{
+  "slos": [
+    {
+      "name": "slo1",
+      "metric": {
+        "type": "errorRatio",
+        "query": "test-query"
+      },
+      "target": 99.5,
+      "timeWindow": {
+        "days": 28
+      },
+      "tags": {
+        "sloth_id": "svc1-slo1",
+        "sloth_service": "svc1",
+        "sloth_slo": "slo1"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotJSON bytes.Buffer
+			test.config.Writer = &gotJSON
+			test.config.Logger = log.Noop
+			repo, err := sysdig.NewIOWriterJSONRepo(test.config)
+			if !assert.NoError(err) {
+				return
+			}
+			err = repo.StoreSLOs(context.TODO(), test.slos)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expJSON, gotJSON.String())
+			}
+		})
+	}
+}
@@ -158,4 +158,8 @@ type Alert struct {
 	Labels map[string]string `yaml:"labels,omitempty"`
 	// Annotations are the Prometheus annotations for the specific alert.
 	Annotations map[string]string `yaml:"annotations,omitempty"`
+	// KeepFiringFor is the Prometheus duration (e.g: `10m`) the alert will keep firing
+	// after the alert condition has been resolved, useful to reduce flapping (requires
+	// Prometheus >=2.42).
+	KeepFiringFor string `yaml:"keep_firing_for,omitempty"`
 }